@@ -0,0 +1,381 @@
+// Package client is the Go client of the REST API of the in-memory storage.
+// A Client is safe for concurrent use and keeps a pool of connections to the server, so a single one
+// should be shared by a service. Requests failing with 429 Too Many Requests or a 5xx status are retried
+// with an exponential backoff, honoring the Retry-After header of the server.
+// Errors are mapped back to the errors of the storage, e.g. errors.Is(err, client.ErrKeyNotFound).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entity is a key along with its value, as returned by List.
+type Entity = domain.Entity
+
+// Change is a change of a key, as streamed by Watch.
+type Change = domain.Change
+
+// ChangeType is the kind of a change, the Type of a Change is one of the constants below.
+type ChangeType = domain.ChangeType
+
+const (
+	// ChangeSet means the key was created or replaced, the Entity of the change holds its new state.
+	ChangeSet = domain.ChangeSet
+	// ChangeDelete means the key was deleted.
+	ChangeDelete = domain.ChangeDelete
+	// ChangeExpire means the key was removed after its ttl elapsed.
+	ChangeExpire = domain.ChangeExpire
+	// ChangeEvict means the key was removed to free memory.
+	ChangeEvict = domain.ChangeEvict
+)
+
+// The errors of the storage the problems of the server are mapped back to, compare them with errors.Is.
+var (
+	ErrKeyNotFound     = domain.ErrKeyNotFound
+	ErrKeyExpired      = domain.ErrKeyExpired
+	ErrWrongType       = domain.ErrWrongType
+	ErrConditionNotMet = domain.ErrConditionNotMet
+	ErrOutOfMemory     = domain.ErrOutOfMemory
+	// ErrRevisionCompacted is returned by Watch when the changes to resume from are no longer kept by the server,
+	// the keys must be read again and watched from now.
+	ErrRevisionCompacted = errors.New("revision compacted")
+	// ErrInvalidTTL is returned by Set for a negative ttl.
+	ErrInvalidTTL = errors.New("ttl can not be negative")
+)
+
+// codeErrors are the errors of the codes of the problems.
+var codeErrors = map[string]error{
+	"key_not_found":      ErrKeyNotFound,
+	"key_expired":        ErrKeyExpired,
+	"wrong_type":         ErrWrongType,
+	"condition_not_met":  ErrConditionNotMet,
+	"out_of_memory":      ErrOutOfMemory,
+	"revision_compacted": ErrRevisionCompacted,
+}
+
+const (
+	// DefaultRetries is the number of times a request is retried by default.
+	DefaultRetries = 3
+	// DefaultMinBackoff is the wait before the first retry by default, doubled for every following one.
+	DefaultMinBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff is the maximum wait between two attempts by default, Retry-After included.
+	DefaultMaxBackoff = 5 * time.Second
+	// DefaultMaxIdleConns is the number of idle connections kept to the server by default.
+	DefaultMaxIdleConns = 64
+)
+
+const (
+	// listCount is the count of the pages of List.
+	listCount = 1000
+	// maxDiscard is the size of the body read before closing it, a connection with more left is not reused.
+	maxDiscard = 64 << 10
+)
+
+// Error is a failed request, it wraps the error of the storage matching its code, if any.
+type Error struct {
+	// StatusCode is the HTTP status of the reply.
+	StatusCode int
+	// Code is the stable code of the problem, e.g. key_not_found.
+	Code string
+	// Detail is the message of the problem.
+	Detail string
+	// RequestID is the id of the request in the logs of the server.
+	RequestID string
+
+	err error
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("storage: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("storage: %d %s: %s", e.StatusCode, e.Code, e.Detail)
+}
+
+// Unwrap returns the error of the storage matching the code, nil if none.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Client is a client of the REST API, create it with New.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithHTTPClient makes the Client send its requests with httpClient, e.g. to configure TLS.
+// httpClient must not have a Timeout, which would end the streams of Watch; the requests are bounded by their contexts.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets the number of times a failed request is retried, 0 disabling the retries.
+func WithRetries(retries int) Option {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled for every following one, and the maximum wait.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff, c.maxBackoff = min, max
+	}
+}
+
+// New returns a new Client of the server at baseURL, e.g. http://localhost:8080.
+// By default it keeps up to DefaultMaxIdleConns idle connections to the server and retries a failed request
+// DefaultRetries times.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("storage: invalid base url %q, expected http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		retries:    DefaultRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		// The default transport keeps only 2 idle connections per host, which a busy service exhausts
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConns = DefaultMaxIdleConns
+		transport.MaxIdleConnsPerHost = DefaultMaxIdleConns
+		c.httpClient = &http.Client{Transport: transport}
+	}
+	return c, nil
+}
+
+// Get returns the value of a string key.
+// It returns ErrKeyNotFound when the key does not exist and ErrKeyExpired when it has just expired.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	res, err := c.do(ctx, http.MethodGet, "/get", url.Values{"key": {key}}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	value, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// Set sets the value of a string key. A ttl of 0 keeps the key until it is deleted,
+// a positive one is rounded up to whole seconds.
+func (c *Client) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if ttl < 0 {
+		return ErrInvalidTTL
+	}
+	body, err := json.Marshal(struct {
+		Key        string `json:"key"`
+		Value      string `json:"value"`
+		Expiration int64  `json:"expiration,omitempty"`
+	}{Key: key, Value: value, Expiration: int64((ttl + time.Second - 1) / time.Second)})
+	if err != nil {
+		return err
+	}
+	res, err := c.do(ctx, http.MethodPost, "/set", nil, body)
+	if err != nil {
+		return err
+	}
+	discard(res)
+	return nil
+}
+
+// Delete deletes a key of any type, it returns ErrKeyNotFound when the key does not exist.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	res, err := c.do(ctx, http.MethodDelete, "/delete", url.Values{"key": {key}}, nil)
+	if err != nil {
+		return err
+	}
+	discard(res)
+	return nil
+}
+
+// List returns the keys matching the glob pattern, e.g. user:*, along with their values; an empty pattern
+// matches every key. It pages through the keys, so a key created or deleted meanwhile may be returned or not.
+func (c *Client) List(ctx context.Context, match string) ([]Entity, error) {
+	entities := []Entity{}
+	cursor := uint64(0)
+	for {
+		query := url.Values{
+			"cursor": {strconv.FormatUint(cursor, 10)},
+			"count":  {strconv.Itoa(listCount)},
+			"values": {"true"},
+		}
+		if match != "" {
+			query.Set("match", match)
+		}
+		res, err := c.do(ctx, http.MethodGet, "/keys", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Cursor   uint64   `json:"cursor"`
+			Entities []Entity `json:"entities"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, page.Entities...)
+		if cursor = page.Cursor; cursor == 0 {
+			return entities, nil
+		}
+	}
+}
+
+// do sends a request, retrying it on 429 and 5xx replies, and returns the successful reply.
+// A reply with an error status is returned as an *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	return c.send(ctx, func() (*http.Request, error) {
+		u := *c.baseURL
+		u.Path += path
+		u.RawQuery = query.Encode()
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
+}
+
+// send sends the requests made by newRequest until one succeeds, fails with a status that is not retried,
+// or the retries are exhausted. A new request is made for every attempt, so its body can be read again.
+func (c *Client) send(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode < http.StatusBadRequest {
+			return res, nil
+		}
+
+		err = newError(res)
+		if !retryable(res.StatusCode) || attempt >= c.retries {
+			return nil, err
+		}
+		timer := time.NewTimer(c.backoff(attempt, res.Header.Get("Retry-After")))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait before the retry following the attempt, starting at 0: the Retry-After of the server
+// when it is given, an exponential backoff with jitter otherwise, both capped by the maximum backoff.
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	if wait, ok := parseRetryAfter(retryAfter, time.Now()); ok {
+		if wait > c.maxBackoff {
+			return c.maxBackoff
+		}
+		return wait
+	}
+	wait := c.minBackoff
+	for i := 0; i < attempt && wait < c.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > c.maxBackoff {
+		wait = c.maxBackoff
+	}
+	// Half of the wait is random, so the clients limited at once do not retry at once
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// parseRetryAfter parses a Retry-After header, either seconds or an HTTP date, into the wait from now.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := at.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// retryable reports whether a request failed with the status may succeed when sent again.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// newError reads the problem of a failed reply into an *Error and closes the body.
+func newError(res *http.Response) error {
+	defer discard(res)
+	e := &Error{StatusCode: res.StatusCode, RequestID: res.Header.Get("X-Request-ID")}
+	var problem struct {
+		Detail    string `json:"detail"`
+		Code      string `json:"code"`
+		RequestID string `json:"request_id"`
+	}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/problem+json") &&
+		json.NewDecoder(res.Body).Decode(&problem) == nil {
+		e.Code, e.Detail = problem.Code, problem.Detail
+		if problem.RequestID != "" {
+			e.RequestID = problem.RequestID
+		}
+	}
+	e.err = codeErrors[e.Code]
+	return e
+}
+
+// discard reads the rest of a short body and closes it, so the connection goes back to the pool.
+func discard(res *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxDiscard))
+	_ = res.Body.Close()
+}
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/api"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer returns a server with all the routes of the API over a new storage, like the one of cmd/main.go.
+func newServer(t *testing.T) (*httptest.Server, domain.Repository) {
+	t.Helper()
+	stor := storage.NewInMemory()
+	bus := events.NewBus(1024)
+	stor.OnChange(bus.Publish)
+
+	router := api.NewRouter()
+	router.Use(api.RequestIDMiddleware)
+	api.RegisterRoutes(router,
+		api.NewHandlers(stor),
+		api.NewAdminHandlers(nil, nil),
+		api.NewWatchHandlers(bus),
+		api.NewPubSubHandlers(pubsub.NewBroker(16, pubsub.Disconnect)))
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		srv.CloseClientConnections()
+		srv.Close()
+		bus.Close()
+	})
+	return srv, stor
+}
+
+// newClient returns a client of the server, retrying with short waits.
+func newClient(t *testing.T, srv *httptest.Server, opts ...Option) *Client {
+	t.Helper()
+	c, err := New(srv.URL, append([]Option{WithBackoff(time.Millisecond, 5*time.Millisecond)}, opts...)...)
+	require.NoError(t, err)
+	return c
+}
+
+// writeProblem replies like the server does on an error.
+func writeProblem(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `{"title":%q,"status":%d,"detail":"failed","code":%q,"request_id":"req-1"}`,
+		http.StatusText(status), status, code)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New("http://localhost:8080/")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", c.baseURL.String())
+	assert.Equal(t, DefaultRetries, c.retries)
+	transport := c.httpClient.Transport.(*http.Transport)
+	assert.Equal(t, DefaultMaxIdleConns, transport.MaxIdleConnsPerHost)
+
+	_, err = New("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestClient_SetGetDelete(t *testing.T) {
+	srv, stor := newServer(t)
+	c := newClient(t, srv)
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "name", "John Doe", 0))
+	value, err := c.Get(ctx, "name")
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", value)
+	ttl, err := stor.TTL("name")
+	require.NoError(t, err)
+	assert.Equal(t, domain.NoExpiration, ttl)
+
+	require.NoError(t, c.Set(ctx, "session", "abc", 1500*time.Millisecond))
+	ttl, err = stor.TTL("session")
+	require.NoError(t, err)
+	assert.InDelta(t, 2*time.Second, ttl, float64(time.Second))
+
+	assert.ErrorIs(t, c.Set(ctx, "session", "abc", -time.Second), ErrInvalidTTL)
+
+	require.NoError(t, c.Delete(ctx, "name"))
+	_, err = c.Get(ctx, "name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	var e *Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusNotFound, e.StatusCode)
+	assert.Equal(t, "key_not_found", e.Code)
+	assert.NotEmpty(t, e.RequestID)
+
+	assert.ErrorIs(t, c.Delete(ctx, "name"), ErrKeyNotFound)
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   string
+		want   error
+	}{
+		{"expired key", http.StatusGone, "key_expired", ErrKeyExpired},
+		{"wrong type", http.StatusConflict, "wrong_type", ErrWrongType},
+		{"unknown code", http.StatusBadRequest, "key_required", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeProblem(w, tt.status, tt.code)
+			}))
+			defer srv.Close()
+
+			_, err := newClient(t, srv).Get(context.Background(), "key")
+			var e *Error
+			require.ErrorAs(t, err, &e)
+			assert.Equal(t, Error{StatusCode: tt.status, Code: tt.code, Detail: "failed", RequestID: "req-1", err: tt.want}, *e)
+			if tt.want != nil {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	srv, stor := newServer(t)
+	c := newClient(t, srv)
+	ctx := context.Background()
+
+	entities, err := c.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, entities)
+
+	// More keys than a page, so List follows the cursor
+	for i := 0; i < listCount+500; i++ {
+		require.NoError(t, stor.Set(fmt.Sprintf("user:%d", i), "v", 0))
+	}
+	require.NoError(t, stor.Set("order:1", "v", 0))
+
+	entities, err = c.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, entities, listCount+501)
+
+	entities, err = c.List(ctx, "order:*")
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	assert.Equal(t, "order:1", entities[0].Key)
+	assert.Equal(t, "v", entities[0].Value)
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		status   int
+		retries  int
+		wantErr  error
+		wantCall int32
+	}{
+		{"succeeds after 5xx", 2, http.StatusServiceUnavailable, 3, nil, 3},
+		{"succeeds after 429", 1, http.StatusTooManyRequests, 3, nil, 2},
+		{"gives up after the retries", 10, http.StatusInternalServerError, 2, &Error{}, 3},
+		{"retries disabled", 10, http.StatusServiceUnavailable, 0, &Error{}, 1},
+		{"4xx is not retried", 10, http.StatusNotFound, 3, ErrKeyNotFound, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					if tt.status == http.StatusTooManyRequests {
+						w.Header().Set("Retry-After", "0")
+					}
+					code := map[int]string{http.StatusNotFound: "key_not_found"}[tt.status]
+					writeProblem(w, tt.status, code)
+					return
+				}
+				_, _ = w.Write([]byte("value"))
+			}))
+			defer srv.Close()
+
+			value, err := newClient(t, srv, WithRetries(tt.retries)).Get(context.Background(), "key")
+			assert.Equal(t, tt.wantCall, calls.Load())
+			switch want := tt.wantErr.(type) {
+			case nil:
+				require.NoError(t, err)
+				assert.Equal(t, "value", value)
+			case *Error:
+				var e *Error
+				require.ErrorAs(t, err, &e)
+				assert.Equal(t, tt.status, e.StatusCode)
+			default:
+				assert.ErrorIs(t, err, want)
+			}
+		})
+	}
+
+	t.Run("the context ends the wait", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			writeProblem(w, http.StatusTooManyRequests, "rate_limited")
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		c, err := New(srv.URL, WithBackoff(time.Millisecond, time.Minute))
+		require.NoError(t, err)
+		start := time.Now()
+		err = c.Set(ctx, "key", "value", 0)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestClient_backoff(t *testing.T) {
+	c := &Client{minBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		min, max   time.Duration
+	}{
+		{"first retry", 0, "", 50 * time.Millisecond, 100 * time.Millisecond},
+		{"doubled", 2, "", 200 * time.Millisecond, 400 * time.Millisecond},
+		{"capped", 10, "", 500 * time.Millisecond, time.Second},
+		{"retry after", 0, "1", time.Second, time.Second},
+		{"retry after capped", 0, "30", time.Second, time.Second},
+		{"invalid retry after", 0, "soon", 50 * time.Millisecond, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait := c.backoff(tt.attempt, tt.retryAfter)
+			assert.GreaterOrEqual(t, wait, tt.min)
+			assert.LessOrEqual(t, wait, tt.max)
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "3", 3 * time.Second, true},
+		{"negative seconds", "-3", 0, false},
+		{"date", now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second, true},
+		{"past date", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"invalid", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watcher receives the changes of the keys starting with a prefix, create it with Client.Watch.
+type Watcher struct {
+	changes chan Change
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// Changes returns the channel of the changes, in the order they were made.
+// It is closed when the watcher ends, Err telling why.
+func (w *Watcher) Changes() <-chan Change {
+	return w.changes
+}
+
+// Err returns why the watcher ended once Changes is closed: ErrRevisionCompacted when it fell too far behind to resume,
+// the error of the context passed to Watch, or the last error once the retries were exhausted.
+// It returns nil after Close.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close ends the watcher and waits for Changes to be closed.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.cancel()
+	<-w.done
+}
+
+// Watch streams the changes of the keys starting with the prefix, an empty prefix watching every key.
+// The changes made after Watch returns are received. When the stream is interrupted, e.g. by a restart of a proxy
+// or because the watcher fell behind, it resumes from the last received change, so no change is missed or repeated,
+// retrying with a backoff like the other requests. The watcher ends when the context is done or on Close.
+func (c *Client) Watch(ctx context.Context, prefix string) (*Watcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	res, err := c.watch(ctx, prefix, "")
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	w := &Watcher{changes: make(chan Change), cancel: cancel, done: make(chan struct{})}
+	go c.run(ctx, w, prefix, res)
+	return w, nil
+}
+
+// run reads the streams of the watcher until it ends, reconnecting after the last received revision.
+func (c *Client) run(ctx context.Context, w *Watcher, prefix string, res *http.Response) {
+	defer close(w.done)
+	defer close(w.changes)
+
+	var revision string
+	failures := 0
+	for {
+		progressed, err := readEvents(ctx, res.Body, &revision, w.changes)
+		res.Body.Close()
+		if progressed {
+			failures = 0
+		}
+		for {
+			if ctx.Err() != nil {
+				w.end(ctx.Err())
+				return
+			}
+			if failures > c.retries || errors.Is(err, ErrRevisionCompacted) {
+				w.end(err)
+				return
+			}
+			if failures > 0 {
+				timer := time.NewTimer(c.backoff(failures-1, ""))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					continue
+				case <-timer.C:
+				}
+			}
+			failures++
+			if res, err = c.watch(ctx, prefix, revision); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// end records why the watcher ended, nothing when it was closed.
+func (w *Watcher) end(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.err = err
+	}
+}
+
+// watch opens a stream of the changes after the revision, from now when it is empty.
+func (c *Client) watch(ctx context.Context, prefix, revision string) (*http.Response, error) {
+	return c.send(ctx, func() (*http.Request, error) {
+		u := *c.baseURL
+		u.Path += "/watch"
+		if prefix != "" {
+			u.RawQuery = url.Values{"prefix": {prefix}}.Encode()
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if revision != "" {
+			req.Header.Set("Last-Event-ID", revision)
+		}
+		return req, nil
+	})
+}
+
+// readEvents sends the changes of a stream of Server-Sent Events to changes until it ends,
+// keeping the id of the last event in revision. It reports whether an event was read.
+func readEvents(ctx context.Context, body io.Reader, revision *string, changes chan<- Change) (bool, error) {
+	reader := bufio.NewReader(body)
+	progressed := false
+	var id, event string
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return progressed, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			// A blank line dispatches the event
+			if id != "" {
+				*revision = id
+				progressed = true
+			}
+			if event == "error" {
+				// The watcher fell behind, the stream ends and is resumed
+				var message string
+				_ = json.Unmarshal(data, &message)
+				return progressed, errors.New("storage: watch: " + message)
+			}
+			if event != "" {
+				var change Change
+				if err := json.Unmarshal(data, &change); err != nil {
+					return progressed, err
+				}
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return progressed, ctx.Err()
+				}
+			}
+			id, event, data = "", "", nil
+		case strings.HasPrefix(line, ":"):
+			// A comment, e.g. a heartbeat
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "id":
+				// The id is opaque, the server resumes after it; an id with a NUL is ignored as in browsers
+				if !strings.ContainsRune(value, 0) {
+					id = value
+				}
+			case "event":
+				event = value
+			case "data":
+				if data != nil {
+					data = append(data, '\n')
+				}
+				data = append(data, value...)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receive returns the next change of the watcher.
+func receive(t *testing.T, w *Watcher) Change {
+	t.Helper()
+	select {
+	case c, ok := <-w.Changes():
+		require.True(t, ok, "the watcher ended: %v", w.Err())
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change received")
+		return Change{}
+	}
+}
+
+// waitEnd waits for the changes of the watcher to be closed.
+func waitEnd(t *testing.T, w *Watcher) {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-w.Changes():
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the watcher did not end")
+		}
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	srv, stor := newServer(t)
+	c := newClient(t, srv)
+
+	w, err := c.Watch(context.Background(), "user:")
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, stor.Set("order:1", "ignored", 0))
+	require.NoError(t, stor.Set("user:1", "John", 0))
+	require.NoError(t, stor.Delete("user:1"))
+
+	change := receive(t, w)
+	assert.Equal(t, ChangeSet, change.Type)
+	assert.Equal(t, "user:1", change.Key)
+	assert.Equal(t, "John", change.Entity.Value)
+	assert.Equal(t, ChangeDelete, receive(t, w).Type)
+
+	t.Run("resumes after the connection is lost", func(t *testing.T) {
+		srv.CloseClientConnections()
+		require.NoError(t, stor.Set("user:2", "Jane", 0))
+		require.NoError(t, stor.Set("user:3", "Joe", 0))
+
+		assert.Equal(t, "user:2", receive(t, w).Key)
+		assert.Equal(t, "user:3", receive(t, w).Key)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		w.Close()
+		waitEnd(t, w)
+		assert.NoError(t, w.Err())
+	})
+}
+
+func TestClient_Watch_end(t *testing.T) {
+	t.Run("the context ends the watcher", func(t *testing.T) {
+		srv, _ := newServer(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		w, err := newClient(t, srv).Watch(ctx, "")
+		require.NoError(t, err)
+
+		cancel()
+		waitEnd(t, w)
+		assert.ErrorIs(t, w.Err(), context.Canceled)
+	})
+
+	t.Run("a compacted revision ends the watcher", func(t *testing.T) {
+		var calls atomic.Int32
+		var resumedFrom atomic.Value
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write([]byte("id: e-6\n\nid: e-7\nevent: set\ndata: {\"type\":\"set\",\"key\":\"k\"}\n\n"))
+				return
+			}
+			resumedFrom.Store(r.Header.Get("Last-Event-ID"))
+			writeProblem(w, http.StatusGone, "revision_compacted")
+		}))
+		defer srv.Close()
+
+		w, err := newClient(t, srv).Watch(context.Background(), "")
+		require.NoError(t, err)
+		assert.Equal(t, "k", receive(t, w).Key)
+		waitEnd(t, w)
+		assert.ErrorIs(t, w.Err(), ErrRevisionCompacted)
+		assert.Equal(t, "e-7", resumedFrom.Load(), "the watcher resumes after the last received id")
+		w.Close()
+	})
+
+	t.Run("the first connection is not retried forever", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeProblem(w, http.StatusServiceUnavailable, "")
+		}))
+		defer srv.Close()
+
+		_, err := newClient(t, srv, WithRetries(1)).Watch(context.Background(), "")
+		var e *Error
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, http.StatusServiceUnavailable, e.StatusCode)
+	})
+}
@@ -5,19 +5,79 @@ import (
 	"errors"
 	"github.com/gynshu-one/in-memory-storage/internal/api"
 	"github.com/gynshu-one/in-memory-storage/internal/config"
+	"github.com/gynshu-one/in-memory-storage/internal/grpcapi"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
 	ratelimiter "github.com/gynshu-one/in-memory-storage/internal/infra/limit"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/persistence"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
 	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/gynshu-one/in-memory-storage/internal/memcache"
+	"github.com/gynshu-one/in-memory-storage/internal/resp"
+	"google.golang.org/grpc"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
 	// Init repo and rate limiter
 	NewLimiter := ratelimiter.NewRateLimiter()
-	repo := storage.NewInMemory()
+	repo := storage.NewSharded(config.GetConf().StorageShards)
+
+	// Replay the append-only file and persist the following changes to it.
+	// The append-only file is the most complete copy of the data, so snapshots are loaded only without it.
+	var aof *persistence.AOF
+	var aofRewriter api.AOFRewriter
+	if path := config.GetConf().AOFPath; path != "" {
+		replayed, err := persistence.Replay(path, repo)
+		if err != nil {
+			log.Fatalf("failed to replay AOF: %v", err)
+		}
+		log.Printf("Replayed %d changes from %s\n", replayed, path)
+
+		aof, err = persistence.OpenAOF(path, persistence.FsyncPolicy(config.GetConf().AOFFsync))
+		if err != nil {
+			log.Fatalf("failed to open AOF: %v", err)
+		}
+		aof.EnableRewrite(repo, config.GetConf().AOFRewritePercentage, config.GetConf().AOFRewriteMinSize)
+		repo.OnChange(aof.Append)
+		aofRewriter = aof
+	}
+
+	// Load the newest snapshot and save new ones on schedule
+	var snapshots *persistence.Snapshots
+	var snapshotSaver api.SnapshotSaver
+	if dir := config.GetConf().SnapshotDir; dir != "" {
+		if aof == nil {
+			path, restored, err := persistence.LoadLatestSnapshot(dir, repo)
+			switch {
+			case errors.Is(err, persistence.ErrNoSnapshot):
+				log.Printf("No snapshot found in %s\n", dir)
+			case err != nil:
+				log.Fatalf("failed to load snapshot: %v", err)
+			default:
+				log.Printf("Restored %d keys from %s\n", restored, path)
+			}
+		}
+
+		var err error
+		snapshots, err = persistence.NewSnapshots(dir, config.GetConf().SnapshotInterval, config.GetConf().SnapshotRetain, repo)
+		if err != nil {
+			log.Fatalf("failed to init snapshots: %v", err)
+		}
+		snapshotSaver = snapshots
+	}
+
+	// Fan the changes out to the watchers
+	bus := events.NewBus(config.GetConf().WatchHistory)
+	repo.OnChange(bus.Publish)
+
+	// Route the messages of the publish/subscribe channels
+	broker := pubsub.NewBroker(config.GetConf().PubSubBuffer, pubsub.SlowConsumerPolicy(config.GetConf().PubSubSlowConsumer))
 
 	Rlm := api.RateLimiterMiddleware(NewLimiter)
 
@@ -26,23 +86,34 @@ func main() {
 
 	// Create a new handlers
 	hands := api.NewHandlers(repo)
+	admin := api.NewAdminHandlers(snapshotSaver, aofRewriter)
+	watch := api.NewWatchHandlers(bus)
+	messaging := api.NewPubSubHandlers(broker)
 
 	// Add the middlewares to the router
+	router.Use(api.RequestIDMiddleware)
 	router.Use(api.LoggingMiddleware)
 	router.Use(Rlm)
 
 	// Add the routes to the router
-	router.Post("/set", hands.Set)
-	router.Delete("/delete", hands.Delete)
-	router.Get("/get", hands.Get)
-	router.Get("/all", hands.GetAll)
+	api.RegisterRoutes(router, hands, admin, watch, messaging)
+
+	// The requests blocked in BLPOP and BRPOP wait on the base context, it is canceled once shutting down
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 
 	// Init the server
 	srv := &http.Server{
 		Addr:        ":" + config.GetConf().ServerPort,
 		Handler:     router,
 		ReadTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
+	// Release the blocked pops, Shutdown waits for them otherwise
+	srv.RegisterOnShutdown(cancelBase)
+	// End the watch streams once shutting down, Shutdown waits for them otherwise
+	srv.RegisterOnShutdown(bus.Close)
+	// WebSocket connections are not tracked by Shutdown, closing the broker ends them
+	srv.RegisterOnShutdown(broker.Close)
 
 	// Start the server in a separate goroutine
 	go func() {
@@ -52,9 +123,49 @@ func main() {
 		}
 	}()
 
+	// Serve Redis clients side by side with the HTTP server
+	var respSrv *resp.Server
+	if port := config.GetConf().RESPPort; port != "" {
+		respSrv = resp.NewServer(":"+port, repo, broker, NewLimiter)
+		go func() {
+			log.Printf("RESP server listening on :%s\n", port)
+			if err := respSrv.ListenAndServe(); err != nil && !errors.Is(err, resp.ErrServerClosed) {
+				log.Fatalf("listen RESP: %s\n", err)
+			}
+		}()
+	}
+
+	// Serve gRPC clients side by side with the HTTP server
+	var grpcSrv *grpc.Server
+	if port := config.GetConf().GRPCPort; port != "" {
+		lis, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			log.Fatalf("listen gRPC: %s\n", err)
+		}
+		grpcSrv = grpcapi.NewServer(grpcapi.NewService(repo, bus), NewLimiter)
+		go func() {
+			log.Printf("gRPC server listening on %s\n", lis.Addr())
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("serve gRPC: %s\n", err)
+			}
+		}()
+	}
+
+	// Serve memcached clients side by side with the HTTP server
+	var memcacheSrv *memcache.Server
+	if port := config.GetConf().MemcachedPort; port != "" {
+		memcacheSrv = memcache.NewServer(":"+port, repo, NewLimiter)
+		go func() {
+			log.Printf("Memcached server listening on :%s\n", port)
+			if err := memcacheSrv.ListenAndServe(); err != nil && !errors.Is(err, memcache.ErrServerClosed) {
+				log.Fatalf("listen memcached: %s\n", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 	log.Println("shutting down server...")
 
@@ -64,6 +175,44 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("failed to shutdown server: %v", err)
 	}
+	if respSrv != nil {
+		if err := respSrv.Shutdown(ctx); err != nil {
+			log.Fatalf("failed to shutdown RESP server: %v", err)
+		}
+	}
+	if memcacheSrv != nil {
+		if err := memcacheSrv.Shutdown(ctx); err != nil {
+			log.Fatalf("failed to shutdown memcached server: %v", err)
+		}
+	}
+
+	// The watch streams were ended when the HTTP server started shutting down
+	if grpcSrv != nil {
+		if err := grpcapi.Shutdown(ctx, grpcSrv); err != nil {
+			log.Fatalf("failed to shutdown gRPC server: %v", err)
+		}
+	}
+
+	// Save the final snapshot once no more requests are served
+	if snapshots != nil {
+		_ = snapshots.Close()
+		path, err := snapshots.Save()
+		if err != nil {
+			log.Fatalf("failed to save snapshot: %v", err)
+		}
+		log.Printf("Snapshot saved to %s\n", path)
+	}
+
+	// Stop the expiration sweeper
+	if err := repo.Close(); err != nil {
+		log.Fatalf("failed to close storage: %v", err)
+	}
+
+	if aof != nil {
+		if err := aof.Close(); err != nil {
+			log.Fatalf("failed to close AOF: %v", err)
+		}
+	}
 
 	log.Println("server shutdown successfully")
 }
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"github.com/rs/zerolog/log"
+	"net/http"
+)
+
+const (
+	SnapshotSaved     = "Snapshot saved to "
+	AOFRewritten      = "AOF rewritten"
+	PersistenceNotSet = "Persistence is not configured"
+)
+
+// SnapshotSaver saves a snapshot of the storage and returns its location.
+type SnapshotSaver interface {
+	Save() (string, error)
+}
+
+// AOFRewriter compacts the append-only file.
+type AOFRewriter interface {
+	Rewrite() error
+}
+
+// AdminHandlers serves the administrative endpoints.
+type AdminHandlers struct {
+	Snapshots SnapshotSaver
+	AOF       AOFRewriter
+}
+
+// NewAdminHandlers returns a new instance of AdminHandlers.
+// Either of the arguments may be nil when the corresponding persistence is disabled.
+func NewAdminHandlers(snapshots SnapshotSaver, aof AOFRewriter) *AdminHandlers {
+	return &AdminHandlers{Snapshots: snapshots, AOF: aof}
+}
+
+// Snapshot saves a snapshot of the storage on demand.
+func (h *AdminHandlers) Snapshot(w http.ResponseWriter, r *http.Request) {
+	if h.Snapshots == nil {
+		writeError(w, PersistenceNotSet, http.StatusNotImplemented)
+		return
+	}
+
+	path, err := h.Snapshots.Save()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(SnapshotSaved + path))
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+		return
+	}
+}
+
+// RewriteAOF compacts the append-only file on demand.
+func (h *AdminHandlers) RewriteAOF(w http.ResponseWriter, r *http.Request) {
+	if h.AOF == nil {
+		writeError(w, PersistenceNotSet, http.StatusNotImplemented)
+		return
+	}
+
+	if err := h.AOF.Rewrite(); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(AOFRewritten))
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+		return
+	}
+}
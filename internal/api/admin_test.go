@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSnapshots struct {
+	err error
+}
+
+func (f fakeSnapshots) Save() (string, error) {
+	return "/data/snapshot.snap", f.err
+}
+
+type fakeAOF struct {
+	err error
+}
+
+func (f fakeAOF) Rewrite() error {
+	return f.err
+}
+
+func TestAdminHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		handlers   *AdminHandlers
+		handler    func(h *AdminHandlers) http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Snapshot returns 200 OK with the snapshot path",
+			handlers:   NewAdminHandlers(fakeSnapshots{}, nil),
+			handler:    func(h *AdminHandlers) http.HandlerFunc { return h.Snapshot },
+			wantStatus: http.StatusOK,
+			wantBody:   SnapshotSaved + "/data/snapshot.snap",
+		},
+		{
+			name:       "Snapshot returns 500 Internal Server Error when saving fails",
+			handlers:   NewAdminHandlers(fakeSnapshots{err: errors.New("disk full")}, nil),
+			handler:    func(h *AdminHandlers) http.HandlerFunc { return h.Snapshot },
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "disk full",
+		},
+		{
+			name:       "Snapshot returns 501 Not Implemented without snapshots",
+			handlers:   NewAdminHandlers(nil, nil),
+			handler:    func(h *AdminHandlers) http.HandlerFunc { return h.Snapshot },
+			wantStatus: http.StatusNotImplemented,
+			wantBody:   PersistenceNotSet,
+		},
+		{
+			name:       "RewriteAOF returns 200 OK",
+			handlers:   NewAdminHandlers(nil, fakeAOF{}),
+			handler:    func(h *AdminHandlers) http.HandlerFunc { return h.RewriteAOF },
+			wantStatus: http.StatusOK,
+			wantBody:   AOFRewritten,
+		},
+		{
+			name:       "RewriteAOF returns 501 Not Implemented without AOF",
+			handlers:   NewAdminHandlers(nil, nil),
+			handler:    func(h *AdminHandlers) http.HandlerFunc { return h.RewriteAOF },
+			wantStatus: http.StatusNotImplemented,
+			wantBody:   PersistenceNotSet,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/admin", nil)
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler(tt.handlers).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+}
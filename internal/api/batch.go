@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"net/http"
+	"time"
+)
+
+// MaxBatchSize is the maximum number of keys of a batch request.
+const MaxBatchSize = 1000
+
+const (
+	KeysCanNotBeEmpty = "Keys can not be empty"
+	TooManyKeys       = "Too many keys, a batch is limited to 1000"
+)
+
+// batchRequest is the body of the batch requests, keys for MGet and MDelete, items for MSet.
+type batchRequest struct {
+	Keys  []string `json:"keys"`
+	Items []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+		// Expiration is in seconds like in Set.
+		Expiration int64 `json:"expiration"`
+	} `json:"items"`
+}
+
+// keyResult is the outcome of a key of a batch request.
+// Status is the one the single key request would reply with.
+type keyResult struct {
+	Key     string  `json:"key"`
+	Status  int     `json:"status"`
+	Value   *string `json:"value,omitempty"`
+	Version uint64  `json:"version,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	// Code is the code of the error, like in the Problem of the single key request.
+	Code string `json:"code,omitempty"`
+}
+
+// MGet returns the values of several keys in a single request, counting as a single request for the rate limiter.
+// Body example:
+//
+//	{
+//	  "keys": ["key1", "key2"]
+//	}
+//
+// It replies with the result of every key in order, its status being the one of GET /get:
+// {"results": [{"key": "key1", "status": 200, "value": "value1", "version": 3},
+// {"key": "key2", "status": 404, "error": "key not found", "code": "key_not_found"}]}
+func (h *Handlers) MGet(w http.ResponseWriter, r *http.Request) {
+	keys, ok := decodeBatchKeys(w, r)
+	if !ok {
+		return
+	}
+
+	results := h.UseCase.MGet(keys)
+	resp := make([]keyResult, len(results))
+	for idx, result := range results {
+		resp[idx] = newKeyResult(result, http.StatusOK)
+		if result.Err == nil {
+			value := result.Value
+			resp[idx].Value = &value
+		}
+	}
+	writeJSON(w, map[string][]keyResult{"results": resp})
+}
+
+// MSet sets several key-value pairs in a single request, each with its own expiration.
+// Body example:
+//
+//	{
+//	  "items": [
+//	    {"key": "key1", "value": "value1", "expiration": 60},
+//	    {"key": "key2", "value": "value2"}
+//	  ]
+//	}
+//
+// It replies with the result of every item in order, its status being the one of POST /set,
+// along with the new version of the key: {"results": [{"key": "key1", "status": 201, "version": 4}, ...]}.
+// An item over the memory limit has the status 507 and is not written, the others are.
+func (h *Handlers) MSet(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if !checkBatchSize(w, len(req.Items)) {
+		return
+	}
+
+	items := make([]domain.SetItem, len(req.Items))
+	for idx, item := range req.Items {
+		switch {
+		case item.Key == "":
+			writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+			return
+		case item.Value == "":
+			writeError(w, ValueCanNotBeEmpty, http.StatusBadRequest)
+			return
+		case item.Expiration < 0:
+			writeError(w, InvalidDuration, http.StatusBadRequest)
+			return
+		}
+		items[idx] = domain.SetItem{Key: item.Key, Value: item.Value, TTL: time.Duration(item.Expiration) * time.Second}
+	}
+
+	results := h.UseCase.MSet(items)
+	resp := make([]keyResult, len(results))
+	for idx, result := range results {
+		resp[idx] = newKeyResult(result, http.StatusCreated)
+	}
+	writeJSON(w, map[string][]keyResult{"results": resp})
+}
+
+// MDelete deletes several keys in a single request.
+// Body example:
+//
+//	{
+//	  "keys": ["key1", "key2"]
+//	}
+//
+// It replies with the result of every key in order, its status being the one of DELETE /delete:
+// {"results": [{"key": "key1", "status": 200}, {"key": "key2", "status": 404, "error": "key not found", "code": "key_not_found"}]}
+func (h *Handlers) MDelete(w http.ResponseWriter, r *http.Request) {
+	keys, ok := decodeBatchKeys(w, r)
+	if !ok {
+		return
+	}
+
+	results := h.UseCase.MDelete(keys)
+	resp := make([]keyResult, len(results))
+	for idx, result := range results {
+		resp[idx] = newKeyResult(result, http.StatusOK)
+	}
+	writeJSON(w, map[string][]keyResult{"results": resp})
+}
+
+// newKeyResult converts the result of a key, ok being its status when it succeeded.
+func newKeyResult(result domain.KeyResult, ok int) keyResult {
+	if result.Err != nil {
+		return keyResult{Key: result.Key, Status: errorStatus(result.Err), Error: result.Err.Error(),
+			Code: errorCode(result.Err)}
+	}
+	return keyResult{Key: result.Key, Status: ok, Version: result.Version}
+}
+
+// decodeBatchKeys decodes the keys of MGet and MDelete.
+// It replies with 400 Bad Request and reports false when the body is invalid, a key is empty or there are too many.
+func decodeBatchKeys(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return nil, false
+	}
+	if !checkBatchSize(w, len(req.Keys)) {
+		return nil, false
+	}
+	for _, key := range req.Keys {
+		if key == "" {
+			writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return req.Keys, true
+}
+
+// checkBatchSize replies with 400 Bad Request and reports false when a batch is empty or larger than MaxBatchSize.
+func checkBatchSize(w http.ResponseWriter, n int) bool {
+	switch {
+	case n == 0:
+		writeError(w, KeysCanNotBeEmpty, http.StatusBadRequest)
+		return false
+	case n > MaxBatchSize:
+		writeError(w, TooManyKeys, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers_Batch(t *testing.T) {
+	stor := storage.NewInMemory()
+	version, err := stor.SetWith("key1", "value1", 0, domain.SetOptions{})
+	assert.NoError(t, err)
+	_, _ = stor.HSet("hash", map[string]string{"f": "v"})
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "MGet returns the status of every key",
+			body:       `{"keys":["key1","missing","hash"]}`,
+			handler:    h.MGet,
+			wantStatus: http.StatusOK,
+			wantBody: `{"results":[{"key":"key1","status":200,"value":"value1","version":` + strconv.FormatUint(version, 10) + `},` +
+				`{"key":"missing","status":404,"error":"key not found","code":"key_not_found"},` +
+				`{"key":"hash","status":409,"error":"` + domain.ErrWrongType.Error() + `","code":"wrong_type"}]}`,
+		},
+		{
+			name:       "MSet sets every item",
+			body:       `{"items":[{"key":"key2","value":"value2","expiration":60},{"key":"key3","value":"value3"}]}`,
+			handler:    h.MSet,
+			wantStatus: http.StatusOK,
+			wantBody: `{"results":[{"key":"key2","status":201,"version":` + strconv.FormatUint(version+2, 10) + `},` +
+				`{"key":"key3","status":201,"version":` + strconv.FormatUint(version+3, 10) + `}]}`,
+		},
+		{
+			name:       "MSet returns 400 Bad Request for an item without a value",
+			body:       `{"items":[{"key":"key4","value":"value4"},{"key":"key5"}]}`,
+			handler:    h.MSet,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   ValueCanNotBeEmpty,
+		},
+		{
+			name:       "MDelete returns the status of every key",
+			body:       `{"keys":["key1","key4"]}`,
+			handler:    h.MDelete,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"results":[{"key":"key1","status":200},{"key":"key4","status":404,"error":"key not found","code":"key_not_found"}]}`,
+		},
+		{
+			name:       "MGet returns 400 Bad Request without keys",
+			body:       `{"keys":[]}`,
+			handler:    h.MGet,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   KeysCanNotBeEmpty,
+		},
+		{
+			name:       "MDelete returns 400 Bad Request for an empty key",
+			body:       `{"keys":["key2",""]}`,
+			handler:    h.MDelete,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   KeyCanNotBeEmpty,
+		},
+		{
+			name:       "MGet returns 400 Bad Request for too many keys",
+			body:       `{"keys":["k"` + strings.Repeat(`,"k"`, MaxBatchSize) + `]}`,
+			handler:    h.MGet,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   TooManyKeys,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/mget", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+
+	ttl, err := stor.TTL("key2")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Second, "every item has its own expiration")
+	_, err = stor.Get("key4")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "an invalid batch writes nothing")
+}
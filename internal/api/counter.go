@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// InvalidDelta is replied when the delta of a counter write is missing or is not a number of the right kind.
+const InvalidDelta = "Invalid delta"
+
+// counterRequest is the body of the counter writes.
+// delta is kept raw so an integer counter can refuse a float delta rather than truncating it.
+type counterRequest struct {
+	Key   string          `json:"key"`
+	Delta json.RawMessage `json:"delta"`
+}
+
+// Incr adds 1 to the integer value of a key, a missing key counts as 0 and the expiration is kept.
+// Body example:
+//
+//	{
+//	  "key": "visits"
+//	}
+//
+// It replies with the new value, or 400 Bad Request when the value is not an integer or would overflow.
+func (h *Handlers) Incr(w http.ResponseWriter, r *http.Request) {
+	if req, ok := decodeCounterRequest(w, r); ok {
+		h.incrBy(w, req.Key, 1)
+	}
+}
+
+// Decr subtracts 1 from the integer value of a key, like Incr.
+func (h *Handlers) Decr(w http.ResponseWriter, r *http.Request) {
+	if req, ok := decodeCounterRequest(w, r); ok {
+		h.incrBy(w, req.Key, -1)
+	}
+}
+
+// IncrBy adds delta to the integer value of a key, like Incr. A negative delta decrements it.
+// Body example:
+//
+//	{
+//	  "key": "visits",
+//	  "delta": 10
+//	}
+func (h *Handlers) IncrBy(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCounterRequest(w, r)
+	if !ok {
+		return
+	}
+	delta, err := strconv.ParseInt(string(req.Delta), 10, 64)
+	if err != nil {
+		writeError(w, InvalidDelta, http.StatusBadRequest)
+		return
+	}
+	h.incrBy(w, req.Key, delta)
+}
+
+// IncrByFloat adds delta to the float value of a key, a missing key counts as 0 and the expiration is kept.
+// The value is stored without an exponent, e.g. 5000 rather than 5e3.
+// Body example:
+//
+//	{
+//	  "key": "balance",
+//	  "delta": 10.5
+//	}
+//
+// It replies with the new value, or 400 Bad Request when the value is not a float.
+func (h *Handlers) IncrByFloat(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCounterRequest(w, r)
+	if !ok {
+		return
+	}
+	delta, err := strconv.ParseFloat(string(req.Delta), 64)
+	if err != nil {
+		writeError(w, InvalidDelta, http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.UseCase.IncrByFloat(req.Key, delta)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.FormatFloat(n, 'f', -1, 64))
+}
+
+// incrBy adds delta to the integer value of the key and replies with the new value.
+func (h *Handlers) incrBy(w http.ResponseWriter, key string, delta int64) {
+	n, err := h.UseCase.IncrBy(key, delta)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.FormatInt(n, 10))
+}
+
+// decodeCounterRequest decodes the body of a counter write.
+// It replies with 400 Bad Request and reports false when the body is invalid or the key is empty.
+func decodeCounterRequest(w http.ResponseWriter, r *http.Request) (counterRequest, bool) {
+	var req counterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return req, false
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
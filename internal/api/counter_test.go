@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers_Counters(t *testing.T) {
+	stor := storage.NewInMemory()
+	_ = stor.Set("text", "abc", 0)
+	_ = stor.Set("max", "9223372036854775807", 0)
+	_ = stor.Set("price", "10", time.Hour)
+	_, _ = stor.HSet("hash", map[string]string{"f": "1"})
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Incr creates a missing key at 1",
+			body:       `{"key":"visits"}`,
+			handler:    h.Incr,
+			wantStatus: http.StatusOK,
+			wantBody:   "1",
+		},
+		{
+			name:       "IncrBy adds the delta",
+			body:       `{"key":"visits","delta":10}`,
+			handler:    h.IncrBy,
+			wantStatus: http.StatusOK,
+			wantBody:   "11",
+		},
+		{
+			name:       "Decr subtracts 1",
+			body:       `{"key":"visits"}`,
+			handler:    h.Decr,
+			wantStatus: http.StatusOK,
+			wantBody:   "10",
+		},
+		{
+			name:       "IncrBy returns 400 Bad Request for a float delta",
+			body:       `{"key":"visits","delta":1.5}`,
+			handler:    h.IncrBy,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidDelta,
+		},
+		{
+			name:       "IncrBy returns 400 Bad Request without a key",
+			body:       `{"delta":1}`,
+			handler:    h.IncrBy,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   KeyCanNotBeEmpty,
+		},
+		{
+			name:       "Incr returns 400 Bad Request for a non-integer value",
+			body:       `{"key":"text"}`,
+			handler:    h.Incr,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   domain.ErrNotInteger.Error(),
+		},
+		{
+			name:       "Incr returns 400 Bad Request on overflow",
+			body:       `{"key":"max"}`,
+			handler:    h.Incr,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   domain.ErrOverflow.Error(),
+		},
+		{
+			name:       "Incr returns 409 Conflict for a hash",
+			body:       `{"key":"hash"}`,
+			handler:    h.Incr,
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+		{
+			name:       "IncrByFloat adds the delta",
+			body:       `{"key":"price","delta":0.25}`,
+			handler:    h.IncrByFloat,
+			wantStatus: http.StatusOK,
+			wantBody:   "10.25",
+		},
+		{
+			name:       "IncrByFloat returns 400 Bad Request for a non-float value",
+			body:       `{"key":"text","delta":1}`,
+			handler:    h.IncrByFloat,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   domain.ErrNotFloat.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/incr", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+
+	ttl, err := stor.TTL("price")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute, "the expiration is kept")
+}
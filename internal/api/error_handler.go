@@ -1,9 +1,12 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/rs/zerolog/log"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -14,19 +17,146 @@ const (
 	KeyDeletedSuccessfully   = "Key deleted successfully"
 	FailToWriteResponse      = "Failed to write response"
 	InvalidDuration          = "Invalid duration"
+	InvalidPrecondition      = "Invalid If-Match or If-None-Match header"
+	SlidingWithoutExpiration = "Sliding expiration requires an expiration"
+	RouteNotFound            = "Route not found"
+	MethodNotAllowed         = "Method not allowed"
+	TooManyRequests          = "Too many requests"
 )
 
+// problemContentType is the media type of the error replies.
+const problemContentType = "application/problem+json"
+
+// Problem is the body of every error reply, an RFC 7807 problem details object.
+// Type is always about:blank, Code tells the errors apart.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Code is a stable machine-readable code of the error, e.g. key_not_found or key_required.
+	Code string `json:"code"`
+	// RequestID is the id of the request, see RequestIDMiddleware, to find it in the logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// domainErrors are the statuses and the codes of the domain errors, the first one the error is matching wins.
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrKeyNotFound, http.StatusNotFound, "key_not_found"},
+	{domain.ErrKeyExpired, http.StatusGone, "key_expired"},
+	{domain.ErrStorageEmpty, http.StatusNotFound, "storage_empty"},
+	{domain.ErrFieldNotFound, http.StatusNotFound, "field_not_found"},
+	{domain.ErrIndexOutOfRange, http.StatusNotFound, "index_out_of_range"},
+	{domain.ErrMemberNotFound, http.StatusNotFound, "member_not_found"},
+	{domain.ErrOutOfMemory, http.StatusInsufficientStorage, "out_of_memory"},
+	{domain.ErrConditionNotMet, http.StatusPreconditionFailed, "condition_not_met"},
+	{domain.ErrTxnAborted, http.StatusPreconditionFailed, "txn_aborted"},
+	{domain.ErrWrongType, http.StatusConflict, "wrong_type"},
+	{domain.ErrNotInteger, http.StatusBadRequest, "not_integer"},
+	{domain.ErrOverflow, http.StatusBadRequest, "overflow"},
+	{domain.ErrNotFloat, http.StatusBadRequest, "not_float"},
+	{domain.ErrInvalidOp, http.StatusBadRequest, "invalid_op"},
+	{domain.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{domain.ErrTimeout, http.StatusRequestTimeout, "timeout"},
+}
+
+// messageCodes are the codes of the messages of the validation failures.
+// A message without a code gets the one of its status, e.g. bad_request.
+var messageCodes = map[string]string{
+	UnableToParseRequestBody: "invalid_body",
+	ValueCanNotBeEmpty:       "value_required",
+	KeyCanNotBeEmpty:         "key_required",
+	InvalidDuration:          "invalid_duration",
+	InvalidPrecondition:      "invalid_precondition",
+	SlidingWithoutExpiration: "sliding_without_expiration",
+	RouteNotFound:            "route_not_found",
+	MethodNotAllowed:         "method_not_allowed",
+	TooManyRequests:          "rate_limited",
+	PersistenceNotSet:        "persistence_not_configured",
+	KeysCanNotBeEmpty:        "keys_required",
+	TooManyKeys:              "too_many_keys",
+	InvalidDelta:             "invalid_delta",
+	FieldCanNotBeEmpty:       "field_required",
+	FieldsCanNotBeEmpty:      "fields_required",
+	InvalidType:              "invalid_type",
+	ValuesCanNotBeEmpty:      "values_required",
+	InvalidIndex:             "invalid_index",
+	InvalidTimeout:           "invalid_timeout",
+	ChannelCanNotBeEmpty:     "channel_required",
+	ChannelsCanNotBeEmpty:    "channels_required",
+	PatternsCanNotBeEmpty:    "patterns_required",
+	UnknownOperation:         "unknown_operation",
+	ServerShuttingDown:       "shutting_down",
+	MemberCanNotBeEmpty:      "member_required",
+	MembersCanNotBeEmpty:     "members_required",
+	InvalidExpiration:        "invalid_expiration",
+	ExpirationOutOfRange:     "invalid_expiration",
+	ExpirationRequired:       "expiration_required",
+	AtRequired:               "at_required",
+	OperationsCanNotBeEmpty:  "operations_required",
+	InvalidContentType:       "invalid_content_type",
+	ValueTooLarge:            "value_too_large",
+	NothingToPatch:           "nothing_to_patch",
+	InvalidRevision:          "invalid_revision",
+	RevisionCompacted:        "revision_compacted",
+	StreamingNotSupported:    "streaming_not_supported",
+	InvalidScore:             "invalid_score",
+	InvalidOffset:            "invalid_offset",
+	InvalidCount:             "invalid_count",
+}
+
+// handleError replies with the error and the status and the code matching it, see errorStatus.
 func handleError(err error, w http.ResponseWriter) {
-	switch {
-	case errors.Is(err, domain.ErrKeyNotFound):
-		http.Error(w, err.Error(), http.StatusNoContent)
-		return
-	case errors.Is(err, domain.ErrKeyExpired):
-		http.Error(w, err.Error(), http.StatusGone)
-		return
-	case errors.Is(err, domain.ErrStorageEmpty):
-		http.Error(w, err.Error(), http.StatusNoContent)
-	default:
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	writeProblem(w, errorStatus(err), errorCode(err), err.Error())
+}
+
+// errorStatus returns the HTTP status reporting the error, 500 for an error that is not a domain error.
+func errorStatus(err error) int {
+	for _, e := range domainErrors {
+		if errors.Is(err, e.err) {
+			return e.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// errorCode returns the code of the error, internal_server_error for an error that is not a domain error.
+func errorCode(err error) string {
+	for _, e := range domainErrors {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return "internal_server_error"
+}
+
+// writeError replies to a failed validation with the message and the status, it is the http.Error of the package.
+func writeError(w http.ResponseWriter, message string, status int) {
+	code, ok := messageCodes[message]
+	if !ok {
+		code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+	writeProblem(w, status, code, message)
+}
+
+// writeProblem replies with a Problem, along with the id of the request set by RequestIDMiddleware.
+func writeProblem(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
 	}
 }
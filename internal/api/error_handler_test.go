@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replyText returns the detail of a problem reply, or the trimmed body of any other reply.
+func replyText(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+	if rr.Header().Get("Content-Type") != problemContentType {
+		return strings.TrimSpace(rr.Body.String())
+	}
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	return problem.Detail
+}
+
+func Test_handleError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "handleError replies 404 Not Found for a missing key",
+			err:        domain.ErrKeyNotFound,
+			wantStatus: http.StatusNotFound,
+			wantCode:   "key_not_found",
+		},
+		{
+			name:       "handleError replies 410 Gone for an expired key",
+			err:        domain.ErrKeyExpired,
+			wantStatus: http.StatusGone,
+			wantCode:   "key_expired",
+		},
+		{
+			name:       "handleError matches a wrapped domain error",
+			err:        fmt.Errorf("hget: %w", domain.ErrWrongType),
+			wantStatus: http.StatusConflict,
+			wantCode:   "wrong_type",
+		},
+		{
+			name:       "handleError replies 500 Internal Server Error for an unknown error",
+			err:        errors.New("disk full"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal_server_error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rr.Header().Set(RequestIDHeader, "req-1")
+			handleError(tt.err, rr)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, problemContentType, rr.Header().Get("Content-Type"))
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, Problem{
+				Type:      "about:blank",
+				Title:     http.StatusText(tt.wantStatus),
+				Status:    tt.wantStatus,
+				Detail:    tt.err.Error(),
+				Code:      tt.wantCode,
+				RequestID: "req-1",
+			}, problem)
+		})
+	}
+}
+
+func Test_domainErrorsHaveCodes(t *testing.T) {
+	codes := make(map[string]bool)
+	for _, e := range domainErrors {
+		assert.NotEqual(t, http.StatusInternalServerError, errorStatus(e.err), e.err)
+		assert.False(t, codes[e.code], "code %s is unique", e.code)
+		codes[e.code] = true
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := NewRouter()
+	router.Use(RequestIDMiddleware)
+	router.Get("/get", NewHandlers(storage.NewInMemory()).Get)
+
+	tests := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{
+			name:      "RequestIDMiddleware keeps the id of the request",
+			requestID: "3f2a-9c:1",
+			wantSame:  true,
+		},
+		{
+			name: "RequestIDMiddleware generates an id when there is none",
+		},
+		{
+			name:      "RequestIDMiddleware replaces an invalid id",
+			requestID: "bad id\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/get?key=missing", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			id := rr.Header().Get(RequestIDHeader)
+			if tt.wantSame {
+				assert.Equal(t, tt.requestID, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, "key_not_found", problem.Code)
+			assert.Equal(t, id, problem.RequestID)
+		})
+	}
+}
+
+// denyLimiter is a rate limiter denying every request.
+type denyLimiter struct{}
+
+func (denyLimiter) Limit(string)      {}
+func (denyLimiter) Check(string) bool { return false }
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	router := NewRouter()
+	router.Use(RateLimiterMiddleware(denyLimiter{}))
+	router.Get("/get", NewHandlers(storage.NewInMemory()).Get)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/get?key=name", nil))
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, "rate_limited", problem.Code)
+}
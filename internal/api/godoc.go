@@ -1,11 +1,71 @@
 // Package api provides the API for the in-memory storage.
 // Contains Middleware, Router and Handler.
 // It is used to handle the requests.
-// Implements the fallowing routes:
+// Implements the fallowing routes, registered by RegisterRoutes:
 /*
-	router.Post("/add", hands.Set)
-	router.Get("/delete/{key}", hands.Delete)
-	router.Get("/get/{key}", hands.Get)
+	router.Post("/set", hands.Set)
+	router.Delete("/delete", hands.Delete)
+	router.Get("/get", hands.Get)
 	router.Get("/all", hands.GetAll)
+	router.Get("/keys", hands.Keys)
+	router.Get("/keys/{key...}", hands.GetValue)
+	router.Put("/keys/{key...}", hands.PutValue)
+	router.Post("/mget", hands.MGet)
+	router.Post("/mset", hands.MSet)
+	router.Post("/mdelete", hands.MDelete)
+	router.Post("/incr", hands.Incr)
+	router.Post("/decr", hands.Decr)
+	router.Post("/incrby", hands.IncrBy)
+	router.Post("/incrbyfloat", hands.IncrByFloat)
+	router.Post("/txn", hands.Txn)
+	router.Post("/expire", hands.Expire)
+	router.Post("/expireat", hands.ExpireAt)
+	router.Post("/persist", hands.Persist)
+	router.Get("/ttl", hands.TTL)
+	router.Post("/getex", hands.GetEx)
+	router.Post("/hset", hands.HSet)
+	router.Get("/hget", hands.HGet)
+	router.Delete("/hdel", hands.HDel)
+	router.Get("/hgetall", hands.HGetAll)
+	router.Get("/hlen", hands.HLen)
+	router.Post("/hincrby", hands.HIncrBy)
+	router.Post("/lpush", hands.LPush)
+	router.Post("/rpush", hands.RPush)
+	router.Post("/lpop", hands.LPop)
+	router.Post("/rpop", hands.RPop)
+	router.Get("/lrange", hands.LRange)
+	router.Get("/llen", hands.LLen)
+	router.Post("/ltrim", hands.LTrim)
+	router.Get("/lindex", hands.LIndex)
+	router.Post("/blpop", hands.BLPop)
+	router.Post("/brpop", hands.BRPop)
+	router.Post("/sadd", hands.SAdd)
+	router.Delete("/srem", hands.SRem)
+	router.Get("/smembers", hands.SMembers)
+	router.Get("/sismember", hands.SIsMember)
+	router.Get("/sinter", hands.SInter)
+	router.Get("/sunion", hands.SUnion)
+	router.Get("/sdiff", hands.SDiff)
+	router.Post("/zadd", hands.ZAdd)
+	router.Post("/zincrby", hands.ZIncrBy)
+	router.Get("/zscore", hands.ZScore)
+	router.Get("/zrank", hands.ZRank)
+	router.Delete("/zrem", hands.ZRem)
+	router.Get("/zrange", hands.ZRange)
+	router.Get("/zrangebyscore", hands.ZRangeByScore)
+	router.Get("/zcount", hands.ZCount)
+	router.Get("/watch", watch.Watch)
+	router.Get("/pubsub", messaging.Subscribe)
+	router.Post("/publish", messaging.Publish)
+	router.Get("/pubsub/stats", messaging.Stats)
+	router.Post("/admin/snapshot", admin.Snapshot)
+	router.Post("/admin/rewrite-aof", admin.RewriteAOF)
+	router.Get("/v2/keys", hands.Keys)
+	router.Get("/v2/keys/{key...}", hands.GetValue)
+	router.Put("/v2/keys/{key...}", hands.PutValue)
+	router.Patch("/v2/keys/{key...}", hands.PatchValue)
+	router.Delete("/v2/keys/{key...}", hands.DeleteValue)
+	router.Get("/openapi.json", docs.OpenAPI)
+	router.Get("/docs", docs.Page)
 */
 package api
@@ -5,6 +5,8 @@ import (
 	"github.com/gynshu-one/in-memory-storage/internal/domain"
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,29 +29,49 @@ func NewHandlers(useCase domain.Repository) *Handlers {
 //	}
 //
 // expiration is optional and is in seconds
+// "sliding": true makes the expiration sliding: every read by Get pushes it back to expiration seconds from the read,
+// so the key expires after expiration seconds of inactivity, e.g. for a session.
+//
+// The write can be made conditional with the headers:
+// If-None-Match: * writes only a missing key, If-Match: * only an existing one,
+// and If-Match: "<version>" only a key still at the version, with the ETag of a previous reply.
+// When the condition is not met, it replies with 412 Precondition Failed.
+// The reply carries the new version of the key in the ETag header.
 func (h *Handlers) Set(w http.ResponseWriter, r *http.Request) {
+	opts, ok := setOptions(w, r)
+	if !ok {
+		return
+	}
+
 	var entity domain.Entity
 	err := json.NewDecoder(r.Body).Decode(&entity)
 	if err != nil {
-		http.Error(w, UnableToParseRequestBody, http.StatusBadRequest)
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
 		return
 	}
 
 	if entity.Value == "" {
-		http.Error(w, ValueCanNotBeEmpty, http.StatusBadRequest)
+		writeError(w, ValueCanNotBeEmpty, http.StatusBadRequest)
 		return
 	}
 
 	if entity.Expiration < 0 {
-		http.Error(w, InvalidDuration, http.StatusBadRequest)
+		writeError(w, InvalidDuration, http.StatusBadRequest)
 		return
 	}
 
-	err = h.UseCase.Set(entity.Key, entity.Value, time.Duration(entity.Expiration)*time.Second)
+	if entity.Sliding && entity.Expiration == 0 {
+		writeError(w, SlidingWithoutExpiration, http.StatusBadRequest)
+		return
+	}
+	opts.Sliding = entity.Sliding
+
+	version, err := h.UseCase.SetWith(entity.Key, entity.Value, time.Duration(entity.Expiration)*time.Second, opts)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleError(err, w)
 		return
 	}
+	w.Header().Set("ETag", etag(version))
 	w.WriteHeader(http.StatusCreated)
 	_, err = w.Write([]byte(KeyAddedSuccessfully))
 	if err != nil {
@@ -64,7 +86,7 @@ func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
 	key := r.URL.Query().Get("key")
 
 	if key == "" {
-		http.Error(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
 		return
 	}
 
@@ -83,21 +105,27 @@ func (h *Handlers) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get returns a value for a given key from the in-memory storage.
+// The version of the key is replied in the ETag header, to be sent back in If-Match by Set.
+// A value written by PUT /keys/{key} is replied with its Content-Type.
 func (h *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 	key := r.URL.Query().Get("key")
 
 	if key == "" {
-		http.Error(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
 		return
 	}
-	value, err := h.UseCase.Get(key)
+	entity, err := h.UseCase.GetEntity(key)
 	if err != nil {
 		handleError(err, w)
 		return
 	}
 
+	w.Header().Set("ETag", etag(entity.Version))
+	if entity.ContentType != "" {
+		w.Header().Set("Content-Type", entity.ContentType)
+	}
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte(value))
+	_, err = w.Write([]byte(entity.Value))
 	if err != nil {
 		log.Error().Err(err).Msg(FailToWriteResponse)
 		return
@@ -105,10 +133,12 @@ func (h *Handlers) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAll returns all keys from the in-memory storage.
+// The whole keyspace is serialized in a single reply, Keys pages through it instead.
+// It replies with 404 Not Found and the storage_empty code when the storage is empty.
 func (h *Handlers) GetAll(w http.ResponseWriter, r *http.Request) {
 	keys, err := h.UseCase.GetAll()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handleError(err, w)
 		return
 	}
 
@@ -119,3 +149,37 @@ func (h *Handlers) GetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// setOptions parses the If-None-Match and If-Match headers of a write.
+// It replies with 400 Bad Request and reports false when they are invalid or both are set.
+func setOptions(w http.ResponseWriter, r *http.Request) (domain.SetOptions, bool) {
+	noneMatch, match := r.Header.Get("If-None-Match"), r.Header.Get("If-Match")
+	switch {
+	case noneMatch == "" && match == "":
+		return domain.SetOptions{}, true
+	case noneMatch == "*" && match == "":
+		return domain.SetOptions{Mode: domain.SetIfAbsent}, true
+	case noneMatch == "" && match == "*":
+		return domain.SetOptions{Mode: domain.SetIfPresent}, true
+	case noneMatch == "":
+		if version, ok := parseETag(match); ok {
+			return domain.SetOptions{Mode: domain.SetIfVersion, Version: version}, true
+		}
+	}
+	writeError(w, InvalidPrecondition, http.StatusBadRequest)
+	return domain.SetOptions{}, false
+}
+
+// etag formats a version as a strong entity tag, e.g. "42".
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseETag parses an entity tag formatted by etag.
+func parseETag(tag string) (uint64, bool) {
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil
+}
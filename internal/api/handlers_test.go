@@ -17,6 +17,7 @@ func TestHandlers_Set(t *testing.T) {
 		key        string
 		value      string
 		expiration int64
+		sliding    bool
 	}
 	tests := []struct {
 		name       string
@@ -54,6 +55,27 @@ func TestHandlers_Set(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantBody:   InvalidDuration,
 		},
+		{
+			name: "Set returns 201 Created for a sliding expiration",
+			args: args{
+				key:        "key4",
+				value:      "value4",
+				expiration: 1800,
+				sliding:    true,
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   KeyAddedSuccessfully,
+		},
+		{
+			name: "Set returns 400 Bad Request for a sliding expiration without an expiration",
+			args: args{
+				key:     "key5",
+				value:   "value5",
+				sliding: true,
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   SlidingWithoutExpiration,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -63,6 +85,7 @@ func TestHandlers_Set(t *testing.T) {
 				Key:        tt.args.key,
 				Value:      tt.args.value,
 				Expiration: tt.args.expiration,
+				Sliding:    tt.args.sliding,
 			}
 			body, err := json.Marshal(entity)
 			assert.NoError(t, err)
@@ -75,9 +98,98 @@ func TestHandlers_Set(t *testing.T) {
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tt.wantStatus, rr.Code)
-			assert.Equal(t, tt.wantBody, strings.TrimSpace(rr.Body.String()))
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+}
+
+func TestHandlers_SetConditional(t *testing.T) {
+	stor := storage.NewInMemory()
+	version, err := stor.SetWith("key1", "value1", 0, domain.SetOptions{})
+	assert.NoError(t, err)
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		key        string
+		header     string
+		value      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "If-None-Match: * returns 412 Precondition Failed for an existing key",
+			key:        "key1",
+			header:     "If-None-Match: *",
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrConditionNotMet.Error(),
+		},
+		{
+			name:       "If-None-Match: * writes a missing key",
+			key:        "key2",
+			header:     "If-None-Match: *",
+			wantStatus: http.StatusCreated,
+			wantBody:   KeyAddedSuccessfully,
+		},
+		{
+			name:       "If-Match: * returns 412 Precondition Failed for a missing key",
+			key:        "key3",
+			header:     "If-Match: *",
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrConditionNotMet.Error(),
+		},
+		{
+			name:       "If-Match with the version writes the key",
+			key:        "key1",
+			header:     "If-Match: " + etag(version),
+			wantStatus: http.StatusCreated,
+			wantBody:   KeyAddedSuccessfully,
+		},
+		{
+			name:       "If-Match with a stale version returns 412 Precondition Failed",
+			key:        "key1",
+			header:     "If-Match: " + etag(version),
+			value:      "lost",
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrConditionNotMet.Error(),
+		},
+		{
+			name:       "If-Match with a weak tag returns 400 Bad Request",
+			key:        "key1",
+			header:     "If-Match: W/" + etag(version),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := tt.value
+			if value == "" {
+				value = "new"
+			}
+			body, err := json.Marshal(domain.Entity{Key: tt.key, Value: value})
+			assert.NoError(t, err)
+			req, err := http.NewRequest(http.MethodPost, "/set", bytes.NewReader(body))
+			assert.NoError(t, err)
+			name, header, _ := strings.Cut(tt.header, ": ")
+			req.Header.Set(name, header)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.Set).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+			if rr.Code == http.StatusCreated {
+				entity, err := stor.GetEntity(tt.key)
+				assert.NoError(t, err)
+				assert.Equal(t, etag(entity.Version), rr.Header().Get("ETag"))
+			}
 		})
 	}
+
+	value, err := stor.Get("key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", value, "the stale write is not applied")
 }
 
 func TestHandlers_Delete(t *testing.T) {
@@ -108,7 +220,7 @@ func TestHandlers_Delete(t *testing.T) {
 			args: args{
 				key: "key2",
 			},
-			wantStatus: http.StatusNoContent,
+			wantStatus: http.StatusNotFound,
 		},
 	}
 	stor := storage.NewInMemory()
@@ -138,6 +250,7 @@ func TestHandlers_Get(t *testing.T) {
 		args       args
 		wantStatus int
 		wantBody   string
+		wantETag   string
 		wantErr    error
 	}{
 		{
@@ -147,6 +260,7 @@ func TestHandlers_Get(t *testing.T) {
 			},
 			wantStatus: http.StatusOK,
 			wantBody:   "value1",
+			wantETag:   `"1"`,
 		},
 		{
 			name: "Get returns 400 Bad Request for an invalid request",
@@ -161,7 +275,7 @@ func TestHandlers_Get(t *testing.T) {
 			args: args{
 				key: "key2",
 			},
-			wantStatus: http.StatusNoContent,
+			wantStatus: http.StatusNotFound,
 			wantBody:   domain.ErrKeyNotFound.Error(),
 		},
 	}
@@ -179,7 +293,8 @@ func TestHandlers_Get(t *testing.T) {
 			handler.ServeHTTP(rr, req)
 
 			assert.Equal(t, tt.wantStatus, rr.Code)
-			assert.Equal(t, tt.wantBody, strings.TrimSpace(rr.Body.String()))
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+			assert.Equal(t, tt.wantETag, rr.Header().Get("ETag"))
 		})
 	}
 }
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	FieldCanNotBeEmpty  = "Field can not be empty"
+	FieldsCanNotBeEmpty = "Fields can not be empty"
+)
+
+// hashRequest is the body of the hash writes.
+type hashRequest struct {
+	Key    string            `json:"key"`
+	Fields map[string]string `json:"fields"`
+	Field  string            `json:"field"`
+	Delta  int64             `json:"delta"`
+}
+
+// HSet sets fields of a hash, creating it if needed.
+// Body example:
+//
+//	{
+//	  "key": "user:1",
+//	  "fields": {"name": "Ann", "age": "30"}
+//	}
+//
+// It replies with the number of the new fields, e.g. {"added": 2}.
+func (h *Handlers) HSet(w http.ResponseWriter, r *http.Request) {
+	var req hashRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(req.Fields) == 0 {
+		writeError(w, FieldsCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.UseCase.HSet(req.Key, req.Fields)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"added": added})
+}
+
+// HGet returns the value of a field of a hash.
+// Example: GET /hget?key=user:1&field=name
+func (h *Handlers) HGet(w http.ResponseWriter, r *http.Request) {
+	key, field := r.URL.Query().Get("key"), r.URL.Query().Get("field")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if field == "" {
+		writeError(w, FieldCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.UseCase.HGet(key, field)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, value)
+}
+
+// HDel deletes fields of a hash, deleting the last field deletes the key.
+// Example: DELETE /hdel?key=user:1&field=name&field=age
+// It replies with the number of the deleted fields, e.g. {"deleted": 2}.
+func (h *Handlers) HDel(w http.ResponseWriter, r *http.Request) {
+	key, fields := r.URL.Query().Get("key"), r.URL.Query()["field"]
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(fields) == 0 {
+		writeError(w, FieldsCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.UseCase.HDel(key, fields...)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"deleted": deleted})
+}
+
+// HGetAll returns all the fields of a hash as a JSON object.
+// Example: GET /hgetall?key=user:1
+func (h *Handlers) HGetAll(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	hash, err := h.UseCase.HGetAll(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, hash)
+}
+
+// HLen returns the number of the fields of a hash.
+// Example: GET /hlen?key=user:1
+func (h *Handlers) HLen(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.UseCase.HLen(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.Itoa(n))
+}
+
+// HIncrBy adds delta to the integer value of a field of a hash, a missing field counts as 0.
+// Body example:
+//
+//	{
+//	  "key": "user:1",
+//	  "field": "visits",
+//	  "delta": 1
+//	}
+//
+// It replies with the new value.
+func (h *Handlers) HIncrBy(w http.ResponseWriter, r *http.Request) {
+	var req hashRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if req.Field == "" {
+		writeError(w, FieldCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.UseCase.HIncrBy(req.Key, req.Field, req.Delta)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.FormatInt(n, 10))
+}
+
+// writeJSON replies with 200 OK and the value encoded as JSON.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
+
+// writeText replies with 200 OK and the text.
+func writeText(w http.ResponseWriter, text string) {
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(text)); err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers_Hash(t *testing.T) {
+	stor := storage.NewInMemory()
+	_ = stor.Set("string", "value", 0)
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "HSet returns the number of the new fields",
+			method:     http.MethodPost,
+			target:     "/hset",
+			body:       `{"key":"user:1","fields":{"name":"Ann","age":"30"}}`,
+			handler:    h.HSet,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"added":2}`,
+		},
+		{
+			name:       "HSet returns 400 Bad Request without fields",
+			method:     http.MethodPost,
+			target:     "/hset",
+			body:       `{"key":"user:1"}`,
+			handler:    h.HSet,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   FieldsCanNotBeEmpty,
+		},
+		{
+			name:       "HSet returns 409 Conflict for a string key",
+			method:     http.MethodPost,
+			target:     "/hset",
+			body:       `{"key":"string","fields":{"name":"Ann"}}`,
+			handler:    h.HSet,
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+		{
+			name:       "HGet returns the value of the field",
+			method:     http.MethodGet,
+			target:     "/hget?key=user:1&field=name",
+			handler:    h.HGet,
+			wantStatus: http.StatusOK,
+			wantBody:   "Ann",
+		},
+		{
+			name:       "HGet returns 404 Not Found for a missing field",
+			method:     http.MethodGet,
+			target:     "/hget?key=user:1&field=missing",
+			handler:    h.HGet,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrFieldNotFound.Error(),
+		},
+		{
+			name:       "HIncrBy returns the new value",
+			method:     http.MethodPost,
+			target:     "/hincrby",
+			body:       `{"key":"user:1","field":"age","delta":2}`,
+			handler:    h.HIncrBy,
+			wantStatus: http.StatusOK,
+			wantBody:   "32",
+		},
+		{
+			name:       "HIncrBy returns 400 Bad Request for a non-integer value",
+			method:     http.MethodPost,
+			target:     "/hincrby",
+			body:       `{"key":"user:1","field":"name","delta":1}`,
+			handler:    h.HIncrBy,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   domain.ErrNotInteger.Error(),
+		},
+		{
+			name:       "HGetAll returns the fields as JSON",
+			method:     http.MethodGet,
+			target:     "/hgetall?key=user:1",
+			handler:    h.HGetAll,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"age":"32","name":"Ann"}`,
+		},
+		{
+			name:       "HLen returns the number of the fields",
+			method:     http.MethodGet,
+			target:     "/hlen?key=user:1",
+			handler:    h.HLen,
+			wantStatus: http.StatusOK,
+			wantBody:   "2",
+		},
+		{
+			name:       "HDel returns the number of the deleted fields",
+			method:     http.MethodDelete,
+			target:     "/hdel?key=user:1&field=age&field=missing",
+			handler:    h.HDel,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"deleted":1}`,
+		},
+		{
+			name:       "Get returns 409 Conflict for a hash key",
+			method:     http.MethodGet,
+			target:     "/get?key=user:1",
+			handler:    h.Get,
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+}
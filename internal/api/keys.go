@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultScanCount is the count of Keys when it is not given.
+	DefaultScanCount = 100
+	// MaxScanCount is the maximum count of Keys.
+	MaxScanCount = 1000
+)
+
+const InvalidType = "Invalid type, expected string, hash, list, set or zset"
+
+// Keys returns a page of the keys, starting the scan with cursor=0 and continuing it with the cursor of the previous
+// reply until it is 0 again. Every key present for the whole scan is returned exactly once, a key created or deleted
+// meanwhile may be returned or not. Only a part of the keyspace is locked at a time, so a scan of millions of keys
+// does not stall the writes.
+// match is a glob pattern, count is a hint of how many keys to return, 100 by default and 1000 at most,
+// and type keeps only the keys of a type: string, hash, list, set or zset.
+// Like in Redis, the pattern and the type are applied to a page after reading it, so a page may be short or even
+// empty before the scan is complete.
+// values=true replies with the stored entities, as GET /all does, instead of the keys.
+// Example: GET /keys?cursor=0&match=user:*&count=100&type=hash
+// It replies with {"cursor": 37, "keys": ["user:1", "user:2"]}, or with "entities" instead of "keys".
+func (h *Handlers) Keys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var cursor uint64
+	if raw := query.Get("cursor"); raw != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			handleError(domain.ErrInvalidCursor, w)
+			return
+		}
+	}
+	count := DefaultScanCount
+	if raw := query.Get("count"); raw != "" {
+		var err error
+		if count, err = strconv.Atoi(raw); err != nil || count < 1 {
+			writeError(w, InvalidCount, http.StatusBadRequest)
+			return
+		}
+		if count > MaxScanCount {
+			count = MaxScanCount
+		}
+	}
+	var typ domain.ValueType
+	filterType := query.Has("type")
+	if filterType {
+		var ok bool
+		if typ, ok = domain.ParseValueType(query.Get("type")); !ok {
+			writeError(w, InvalidType, http.StatusBadRequest)
+			return
+		}
+	}
+	values := query.Get("values") == "true"
+
+	if !filterType && !values {
+		keys, next, err := h.UseCase.Scan(cursor, query.Get("match"), count)
+		if err != nil {
+			handleError(err, w)
+			return
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		writeJSON(w, map[string]interface{}{"cursor": next, "keys": keys})
+		return
+	}
+
+	entities, next, err := h.UseCase.ScanEntities(cursor, query.Get("match"), count)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	keys, matching := []string{}, []domain.Entity{}
+	for _, entity := range entities {
+		if !filterType || entity.Type == typ {
+			keys = append(keys, entity.Key)
+			matching = append(matching, entity)
+		}
+	}
+	if values {
+		writeJSON(w, map[string]interface{}{"cursor": next, "entities": matching})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"cursor": next, "keys": keys})
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers_Keys(t *testing.T) {
+	stor := storage.NewInMemory()
+	_, err := stor.SetWith("user:1", "value1", 0, domain.SetOptions{})
+	require.NoError(t, err)
+	_, err = stor.HSet("user:2", map[string]string{"f": "v"})
+	require.NoError(t, err)
+	_, err = stor.SetWith("order:1", "value2", 0, domain.SetOptions{})
+	require.NoError(t, err)
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantKeys   []string
+		wantBody   string
+	}{
+		{
+			name:       "Keys returns every key with a large count",
+			query:      "count=1000",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"order:1", "user:1", "user:2"},
+		},
+		{
+			name:       "Keys filters the keys by the glob pattern",
+			query:      "match=user:*",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"user:1", "user:2"},
+		},
+		{
+			name:       "Keys filters the keys by type",
+			query:      "type=hash",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"user:2"},
+		},
+		{
+			name:       "Keys returns 400 Bad Request for an unknown type",
+			query:      "type=tree",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidType,
+		},
+		{
+			name:       "Keys returns 400 Bad Request for a count of 0",
+			query:      "count=0",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidCount,
+		},
+		{
+			name:       "Keys returns 400 Bad Request for a cursor out of the keyspace",
+			query:      "cursor=18446744073709551615",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   domain.ErrInvalidCursor.Error(),
+		},
+		{
+			name:       "Keys returns 400 Bad Request for a malformed cursor",
+			query:      "cursor=abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   domain.ErrInvalidCursor.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/keys?"+tt.query, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			h.Keys(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, tt.wantBody, replyText(t, rr))
+				return
+			}
+			var resp struct {
+				Cursor uint64   `json:"cursor"`
+				Keys   []string `json:"keys"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Zero(t, resp.Cursor, "a single page covers the keyspace")
+			assert.ElementsMatch(t, tt.wantKeys, resp.Keys)
+		})
+	}
+
+	t.Run("Keys returns the entities with values=true", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/keys?match=user:1&values=true", nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.Keys(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp struct {
+			Entities []domain.Entity `json:"entities"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp.Entities, 1)
+		assert.Equal(t, "value1", resp.Entities[0].Value)
+	})
+
+	t.Run("Keys replies invalid_cursor for any invalid cursor", func(t *testing.T) {
+		for _, cursor := range []string{"abc", "18446744073709551615"} {
+			req, err := http.NewRequest(http.MethodGet, "/keys?cursor="+cursor, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			h.Keys(rr, req)
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, "invalid_cursor", problem.Code, "cursor=%s", cursor)
+		}
+	})
+}
+
+func TestHandlers_KeysPagination(t *testing.T) {
+	stor := storage.NewInMemory()
+	for idx := 0; idx < 500; idx++ {
+		_, err := stor.SetWith("key"+strconv.Itoa(idx), "value", 0, domain.SetOptions{})
+		require.NoError(t, err)
+	}
+	h := NewHandlers(stor)
+
+	seen := make(map[string]int)
+	var cursor uint64
+	pages := 0
+	for {
+		req, err := http.NewRequest(http.MethodGet, "/keys?count=50&cursor="+strconv.FormatUint(cursor, 10), nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.Keys(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp struct {
+			Cursor uint64   `json:"cursor"`
+			Keys   []string `json:"keys"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		for _, key := range resp.Keys {
+			seen[key]++
+		}
+		pages++
+		if cursor = resp.Cursor; cursor == 0 {
+			break
+		}
+	}
+
+	assert.Greater(t, pages, 1, "the scan is paginated")
+	assert.Len(t, seen, 500)
+	for key, n := range seen {
+		assert.Equal(t, 1, n, "key %s is returned once", key)
+	}
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	ValuesCanNotBeEmpty = "Values can not be empty"
+	InvalidIndex        = "Invalid index"
+	InvalidTimeout      = "Invalid timeout"
+)
+
+// listRequest is the body of the list pushes.
+type listRequest struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+// poppedValue is the reply of the blocking pops.
+type poppedValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// LPush inserts values at the head of a list, creating it if needed.
+// The values are inserted one after another, so the last one becomes the head.
+// Body example:
+//
+//	{
+//	  "key": "jobs",
+//	  "values": ["job1", "job2"]
+//	}
+//
+// It replies with the length of the list, e.g. {"length": 2}.
+func (h *Handlers) LPush(w http.ResponseWriter, r *http.Request) {
+	h.push(w, r, h.UseCase.LPush)
+}
+
+// RPush appends values at the tail of a list, creating it if needed, the body is the one of LPush.
+// It replies with the length of the list, e.g. {"length": 2}.
+func (h *Handlers) RPush(w http.ResponseWriter, r *http.Request) {
+	h.push(w, r, h.UseCase.RPush)
+}
+
+func (h *Handlers) push(w http.ResponseWriter, r *http.Request, push func(key string, values ...string) (int, error)) {
+	var req listRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(req.Values) == 0 {
+		writeError(w, ValuesCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	n, err := push(req.Key, req.Values...)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"length": n})
+}
+
+// LPop removes and returns the head of a list, popping the last value deletes the key.
+// Example: POST /lpop?key=jobs
+func (h *Handlers) LPop(w http.ResponseWriter, r *http.Request) {
+	h.pop(w, r, h.UseCase.LPop)
+}
+
+// RPop removes and returns the tail of a list, popping the last value deletes the key.
+// Example: POST /rpop?key=jobs
+func (h *Handlers) RPop(w http.ResponseWriter, r *http.Request) {
+	h.pop(w, r, h.UseCase.RPop)
+}
+
+func (h *Handlers) pop(w http.ResponseWriter, r *http.Request, pop func(key string) (string, error)) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	value, err := pop(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, value)
+}
+
+// LRange returns the values of a list from start to stop, both inclusive, as a JSON array.
+// Negative indexes count from the tail, start and stop default to the whole list.
+// Example: GET /lrange?key=jobs&start=0&stop=-1
+func (h *Handlers) LRange(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	start, stop, ok := rangeParams(w, r)
+	if !ok {
+		return
+	}
+
+	values, err := h.UseCase.LRange(key, start, stop)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, values)
+}
+
+// LLen returns the length of a list.
+// Example: GET /llen?key=jobs
+func (h *Handlers) LLen(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.UseCase.LLen(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.Itoa(n))
+}
+
+// LTrim keeps only the values of a list from start to stop, both inclusive, an empty range deletes the key.
+// Example: POST /ltrim?key=jobs&start=0&stop=99
+func (h *Handlers) LTrim(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	start, stop, ok := rangeParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.UseCase.LTrim(key, start, stop); err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, "OK")
+}
+
+// LIndex returns the value of a list at the index, a negative index counts from the tail.
+// Example: GET /lindex?key=jobs&index=-1
+func (h *Handlers) LIndex(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		writeError(w, InvalidIndex, http.StatusBadRequest)
+		return
+	}
+
+	value, err := h.UseCase.LIndex(key, index)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, value)
+}
+
+// BLPop pops the head of the first of the lists holding a value.
+// When all of them are empty, the request waits until another request pushes to one of them
+// or the timeout, in seconds, passes. A timeout of 0 or none waits indefinitely.
+// Example: POST /blpop?key=jobs:high&key=jobs:low&timeout=30
+// It replies with the key and the value, e.g. {"key": "jobs:high", "value": "job1"},
+// with 204 No Content on timeout and with 503 Service Unavailable when the server shuts down.
+func (h *Handlers) BLPop(w http.ResponseWriter, r *http.Request) {
+	h.blockingPop(w, r, h.UseCase.BLPop)
+}
+
+// BRPop is BLPop popping the tail.
+// Example: POST /brpop?key=jobs&timeout=0.5
+func (h *Handlers) BRPop(w http.ResponseWriter, r *http.Request) {
+	h.blockingPop(w, r, h.UseCase.BRPop)
+}
+
+func (h *Handlers) blockingPop(w http.ResponseWriter, r *http.Request,
+	pop func(ctx context.Context, keys []string, timeout time.Duration) (string, string, error)) {
+	keys := r.URL.Query()["key"]
+	if len(keys) == 0 {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	var timeout time.Duration
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		seconds, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(seconds) || seconds < 0 || seconds > math.MaxInt64/float64(time.Second) {
+			writeError(w, InvalidTimeout, http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	key, value, err := pop(r.Context(), keys, timeout)
+	switch {
+	case err == nil:
+		writeJSON(w, poppedValue{Key: key, Value: value})
+	case errors.Is(err, domain.ErrTimeout):
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// The client is gone or the server shuts down, see the BaseContext of the server
+		writeError(w, ServerShuttingDown, http.StatusServiceUnavailable)
+	default:
+		handleError(err, w)
+	}
+}
+
+// rangeParams parses the start and stop query parameters, defaulting to 0 and -1.
+// It replies with 400 Bad Request and reports false when one of them is not an integer.
+func rangeParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	start, stop := 0, -1
+	var err error
+	if raw := r.URL.Query().Get("start"); raw != "" {
+		if start, err = strconv.Atoi(raw); err != nil {
+			writeError(w, InvalidIndex, http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	if raw := r.URL.Query().Get("stop"); raw != "" {
+		if stop, err = strconv.Atoi(raw); err != nil {
+			writeError(w, InvalidIndex, http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	return start, stop, true
+}
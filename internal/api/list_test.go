@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers_List(t *testing.T) {
+	stor := storage.NewInMemory()
+	_ = stor.Set("string", "value", 0)
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "RPush returns the length of the list",
+			method:     http.MethodPost,
+			target:     "/rpush",
+			body:       `{"key":"jobs","values":["b","c"]}`,
+			handler:    h.RPush,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"length":2}`,
+		},
+		{
+			name:       "LPush inserts at the head",
+			method:     http.MethodPost,
+			target:     "/lpush",
+			body:       `{"key":"jobs","values":["a"]}`,
+			handler:    h.LPush,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"length":3}`,
+		},
+		{
+			name:       "LPush returns 400 Bad Request without values",
+			method:     http.MethodPost,
+			target:     "/lpush",
+			body:       `{"key":"jobs"}`,
+			handler:    h.LPush,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   ValuesCanNotBeEmpty,
+		},
+		{
+			name:       "RPush returns 409 Conflict for a string key",
+			method:     http.MethodPost,
+			target:     "/rpush",
+			body:       `{"key":"string","values":["a"]}`,
+			handler:    h.RPush,
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+		{
+			name:       "LRange returns the whole list by default",
+			method:     http.MethodGet,
+			target:     "/lrange?key=jobs",
+			handler:    h.LRange,
+			wantStatus: http.StatusOK,
+			wantBody:   `["a","b","c"]`,
+		},
+		{
+			name:       "LRange returns 400 Bad Request for an invalid index",
+			method:     http.MethodGet,
+			target:     "/lrange?key=jobs&start=x",
+			handler:    h.LRange,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidIndex,
+		},
+		{
+			name:       "LIndex returns the value at a negative index",
+			method:     http.MethodGet,
+			target:     "/lindex?key=jobs&index=-2",
+			handler:    h.LIndex,
+			wantStatus: http.StatusOK,
+			wantBody:   "b",
+		},
+		{
+			name:       "LIndex returns 404 Not Found out of range",
+			method:     http.MethodGet,
+			target:     "/lindex?key=jobs&index=3",
+			handler:    h.LIndex,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrIndexOutOfRange.Error(),
+		},
+		{
+			name:       "LPop returns the head",
+			method:     http.MethodPost,
+			target:     "/lpop?key=jobs",
+			handler:    h.LPop,
+			wantStatus: http.StatusOK,
+			wantBody:   "a",
+		},
+		{
+			name:       "LTrim keeps the range",
+			method:     http.MethodPost,
+			target:     "/ltrim?key=jobs&start=-1",
+			handler:    h.LTrim,
+			wantStatus: http.StatusOK,
+			wantBody:   "OK",
+		},
+		{
+			name:       "LLen returns the length",
+			method:     http.MethodGet,
+			target:     "/llen?key=jobs",
+			handler:    h.LLen,
+			wantStatus: http.StatusOK,
+			wantBody:   "1",
+		},
+		{
+			name:       "BRPop pops right away from a non-empty list",
+			method:     http.MethodPost,
+			target:     "/brpop?key=empty&key=jobs&timeout=1",
+			handler:    h.BRPop,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"key":"jobs","value":"c"}`,
+		},
+		{
+			name:       "RPop returns 404 Not Found once the list is gone",
+			method:     http.MethodPost,
+			target:     "/rpop?key=jobs",
+			handler:    h.RPop,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrKeyNotFound.Error(),
+		},
+		{
+			name:       "BLPop returns 204 No Content on timeout",
+			method:     http.MethodPost,
+			target:     "/blpop?key=jobs&timeout=0.01",
+			handler:    h.BLPop,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "BLPop returns 400 Bad Request for an invalid timeout",
+			method:     http.MethodPost,
+			target:     "/blpop?key=jobs&timeout=-1",
+			handler:    h.BLPop,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+}
+
+func TestHandlers_BLPop(t *testing.T) {
+	stor := storage.NewInMemory()
+	defer stor.Close()
+	h := NewHandlers(stor)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blpop", h.BLPop)
+	mux.HandleFunc("/rpush", h.RPush)
+
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.BaseContext = func(net.Listener) context.Context { return baseCtx }
+	srv.Config.RegisterOnShutdown(cancelBase)
+	srv.Start()
+	defer srv.Close()
+
+	type response struct {
+		status int
+		body   string
+	}
+	blpop := func() <-chan response {
+		result := make(chan response, 1)
+		go func() {
+			res, err := http.Post(srv.URL+"/blpop?key=jobs", "", nil)
+			if err != nil {
+				result <- response{body: err.Error()}
+				return
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			result <- response{status: res.StatusCode, body: strings.TrimSpace(string(body))}
+		}()
+		return result
+	}
+	wait := func(result <-chan response) response {
+		select {
+		case r := <-result:
+			return r
+		case <-time.After(2 * time.Second):
+			t.Fatal("BLPop was not released")
+			return response{}
+		}
+	}
+
+	t.Run("a push wakes the blocked request", func(t *testing.T) {
+		result := blpop()
+		time.Sleep(20 * time.Millisecond)
+		res, err := http.Post(srv.URL+"/rpush", "application/json", bytes.NewBufferString(`{"key":"jobs","values":["job1"]}`))
+		require.NoError(t, err)
+		res.Body.Close()
+
+		assert.Equal(t, response{status: http.StatusOK, body: `{"key":"jobs","value":"job1"}`}, wait(result))
+	})
+
+	t.Run("Shutdown releases the blocked request", func(t *testing.T) {
+		result := blpop()
+		time.Sleep(20 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.NoError(t, srv.Config.Shutdown(ctx))
+
+		res := wait(result)
+		assert.Equal(t, http.StatusServiceUnavailable, res.status)
+		assert.Contains(t, res.body, `"code":"shutting_down"`)
+	})
+}
@@ -1,13 +1,65 @@
 package api
 
 import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"net"
 	"net/http"
 	"time"
 )
 
+// RequestIDHeader is the header carrying the id of a request, it is echoed in the reply and in the errors.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of an id given by the client.
+const maxRequestIDLength = 64
+
+// RequestIDMiddleware sets the X-Request-ID header of the reply to the one of the request,
+// or to a new random id when the request has none or an invalid one, so an error can be found in the logs.
+// It must be the first middleware for the other ones to see the id.
+func RequestIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	}
+}
+
+// validRequestID reports whether an id given by the client is safe to echo and log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit id in hex.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
 // RateLimiterMiddleware returns a middleware function that limits the number of requests per second for a given IP address.
+// A limited request is replied with 429 Too Many Requests and Retry-After: 1, the limit being at least one request per second.
 func RateLimiterMiddleware(rl domain.RateLimiter) Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
@@ -17,12 +69,8 @@ func RateLimiterMiddleware(rl domain.RateLimiter) Middleware {
 			}
 
 			if !rl.Check(ip) {
-				w.WriteHeader(http.StatusTooManyRequests)
-				_, err := w.Write([]byte("Too many requests"))
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
+				w.Header().Set("Retry-After", "1")
+				writeError(w, TooManyRequests, http.StatusTooManyRequests)
 				return
 			}
 			rl.Limit(ip)
@@ -41,7 +89,8 @@ func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 
 		duration := time.Since(start)
 
-		fmt.Printf("%s %s %s %d %d %v\n", r.Method, r.URL.Path, r.Proto, rw.status, rw.length, duration)
+		fmt.Printf("%s %s %s %d %d %v %s\n", r.Method, r.URL.Path, r.Proto, rw.status, rw.length, duration,
+			w.Header().Get(RequestIDHeader))
 	}
 }
 
@@ -64,3 +113,20 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	rw.length += n
 	return n, err
 }
+
+// Flush sends the buffered response to the client, so streaming handlers work behind the middleware.
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack takes over the connection, so WebSocket handlers work behind the middleware.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	rw.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// docsPage is the page browsing the OpenAPI document, it loads the document from /openapi.json.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// DocsHandlers serves the OpenAPI 3 document of the routes registered on a Router and a page browsing it.
+type DocsHandlers struct {
+	Router *Router
+
+	once     sync.Once
+	document []byte
+}
+
+// NewDocsHandlers returns a new instance of DocsHandlers describing the routes of the router.
+func NewDocsHandlers(router *Router) *DocsHandlers {
+	return &DocsHandlers{Router: router}
+}
+
+// OpenAPI replies with the OpenAPI 3 document of the routes registered on the router.
+// The document is built on the first request, once all the routes are registered.
+func (h *DocsHandlers) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		var err error
+		if h.document, err = json.Marshal(newDocument(h.Router.Routes())); err != nil {
+			log.Error().Err(err).Msg("failed to encode the OpenAPI document")
+		}
+	})
+	if h.document == nil {
+		writeError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(h.document); err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
+
+// Page replies with an HTML page listing the operations of the OpenAPI document and sending requests to them.
+func (h *DocsHandlers) Page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(docsPage); err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
+
+// schema is a JSON schema of the OpenAPI document.
+type schema map[string]interface{}
+
+// operation is an OpenAPI operation, the responses common to all the routes are added by newDocument.
+type operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema schema `json:"schema"`
+}
+
+// response is an OpenAPI response, or a reference to one of the components when Ref is set.
+type response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]schema    `json:"headers,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+// newDocument returns the OpenAPI document of the routes, a route without an entry in operations
+// is listed with its method and path only.
+func newDocument(routes []Route) map[string]interface{} {
+	paths := make(map[string]map[string]operation)
+	problemStatuses := map[int]bool{http.StatusTooManyRequests: true, http.StatusInternalServerError: true}
+	for _, route := range routes {
+		op, ok := operations[route]
+		if !ok {
+			op = operation{Summary: route.Method + " " + route.Path, Responses: map[string]response{}}
+		}
+		op.Parameters = append(pathParameters(route.Path), op.Parameters...)
+		responses := make(map[string]response, len(op.Responses)+2)
+		for status, resp := range op.Responses {
+			responses[status] = resp
+			if code, err := strconv.Atoi(status); err == nil && code >= http.StatusBadRequest {
+				problemStatuses[code] = true
+			}
+		}
+		responses[strconv.Itoa(http.StatusTooManyRequests)] = problemResponse(http.StatusTooManyRequests)
+		responses[strconv.Itoa(http.StatusInternalServerError)] = problemResponse(http.StatusInternalServerError)
+		op.Responses = responses
+
+		path := openAPIPath(route.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]operation)
+		}
+		paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	statuses := make([]int, 0, len(problemStatuses))
+	for status := range problemStatuses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	responses := make(map[string]response, len(statuses))
+	for _, status := range statuses {
+		responses[responseName(status)] = response{
+			Description: http.StatusText(status),
+			Headers:     map[string]schema{RequestIDHeader: {"$ref": "#/components/headers/RequestID"}},
+			Content:     map[string]mediaType{problemContentType: {Schema: ref("Problem")}},
+		}
+	}
+	limited := responses[responseName(http.StatusTooManyRequests)]
+	limited.Headers["Retry-After"] = schema{"$ref": "#/components/headers/RetryAfter"}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   "In-Memory Storage",
+			"version": "1.0.0",
+			"description": "A key-value storage with strings, hashes, lists, sets and sorted sets. " +
+				"Every error is an application/problem+json Problem with a stable code, " +
+				"every route is rate limited per client IP.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas":   schemas,
+			"responses": responses,
+			"headers": map[string]schema{
+				"ETag": {
+					"description": `The version of the key, e.g. "42", to send back in If-Match.`,
+					"schema":      str(""),
+				},
+				"RetryAfter": {
+					"description": "The seconds to wait before retrying.",
+					"schema":      integer(""),
+				},
+				"RequestID": {
+					"description": "The id of the request, the one of the request when it was valid.",
+					"schema":      str(""),
+				},
+			},
+		},
+	}
+}
+
+// openAPIPath returns the path of a route in the OpenAPI syntax, e.g. /keys/{key} for /keys/{key...}.
+func openAPIPath(path string) string {
+	return strings.ReplaceAll(path, "...}", "}")
+}
+
+// pathParameters returns the parameters of the {name} segments of a path.
+func pathParameters(path string) []parameter {
+	var params []parameter
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := paramName(segment)
+		if !ok {
+			continue
+		}
+		description := "The key."
+		if strings.HasSuffix(name, "...") {
+			name = strings.TrimSuffix(name, "...")
+			description = "The key, it may contain slashes."
+		}
+		params = append(params, parameter{Name: name, In: "path", Description: description, Required: true, Schema: str("")})
+	}
+	return params
+}
+
+// responseName returns the name of the problem response of a status, e.g. NotFound.
+func responseName(status int) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(http.StatusText(status))
+}
+
+// problemResponse returns a reference to the problem response of a status.
+func problemResponse(status int) response {
+	return response{Ref: "#/components/responses/" + responseName(status)}
+}
+
+// replies returns the responses of an operation, the successful ones along with the problems of the statuses.
+func replies(ok map[int]response, problems ...int) map[string]response {
+	responses := make(map[string]response, len(ok)+len(problems))
+	for status, resp := range ok {
+		responses[strconv.Itoa(status)] = resp
+	}
+	for _, status := range problems {
+		responses[strconv.Itoa(status)] = problemResponse(status)
+	}
+	return responses
+}
+
+// jsonReply returns a response with a JSON body.
+func jsonReply(description string, s schema) response {
+	return response{Description: description, Content: map[string]mediaType{"application/json": {Schema: s}}}
+}
+
+// textReply returns a response with a plain text body.
+func textReply(description string) response {
+	return response{Description: description, Content: map[string]mediaType{"text/plain": {Schema: str("")}}}
+}
+
+// withETag returns the response with the ETag header.
+func withETag(resp response) response {
+	resp.Headers = map[string]schema{"ETag": {"$ref": "#/components/headers/ETag"}}
+	return resp
+}
+
+// jsonBody returns a required JSON request body.
+func jsonBody(s schema) *requestBody {
+	return &requestBody{Required: true, Content: map[string]mediaType{"application/json": {Schema: s}}}
+}
+
+// query returns a query parameter.
+func query(name, description string, required bool, s schema) parameter {
+	return parameter{Name: name, In: "query", Description: description, Required: required, Schema: s}
+}
+
+// keyQuery returns the required key query parameter.
+func keyQuery() parameter {
+	return query("key", "The key.", true, str(""))
+}
+
+// header returns an optional header parameter.
+func header(name, description string) parameter {
+	return parameter{Name: name, In: "header", Description: description, Schema: str("")}
+}
+
+func ref(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+func str(description string) schema {
+	return describe(schema{"type": "string"}, description)
+}
+
+func integer(description string) schema {
+	return describe(schema{"type": "integer", "format": "int64"}, description)
+}
+
+func number(description string) schema {
+	return describe(schema{"type": "number", "format": "double"}, description)
+}
+
+func boolean(description string) schema {
+	return describe(schema{"type": "boolean"}, description)
+}
+
+func array(items schema) schema {
+	return schema{"type": "array", "items": items}
+}
+
+// dictionary returns the schema of a JSON object with arbitrary keys.
+func dictionary(values schema) schema {
+	return schema{"type": "object", "additionalProperties": values}
+}
+
+// object returns the schema of a JSON object with the properties, required listing the ones that must be given.
+func object(required []string, properties map[string]schema) schema {
+	s := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func describe(s schema, description string) schema {
+	if description != "" {
+		s["description"] = description
+	}
+	return s
+}
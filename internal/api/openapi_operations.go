@@ -0,0 +1,682 @@
+package api
+
+import (
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"net/http"
+)
+
+// operations documents the routes registered by RegisterRoutes, by method and path as registered.
+// The path parameters and the 429 and 500 problems of every route are added by newDocument.
+// A route registered without an entry here fails TestDocsHandlers_OpenAPI.
+var operations = map[Route]operation{
+	{http.MethodPost, "/set"}: {
+		OperationID: "set",
+		Tags:        []string{"strings"},
+		Summary:     "Set a string key",
+		Description: "If-None-Match: * writes only a missing key, If-Match: * only an existing one " +
+			`and If-Match: "<version>" only a key still at the version.`,
+		Parameters:  preconditions(),
+		RequestBody: jsonBody(ref("SetRequest")),
+		Responses: replies(map[int]response{http.StatusCreated: withETag(textReply("The key is set."))},
+			http.StatusBadRequest, http.StatusPreconditionFailed, http.StatusInsufficientStorage),
+	},
+	{http.MethodDelete, "/delete"}: {
+		OperationID: "delete",
+		Tags:        []string{"strings"},
+		Summary:     "Delete a key of any type",
+		Parameters:  []parameter{keyQuery()},
+		Responses: replies(map[int]response{http.StatusOK: textReply("The key is deleted.")},
+			http.StatusBadRequest, http.StatusNotFound),
+	},
+	{http.MethodGet, "/get"}: {
+		OperationID: "get",
+		Tags:        []string{"strings"},
+		Summary:     "Get the value of a string key",
+		Description: "A sliding key gets its expiration pushed back. The Content-Type is the one of PUT /keys/{key}.",
+		Parameters:  []parameter{keyQuery()},
+		Responses: replies(map[int]response{http.StatusOK: withETag(textReply("The value."))},
+			http.StatusBadRequest, http.StatusNotFound, http.StatusGone),
+	},
+	{http.MethodGet, "/all"}: {
+		OperationID: "getAll",
+		Tags:        []string{"keys"},
+		Summary:     "Get all the keys in a single reply",
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("The entities.", array(ref("Entity")))},
+			http.StatusNotFound),
+	},
+	{http.MethodGet, "/keys"}:             withID(scanOperation, "scan"),
+	{http.MethodGet, "/keys/{key...}"}:    withID(getValueOperation, "getValue"),
+	{http.MethodPut, "/keys/{key...}"}:    withID(putValueOperation, "putValue"),
+	{http.MethodGet, "/v2/keys"}:          withID(scanOperation, "v2Scan"),
+	{http.MethodGet, "/v2/keys/{key...}"}: withID(getValueOperation, "v2GetValue"),
+	{http.MethodPut, "/v2/keys/{key...}"}: withID(putValueOperation, "v2PutValue"),
+	{http.MethodPatch, "/v2/keys/{key...}"}: {
+		OperationID: "v2PatchValue",
+		Tags:        []string{"keys"},
+		Summary:     "Change a part of a string key, keeping the rest",
+		Parameters:  []parameter{header("If-Match", `Change the key only if it is still at the version, e.g. "42".`)},
+		RequestBody: jsonBody(ref("PatchRequest")),
+		Responses: replies(map[int]response{http.StatusOK: withETag(jsonReply("The key is changed.", ref("PatchReply")))},
+			http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed),
+	},
+	{http.MethodDelete, "/v2/keys/{key...}"}: {
+		OperationID: "v2DeleteValue",
+		Tags:        []string{"keys"},
+		Summary:     "Delete a key of any type",
+		Parameters:  []parameter{header("If-Match", `Delete the key only if it is still at the version, e.g. "42".`)},
+		Responses: replies(map[int]response{http.StatusNoContent: {Description: "The key is deleted."}},
+			http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed),
+	},
+	{http.MethodPost, "/mget"}: {
+		OperationID: "mget",
+		Tags:        []string{"batches"},
+		Summary:     "Get several string keys",
+		RequestBody: jsonBody(ref("BatchKeysRequest")),
+		Responses:   replies(batchReply, http.StatusBadRequest),
+	},
+	{http.MethodPost, "/mset"}: {
+		OperationID: "mset",
+		Tags:        []string{"batches"},
+		Summary:     "Set several string keys",
+		RequestBody: jsonBody(ref("MSetRequest")),
+		Responses:   replies(batchReply, http.StatusBadRequest),
+	},
+	{http.MethodPost, "/mdelete"}: {
+		OperationID: "mdelete",
+		Tags:        []string{"batches"},
+		Summary:     "Delete several keys",
+		RequestBody: jsonBody(ref("BatchKeysRequest")),
+		Responses:   replies(batchReply, http.StatusBadRequest),
+	},
+	{http.MethodPost, "/incr"}:        counterOperation("incr", "Add 1 to an integer", ref("KeyRequest")),
+	{http.MethodPost, "/decr"}:        counterOperation("decr", "Subtract 1 from an integer", ref("KeyRequest")),
+	{http.MethodPost, "/incrby"}:      counterOperation("incrBy", "Add an integer delta to an integer", ref("CounterRequest")),
+	{http.MethodPost, "/incrbyfloat"}: counterOperation("incrByFloat", "Add a delta to a float", ref("CounterRequest")),
+	{http.MethodPost, "/txn"}: {
+		OperationID: "txn",
+		Tags:        []string{"transactions"},
+		Summary:     "Apply operations atomically, provided the watched keys did not change",
+		RequestBody: jsonBody(ref("TxnRequest")),
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("The transaction is committed.", ref("TxnResponse"))},
+			http.StatusBadRequest, http.StatusConflict, http.StatusPreconditionFailed, http.StatusInsufficientStorage),
+	},
+	{http.MethodPost, "/expire"}: {
+		OperationID: "expire",
+		Tags:        []string{"expiration"},
+		Summary:     "Set the ttl of a key in seconds, a non-positive one deleting it",
+		RequestBody: jsonBody(ref("TTLRequest")),
+		Responses: replies(map[int]response{http.StatusOK: textReply("The expiration is set.")},
+			http.StatusBadRequest, http.StatusNotFound),
+	},
+	{http.MethodPost, "/expireat"}: {
+		OperationID: "expireAt",
+		Tags:        []string{"expiration"},
+		Summary:     "Set the expiration of a key in unix seconds, a past one deleting it",
+		RequestBody: jsonBody(ref("TTLRequest")),
+		Responses: replies(map[int]response{http.StatusOK: textReply("The expiration is set.")},
+			http.StatusBadRequest, http.StatusNotFound),
+	},
+	{http.MethodPost, "/persist"}: {
+		OperationID: "persist",
+		Tags:        []string{"expiration"},
+		Summary:     "Remove the expiration of a key",
+		RequestBody: jsonBody(ref("KeyRequest")),
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("Whether the key had an expiration.",
+			object([]string{"persisted"}, map[string]schema{"persisted": boolean("")}))},
+			http.StatusBadRequest, http.StatusNotFound),
+	},
+	{http.MethodGet, "/ttl"}: {
+		OperationID: "ttl",
+		Tags:        []string{"expiration"},
+		Summary:     "Get the remaining ttl of a key",
+		Parameters:  []parameter{keyQuery()},
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("The ttl, -1 when the key does not expire.", ref("TTL"))},
+			http.StatusBadRequest, http.StatusNotFound),
+	},
+	{http.MethodPost, "/getex"}: {
+		OperationID: "getEx",
+		Tags:        []string{"expiration"},
+		Summary:     "Get the value of a string key and set its ttl",
+		RequestBody: jsonBody(ref("TTLRequest")),
+		Responses: replies(map[int]response{http.StatusOK: textReply("The value.")},
+			http.StatusBadRequest, http.StatusNotFound, http.StatusConflict),
+	},
+	{http.MethodPost, "/hset"}: {
+		OperationID: "hset",
+		Tags:        []string{"hashes"},
+		Summary:     "Set fields of a hash",
+		RequestBody: jsonBody(ref("HashRequest")),
+		Responses:   replies(countReply("added", "The number of the new fields."), writeProblems...),
+	},
+	{http.MethodGet, "/hget"}: {
+		OperationID: "hget",
+		Tags:        []string{"hashes"},
+		Summary:     "Get a field of a hash",
+		Parameters:  []parameter{keyQuery(), query("field", "The field.", true, str(""))},
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The value of the field.")}, readProblems...),
+	},
+	{http.MethodDelete, "/hdel"}: {
+		OperationID: "hdel",
+		Tags:        []string{"hashes"},
+		Summary:     "Delete fields of a hash",
+		Parameters:  []parameter{keyQuery(), query("field", "The fields.", true, array(str("")))},
+		Responses:   replies(countReply("deleted", "The number of the deleted fields."), readProblems...),
+	},
+	{http.MethodGet, "/hgetall"}: {
+		OperationID: "hgetAll",
+		Tags:        []string{"hashes"},
+		Summary:     "Get all the fields of a hash",
+		Parameters:  []parameter{keyQuery()},
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("The fields.", dictionary(str("")))}, readProblems...),
+	},
+	{http.MethodGet, "/hlen"}: {
+		OperationID: "hlen",
+		Tags:        []string{"hashes"},
+		Summary:     "Get the number of the fields of a hash",
+		Parameters:  []parameter{keyQuery()},
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The number of the fields.")}, readProblems...),
+	},
+	{http.MethodPost, "/hincrby"}: {
+		OperationID: "hincrBy",
+		Tags:        []string{"hashes"},
+		Summary:     "Add a delta to an integer field of a hash",
+		RequestBody: jsonBody(ref("HIncrByRequest")),
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The new value.")}, writeProblems...),
+	},
+	{http.MethodPost, "/lpush"}: pushOperation("lpush", "Insert values at the head of a list"),
+	{http.MethodPost, "/rpush"}: pushOperation("rpush", "Append values at the tail of a list"),
+	{http.MethodPost, "/lpop"}:  popOperation("lpop", "Remove and get the head of a list"),
+	{http.MethodPost, "/rpop"}:  popOperation("rpop", "Remove and get the tail of a list"),
+	{http.MethodPost, "/blpop"}: blockingPopOperation("blpop", "Pop the head of the first non-empty list, waiting for a push"),
+	{http.MethodPost, "/brpop"}: blockingPopOperation("brpop", "Pop the tail of the first non-empty list, waiting for a push"),
+	{http.MethodGet, "/lrange"}: {
+		OperationID: "lrange",
+		Tags:        []string{"lists"},
+		Summary:     "Get the values of a list from start to stop, both inclusive",
+		Parameters:  append([]parameter{keyQuery()}, rangeParameters()...),
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("The values.", array(str("")))}, readProblems...),
+	},
+	{http.MethodGet, "/llen"}: {
+		OperationID: "llen",
+		Tags:        []string{"lists"},
+		Summary:     "Get the length of a list",
+		Parameters:  []parameter{keyQuery()},
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The length.")}, readProblems...),
+	},
+	{http.MethodPost, "/ltrim"}: {
+		OperationID: "ltrim",
+		Tags:        []string{"lists"},
+		Summary:     "Keep only the values of a list from start to stop",
+		Parameters:  append([]parameter{keyQuery()}, rangeParameters()...),
+		Responses:   replies(map[int]response{http.StatusOK: textReply("OK.")}, writeProblems...),
+	},
+	{http.MethodGet, "/lindex"}: {
+		OperationID: "lindex",
+		Tags:        []string{"lists"},
+		Summary:     "Get the value of a list at an index",
+		Parameters: []parameter{keyQuery(),
+			query("index", "The index, a negative one counts from the tail.", true, integer(""))},
+		Responses: replies(map[int]response{http.StatusOK: textReply("The value.")}, readProblems...),
+	},
+	{http.MethodPost, "/sadd"}: {
+		OperationID: "sadd",
+		Tags:        []string{"sets"},
+		Summary:     "Add members to a set",
+		RequestBody: jsonBody(ref("SetMembersRequest")),
+		Responses:   replies(countReply("added", "The number of the new members."), writeProblems...),
+	},
+	{http.MethodDelete, "/srem"}: {
+		OperationID: "srem",
+		Tags:        []string{"sets"},
+		Summary:     "Remove members from a set",
+		Parameters:  []parameter{keyQuery(), query("member", "The members.", true, array(str("")))},
+		Responses:   replies(countReply("removed", "The number of the removed members."), readProblems...),
+	},
+	{http.MethodGet, "/smembers"}: {
+		OperationID: "smembers",
+		Tags:        []string{"sets"},
+		Summary:     "Get the members of a set in lexical order",
+		Parameters:  []parameter{keyQuery()},
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("The members.", array(str("")))}, readProblems...),
+	},
+	{http.MethodGet, "/sismember"}: {
+		OperationID: "sismember",
+		Tags:        []string{"sets"},
+		Summary:     "Check whether a member belongs to a set",
+		Parameters:  []parameter{keyQuery(), query("member", "The member.", true, str(""))},
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("Whether the member belongs to the set.", boolean(""))}, readProblems...),
+	},
+	{http.MethodGet, "/sinter"}: combineOperation("sinter", "Get the members belonging to all the sets"),
+	{http.MethodGet, "/sunion"}: combineOperation("sunion", "Get the members belonging to any of the sets"),
+	{http.MethodGet, "/sdiff"}:  combineOperation("sdiff", "Get the members of the first set not belonging to the others"),
+	{http.MethodPost, "/zadd"}: {
+		OperationID: "zadd",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Add members with their scores to a sorted set",
+		RequestBody: jsonBody(ref("ZAddRequest")),
+		Responses:   replies(countReply("added", "The number of the new members."), writeProblems...),
+	},
+	{http.MethodPost, "/zincrby"}: {
+		OperationID: "zincrBy",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Add a delta to the score of a member",
+		RequestBody: jsonBody(ref("ZIncrByRequest")),
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The new score.")}, writeProblems...),
+	},
+	{http.MethodGet, "/zscore"}: {
+		OperationID: "zscore",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Get the score of a member",
+		Parameters:  []parameter{keyQuery(), query("member", "The member.", true, str(""))},
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The score.")}, readProblems...),
+	},
+	{http.MethodGet, "/zrank"}: {
+		OperationID: "zrank",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Get the rank of a member, starting at 0",
+		Parameters:  []parameter{keyQuery(), query("member", "The member.", true, str("")), revQuery()},
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The rank.")}, readProblems...),
+	},
+	{http.MethodDelete, "/zrem"}: {
+		OperationID: "zrem",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Remove members from a sorted set",
+		Parameters:  []parameter{keyQuery(), query("member", "The members.", true, array(str("")))},
+		Responses:   replies(countReply("removed", "The number of the removed members."), readProblems...),
+	},
+	{http.MethodGet, "/zrange"}: {
+		OperationID: "zrange",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Get the members of a sorted set by rank",
+		Parameters:  append(append([]parameter{keyQuery()}, rangeParameters()...), revQuery()),
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("The members.", array(ref("ScoredMember")))}, readProblems...),
+	},
+	{http.MethodGet, "/zrangebyscore"}: {
+		OperationID: "zrangeByScore",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Get the members of a sorted set by score",
+		Parameters: append(append([]parameter{keyQuery()}, scoreParameters()...), revQuery(),
+			query("offset", "The number of the members to skip.", false, integer("")),
+			query("count", "The maximum number of the members.", false, integer(""))),
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("The members.", array(ref("ScoredMember")))}, readProblems...),
+	},
+	{http.MethodGet, "/zcount"}: {
+		OperationID: "zcount",
+		Tags:        []string{"sorted sets"},
+		Summary:     "Count the members of a sorted set by score",
+		Parameters:  append([]parameter{keyQuery()}, scoreParameters()...),
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The number of the members.")}, readProblems...),
+	},
+	{http.MethodGet, "/watch"}: {
+		OperationID: "watch",
+		Tags:        []string{"watch"},
+		Summary:     "Stream the changes of the keys as Server-Sent Events",
+		Description: "Every event has the kind of the change as its type, the Change as its data and " +
+			"the epoch of the server and its revision as its id, e.g. 9f1c2a7b3d4e5f60-42. " +
+			"An id of another epoch, e.g. issued before a restart, is refused with 410 Gone.",
+		Parameters: []parameter{
+			query("prefix", "Stream only the keys starting with the prefix.", false, str("")),
+			query("revision", "Resume after the event id.", false, str("")),
+			header("Last-Event-ID", "Resume after the event id, it takes precedence over the query."),
+		},
+		Responses: replies(map[int]response{http.StatusOK: {
+			Description: "The stream of the changes.",
+			Content:     map[string]mediaType{"text/event-stream": {Schema: ref("Change")}},
+		}}, http.StatusBadRequest, http.StatusGone),
+	},
+	{http.MethodGet, "/pubsub"}: {
+		OperationID: "subscribe",
+		Tags:        []string{"pubsub"},
+		Summary:     "Subscribe to channels and publish over a WebSocket exchanging JSON frames",
+		Responses: replies(map[int]response{http.StatusSwitchingProtocols: {Description: "The connection is upgraded."}},
+			http.StatusBadRequest),
+	},
+	{http.MethodPost, "/publish"}: {
+		OperationID: "publish",
+		Tags:        []string{"pubsub"},
+		Summary:     "Publish a message to a channel",
+		RequestBody: jsonBody(ref("PublishRequest")),
+		Responses:   replies(countReply("receivers", "The number of the receivers."), http.StatusBadRequest),
+	},
+	{http.MethodGet, "/pubsub/stats"}: {
+		OperationID: "pubsubStats",
+		Tags:        []string{"pubsub"},
+		Summary:     "Get the subscriptions and the message counters",
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("The counters.", ref("PubSubStats"))}),
+	},
+	{http.MethodPost, "/admin/snapshot"}: {
+		OperationID: "snapshot",
+		Tags:        []string{"admin"},
+		Summary:     "Save a snapshot of the storage",
+		Responses: replies(map[int]response{http.StatusOK: textReply("The path of the snapshot.")},
+			http.StatusNotImplemented),
+	},
+	{http.MethodPost, "/admin/rewrite-aof"}: {
+		OperationID: "rewriteAOF",
+		Tags:        []string{"admin"},
+		Summary:     "Compact the append-only file",
+		Responses: replies(map[int]response{http.StatusOK: textReply("The file is compacted.")},
+			http.StatusNotImplemented),
+	},
+	{http.MethodGet, "/openapi.json"}: {
+		OperationID: "openAPI",
+		Tags:        []string{"docs"},
+		Summary:     "Get this document",
+		Responses:   replies(map[int]response{http.StatusOK: jsonReply("The OpenAPI document.", schema{"type": "object"})}),
+	},
+	{http.MethodGet, "/docs"}: {
+		OperationID: "docs",
+		Tags:        []string{"docs"},
+		Summary:     "Browse this document",
+		Responses: replies(map[int]response{http.StatusOK: {
+			Description: "The page.",
+			Content:     map[string]mediaType{"text/html": {Schema: str("")}},
+		}}),
+	},
+}
+
+var (
+	// readProblems are the problems of a read of a hash, a list, a set or a sorted set.
+	readProblems = []int{http.StatusBadRequest, http.StatusNotFound, http.StatusGone, http.StatusConflict}
+	// writeProblems are the problems of a write of a hash, a list, a set or a sorted set.
+	writeProblems = []int{http.StatusBadRequest, http.StatusConflict, http.StatusInsufficientStorage}
+
+	batchReply = map[int]response{http.StatusOK: jsonReply("The result of every key in order.", ref("BatchResults"))}
+
+	scanOperation = operation{
+		Tags:    []string{"keys"},
+		Summary: "Page through the keys",
+		Description: "Start with cursor=0 and continue with the cursor of the previous reply until it is 0 again. " +
+			"A page may be short or even empty before the scan is complete.",
+		Parameters: []parameter{
+			query("cursor", "The cursor of the previous reply, 0 to start.", false, integer("")),
+			query("match", "A glob pattern of the keys, e.g. user:*.", false, str("")),
+			query("count", "How many keys to return, 100 by default and 1000 at most.", false, integer("")),
+			query("type", "Keep only the keys of the type.", false,
+				schema{"type": "string", "enum": []string{"string", "hash", "list", "set", "zset"}}),
+			query("values", "Reply with the entities instead of the keys.", false, boolean("")),
+		},
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("A page of the keys.", ref("ScanPage"))},
+			http.StatusBadRequest),
+	}
+
+	getValueOperation = operation{
+		Tags:        []string{"keys"},
+		Summary:     "Get the raw value of a string key with its content type",
+		Description: "HEAD replies with the headers only.",
+		Parameters:  []parameter{header("If-None-Match", `Reply with 304 Not Modified when the key is still at the version, e.g. "42".`)},
+		Responses: replies(map[int]response{
+			http.StatusOK: withETag(response{
+				Description: "The value, with the Content-Type it was written with.",
+				Content:     map[string]mediaType{"*/*": {Schema: schema{"type": "string", "format": "binary"}}},
+			}),
+			http.StatusNotModified: {Description: "The key is still at the version of If-None-Match."},
+		}, http.StatusBadRequest, http.StatusNotFound, http.StatusGone, http.StatusConflict),
+	}
+
+	putValueOperation = operation{
+		Tags:    []string{"keys"},
+		Summary: "Write the request body as the value of a key, of any content type",
+		Parameters: append([]parameter{
+			query("expiration", "The ttl in seconds.", false, integer("")),
+		}, preconditions()...),
+		RequestBody: &requestBody{Required: true, Content: map[string]mediaType{
+			"*/*": {Schema: schema{"type": "string", "format": "binary"}},
+		}},
+		Responses: replies(map[int]response{http.StatusCreated: withETag(textReply("The key is set."))},
+			http.StatusBadRequest, http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge,
+			http.StatusInsufficientStorage),
+	}
+)
+
+// withID returns the operation with the id, for the operations shared by several routes.
+func withID(op operation, id string) operation {
+	op.OperationID = id
+	return op
+}
+
+func counterOperation(id, summary string, body schema) operation {
+	return operation{
+		OperationID: id,
+		Tags:        []string{"counters"},
+		Summary:     summary + ", a missing key counting as 0",
+		RequestBody: jsonBody(body),
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The new value.")}, writeProblems...),
+	}
+}
+
+func pushOperation(id, summary string) operation {
+	return operation{
+		OperationID: id,
+		Tags:        []string{"lists"},
+		Summary:     summary,
+		RequestBody: jsonBody(ref("ListRequest")),
+		Responses:   replies(countReply("length", "The length of the list."), writeProblems...),
+	}
+}
+
+func popOperation(id, summary string) operation {
+	return operation{
+		OperationID: id,
+		Tags:        []string{"lists"},
+		Summary:     summary,
+		Parameters:  []parameter{keyQuery()},
+		Responses:   replies(map[int]response{http.StatusOK: textReply("The value.")}, readProblems...),
+	}
+}
+
+func blockingPopOperation(id, summary string) operation {
+	return operation{
+		OperationID: id,
+		Tags:        []string{"lists"},
+		Summary:     summary,
+		Parameters: []parameter{
+			query("key", "The keys, tried in order.", true, array(str(""))),
+			query("timeout", "The seconds to wait, 0 or none waiting indefinitely.", false, number("")),
+		},
+		Responses: replies(map[int]response{
+			http.StatusOK:        jsonReply("The popped value.", ref("PoppedValue")),
+			http.StatusNoContent: {Description: "The timeout passed."},
+		}, http.StatusBadRequest, http.StatusConflict, http.StatusServiceUnavailable),
+	}
+}
+
+func combineOperation(id, summary string) operation {
+	return operation{
+		OperationID: id,
+		Tags:        []string{"sets"},
+		Summary:     summary + ", a missing key being an empty set",
+		Parameters:  []parameter{query("key", "The keys.", true, array(str("")))},
+		Responses: replies(map[int]response{http.StatusOK: jsonReply("The members.", array(str("")))},
+			http.StatusBadRequest, http.StatusConflict),
+	}
+}
+
+// countReply returns a 200 OK response with a JSON object holding a count, e.g. {"added": 2}.
+func countReply(name, description string) map[int]response {
+	return map[int]response{http.StatusOK: jsonReply(description,
+		object([]string{name}, map[string]schema{name: integer("")}))}
+}
+
+func preconditions() []parameter {
+	return []parameter{
+		header("If-None-Match", "* writes only a missing key."),
+		header("If-Match", `* writes only an existing key, "<version>" only a key still at the version.`),
+	}
+}
+
+func rangeParameters() []parameter {
+	return []parameter{
+		query("start", "The first index, 0 by default, a negative one counts from the end.", false, integer("")),
+		query("stop", "The last index, -1 by default.", false, integer("")),
+	}
+}
+
+func scoreParameters() []parameter {
+	return []parameter{
+		query("min", "The minimum score, a float, -inf or inf, excluded when prefixed with (.", false, str("")),
+		query("max", "The maximum score, like min.", false, str("")),
+	}
+}
+
+func revQuery() parameter {
+	return query("rev", "Order the members from the highest score.", false, boolean(""))
+}
+
+// schemas are the bodies of the requests and the replies.
+var schemas = map[string]schema{
+	"Entity": object([]string{"key", "value", "expiration"}, map[string]schema{
+		"key":   str(""),
+		"value": str("The value of a string."),
+		"type": schema{"type": "string", "description": "Missing for a string.",
+			"enum": []domain.ValueType{domain.TypeHash, domain.TypeList, domain.TypeSet, domain.TypeZSet}},
+		"hash":         dictionary(str("")),
+		"list":         array(str("")),
+		"set":          array(str("")),
+		"zset":         array(ref("ScoredMember")),
+		"expiration":   integer("The unix time in nanoseconds the key expires at, 0 when it does not."),
+		"sliding":      boolean("Whether every read pushes the expiration back by sliding_ttl."),
+		"sliding_ttl":  integer("The ttl of a sliding key in nanoseconds."),
+		"content_type": str("The media type of the value."),
+		"flags":        integer("The flags of a memcached client."),
+		"version":      integer("The version of the key, it changes on every write."),
+	}),
+	"Problem": object([]string{"type", "title", "status", "code"}, map[string]schema{
+		"type":       str("Always about:blank."),
+		"title":      str("The text of the status."),
+		"status":     integer(""),
+		"detail":     str("A message for humans."),
+		"code":       str("The stable code of the error, e.g. key_not_found."),
+		"request_id": str("The X-Request-ID of the request."),
+	}),
+	"SetRequest": object([]string{"key", "value"}, map[string]schema{
+		"key":        str(""),
+		"value":      str(""),
+		"expiration": integer("The ttl in seconds, 0 for none."),
+		"sliding":    boolean("Push the expiration back on every read, it requires an expiration."),
+	}),
+	"KeyRequest": object([]string{"key"}, map[string]schema{"key": str("")}),
+	"CounterRequest": object([]string{"key", "delta"}, map[string]schema{
+		"key":   str(""),
+		"delta": number("An integer for incrby."),
+	}),
+	"TTLRequest": object([]string{"key"}, map[string]schema{
+		"key":        str(""),
+		"expiration": integer("The ttl in seconds, required by expire, at most 9223372036."),
+		"at":         integer("The expiration in unix seconds, required by expireat, at most 9223372036."),
+		"persist":    boolean("Remove the expiration, for getex."),
+	}),
+	"TTL": object([]string{"ttl", "ttl_ms"}, map[string]schema{
+		"ttl":    integer("The ttl in seconds, rounded."),
+		"ttl_ms": integer("The ttl in milliseconds."),
+	}),
+	"ScanPage": object([]string{"cursor"}, map[string]schema{
+		"cursor":   integer("The cursor of the next page, 0 at the end."),
+		"keys":     array(str("")),
+		"entities": array(ref("Entity")),
+	}),
+	"PatchRequest": object(nil, map[string]schema{
+		"value":        str(""),
+		"expiration":   integer("The ttl in seconds, 0 removing the expiration."),
+		"content_type": str(""),
+	}),
+	"PatchReply": object([]string{"version", "expiration"}, map[string]schema{
+		"version":      integer(""),
+		"expiration":   integer("The unix time in nanoseconds the key expires at, 0 when it does not."),
+		"content_type": str(""),
+	}),
+	"BatchKeysRequest": object([]string{"keys"}, map[string]schema{
+		"keys": describe(array(str("")), "Up to 1000 keys."),
+	}),
+	"MSetRequest": object([]string{"items"}, map[string]schema{
+		"items": describe(array(object([]string{"key", "value"}, map[string]schema{
+			"key":        str(""),
+			"value":      str(""),
+			"expiration": integer("The ttl in seconds."),
+		})), "Up to 1000 items."),
+	}),
+	"BatchResults": object([]string{"results"}, map[string]schema{
+		"results": array(object([]string{"key", "status"}, map[string]schema{
+			"key":     str(""),
+			"status":  integer("The status of the single key request."),
+			"value":   str(""),
+			"version": integer(""),
+			"error":   str(""),
+			"code":    str("The code of the Problem of the single key request."),
+		})),
+	}),
+	"TxnRequest": object([]string{"ops"}, map[string]schema{
+		"watch": array(object([]string{"key", "version"}, map[string]schema{
+			"key":     str(""),
+			"version": integer("The version read from the ETag, 0 meaning the key must be missing."),
+		})),
+		"ops": array(object([]string{"op", "key"}, map[string]schema{
+			"op":         schema{"type": "string", "enum": []domain.OpType{domain.OpSet, domain.OpDelete, domain.OpIncr}},
+			"key":        str(""),
+			"value":      str(""),
+			"expiration": integer("The ttl of set in seconds."),
+			"delta":      integer("The delta of incr."),
+		})),
+	}),
+	"TxnResponse": object([]string{"committed"}, map[string]schema{
+		"committed": boolean(""),
+		"results": array(object(nil, map[string]schema{
+			"version": integer(""),
+			"value":   integer("The new value of incr."),
+			"deleted": boolean("Whether delete removed the key."),
+		})),
+	}),
+	"HashRequest": object([]string{"key", "fields"}, map[string]schema{
+		"key":    str(""),
+		"fields": dictionary(str("")),
+	}),
+	"HIncrByRequest": object([]string{"key", "field", "delta"}, map[string]schema{
+		"key":   str(""),
+		"field": str(""),
+		"delta": integer(""),
+	}),
+	"ListRequest": object([]string{"key", "values"}, map[string]schema{
+		"key":    str(""),
+		"values": array(str("")),
+	}),
+	"PoppedValue": object([]string{"key", "value"}, map[string]schema{
+		"key":   str(""),
+		"value": str(""),
+	}),
+	"SetMembersRequest": object([]string{"key", "members"}, map[string]schema{
+		"key":     str(""),
+		"members": array(str("")),
+	}),
+	"ZAddRequest": object([]string{"key", "members"}, map[string]schema{
+		"key":     str(""),
+		"members": dictionary(number("")),
+	}),
+	"ZIncrByRequest": object([]string{"key", "member", "delta"}, map[string]schema{
+		"key":    str(""),
+		"member": str(""),
+		"delta":  number(""),
+	}),
+	"ScoredMember": object([]string{"member", "score"}, map[string]schema{
+		"member": str(""),
+		"score":  number(""),
+	}),
+	"Change": object([]string{"type", "key"}, map[string]schema{
+		"type": schema{"type": "string", "enum": []domain.ChangeType{domain.ChangeSet, domain.ChangeDelete,
+			domain.ChangeExpire, domain.ChangeEvict}},
+		"key":      str(""),
+		"entity":   ref("Entity"),
+		"revision": integer(""),
+	}),
+	"PublishRequest": object([]string{"channel"}, map[string]schema{
+		"channel": str(""),
+		"payload": str(""),
+	}),
+	"PubSubStats": object(nil, map[string]schema{
+		"channels":     integer(""),
+		"patterns":     integer(""),
+		"subscribers":  integer(""),
+		"delivered":    integer(""),
+		"dropped":      integer(""),
+		"disconnected": integer(""),
+	}),
+}
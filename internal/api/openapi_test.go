@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPIRouter returns a router with all the routes of the API, like the one of the server.
+func newAPIRouter() *Router {
+	router := NewRouter()
+	RegisterRoutes(router,
+		NewHandlers(storage.NewInMemory()),
+		NewAdminHandlers(nil, nil),
+		NewWatchHandlers(events.NewBus(16)),
+		NewPubSubHandlers(pubsub.NewBroker(16, pubsub.Disconnect)))
+	return router
+}
+
+func TestDocsHandlers_OpenAPI(t *testing.T) {
+	router := newAPIRouter()
+
+	t.Run("every registered route has a spec entry", func(t *testing.T) {
+		registered := make(map[Route]bool)
+		for _, route := range router.Routes() {
+			registered[route] = true
+			_, ok := operations[route]
+			assert.True(t, ok, "%s %s is registered without an entry in operations", route.Method, route.Path)
+		}
+		for route := range operations {
+			assert.True(t, registered[route], "%s %s has an entry in operations but is not registered", route.Method, route.Path)
+		}
+	})
+
+	t.Run("the operation ids are unique", func(t *testing.T) {
+		ids := make(map[string]Route)
+		for route, op := range operations {
+			require.NotEmpty(t, op.OperationID, "%s %s", route.Method, route.Path)
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("%s is the id of %v and %v", op.OperationID, route, other)
+			}
+			ids[op.OperationID] = route
+		}
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+
+	t.Run("the document lists the routes in the OpenAPI syntax", func(t *testing.T) {
+		assert.Equal(t, "3.0.3", doc["openapi"])
+		paths := doc["paths"].(map[string]interface{})
+		assert.Len(t, paths, len(pathsOf(router.Routes())))
+
+		value := paths["/v2/keys/{key}"].(map[string]interface{})
+		assert.ElementsMatch(t, []string{"get", "put", "patch", "delete"}, keysOf(value))
+		get := value["get"].(map[string]interface{})
+		param := get["parameters"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{
+			"name": "key", "in": "path", "description": "The key, it may contain slashes.", "required": true,
+			"schema": map[string]interface{}{"type": "string"},
+		}, param)
+		responses := get["responses"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"$ref": "#/components/responses/TooManyRequests"}, responses["429"])
+		assert.Equal(t, map[string]interface{}{"$ref": "#/components/responses/NotFound"}, responses["404"])
+	})
+
+	t.Run("every reference resolves", func(t *testing.T) {
+		var walk func(node interface{})
+		walk = func(node interface{}) {
+			switch node := node.(type) {
+			case map[string]interface{}:
+				if target, ok := node["$ref"].(string); ok {
+					assert.NotNil(t, lookup(doc, target), "%s does not resolve", target)
+				}
+				for _, child := range node {
+					walk(child)
+				}
+			case []interface{}:
+				for _, child := range node {
+					walk(child)
+				}
+			}
+		}
+		walk(doc)
+	})
+}
+
+func TestDocsHandlers_Page(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newAPIRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `fetch("openapi.json")`)
+}
+
+// lookup returns the node of the document at a local reference, e.g. #/components/schemas/Entity, nil if none.
+func lookup(doc map[string]interface{}, ref string) interface{} {
+	var node interface{} = doc
+	for _, name := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = object[name]
+	}
+	return node
+}
+
+func pathsOf(routes []Route) map[string]bool {
+	paths := make(map[string]bool)
+	for _, route := range routes {
+		paths[openAPIPath(route.Path)] = true
+	}
+	return paths
+}
+
+func keysOf(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
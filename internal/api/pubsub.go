@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"time"
+)
+
+const (
+	ChannelCanNotBeEmpty  = "Channel can not be empty"
+	ChannelsCanNotBeEmpty = "Channels can not be empty"
+	PatternsCanNotBeEmpty = "Patterns can not be empty"
+	UnknownOperation      = "Unknown operation"
+	ServerShuttingDown    = "Server is shutting down"
+)
+
+const (
+	// pubsubReadLimit is the maximum size of a frame sent by a client.
+	pubsubReadLimit = 1 << 20
+	// pubsubWriteWait is how long writing a frame to a client may take.
+	pubsubWriteWait = 10 * time.Second
+	// pubsubReplies is the number of replies queued for the writer of a connection.
+	pubsubReplies = 16
+)
+
+// PubSubHandlers serves the publish/subscribe messaging.
+type PubSubHandlers struct {
+	Broker   *pubsub.Broker
+	upgrader websocket.Upgrader
+}
+
+// NewPubSubHandlers returns a new instance of PubSubHandlers.
+func NewPubSubHandlers(broker *pubsub.Broker) *PubSubHandlers {
+	return &PubSubHandlers{Broker: broker}
+}
+
+// pubsubRequest is a frame sent by a WebSocket client.
+type pubsubRequest struct {
+	Op       string   `json:"op"`
+	Channels []string `json:"channels"`
+	Patterns []string `json:"patterns"`
+	Channel  string   `json:"channel"`
+	Payload  string   `json:"payload"`
+}
+
+// subscriptionFrame confirms a change of the subscriptions, Count is the number of the subscriptions left.
+type subscriptionFrame struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// messageFrame is a message received by the client, its type is message or pmessage for a pattern subscription.
+type messageFrame struct {
+	Type string `json:"type"`
+	pubsub.Message
+}
+
+// publishFrame confirms a publish with the number of the receivers.
+type publishFrame struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel"`
+	Receivers int    `json:"receivers"`
+}
+
+// statusFrame is a reply without data, e.g. pong, or an error.
+type statusFrame struct {
+	Type  string `json:"type"`
+	Error string `json:"error,omitempty"`
+}
+
+// Subscribe upgrades the request to a WebSocket connection exchanging JSON frames.
+// The client sends operations:
+//
+//	{"op": "subscribe", "channels": ["news.tech"]}
+//	{"op": "psubscribe", "patterns": ["news.*"]}
+//	{"op": "unsubscribe", "channels": []}
+//	{"op": "punsubscribe", "patterns": []}
+//	{"op": "publish", "channel": "news.tech", "payload": "hello"}
+//	{"op": "ping"}
+//
+// and gets a reply per channel or pattern, e.g. {"type": "subscribe", "channel": "news.tech", "count": 1},
+// along with the messages: {"type": "pmessage", "pattern": "news.*", "channel": "news.tech", "payload": "hello"}.
+// Unsubscribing without channels or patterns removes all of them.
+// A client that does not keep up loses its oldest messages or is disconnected, depending on the broker policy.
+func (h *PubSubHandlers) Subscribe(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has replied with the error
+		return
+	}
+	conn.SetReadLimit(pubsubReadLimit)
+
+	sub := h.Broker.NewSubscriber()
+	replies := make(chan interface{}, pubsubReplies)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		writeFrames(conn, sub, replies)
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		var frames []interface{}
+		var req pubsubRequest
+		if err = json.Unmarshal(data, &req); err != nil {
+			frames = []interface{}{statusFrame{Type: "error", Error: UnableToParseRequestBody}}
+		} else {
+			frames = h.handle(sub, req)
+		}
+
+		stopped := false
+		for _, frame := range frames {
+			select {
+			case replies <- frame:
+			case <-done:
+				stopped = true
+			}
+		}
+		if stopped {
+			break
+		}
+	}
+
+	close(replies)
+	<-done
+	sub.Close()
+	_ = conn.Close()
+}
+
+// handle runs the operation and returns the replies.
+func (h *PubSubHandlers) handle(sub *pubsub.Subscriber, req pubsubRequest) []interface{} {
+	var frames []interface{}
+	switch req.Op {
+	case "subscribe":
+		if len(req.Channels) == 0 {
+			return []interface{}{statusFrame{Type: "error", Error: ChannelsCanNotBeEmpty}}
+		}
+		for _, channel := range req.Channels {
+			frames = append(frames, subscriptionFrame{Type: req.Op, Channel: channel, Count: sub.Subscribe(channel)})
+		}
+	case "psubscribe":
+		if len(req.Patterns) == 0 {
+			return []interface{}{statusFrame{Type: "error", Error: PatternsCanNotBeEmpty}}
+		}
+		for _, pattern := range req.Patterns {
+			frames = append(frames, subscriptionFrame{Type: req.Op, Pattern: pattern, Count: sub.PSubscribe(pattern)})
+		}
+	case "unsubscribe":
+		channels := req.Channels
+		if len(channels) == 0 {
+			channels = sub.Channels()
+		}
+		for _, channel := range channels {
+			frames = append(frames, subscriptionFrame{Type: req.Op, Channel: channel, Count: sub.Unsubscribe(channel)})
+		}
+		if len(frames) == 0 {
+			frames = append(frames, subscriptionFrame{Type: req.Op, Count: sub.Count()})
+		}
+	case "punsubscribe":
+		patterns := req.Patterns
+		if len(patterns) == 0 {
+			patterns = sub.Patterns()
+		}
+		for _, pattern := range patterns {
+			frames = append(frames, subscriptionFrame{Type: req.Op, Pattern: pattern, Count: sub.PUnsubscribe(pattern)})
+		}
+		if len(frames) == 0 {
+			frames = append(frames, subscriptionFrame{Type: req.Op, Count: sub.Count()})
+		}
+	case "publish":
+		if req.Channel == "" {
+			return []interface{}{statusFrame{Type: "error", Error: ChannelCanNotBeEmpty}}
+		}
+		frames = append(frames, publishFrame{Type: req.Op, Channel: req.Channel, Receivers: h.Broker.Publish(req.Channel, req.Payload)})
+	case "ping":
+		frames = append(frames, statusFrame{Type: "pong"})
+	default:
+		frames = append(frames, statusFrame{Type: "error", Error: UnknownOperation})
+	}
+	return frames
+}
+
+// writeFrames writes the replies and the messages of the subscriber to the connection until the replies are closed,
+// the subscriber ends or a write fails. It is the only writer of the connection.
+func writeFrames(conn *websocket.Conn, sub *pubsub.Subscriber, replies <-chan interface{}) {
+	for {
+		var frame interface{}
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				return
+			}
+			frame = reply
+		case m, ok := <-sub.C():
+			if !ok {
+				code, reason := websocket.CloseGoingAway, ServerShuttingDown
+				if errors.Is(sub.Err(), pubsub.ErrSlowConsumer) {
+					code, reason = websocket.ClosePolicyViolation, sub.Err().Error()
+				}
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(pubsubWriteWait))
+				// Closing the connection ends the reader too
+				_ = conn.Close()
+				return
+			}
+			typ := "message"
+			if m.Pattern != "" {
+				typ = "pmessage"
+			}
+			frame = messageFrame{Type: typ, Message: m}
+		}
+
+		_ = conn.SetWriteDeadline(time.Now().Add(pubsubWriteWait))
+		if err := conn.WriteJSON(frame); err != nil {
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+// Publish sends a message to a channel.
+// Body example:
+//
+//	{
+//	  "channel": "news.tech",
+//	  "payload": "hello"
+//	}
+//
+// It replies with the number of the receivers, e.g. {"receivers": 2}.
+func (h *PubSubHandlers) Publish(w http.ResponseWriter, r *http.Request) {
+	var req pubsubRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Channel == "" {
+		writeError(w, ChannelCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	receivers := h.Broker.Publish(req.Channel, req.Payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(map[string]int{"receivers": receivers})
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+		return
+	}
+}
+
+// Stats returns the subscriptions and the delivered and dropped message counters of the broker.
+func (h *PubSubHandlers) Stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.Broker.Stats())
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+		return
+	}
+}
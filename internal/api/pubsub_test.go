@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readFrame reads the next frame of the connection as a map.
+func readFrame(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	var frame map[string]interface{}
+	require.NoError(t, conn.ReadJSON(&frame))
+	return frame
+}
+
+func TestPubSubHandlers(t *testing.T) {
+	broker := pubsub.NewBroker(16, pubsub.Disconnect)
+	hands := NewPubSubHandlers(broker)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pubsub", LoggingMiddleware(hands.Subscribe))
+	mux.HandleFunc("/publish", LoggingMiddleware(hands.Publish))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/pubsub", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": "subscribe", "channels": []string{"news.tech"}}))
+	assert.Equal(t, map[string]interface{}{"type": "subscribe", "channel": "news.tech", "count": 1.0}, readFrame(t, conn))
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": "psubscribe", "patterns": []string{"news.*"}}))
+	assert.Equal(t, map[string]interface{}{"type": "psubscribe", "pattern": "news.*", "count": 2.0}, readFrame(t, conn))
+
+	t.Run("Publish delivers the message to the subscriptions", func(t *testing.T) {
+		res, err := http.Post(srv.URL+"/publish", "application/json", bytes.NewBufferString(`{"channel":"news.tech","payload":"hello"}`))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var body map[string]int
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, map[string]int{"receivers": 2}, body)
+
+		got := []map[string]interface{}{readFrame(t, conn), readFrame(t, conn)}
+		assert.ElementsMatch(t, []map[string]interface{}{
+			{"type": "message", "channel": "news.tech", "payload": "hello"},
+			{"type": "pmessage", "pattern": "news.*", "channel": "news.tech", "payload": "hello"},
+		}, got)
+	})
+
+	t.Run("Publish returns 400 Bad Request without a channel", func(t *testing.T) {
+		res, err := http.Post(srv.URL+"/publish", "application/json", bytes.NewBufferString(`{"payload":"hello"}`))
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("operations are answered in order", func(t *testing.T) {
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": "publish", "channel": "weather", "payload": "rain"}))
+		assert.Equal(t, map[string]interface{}{"type": "publish", "channel": "weather", "receivers": 0.0}, readFrame(t, conn))
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": "ping"}))
+		assert.Equal(t, map[string]interface{}{"type": "pong"}, readFrame(t, conn))
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": "unknown"}))
+		assert.Equal(t, map[string]interface{}{"type": "error", "error": UnknownOperation}, readFrame(t, conn))
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+		assert.Equal(t, map[string]interface{}{"type": "error", "error": UnableToParseRequestBody}, readFrame(t, conn))
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"op": "unsubscribe"}))
+		assert.Equal(t, map[string]interface{}{"type": "unsubscribe", "channel": "news.tech", "count": 1.0}, readFrame(t, conn))
+	})
+
+	t.Run("the connection is closed when the broker closes", func(t *testing.T) {
+		broker.Close()
+		_, _, err := conn.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	})
+}
@@ -1,7 +1,11 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 )
 
 // Middleware is a function that takes  http.HandlerFunc and returns a new http.HandlerFunc.
@@ -9,19 +13,89 @@ type Middleware func(http.HandlerFunc) http.HandlerFunc
 
 // NewRouter returns a new router with an empty middleware stack.
 func NewRouter() *Router {
-	r := &Router{middlewares: []Middleware{}}
-	r.mux = http.NewServeMux()
-	return r
+	return &Router{middlewares: []Middleware{}, static: make(map[string]*route)}
 }
 
-// Router is a simple router that supports middleware.
+// Router is a simple router that supports middleware, methods and path parameters.
+// A path segment {name} matches any non-empty segment and a last segment {name...} matches the non-empty rest
+// of the path, slashes included, e.g. /keys/{key...} matches /keys/img/1.png. They are read with PathParam.
+// A path without parameters is preferred to the patterns, which are tried in the order they were added.
+// A request for a known path with another method is replied with 405 Method Not Allowed and the Allow header,
+// a GET route serving HEAD as well. The middlewares must be added before the routes.
 type Router struct {
-	mux         *http.ServeMux
+	// static holds the routes without parameters by path.
+	static map[string]*route
+	// patterns holds the routes with parameters.
+	patterns    []*route
 	middlewares []Middleware
 }
 
+// route is a path along with its handlers by method.
+type route struct {
+	path     string
+	segments []string
+	handlers map[string]http.HandlerFunc
+}
+
+// Route is a method and a path registered on the Router, the path as it was registered, e.g. /keys/{key...}.
+type Route struct {
+	Method string
+	Path   string
+}
+
+// paramsKey is the context key of the path parameters.
+type paramsKey struct{}
+
+// PathParam returns the value of the path parameter name of the route serving the request, empty if it has none.
+func PathParam(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.mux.ServeHTTP(w, req)
+	rt, params := r.match(req.URL)
+	if rt == nil {
+		r.applyMiddlewares(routeNotFound)(w, req)
+		return
+	}
+
+	handler, ok := rt.handlers[req.Method]
+	if !ok && req.Method == http.MethodHead {
+		handler, ok = rt.handlers[http.MethodGet]
+	}
+	if !ok {
+		w.Header().Set("Allow", rt.allow())
+		r.applyMiddlewares(methodNotAllowed)(w, req)
+		return
+	}
+
+	if params != nil {
+		req = req.WithContext(context.WithValue(req.Context(), paramsKey{}, params))
+	}
+	handler(w, req)
+}
+
+// Routes returns the registered routes ordered by path and method, HEAD being implied by GET.
+func (r *Router) Routes() []Route {
+	var routes []Route
+	add := func(rt *route) {
+		for method := range rt.handlers {
+			routes = append(routes, Route{Method: method, Path: rt.path})
+		}
+	}
+	for _, rt := range r.static {
+		add(rt)
+	}
+	for _, rt := range r.patterns {
+		add(rt)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
 }
 
 // Use adds a new middleware to the middleware stack.
@@ -29,19 +103,145 @@ func (r *Router) Use(m Middleware) {
 	r.middlewares = append(r.middlewares, m)
 }
 
-// Get adds a new GET route to the router.
+// Get adds a new GET route to the router, it serves HEAD too.
 func (r *Router) Get(path string, handler http.HandlerFunc) {
-	r.mux.HandleFunc(path, r.applyMiddlewares(handler))
+	r.handle(http.MethodGet, path, handler)
+}
+
+// Post adds a new POST route to the router.
+func (r *Router) Post(path string, handler http.HandlerFunc) {
+	r.handle(http.MethodPost, path, handler)
+}
+
+// Put adds a new PUT route to the router.
+func (r *Router) Put(path string, handler http.HandlerFunc) {
+	r.handle(http.MethodPut, path, handler)
+}
+
+// Patch adds a new PATCH route to the router.
+func (r *Router) Patch(path string, handler http.HandlerFunc) {
+	r.handle(http.MethodPatch, path, handler)
 }
 
 // Delete adds a new DELETE route to the router.
 func (r *Router) Delete(path string, handler http.HandlerFunc) {
-	r.mux.HandleFunc(path, r.applyMiddlewares(handler))
+	r.handle(http.MethodDelete, path, handler)
 }
 
-// Post adds a new POST route to the router.
-func (r *Router) Post(path string, handler http.HandlerFunc) {
-	r.mux.HandleFunc(path, r.applyMiddlewares(handler))
+// handle adds the handler of the method to the route of the path.
+// It panics when the method of the path already has a handler, like http.ServeMux.
+func (r *Router) handle(method, path string, handler http.HandlerFunc) {
+	rt := r.route(path)
+	if _, ok := rt.handlers[method]; ok {
+		panic("api: multiple registrations for " + method + " " + path)
+	}
+	rt.handlers[method] = r.applyMiddlewares(handler)
+}
+
+// route returns the route of the path, adding it if needed.
+func (r *Router) route(path string) *route {
+	if !strings.Contains(path, "{") {
+		if rt, ok := r.static[path]; ok {
+			return rt
+		}
+		rt := &route{path: path, handlers: make(map[string]http.HandlerFunc)}
+		r.static[path] = rt
+		return rt
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for _, rt := range r.patterns {
+		if strings.Join(rt.segments, "/") == strings.Join(segments, "/") {
+			return rt
+		}
+	}
+	rt := &route{path: path, segments: segments, handlers: make(map[string]http.HandlerFunc)}
+	r.patterns = append(r.patterns, rt)
+	return rt
+}
+
+// match returns the route of the url along with its path parameters.
+// The escaped path is split, so an escaped slash in a parameter does not split it.
+func (r *Router) match(u *url.URL) (*route, map[string]string) {
+	if rt, ok := r.static[u.Path]; ok {
+		return rt, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	for _, rt := range r.patterns {
+		if params, ok := rt.match(parts); ok {
+			return rt, params
+		}
+	}
+	return nil, nil
+}
+
+// match reports whether the escaped segments of a path match the route and returns its parameters.
+func (rt *route) match(parts []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for idx, segment := range rt.segments {
+		name, isParam := paramName(segment)
+		if isParam && strings.HasSuffix(name, "...") {
+			if idx >= len(parts) {
+				return nil, false
+			}
+			return params, capture(params, strings.TrimSuffix(name, "..."), strings.Join(parts[idx:], "/"))
+		}
+		if idx >= len(parts) {
+			return nil, false
+		}
+		if isParam {
+			if !capture(params, name, parts[idx]) {
+				return nil, false
+			}
+			continue
+		}
+		if unescaped, err := url.PathUnescape(parts[idx]); err != nil || unescaped != segment {
+			return nil, false
+		}
+	}
+	return params, len(parts) == len(rt.segments)
+}
+
+// allow returns the value of the Allow header of the route.
+func (rt *route) allow() string {
+	methods := make([]string, 0, len(rt.handlers)+1)
+	for method := range rt.handlers {
+		methods = append(methods, method)
+	}
+	if _, ok := rt.handlers[http.MethodHead]; !ok && rt.handlers[http.MethodGet] != nil {
+		methods = append(methods, http.MethodHead)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// paramName returns the name of a {name} segment.
+func paramName(segment string) (string, bool) {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+		return "", false
+	}
+	return segment[1 : len(segment)-1], true
+}
+
+// capture unescapes the value of a parameter into params, it reports false when the value is empty or invalid.
+func capture(params map[string]string, name, escaped string) bool {
+	value, err := url.PathUnescape(escaped)
+	if err != nil || value == "" {
+		return false
+	}
+	params[name] = value
+	return true
+}
+
+// routeNotFound replies to a request for an unknown path.
+func routeNotFound(w http.ResponseWriter, _ *http.Request) {
+	writeError(w, RouteNotFound, http.StatusNotFound)
+}
+
+// methodNotAllowed replies to a request for a known path with another method.
+func methodNotAllowed(w http.ResponseWriter, _ *http.Request) {
+	writeError(w, MethodNotAllowed, http.StatusMethodNotAllowed)
 }
 
 // applyMiddlewares returns a new http.HandlerFunc that applies all the middlewares to the original handler.
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter(t *testing.T) {
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(body + PathParam(r, "key") + PathParam(r, "field")))
+		}
+	}
+	router := NewRouter()
+	router.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "1")
+			next(w, r)
+		}
+	})
+	router.Delete("/delete", reply("delete"))
+	router.Get("/keys", reply("list"))
+	router.Get("/keys/count", reply("count"))
+	router.Get("/hashes/{key}/{field}", reply("field:"))
+	router.Get("/keys/{key...}", reply("get:"))
+	router.Put("/keys/{key...}", reply("put:"))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		{
+			name:       "a static route",
+			method:     http.MethodDelete,
+			target:     "/delete",
+			wantStatus: http.StatusOK,
+			wantBody:   "delete",
+		},
+		{
+			name:       "another method returns 405 Method Not Allowed",
+			method:     http.MethodGet,
+			target:     "/delete",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   MethodNotAllowed,
+			wantAllow:  "DELETE",
+		},
+		{
+			name:       "a static route is preferred to a pattern",
+			method:     http.MethodGet,
+			target:     "/keys/count",
+			wantStatus: http.StatusOK,
+			wantBody:   "count",
+		},
+		{
+			name:       "a rest parameter matches the slashes",
+			method:     http.MethodPut,
+			target:     "/keys/img/1.png",
+			wantStatus: http.StatusOK,
+			wantBody:   "put:img/1.png",
+		},
+		{
+			name:       "a parameter is unescaped",
+			method:     http.MethodGet,
+			target:     "/hashes/user%2F1/first%20name",
+			wantStatus: http.StatusOK,
+			wantBody:   "field:user/1first name",
+		},
+		{
+			name:       "a GET route serves HEAD",
+			method:     http.MethodHead,
+			target:     "/keys/a",
+			wantStatus: http.StatusOK,
+			wantBody:   "get:a",
+		},
+		{
+			name:       "a pattern with another method returns 405 Method Not Allowed",
+			method:     http.MethodPost,
+			target:     "/keys/a",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   MethodNotAllowed,
+			wantAllow:  "GET, HEAD, PUT",
+		},
+		{
+			name:       "a parameter does not match an empty segment",
+			method:     http.MethodGet,
+			target:     "/hashes/user/",
+			wantStatus: http.StatusNotFound,
+			wantBody:   RouteNotFound,
+		},
+		{
+			name:       "an unknown path returns 404 Not Found",
+			method:     http.MethodGet,
+			target:     "/unknown",
+			wantStatus: http.StatusNotFound,
+			wantBody:   RouteNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(""))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+			assert.Equal(t, tt.wantAllow, rr.Header().Get("Allow"))
+			assert.Equal(t, "1", rr.Header().Get("X-Middleware"), "the middlewares apply to every reply")
+		})
+	}
+
+	assert.Panics(t, func() { router.Get("/keys/{key...}", reply("again")) })
+
+	assert.Equal(t, []Route{
+		{Method: http.MethodDelete, Path: "/delete"},
+		{Method: http.MethodGet, Path: "/hashes/{key}/{field}"},
+		{Method: http.MethodGet, Path: "/keys"},
+		{Method: http.MethodGet, Path: "/keys/count"},
+		{Method: http.MethodGet, Path: "/keys/{key...}"},
+		{Method: http.MethodPut, Path: "/keys/{key...}"},
+	}, router.Routes())
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	MemberCanNotBeEmpty  = "Member can not be empty"
+	MembersCanNotBeEmpty = "Members can not be empty"
+)
+
+// setRequest is the body of the set writes.
+type setRequest struct {
+	Key     string   `json:"key"`
+	Members []string `json:"members"`
+}
+
+// SAdd adds members to a set, creating it if needed.
+// Body example:
+//
+//	{
+//	  "key": "tags:post:1",
+//	  "members": ["go", "databases"]
+//	}
+//
+// It replies with the number of the new members, e.g. {"added": 2}.
+func (h *Handlers) SAdd(w http.ResponseWriter, r *http.Request) {
+	var req setRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(req.Members) == 0 {
+		writeError(w, MembersCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.UseCase.SAdd(req.Key, req.Members...)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"added": added})
+}
+
+// SRem removes members from a set, removing the last member deletes the key.
+// Example: DELETE /srem?key=tags:post:1&member=go&member=databases
+// It replies with the number of the removed members, e.g. {"removed": 2}.
+func (h *Handlers) SRem(w http.ResponseWriter, r *http.Request) {
+	key, members := r.URL.Query().Get("key"), r.URL.Query()["member"]
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(members) == 0 {
+		writeError(w, MembersCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.UseCase.SRem(key, members...)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"removed": n})
+}
+
+// SMembers returns the members of a set as a JSON array in lexical order.
+// Example: GET /smembers?key=tags:post:1
+func (h *Handlers) SMembers(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.UseCase.SMembers(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, members)
+}
+
+// SIsMember replies with true or false depending on whether the member belongs to a set.
+// Example: GET /sismember?key=tags:post:1&member=go
+func (h *Handlers) SIsMember(w http.ResponseWriter, r *http.Request) {
+	key, member := r.URL.Query().Get("key"), r.URL.Query().Get("member")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if member == "" {
+		writeError(w, MemberCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.UseCase.SIsMember(key, member)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, ok)
+}
+
+// SInter returns the members belonging to all the sets as a JSON array, a missing key is an empty set.
+// Example: GET /sinter?key=tags:post:1&key=tags:post:2
+func (h *Handlers) SInter(w http.ResponseWriter, r *http.Request) {
+	h.combine(w, r, h.UseCase.SInter)
+}
+
+// SUnion returns the members belonging to any of the sets as a JSON array, a missing key is an empty set.
+// Example: GET /sunion?key=tags:post:1&key=tags:post:2
+func (h *Handlers) SUnion(w http.ResponseWriter, r *http.Request) {
+	h.combine(w, r, h.UseCase.SUnion)
+}
+
+// SDiff returns the members of the first set not belonging to any of the others as a JSON array,
+// a missing key is an empty set.
+// Example: GET /sdiff?key=tags:post:1&key=tags:post:2
+func (h *Handlers) SDiff(w http.ResponseWriter, r *http.Request) {
+	h.combine(w, r, h.UseCase.SDiff)
+}
+
+func (h *Handlers) combine(w http.ResponseWriter, r *http.Request, op func(keys ...string) ([]string, error)) {
+	keys := r.URL.Query()["key"]
+	if len(keys) == 0 {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	members, err := op(keys...)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, members)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers_Sets(t *testing.T) {
+	stor := storage.NewInMemory()
+	_ = stor.Set("string", "value", 0)
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "SAdd returns the number of the new members",
+			method:     http.MethodPost,
+			target:     "/sadd",
+			body:       `{"key":"tags:1","members":["go","db","go"]}`,
+			handler:    h.SAdd,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"added":2}`,
+		},
+		{
+			name:       "SAdd returns 400 Bad Request without members",
+			method:     http.MethodPost,
+			target:     "/sadd",
+			body:       `{"key":"tags:1"}`,
+			handler:    h.SAdd,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   MembersCanNotBeEmpty,
+		},
+		{
+			name:       "SAdd returns 409 Conflict for a string key",
+			method:     http.MethodPost,
+			target:     "/sadd",
+			body:       `{"key":"string","members":["go"]}`,
+			handler:    h.SAdd,
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+		{
+			name:       "SAdd creates a second set",
+			method:     http.MethodPost,
+			target:     "/sadd",
+			body:       `{"key":"tags:2","members":["go","cache"]}`,
+			handler:    h.SAdd,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"added":2}`,
+		},
+		{
+			name:       "SMembers returns the members in lexical order",
+			method:     http.MethodGet,
+			target:     "/smembers?key=tags:1",
+			handler:    h.SMembers,
+			wantStatus: http.StatusOK,
+			wantBody:   `["db","go"]`,
+		},
+		{
+			name:       "SIsMember returns false for a missing member",
+			method:     http.MethodGet,
+			target:     "/sismember?key=tags:1&member=cache",
+			handler:    h.SIsMember,
+			wantStatus: http.StatusOK,
+			wantBody:   `false`,
+		},
+		{
+			name:       "SInter returns the common members",
+			method:     http.MethodGet,
+			target:     "/sinter?key=tags:1&key=tags:2",
+			handler:    h.SInter,
+			wantStatus: http.StatusOK,
+			wantBody:   `["go"]`,
+		},
+		{
+			name:       "SUnion returns all the members",
+			method:     http.MethodGet,
+			target:     "/sunion?key=tags:1&key=tags:2&key=missing",
+			handler:    h.SUnion,
+			wantStatus: http.StatusOK,
+			wantBody:   `["cache","db","go"]`,
+		},
+		{
+			name:       "SDiff returns the members of the first set only",
+			method:     http.MethodGet,
+			target:     "/sdiff?key=tags:1&key=tags:2",
+			handler:    h.SDiff,
+			wantStatus: http.StatusOK,
+			wantBody:   `["db"]`,
+		},
+		{
+			name:       "SRem returns the number of the removed members",
+			method:     http.MethodDelete,
+			target:     "/srem?key=tags:1&member=db&member=missing",
+			handler:    h.SRem,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"removed":1}`,
+		},
+		{
+			name:       "ZAdd returns the number of the new members",
+			method:     http.MethodPost,
+			target:     "/zadd",
+			body:       `{"key":"board","members":{"alice":120,"bob":95.5,"carol":100}}`,
+			handler:    h.ZAdd,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"added":3}`,
+		},
+		{
+			name:       "ZIncrBy returns the new score",
+			method:     http.MethodPost,
+			target:     "/zincrby",
+			body:       `{"key":"board","member":"bob","delta":10}`,
+			handler:    h.ZIncrBy,
+			wantStatus: http.StatusOK,
+			wantBody:   "105.5",
+		},
+		{
+			name:       "ZRange returns the top members with rev",
+			method:     http.MethodGet,
+			target:     "/zrange?key=board&start=0&stop=1&rev=true",
+			handler:    h.ZRange,
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"member":"alice","score":120},{"member":"bob","score":105.5}]`,
+		},
+		{
+			name:       "ZRangeByScore returns the members between the bounds",
+			method:     http.MethodGet,
+			target:     "/zrangebyscore?key=board&min=(100&max=inf",
+			handler:    h.ZRangeByScore,
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"member":"bob","score":105.5},{"member":"alice","score":120}]`,
+		},
+		{
+			name:       "ZRangeByScore returns 400 Bad Request for an invalid bound",
+			method:     http.MethodGet,
+			target:     "/zrangebyscore?key=board&min=abc",
+			handler:    h.ZRangeByScore,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidScore,
+		},
+		{
+			name:       "ZCount returns the number of the members between the bounds",
+			method:     http.MethodGet,
+			target:     "/zcount?key=board&min=100&max=110",
+			handler:    h.ZCount,
+			wantStatus: http.StatusOK,
+			wantBody:   "2",
+		},
+		{
+			name:       "ZRank returns the rank from the lowest score",
+			method:     http.MethodGet,
+			target:     "/zrank?key=board&member=alice",
+			handler:    h.ZRank,
+			wantStatus: http.StatusOK,
+			wantBody:   "2",
+		},
+		{
+			name:       "ZRank returns 404 Not Found for a missing member",
+			method:     http.MethodGet,
+			target:     "/zrank?key=board&member=dave",
+			handler:    h.ZRank,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrMemberNotFound.Error(),
+		},
+		{
+			name:       "ZRem returns the number of the removed members",
+			method:     http.MethodDelete,
+			target:     "/zrem?key=board&member=alice",
+			handler:    h.ZRem,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"removed":1}`,
+		},
+		{
+			name:       "ZScore returns the score",
+			method:     http.MethodGet,
+			target:     "/zscore?key=board&member=carol",
+			handler:    h.ZScore,
+			wantStatus: http.StatusOK,
+			wantBody:   "100",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/rs/zerolog/log"
+	"math"
+	"net/http"
+	"time"
+)
+
+const (
+	ExpirationSetSuccessfully = "Expiration set successfully"
+	InvalidExpiration         = "Invalid expiration, give either expiration or persist"
+	ExpirationRequired        = "Expiration is required"
+	AtRequired                = "At is required"
+	ExpirationOutOfRange      = "Expiration is out of range"
+)
+
+// maxExpiration is the largest expiration, or unix time, in seconds that fits in nanoseconds,
+// a larger one would wrap around to a time in the past and delete the key.
+const maxExpiration = math.MaxInt64 / int64(time.Second)
+
+// ttlRequest is the body of the TTL writes.
+type ttlRequest struct {
+	Key string `json:"key"`
+	// Expiration is a ttl in seconds like in Set, nil when it is not given.
+	Expiration *int64 `json:"expiration"`
+	// At is an absolute expiration, in unix seconds, nil when it is not given.
+	At *int64 `json:"at"`
+	// Persist removes the expiration in GetEx.
+	Persist bool `json:"persist"`
+}
+
+// Expire sets the time to live of an existing key, of any type, without rewriting its value.
+// A non-positive expiration deletes the key, a missing or too large one is refused with 400 Bad Request.
+// Body example:
+//
+//	{
+//	  "key": "session:1",
+//	  "expiration": 60
+//	}
+//
+// It replies with 404 Not Found when the key does not exist.
+func (h *Handlers) Expire(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTTLRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Expiration == nil {
+		writeError(w, ExpirationRequired, http.StatusBadRequest)
+		return
+	}
+	if *req.Expiration > maxExpiration {
+		writeError(w, ExpirationOutOfRange, http.StatusBadRequest)
+		return
+	}
+	replyExpire(w, h.UseCase.Expire(req.Key, time.Duration(*req.Expiration)*time.Second))
+}
+
+// ExpireAt sets the expiration of an existing key to an absolute unix time in seconds, like Expire.
+// A time in the past deletes the key, a missing or too large one is refused with 400 Bad Request.
+// Body example:
+//
+//	{
+//	  "key": "session:1",
+//	  "at": 1767225600
+//	}
+func (h *Handlers) ExpireAt(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTTLRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.At == nil {
+		writeError(w, AtRequired, http.StatusBadRequest)
+		return
+	}
+	if *req.At > maxExpiration {
+		writeError(w, ExpirationOutOfRange, http.StatusBadRequest)
+		return
+	}
+	replyExpire(w, h.UseCase.ExpireAt(req.Key, time.Unix(*req.At, 0)))
+}
+
+// Persist removes the expiration of a key, so it no longer expires.
+// Body example:
+//
+//	{
+//	  "key": "session:1"
+//	}
+//
+// It replies with {"persisted": true}, or false when the key had no expiration,
+// and with 404 Not Found when the key does not exist.
+func (h *Handlers) Persist(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTTLRequest(w, r)
+	if !ok {
+		return
+	}
+	persisted, err := h.UseCase.Persist(req.Key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]bool{"persisted": persisted})
+}
+
+// TTL returns the remaining time to live of a key.
+// Example: GET /ttl?key=session:1
+// It replies with {"ttl": 59, "ttl_ms": 59342}, rounded like in Redis, or {"ttl": -1, "ttl_ms": -1}
+// when the key does not expire, and with 404 Not Found when the key does not exist.
+func (h *Handlers) TTL(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	ttl, err := h.UseCase.TTL(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	if ttl == domain.NoExpiration {
+		writeJSON(w, map[string]int64{"ttl": -1, "ttl_ms": -1})
+		return
+	}
+	writeJSON(w, map[string]int64{
+		"ttl":    int64((ttl + time.Second/2) / time.Second),
+		"ttl_ms": int64((ttl + time.Millisecond/2) / time.Millisecond),
+	})
+}
+
+// GetEx returns the value of a key like Get and sets its time to live in the same step,
+// so a session can be read and kept alive at once.
+// Body example:
+//
+//	{
+//	  "key": "session:1",
+//	  "expiration": 1800
+//	}
+//
+// persist: true removes the expiration instead, without either the expiration is kept.
+func (h *Handlers) GetEx(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTTLRequest(w, r)
+	if !ok {
+		return
+	}
+	var expiration int64
+	if req.Expiration != nil {
+		expiration = *req.Expiration
+	}
+	ttl := time.Duration(expiration) * time.Second
+	switch {
+	case req.Persist && expiration != 0:
+		writeError(w, InvalidExpiration, http.StatusBadRequest)
+		return
+	case expiration < 0:
+		writeError(w, InvalidDuration, http.StatusBadRequest)
+		return
+	case expiration > maxExpiration:
+		writeError(w, ExpirationOutOfRange, http.StatusBadRequest)
+		return
+	case req.Persist:
+		ttl = domain.NoExpiration
+	}
+
+	value, err := h.UseCase.GetEx(req.Key, ttl)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	_, err = w.Write([]byte(value))
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
+
+// replyExpire replies to Expire and ExpireAt.
+func replyExpire(w http.ResponseWriter, err error) {
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	_, err = w.Write([]byte(ExpirationSetSuccessfully))
+	if err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
+
+// decodeTTLRequest decodes the body of the TTL writes.
+// It replies with 400 Bad Request and reports false when the body is invalid or the key is empty.
+func decodeTTLRequest(w http.ResponseWriter, r *http.Request) (ttlRequest, bool) {
+	var req ttlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return req, false
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlers_TTL(t *testing.T) {
+	stor := storage.NewInMemory()
+	_ = stor.Set("session", "alice", 0)
+	_ = stor.Set("volatile", "v", time.Hour)
+	_, _ = stor.HSet("hash", map[string]string{"f": "v"})
+	h := NewHandlers(stor)
+	at := time.Now().Add(2 * time.Hour).Unix()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "TTL of a key without expiration is -1",
+			method:     http.MethodGet,
+			target:     "/ttl?key=session",
+			handler:    h.TTL,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ttl":-1,"ttl_ms":-1}`,
+		},
+		{
+			name:       "TTL of a missing key returns 404 Not Found",
+			method:     http.MethodGet,
+			target:     "/ttl?key=missing",
+			handler:    h.TTL,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrKeyNotFound.Error(),
+		},
+		{
+			name:       "Expire sets the ttl of a key",
+			method:     http.MethodPost,
+			target:     "/expire",
+			body:       `{"key":"session","expiration":60}`,
+			handler:    h.Expire,
+			wantStatus: http.StatusOK,
+			wantBody:   ExpirationSetSuccessfully,
+		},
+		{
+			name:       "TTL returns the remaining time to live",
+			method:     http.MethodGet,
+			target:     "/ttl?key=session",
+			handler:    h.TTL,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"ttl":60,"ttl_ms":60000}`,
+		},
+		{
+			name:       "Expire sets the ttl of a hash",
+			method:     http.MethodPost,
+			target:     "/expire",
+			body:       `{"key":"hash","expiration":60}`,
+			handler:    h.Expire,
+			wantStatus: http.StatusOK,
+			wantBody:   ExpirationSetSuccessfully,
+		},
+		{
+			name:       "Expire of a missing key returns 404 Not Found",
+			method:     http.MethodPost,
+			target:     "/expire",
+			body:       `{"key":"missing","expiration":60}`,
+			handler:    h.Expire,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrKeyNotFound.Error(),
+		},
+		{
+			name:       "ExpireAt sets an absolute expiration",
+			method:     http.MethodPost,
+			target:     "/expireat",
+			body:       `{"key":"volatile","at":` + strconv.FormatInt(at, 10) + `}`,
+			handler:    h.ExpireAt,
+			wantStatus: http.StatusOK,
+			wantBody:   ExpirationSetSuccessfully,
+		},
+		{
+			name:       "Persist removes the expiration",
+			method:     http.MethodPost,
+			target:     "/persist",
+			body:       `{"key":"hash"}`,
+			handler:    h.Persist,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"persisted":true}`,
+		},
+		{
+			name:       "Persist of a key without expiration",
+			method:     http.MethodPost,
+			target:     "/persist",
+			body:       `{"key":"hash"}`,
+			handler:    h.Persist,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"persisted":false}`,
+		},
+		{
+			name:       "Expire returns 400 Bad Request without an expiration",
+			method:     http.MethodPost,
+			target:     "/expire",
+			body:       `{"key":"session","ttl":60}`,
+			handler:    h.Expire,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   ExpirationRequired,
+		},
+		{
+			name:       "ExpireAt returns 400 Bad Request without a time",
+			method:     http.MethodPost,
+			target:     "/expireat",
+			body:       `{"key":"session"}`,
+			handler:    h.ExpireAt,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   AtRequired,
+		},
+		{
+			name:       "GetEx returns the value and refreshes the ttl",
+			method:     http.MethodPost,
+			target:     "/getex",
+			body:       `{"key":"session","expiration":1800}`,
+			handler:    h.GetEx,
+			wantStatus: http.StatusOK,
+			wantBody:   "alice",
+		},
+		{
+			name:       "GetEx of a hash returns 409 Conflict",
+			method:     http.MethodPost,
+			target:     "/getex",
+			body:       `{"key":"hash","expiration":1800}`,
+			handler:    h.GetEx,
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+		{
+			name:       "GetEx returns 400 Bad Request for both an expiration and persist",
+			method:     http.MethodPost,
+			target:     "/getex",
+			body:       `{"key":"session","expiration":1800,"persist":true}`,
+			handler:    h.GetEx,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidExpiration,
+		},
+		{
+			name:       "Expire returns 400 Bad Request without a key",
+			method:     http.MethodPost,
+			target:     "/expire",
+			body:       `{"expiration":60}`,
+			handler:    h.Expire,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   KeyCanNotBeEmpty,
+		},
+		{
+			name:       "Expire with a non-positive expiration deletes the key",
+			method:     http.MethodPost,
+			target:     "/expire",
+			body:       `{"key":"volatile","expiration":0}`,
+			handler:    h.Expire,
+			wantStatus: http.StatusOK,
+			wantBody:   ExpirationSetSuccessfully,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+
+	ttl, err := stor.TTL("session")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 29*time.Minute, "GetEx refreshes the ttl")
+	_, err = stor.Get("volatile")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+}
+
+func TestHandlers_TTLOverflow(t *testing.T) {
+	stor := storage.NewInMemory()
+	_ = stor.Set("session", "alice", 0)
+	h := NewHandlers(stor)
+
+	// 10^10 seconds overflows a time.Duration, which would wrap around to a time in the past.
+	tests := []struct {
+		name    string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{"Expire", `{"key":"session","expiration":10000000000}`, h.Expire},
+		{"ExpireAt", `{"key":"session","at":10000000000}`, h.ExpireAt},
+		{"GetEx", `{"key":"session","expiration":10000000000}`, h.GetEx},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" refuses an expiration out of range and keeps the key", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, "invalid_expiration", problem.Code)
+
+			value, err := stor.Get("session")
+			require.NoError(t, err)
+			assert.Equal(t, "alice", value)
+			ttl, err := stor.TTL("session")
+			require.NoError(t, err)
+			assert.Equal(t, domain.NoExpiration, ttl)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"net/http"
+	"time"
+)
+
+const OperationsCanNotBeEmpty = "Operations can not be empty"
+
+// txnRequest is the body of Txn.
+type txnRequest struct {
+	Watch []struct {
+		Key     string `json:"key"`
+		Version uint64 `json:"version"`
+	} `json:"watch"`
+	Ops []struct {
+		Op    domain.OpType `json:"op"`
+		Key   string        `json:"key"`
+		Value string        `json:"value"`
+		// Expiration is the ttl set by a set operation, in seconds like in Set.
+		Expiration int64 `json:"expiration"`
+		Delta      int64 `json:"delta"`
+	} `json:"ops"`
+}
+
+// txnResult is the result of an operation of a committed transaction.
+type txnResult struct {
+	Version uint64 `json:"version,omitempty"`
+	Value   *int64 `json:"value,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// txnResponse is the reply of Txn.
+type txnResponse struct {
+	Committed bool        `json:"committed"`
+	Results   []txnResult `json:"results,omitempty"`
+}
+
+// Txn applies a batch of set, delete and incr operations atomically: either all of them are applied or none.
+// The transaction commits only if every watched key is still at its version, the ETag of GET /get,
+// version 0 meaning the key must be missing.
+// Body example, moving 30 from a balance to another one after reading the first one:
+//
+//	{
+//	  "watch": [{"key": "balance:alice", "version": 42}],
+//	  "ops": [
+//	    {"op": "incr", "key": "balance:alice", "delta": -30},
+//	    {"op": "incr", "key": "balance:bob", "delta": 30},
+//	    {"op": "set", "key": "transfer:1", "value": "done", "expiration": 3600},
+//	    {"op": "delete", "key": "transfer:1:pending"}
+//	  ]
+//	}
+//
+// It replies with the result of every operation in order, the new version of the key, the new value for incr
+// and whether delete removed the key, e.g. {"committed": true, "results": [{"version": 43, "value": 70}, ...]}.
+// When a watched key has changed it replies with 412 Precondition Failed and the txn_aborted problem,
+// when an operation fails nothing is applied and the error is replied like for a single operation.
+func (h *Handlers) Txn(w http.ResponseWriter, r *http.Request) {
+	var req txnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if len(req.Ops) == 0 {
+		writeError(w, OperationsCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	watches := make([]domain.Watch, len(req.Watch))
+	for idx, watch := range req.Watch {
+		if watch.Key == "" {
+			writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+			return
+		}
+		watches[idx] = domain.Watch{Key: watch.Key, Version: watch.Version}
+	}
+	ops := make([]domain.Op, len(req.Ops))
+	for idx, op := range req.Ops {
+		switch {
+		case op.Key == "":
+			writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+			return
+		case op.Op == domain.OpSet && op.Value == "":
+			writeError(w, ValueCanNotBeEmpty, http.StatusBadRequest)
+			return
+		case op.Expiration < 0:
+			writeError(w, InvalidDuration, http.StatusBadRequest)
+			return
+		}
+		ops[idx] = domain.Op{
+			Type:  op.Op,
+			Key:   op.Key,
+			Value: op.Value,
+			TTL:   time.Duration(op.Expiration) * time.Second,
+			Delta: op.Delta,
+		}
+	}
+
+	results, err := h.UseCase.Txn(watches, ops)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	resp := txnResponse{Committed: true, Results: make([]txnResult, len(results))}
+	for idx, result := range results {
+		resp.Results[idx] = txnResult{Version: result.Version, Deleted: result.Deleted}
+		if ops[idx].Type == domain.OpIncr {
+			value := result.Value
+			resp.Results[idx].Value = &value
+		}
+	}
+	writeJSON(w, resp)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlers_Txn(t *testing.T) {
+	stor := storage.NewInMemory()
+	version, err := stor.SetWith("balance:alice", "100", 0, domain.SetOptions{})
+	assert.NoError(t, err)
+	last, err := stor.SetWith("text", "abc", 0, domain.SetOptions{})
+	assert.NoError(t, err)
+	h := NewHandlers(stor)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "Txn applies the operations and returns their results",
+			body: `{"watch":[{"key":"balance:alice","version":` + strconv.FormatUint(version, 10) + `}],"ops":[` +
+				`{"op":"incr","key":"balance:alice","delta":-30},{"op":"incr","key":"balance:bob","delta":30},` +
+				`{"op":"set","key":"transfer:1","value":"done","expiration":60},{"op":"delete","key":"transfer:1:pending"}]}`,
+			wantStatus: http.StatusOK,
+			wantBody: `{"committed":true,"results":[{"version":` + strconv.FormatUint(last+1, 10) + `,"value":70},` +
+				`{"version":` + strconv.FormatUint(last+2, 10) + `,"value":30},` +
+				`{"version":` + strconv.FormatUint(last+3, 10) + `},{}]}`,
+		},
+		{
+			name:       "Txn returns 412 Precondition Failed when a watched key changed",
+			body:       `{"watch":[{"key":"balance:alice","version":` + strconv.FormatUint(version, 10) + `}],"ops":[{"op":"delete","key":"balance:alice"}]}`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrTxnAborted.Error(),
+		},
+		{
+			name:       "Txn returns 400 Bad Request and applies nothing when an operation fails",
+			body:       `{"ops":[{"op":"delete","key":"balance:alice"},{"op":"incr","key":"text","delta":1}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "operation 1: " + domain.ErrNotInteger.Error(),
+		},
+		{
+			name:       "Txn returns 400 Bad Request for an unknown operation",
+			body:       `{"ops":[{"op":"rename","key":"text"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `operation 0: invalid operation "rename"`,
+		},
+		{
+			name:       "Txn returns 400 Bad Request without operations",
+			body:       `{"ops":[]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   OperationsCanNotBeEmpty,
+		},
+		{
+			name:       "Txn returns 400 Bad Request for a set without a value",
+			body:       `{"ops":[{"op":"set","key":"text"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   ValueCanNotBeEmpty,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/txn", strings.NewReader(tt.body))
+			assert.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(h.Txn).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+		})
+	}
+
+	value, err := stor.Get("balance:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "70", value, "the failed transactions apply nothing")
+}
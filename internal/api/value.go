@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/rs/zerolog/log"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxValueSize is the maximum size of a value written by PUT /keys/{key}.
+const MaxValueSize = 32 << 20
+
+const (
+	InvalidContentType = "Invalid Content-Type"
+	ValueTooLarge      = "Value too large, it is limited to 32 MiB"
+	NothingToPatch     = "Nothing to patch, give a value, an expiration or a content type"
+)
+
+const (
+	// defaultContentType is the media type of a value written without one by PUT /keys/{key}.
+	defaultContentType = "application/octet-stream"
+	// textContentType is the media type of a value written by the JSON endpoints.
+	textContentType = "text/plain; charset=utf-8"
+)
+
+// PutValue stores the request body as is, of any content type, e.g. an image or a gzip blob without base64.
+// The key is the path parameter key, it may contain slashes.
+// Example: PUT /keys/avatar:1?expiration=3600 with Content-Type: image/png and the image as the body.
+// expiration is optional and is in seconds, the Content-Type is application/octet-stream when it is missing.
+// The write can be made conditional with the If-Match and If-None-Match headers like POST /set.
+// It replies with 201 Created and the new version of the key in the ETag header.
+func (h *Handlers) PutValue(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	opts, ok := setOptions(w, r)
+	if !ok {
+		return
+	}
+
+	var ttl time.Duration
+	if raw := r.URL.Query().Get("expiration"); raw != "" {
+		seconds, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || seconds < 0 {
+			writeError(w, InvalidDuration, http.StatusBadRequest)
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	opts.ContentType = r.Header.Get("Content-Type")
+	if opts.ContentType == "" {
+		opts.ContentType = defaultContentType
+	}
+	if _, _, err := mime.ParseMediaType(opts.ContentType); err != nil {
+		writeError(w, InvalidContentType, http.StatusBadRequest)
+		return
+	}
+
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxValueSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, ValueTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if len(value) == 0 {
+		writeError(w, ValueCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	version, err := h.UseCase.SetWith(key, string(value), ttl, opts)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	w.Header().Set("ETag", etag(version))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetValue replies with the value of the key as is, with the Content-Type it was written with,
+// text/plain for the values written by POST /set, its Content-Length and its version in the ETag header.
+// It replies with 304 Not Modified when If-None-Match holds the current ETag.
+// HEAD replies with the headers only.
+func (h *Handlers) GetValue(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	entity, err := h.UseCase.GetEntity(key)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	tag := etag(entity.Version)
+	w.Header().Set("ETag", tag)
+	if r.Header.Get("If-None-Match") == tag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType(entity))
+	w.Header().Set("Content-Length", strconv.Itoa(len(entity.Value)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err = io.WriteString(w, entity.Value); err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+	}
+}
+
+// DeleteValue deletes the key, the path parameter key.
+// With If-Match: "<version>" it deletes the key only if it is still at the version,
+// replying with 412 Precondition Failed otherwise.
+// It replies with 204 No Content once the key is deleted.
+func (h *Handlers) DeleteValue(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	match := r.Header.Get("If-Match")
+	switch version, valid := parseETag(match); {
+	case match == "" || match == "*":
+		err = h.UseCase.Delete(key)
+	case !valid:
+		writeError(w, InvalidPrecondition, http.StatusBadRequest)
+		return
+	default:
+		// A watched key that is missing or at another version aborts the transaction.
+		_, err = h.UseCase.Txn([]domain.Watch{{Key: key, Version: version}}, []domain.Op{{Type: domain.OpDelete, Key: key}})
+	}
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// patchRequest is the body of PatchValue, a missing field is kept as is.
+type patchRequest struct {
+	Value *string `json:"value"`
+	// Expiration is a ttl in seconds, 0 removes the expiration.
+	Expiration  *int64  `json:"expiration"`
+	ContentType *string `json:"content_type"`
+}
+
+// PatchValue changes a part of an existing string key, the path parameter key, keeping the rest of it.
+// Body example, replacing the value while keeping the expiration and the content type:
+//
+//	{
+//	  "value": "new value"
+//	}
+//
+// "expiration" sets the ttl in seconds, 0 removing it, and "content_type" the media type of the value.
+// With If-Match: "<version>" the key is changed only if it is still at the version.
+// It replies with the new version, expiration and content type of the key, e.g.
+// {"version": 43, "expiration": 1767225600000000000, "content_type": "text/csv"}, and the version in the ETag header.
+func (h *Handlers) PatchValue(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	var version uint64
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		if version, ok = parseETag(match); !ok {
+			writeError(w, InvalidPrecondition, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var req patchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	switch {
+	case req.Value == nil && req.Expiration == nil && req.ContentType == nil:
+		writeError(w, NothingToPatch, http.StatusBadRequest)
+		return
+	case req.Value != nil && *req.Value == "":
+		writeError(w, ValueCanNotBeEmpty, http.StatusBadRequest)
+		return
+	case req.Expiration != nil && *req.Expiration < 0:
+		writeError(w, InvalidDuration, http.StatusBadRequest)
+		return
+	case req.ContentType != nil && *req.ContentType != "":
+		if _, _, err := mime.ParseMediaType(*req.ContentType); err != nil {
+			writeError(w, InvalidContentType, http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := h.UseCase.Now()
+	entity, err := h.UseCase.Update(key, func(current domain.Entity, exists bool) (domain.Entity, error) {
+		switch {
+		case !exists:
+			return current, domain.ErrKeyNotFound
+		case current.Type != domain.TypeString:
+			return current, domain.ErrWrongType
+		case version != 0 && current.Version != version:
+			return current, domain.ErrConditionNotMet
+		}
+		if req.Value != nil {
+			current.Value = *req.Value
+		}
+		if req.ContentType != nil {
+			current.ContentType = *req.ContentType
+		}
+		if req.Expiration != nil {
+			// An explicit expiration ends the sliding, like Expire.
+			current.Expiration, current.Sliding, current.SlidingTTL = 0, false, 0
+			if *req.Expiration > 0 {
+				current.Expiration = now.Add(time.Duration(*req.Expiration) * time.Second).UnixNano()
+			}
+		}
+		return current, nil
+	})
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+
+	w.Header().Set("ETag", etag(entity.Version))
+	writeJSON(w, map[string]interface{}{
+		"version":      entity.Version,
+		"expiration":   entity.Expiration,
+		"content_type": entity.ContentType,
+	})
+}
+
+// pathKey returns the path parameter key.
+// It replies with 400 Bad Request and reports false when it is empty.
+func pathKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := PathParam(r, "key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return "", false
+	}
+	return key, true
+}
+
+// contentType returns the media type of the value of the entity.
+func contentType(entity domain.Entity) string {
+	if entity.ContentType == "" {
+		return textContentType
+	}
+	return entity.ContentType
+}
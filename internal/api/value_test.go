@@ -0,0 +1,327 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newValueRouter routes the values like the server does.
+func newValueRouter(h *Handlers) *Router {
+	router := NewRouter()
+	router.Get("/keys/{key...}", h.GetValue)
+	router.Put("/keys/{key...}", h.PutValue)
+	router.Get("/v2/keys/{key...}", h.GetValue)
+	router.Put("/v2/keys/{key...}", h.PutValue)
+	router.Patch("/v2/keys/{key...}", h.PatchValue)
+	router.Delete("/v2/keys/{key...}", h.DeleteValue)
+	return router
+}
+
+func TestHandlers_Value(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff"
+	stor := storage.NewInMemory()
+	_ = stor.Set("text", "hello", 0)
+	_, _ = stor.HSet("hash", map[string]string{"f": "v"})
+	router := newValueRouter(NewHandlers(stor))
+
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		header          map[string]string
+		body            string
+		wantStatus      int
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:       "PUT stores the raw body",
+			method:     http.MethodPut,
+			target:     "/keys/avatar:1",
+			header:     map[string]string{"Content-Type": "image/png"},
+			body:       png,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:            "GET returns the value with its content type",
+			method:          http.MethodGet,
+			target:          "/keys/avatar:1",
+			wantStatus:      http.StatusOK,
+			wantBody:        png,
+			wantContentType: "image/png",
+		},
+		{
+			name:            "HEAD returns the headers only",
+			method:          http.MethodHead,
+			target:          "/keys/avatar:1",
+			wantStatus:      http.StatusOK,
+			wantContentType: "image/png",
+		},
+		{
+			name:       "PUT without a content type stores an octet stream under a key with slashes",
+			method:     http.MethodPut,
+			target:     "/keys/blobs/2024/1.gz?expiration=60",
+			body:       "\x1f\x8b\x08\x00",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:            "GET of a key with slashes",
+			method:          http.MethodGet,
+			target:          "/keys/blobs/2024/1.gz",
+			wantStatus:      http.StatusOK,
+			wantBody:        "\x1f\x8b\x08\x00",
+			wantContentType: defaultContentType,
+		},
+		{
+			name:            "GET of a value written by POST /set is plain text",
+			method:          http.MethodGet,
+			target:          "/keys/text",
+			wantStatus:      http.StatusOK,
+			wantBody:        "hello",
+			wantContentType: textContentType,
+		},
+		{
+			name:       "GET of a missing key returns 404 Not Found",
+			method:     http.MethodGet,
+			target:     "/keys/missing",
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrKeyNotFound.Error(),
+		},
+		{
+			name:       "GET of a hash returns 409 Conflict",
+			method:     http.MethodGet,
+			target:     "/keys/hash",
+			wantStatus: http.StatusConflict,
+			wantBody:   domain.ErrWrongType.Error(),
+		},
+		{
+			name:       "PUT with If-None-Match does not overwrite an existing key",
+			method:     http.MethodPut,
+			target:     "/keys/avatar:1",
+			header:     map[string]string{"If-None-Match": "*"},
+			body:       "other",
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrConditionNotMet.Error(),
+		},
+		{
+			name:       "PUT returns 400 Bad Request for an invalid content type",
+			method:     http.MethodPut,
+			target:     "/keys/avatar:2",
+			header:     map[string]string{"Content-Type": "image/"},
+			body:       png,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   InvalidContentType,
+		},
+		{
+			name:       "PUT returns 400 Bad Request for an empty body",
+			method:     http.MethodPut,
+			target:     "/keys/avatar:2",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   ValueCanNotBeEmpty,
+		},
+		{
+			name:       "PUT without a key returns 404 Not Found",
+			method:     http.MethodPut,
+			target:     "/keys/",
+			body:       png,
+			wantStatus: http.StatusNotFound,
+			wantBody:   RouteNotFound,
+		},
+		{
+			name:       "POST returns 405 Method Not Allowed",
+			method:     http.MethodPost,
+			target:     "/keys/avatar:1",
+			body:       png,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   MethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+				assert.NotEmpty(t, rr.Header().Get("Content-Length"))
+			}
+			if tt.wantStatus == http.StatusOK || tt.wantStatus == http.StatusCreated {
+				assert.NotEmpty(t, rr.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestHandlers_ValueNotModified(t *testing.T) {
+	h := NewHandlers(storage.NewInMemory())
+	router := newValueRouter(h)
+
+	put := httptest.NewRequest(http.MethodPut, "/keys/doc", strings.NewReader(`{"a":1}`))
+	put.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, put)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	tag := rr.Header().Get("ETag")
+
+	get := httptest.NewRequest(http.MethodGet, "/keys/doc", nil)
+	get.Header.Set("If-None-Match", tag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, get)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	h.Get(rr, httptest.NewRequest(http.MethodGet, "/get?key=doc", nil))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "GET /get replies with the content type too")
+	assert.Equal(t, tag, rr.Header().Get("ETag"))
+}
+
+func TestHandlers_ValueV2(t *testing.T) {
+	stor := storage.NewInMemory()
+	version, err := stor.SetWith("doc", "v1", time.Hour, domain.SetOptions{ContentType: "text/csv"})
+	require.NoError(t, err)
+	_ = stor.Set("other", "v", 0)
+	router := newValueRouter(NewHandlers(stor))
+	current := strconv.Quote(strconv.FormatUint(version, 10))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		header     map[string]string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "PATCH with a stale If-Match returns 412 Precondition Failed",
+			method:     http.MethodPatch,
+			target:     "/v2/keys/doc",
+			header:     map[string]string{"If-Match": `"999"`},
+			body:       `{"value":"v2"}`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrConditionNotMet.Error(),
+		},
+		{
+			name:       "PATCH replaces the value and keeps the rest",
+			method:     http.MethodPatch,
+			target:     "/v2/keys/doc",
+			header:     map[string]string{"If-Match": current},
+			body:       `{"value":"v2"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "PATCH returns 400 Bad Request for an empty patch",
+			method:     http.MethodPatch,
+			target:     "/v2/keys/doc",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   NothingToPatch,
+		},
+		{
+			name:       "PATCH of a missing key returns 404 Not Found",
+			method:     http.MethodPatch,
+			target:     "/v2/keys/missing",
+			body:       `{"expiration":60}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   domain.ErrKeyNotFound.Error(),
+		},
+		{
+			name:       "DELETE with a stale If-Match returns 412 Precondition Failed",
+			method:     http.MethodDelete,
+			target:     "/v2/keys/doc",
+			header:     map[string]string{"If-Match": current},
+			wantStatus: http.StatusPreconditionFailed,
+			wantBody:   domain.ErrTxnAborted.Error(),
+		},
+		{
+			name:       "DELETE deletes the key",
+			method:     http.MethodDelete,
+			target:     "/v2/keys/other",
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "POST returns 405 Method Not Allowed",
+			method:     http.MethodPost,
+			target:     "/v2/keys/doc",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   MethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.NotEqual(t, current, rr.Header().Get("ETag"))
+				return
+			}
+			assert.Equal(t, tt.wantBody, replyText(t, rr))
+			if tt.wantStatus == http.StatusMethodNotAllowed {
+				assert.Equal(t, "DELETE, GET, HEAD, PATCH, PUT", rr.Header().Get("Allow"))
+			}
+		})
+	}
+
+	entity, err := stor.GetEntity("doc")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", entity.Value)
+	assert.Equal(t, "text/csv", entity.ContentType, "PATCH keeps the content type")
+	assert.NotZero(t, entity.Expiration, "PATCH keeps the expiration")
+	_, err = stor.Get("other")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+}
+
+// frozenRepository is a storage whose clock stands still, like the fake clock of the storage tests.
+type frozenRepository struct {
+	domain.Repository
+	now time.Time
+}
+
+func (r frozenRepository) Now() time.Time {
+	return r.now
+}
+
+func TestHandlers_PatchValueClock(t *testing.T) {
+	stor := storage.NewInMemory()
+	_, err := stor.SetWith("session", "token", time.Hour, domain.SetOptions{Sliding: true})
+	require.NoError(t, err)
+	now := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	router := newValueRouter(NewHandlers(frozenRepository{Repository: stor, now: now}))
+
+	req, err := http.NewRequest(http.MethodPatch, "/v2/keys/session", strings.NewReader(`{"expiration":60}`))
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	entity, err := stor.GetEntity("session")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute).UnixNano(), entity.Expiration, "the expiration is measured against the storage clock")
+	assert.False(t, entity.Sliding)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	InvalidRevision       = "Invalid revision"
+	RevisionCompacted     = "Revision is no longer available, read the keys again and watch from now"
+	StreamingNotSupported = "Streaming is not supported"
+)
+
+const (
+	// watchBuffer is the number of changes queued for a watcher before it is considered lagging.
+	watchBuffer = 256
+	// watchHeartbeat is how often an idle stream sends a comment, so proxies do not close it.
+	watchHeartbeat = 15 * time.Second
+)
+
+// WatchHandlers streams the changes of the storage.
+type WatchHandlers struct {
+	Bus *events.Bus
+	// Heartbeat is how often an idle stream sends a comment.
+	Heartbeat time.Duration
+}
+
+// NewWatchHandlers returns a new instance of WatchHandlers.
+func NewWatchHandlers(bus *events.Bus) *WatchHandlers {
+	return &WatchHandlers{Bus: bus, Heartbeat: watchHeartbeat}
+}
+
+// Watch streams the changes of the keys starting with the prefix query parameter as Server-Sent Events.
+// Every event has the kind of the change as its type, the change encoded as JSON as its data
+// and the epoch of the bus and the revision of the change as its id, e.g. 9f1c2a7b3d4e5f60-42.
+// The stream starts with an id-only event holding the id the changes follow, so a client reconnecting
+// before any change, or during the replay of a resumed stream, does not miss one.
+//
+// The stream resumes after the id given in the Last-Event-ID header, which browsers send when reconnecting,
+// or the revision query parameter. It returns 410 Gone when the changes after the id are no longer kept,
+// or when the id is of another epoch, as the revisions restart with the server.
+// A watcher falling behind gets an error event and the stream ends, it may reconnect to resume.
+//
+// Example: GET /watch?prefix=user:&revision=9f1c2a7b3d4e5f60-42
+func (h *WatchHandlers) Watch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, StreamingNotSupported, http.StatusInternalServerError)
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	from := r.Header.Get("Last-Event-ID")
+	if from == "" {
+		from = r.URL.Query().Get("revision")
+	}
+
+	var sub *events.Subscription
+	if from == "" {
+		sub = h.Bus.Subscribe(prefix, watchBuffer)
+	} else {
+		revision, err := parseEventID(from, h.Bus.Epoch())
+		if errors.Is(err, strconv.ErrSyntax) || errors.Is(err, strconv.ErrRange) {
+			writeError(w, InvalidRevision, http.StatusBadRequest)
+			return
+		}
+		if err == nil {
+			sub, err = h.Bus.SubscribeAfter(prefix, watchBuffer, revision)
+		}
+		if errors.Is(err, events.ErrCompacted) {
+			writeError(w, RevisionCompacted, http.StatusGone)
+			return
+		}
+		if err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "id: %s\n\n", eventID(h.Bus.Epoch(), sub.Start())); err != nil {
+		log.Error().Err(err).Msg(FailToWriteResponse)
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		done := false
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case c, ok := <-sub.C():
+			if !ok {
+				// The bus is closed on shutdown, only a lagging watcher is told why the stream ends
+				if !errors.Is(sub.Err(), events.ErrLagged) {
+					return
+				}
+				data, _ := json.Marshal(sub.Err().Error())
+				err = writeEvent(w, "", "error", data)
+				done = true
+				break
+			}
+
+			data, _ := json.Marshal(c)
+			err = writeEvent(w, eventID(h.Bus.Epoch(), c.Revision), string(c.Type), data)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg(FailToWriteResponse)
+			return
+		}
+		flusher.Flush()
+		if done {
+			return
+		}
+	}
+}
+
+// eventID returns the id of the event of a revision of the bus of the epoch.
+func eventID(epoch string, revision uint64) string {
+	return epoch + "-" + strconv.FormatUint(revision, 10)
+}
+
+// parseEventID returns the revision of an event id, a strconv error when it is invalid
+// and events.ErrCompacted when it is of another epoch, e.g. a bare revision or one issued before a restart.
+func parseEventID(id, epoch string) (uint64, error) {
+	idEpoch, raw, ok := strings.Cut(id, "-")
+	if !ok {
+		idEpoch, raw = "", id
+	}
+	revision, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if idEpoch != epoch {
+		return 0, events.ErrCompacted
+	}
+	return revision, nil
+}
+
+// writeEvent writes a Server-Sent Event, the id is omitted when empty.
+func writeEvent(w http.ResponseWriter, id string, typ string, data []byte) error {
+	var event []byte
+	if id != "" {
+		event = append(event, "id: "+id+"\n"...)
+	}
+	event = append(event, "event: "+typ+"\ndata: "...)
+	event = append(event, data...)
+	event = append(event, "\n\n"...)
+	_, err := w.Write(event)
+	return err
+}
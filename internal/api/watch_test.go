@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is a parsed Server-Sent Event.
+type sseEvent struct {
+	id, event, data string
+}
+
+// readEvent reads the next event of the stream, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if ev != (sseEvent{}) {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// watch starts a watch request against the server and returns the response.
+func watch(t *testing.T, srv *httptest.Server, query, lastEventID string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/watch"+query, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+	return res
+}
+
+func TestWatchHandlers_Watch(t *testing.T) {
+	bus := events.NewBus(2)
+	srv := httptest.NewServer(LoggingMiddleware(NewWatchHandlers(bus).Watch))
+	defer srv.Close()
+
+	res := watch(t, srv, "?prefix=user:", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	stream := bufio.NewReader(res.Body)
+	id := func(revision uint64) string { return eventID(bus.Epoch(), revision) }
+	assert.Equal(t, sseEvent{id: id(0)}, readEvent(t, stream))
+
+	bus.Publish(domain.Change{Type: domain.ChangeSet, Key: "order:1", Entity: domain.Entity{Key: "order:1", Value: "v"}})
+	bus.Publish(domain.Change{Type: domain.ChangeSet, Key: "user:1", Entity: domain.Entity{Key: "user:1", Value: "v"}})
+	bus.Publish(domain.Change{Type: domain.ChangeExpire, Key: "user:1"})
+
+	ev := readEvent(t, stream)
+	assert.Equal(t, id(2), ev.id)
+	assert.Equal(t, "set", ev.event)
+	var c domain.Change
+	require.NoError(t, json.Unmarshal([]byte(ev.data), &c))
+	assert.Equal(t, domain.Change{Type: domain.ChangeSet, Key: "user:1", Entity: domain.Entity{Key: "user:1", Value: "v"}, Revision: 2}, c)
+
+	ev = readEvent(t, stream)
+	assert.Equal(t, id(3), ev.id)
+	assert.Equal(t, "expired", ev.event)
+
+	t.Run("resumes after the Last-Event-ID", func(t *testing.T) {
+		stream := bufio.NewReader(watch(t, srv, "?prefix=user:&revision="+id(0), id(2)).Body)
+		assert.Equal(t, sseEvent{id: id(2)}, readEvent(t, stream))
+		assert.Equal(t, id(3), readEvent(t, stream).id)
+	})
+
+	t.Run("resumes after the revision parameter", func(t *testing.T) {
+		stream := bufio.NewReader(watch(t, srv, "?revision="+id(1), "").Body)
+		assert.Equal(t, sseEvent{id: id(1)}, readEvent(t, stream))
+		assert.Equal(t, id(2), readEvent(t, stream).id)
+		assert.Equal(t, id(3), readEvent(t, stream).id)
+	})
+
+	t.Run("resumes after a connection dropped during the replay", func(t *testing.T) {
+		res := watch(t, srv, "", id(1))
+		stream := bufio.NewReader(res.Body)
+		last := readEvent(t, stream).id
+		assert.Equal(t, id(1), last, "the stream starts at the resumed id, not at the head")
+		last = readEvent(t, stream).id
+		require.NoError(t, res.Body.Close())
+
+		stream = bufio.NewReader(watch(t, srv, "", last).Body)
+		assert.Equal(t, sseEvent{id: id(2)}, readEvent(t, stream))
+		assert.Equal(t, id(3), readEvent(t, stream).id, "the rest of the replay is not skipped")
+	})
+
+	t.Run("returns 410 Gone for a compacted revision", func(t *testing.T) {
+		assert.Equal(t, http.StatusGone, watch(t, srv, "?revision="+id(0), "").StatusCode)
+	})
+
+	t.Run("returns 410 Gone for an id of another epoch", func(t *testing.T) {
+		other := eventID(events.NewBus(0).Epoch(), 3)
+		assert.Equal(t, http.StatusGone, watch(t, srv, "", other).StatusCode)
+		assert.Equal(t, http.StatusGone, watch(t, srv, "?revision=3", "").StatusCode, "a bare revision has no epoch")
+	})
+
+	t.Run("returns 400 Bad Request for an invalid revision", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, watch(t, srv, "?revision=abc", "").StatusCode)
+		assert.Equal(t, http.StatusBadRequest, watch(t, srv, "?revision="+bus.Epoch()+"-abc", "").StatusCode)
+	})
+
+	t.Run("ends the stream when the bus is closed", func(t *testing.T) {
+		bus.Close()
+		_, err := stream.ReadString('\n')
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"net/http"
+	"strconv"
+)
+
+const (
+	InvalidScore  = "Invalid score"
+	InvalidOffset = "Invalid offset"
+	InvalidCount  = "Invalid count"
+)
+
+// zsetRequest is the body of the sorted set writes.
+type zsetRequest struct {
+	Key     string             `json:"key"`
+	Members map[string]float64 `json:"members"`
+	Member  string             `json:"member"`
+	Delta   float64            `json:"delta"`
+}
+
+// ZAdd adds members with their scores to a sorted set, or updates the scores of the existing ones,
+// creating the key if needed.
+// Body example:
+//
+//	{
+//	  "key": "leaderboard",
+//	  "members": {"alice": 120, "bob": 95.5}
+//	}
+//
+// It replies with the number of the new members, e.g. {"added": 2}.
+func (h *Handlers) ZAdd(w http.ResponseWriter, r *http.Request) {
+	var req zsetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(req.Members) == 0 {
+		writeError(w, MembersCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.UseCase.ZAdd(req.Key, req.Members)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"added": added})
+}
+
+// ZIncrBy adds delta to the score of a member of a sorted set, a missing member counting as 0.
+// Body example:
+//
+//	{
+//	  "key": "leaderboard",
+//	  "member": "alice",
+//	  "delta": 10
+//	}
+//
+// It replies with the new score.
+func (h *Handlers) ZIncrBy(w http.ResponseWriter, r *http.Request) {
+	var req zsetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, UnableToParseRequestBody, http.StatusBadRequest)
+		return
+	}
+	if req.Key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if req.Member == "" {
+		writeError(w, MemberCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	score, err := h.UseCase.ZIncrBy(req.Key, req.Member, req.Delta)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.FormatFloat(score, 'g', -1, 64))
+}
+
+// ZScore returns the score of a member of a sorted set.
+// Example: GET /zscore?key=leaderboard&member=alice
+func (h *Handlers) ZScore(w http.ResponseWriter, r *http.Request) {
+	key, member := r.URL.Query().Get("key"), r.URL.Query().Get("member")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if member == "" {
+		writeError(w, MemberCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	score, err := h.UseCase.ZScore(key, member)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.FormatFloat(score, 'g', -1, 64))
+}
+
+// ZRank returns the rank of a member of a sorted set, starting at 0, counting from the highest score with rev=true.
+// Example: GET /zrank?key=leaderboard&member=alice&rev=true
+func (h *Handlers) ZRank(w http.ResponseWriter, r *http.Request) {
+	key, member := r.URL.Query().Get("key"), r.URL.Query().Get("member")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if member == "" {
+		writeError(w, MemberCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	rank, err := h.UseCase.ZRank(key, member, r.URL.Query().Get("rev") == "true")
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.Itoa(rank))
+}
+
+// ZRem removes members from a sorted set, removing the last member deletes the key.
+// Example: DELETE /zrem?key=leaderboard&member=alice&member=bob
+// It replies with the number of the removed members, e.g. {"removed": 2}.
+func (h *Handlers) ZRem(w http.ResponseWriter, r *http.Request) {
+	key, members := r.URL.Query().Get("key"), r.URL.Query()["member"]
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	if len(members) == 0 {
+		writeError(w, MembersCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.UseCase.ZRem(key, members...)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, map[string]int{"removed": n})
+}
+
+// ZRange returns the members of a sorted set from the rank start to the rank stop, both inclusive, as a JSON array
+// of {"member": "alice", "score": 120}. Negative ranks count from the end, start and stop default to the whole set.
+// rev=true orders the members from the highest score.
+// Example: GET /zrange?key=leaderboard&start=0&stop=9&rev=true
+func (h *Handlers) ZRange(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	start, stop, ok := rangeParams(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := h.UseCase.ZRange(key, start, stop, r.URL.Query().Get("rev") == "true")
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, members)
+}
+
+// ZRangeByScore returns the members of a sorted set with a score between min and max as a JSON array,
+// like ZRange. The bounds are floats, -inf or inf, and default to the whole set; a bound prefixed with (
+// is excluded, e.g. min=(100. offset skips the first members and count limits their number.
+// Example: GET /zrangebyscore?key=leaderboard&min=100&max=inf&rev=true&offset=0&count=10
+func (h *Handlers) ZRangeByScore(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	min, max, ok := scoreParams(w, r)
+	if !ok {
+		return
+	}
+	offset, count := 0, -1
+	var err error
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			writeError(w, InvalidOffset, http.StatusBadRequest)
+			return
+		}
+	}
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		if count, err = strconv.Atoi(raw); err != nil {
+			writeError(w, InvalidCount, http.StatusBadRequest)
+			return
+		}
+	}
+
+	members, err := h.UseCase.ZRangeByScore(key, min, max, r.URL.Query().Get("rev") == "true", offset, count)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeJSON(w, members)
+}
+
+// ZCount returns the number of the members of a sorted set with a score between min and max,
+// the bounds are the ones of ZRangeByScore.
+// Example: GET /zcount?key=leaderboard&min=(100&max=200
+func (h *Handlers) ZCount(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, KeyCanNotBeEmpty, http.StatusBadRequest)
+		return
+	}
+	min, max, ok := scoreParams(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := h.UseCase.ZCount(key, min, max)
+	if err != nil {
+		handleError(err, w)
+		return
+	}
+	writeText(w, strconv.Itoa(n))
+}
+
+// scoreParams parses the min and max query parameters, defaulting to -inf and inf.
+// It replies with 400 Bad Request and reports false when one of them is not a valid bound.
+func scoreParams(w http.ResponseWriter, r *http.Request) (domain.ScoreBound, domain.ScoreBound, bool) {
+	bounds := [2]string{"-inf", "inf"}
+	var parsed [2]domain.ScoreBound
+	for idx, name := range [2]string{"min", "max"} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			raw = bounds[idx]
+		}
+		bound, err := domain.ParseScoreBound(raw)
+		if err != nil {
+			writeError(w, InvalidScore, http.StatusBadRequest)
+			return domain.ScoreBound{}, domain.ScoreBound{}, false
+		}
+		parsed[idx] = bound
+	}
+	return parsed[0], parsed[1], true
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"os"
 	"strconv"
+	"time"
 )
 
 func init() {
@@ -23,11 +24,59 @@ func init() {
 		cfg.RateLimit = int64(limit)
 	}
 
+	lookupDuration("SWEEP_INTERVAL", &cfg.SweepInterval)
+	lookupInt("SWEEP_BUDGET", &cfg.SweepBudget)
+	lookupInt("STORAGE_SHARDS", &cfg.StorageShards)
+	lookupInt64("MAX_MEMORY", &cfg.MaxMemory)
+	lookupInt64("MAX_KEYS", &cfg.MaxKeys)
+	lookupChoice("EVICTION_POLICY", &cfg.EvictionPolicy, evictionPolicies)
+	lookupString("AOF_PATH", &cfg.AOFPath)
+	lookupChoice("AOF_FSYNC", &cfg.AOFFsync, fsyncPolicies)
+	lookupInt("AOF_REWRITE_PERCENTAGE", &cfg.AOFRewritePercentage)
+	lookupInt64("AOF_REWRITE_MIN_SIZE", &cfg.AOFRewriteMinSize)
+	lookupString("SNAPSHOT_DIR", &cfg.SnapshotDir)
+	lookupDuration("SNAPSHOT_INTERVAL", &cfg.SnapshotInterval)
+	lookupInt("SNAPSHOT_RETAIN", &cfg.SnapshotRetain)
+	lookupString("RESP_PORT", &cfg.RESPPort)
+	lookupString("MEMCACHED_PORT", &cfg.MemcachedPort)
+	lookupString("GRPC_PORT", &cfg.GRPCPort)
+	lookupInt("WATCH_HISTORY", &cfg.WatchHistory)
+	lookupInt("PUBSUB_BUFFER", &cfg.PubSubBuffer)
+	lookupChoice("PUBSUB_SLOW_CONSUMER", &cfg.PubSubSlowConsumer, slowConsumerPolicies)
+}
+
+// fsyncPolicies are the accepted values of AOF_FSYNC.
+var fsyncPolicies = []string{"always", "everysec", "no"}
+
+// slowConsumerPolicies are the accepted values of PUBSUB_SLOW_CONSUMER.
+var slowConsumerPolicies = []string{"drop-oldest", "disconnect"}
+
+// evictionPolicies are the accepted values of EVICTION_POLICY.
+var evictionPolicies = []string{
+	"noeviction",
+	"allkeys-lru",
+	"allkeys-lfu",
+	"allkeys-random",
+	"volatile-lru",
+	"volatile-ttl",
 }
 
 var cfg = &config{
-	ServerPort: "8080",
-	RateLimit:  10,
+	ServerPort:     "8080",
+	RateLimit:      10,
+	SweepInterval:  100 * time.Millisecond,
+	SweepBudget:    200,
+	StorageShards:  1,
+	EvictionPolicy: "noeviction",
+	AOFFsync:       "everysec",
+
+	AOFRewritePercentage: 100,
+	AOFRewriteMinSize:    64 << 20,
+	SnapshotInterval:     5 * time.Minute,
+	SnapshotRetain:       3,
+	WatchHistory:         1024,
+	PubSubBuffer:         128,
+	PubSubSlowConsumer:   "disconnect",
 }
 
 // config represents the configuration for the application.
@@ -35,9 +84,108 @@ type config struct {
 	ServerPort string `json:"server_port"`
 	// RateLimitWindow is the time window for rate limiting in seconds.
 	RateLimit int64 `json:"rate_limit"`
+	// SweepInterval is how often expired keys are actively reclaimed, 0 disables the sweeper.
+	SweepInterval time.Duration `json:"sweep_interval"`
+	// SweepBudget is the maximum number of keys with a ttl examined in a single sweep cycle.
+	SweepBudget int `json:"sweep_budget"`
+	// StorageShards is the number of independently locked partitions of the keyspace.
+	StorageShards int `json:"storage_shards"`
+	// MaxMemory is the maximum estimated memory of the stored keys in bytes, 0 means unlimited.
+	MaxMemory int64 `json:"max_memory"`
+	// MaxKeys is the maximum number of stored keys, 0 means unlimited.
+	MaxKeys int64 `json:"max_keys"`
+	// EvictionPolicy selects the keys removed when a limit is reached, one of evictionPolicies.
+	EvictionPolicy string `json:"eviction_policy"`
+	// AOFPath is the append-only file the changes are persisted to, empty disables persistence.
+	AOFPath string `json:"aof_path"`
+	// AOFFsync is how often the append-only file is synced to the disk, one of fsyncPolicies.
+	AOFFsync string `json:"aof_fsync"`
+	// AOFRewritePercentage is the growth since the last rewrite that triggers a background rewrite, 0 disables it.
+	AOFRewritePercentage int `json:"aof_rewrite_percentage"`
+	// AOFRewriteMinSize is the minimum size in bytes of the append-only file to be rewritten automatically.
+	AOFRewriteMinSize int64 `json:"aof_rewrite_min_size"`
+	// SnapshotDir is the directory snapshots are saved to, empty disables snapshots.
+	SnapshotDir string `json:"snapshot_dir"`
+	// SnapshotInterval is how often a snapshot is saved, 0 saves only on demand and on shutdown.
+	SnapshotInterval time.Duration `json:"snapshot_interval"`
+	// SnapshotRetain is the number of the newest snapshots kept in SnapshotDir.
+	SnapshotRetain int `json:"snapshot_retain"`
+	// RESPPort is the port of the Redis protocol listener, empty disables it.
+	RESPPort string `json:"resp_port"`
+	// MemcachedPort is the port of the memcached protocol listener, empty disables it.
+	MemcachedPort string `json:"memcached_port"`
+	// GRPCPort is the port of the gRPC API, empty disables it.
+	GRPCPort string `json:"grpc_port"`
+	// WatchHistory is the number of the latest changes kept for the watchers resuming after a revision.
+	WatchHistory int `json:"watch_history"`
+	// PubSubBuffer is the number of messages queued for a subscriber.
+	PubSubBuffer int `json:"pubsub_buffer"`
+	// PubSubSlowConsumer is what happens to a subscriber with a full buffer, one of slowConsumerPolicies.
+	PubSubSlowConsumer string `json:"pubsub_slow_consumer"`
 }
 
 // GetConf returns a new config instance with default values.
 func GetConf() *config {
 	return cfg
 }
+
+// lookupInt overrides dst with the integer value of the environment variable if it is set.
+func lookupInt(name string, dst *int) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to convert %s to int", name)
+	}
+	*dst = v
+}
+
+// lookupInt64 overrides dst with the 64-bit integer value of the environment variable if it is set.
+func lookupInt64(name string, dst *int64) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to convert %s to int", name)
+	}
+	*dst = v
+}
+
+// lookupString overrides dst with the value of the environment variable if it is set.
+func lookupString(name string, dst *string) {
+	if value := os.Getenv(name); value != "" {
+		*dst = value
+	}
+}
+
+// lookupChoice overrides dst with the value of the environment variable if it is set and is one of choices.
+func lookupChoice(name string, dst *string, choices []string) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	for _, choice := range choices {
+		if value == choice {
+			*dst = value
+			return
+		}
+	}
+	log.Fatal().Msgf("Invalid %s %q, expected one of %v", name, value, choices)
+}
+
+// lookupDuration overrides dst with the duration value (e.g. "100ms") of the environment variable if it is set.
+func lookupDuration(name string, dst *time.Duration) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+	v, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to convert %s to duration", name)
+	}
+	*dst = v
+}
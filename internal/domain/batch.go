@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// SetItem is a key-value pair written by Repository.MSet.
+type SetItem struct {
+	Key   string
+	Value string
+	// TTL is the time to live of the key, 0 meaning no expiration.
+	TTL time.Duration
+}
+
+// KeyResult is the outcome of a key of a batch operation.
+type KeyResult struct {
+	Key string
+	// Value is the value read by MGet.
+	Value string
+	// Version is the version read by MGet or written by MSet.
+	Version uint64
+	// Err is the error of the single key operation, nil when it succeeded.
+	Err error
+}
@@ -0,0 +1,34 @@
+package domain
+
+// ChangeType is the kind of mutation of a key.
+type ChangeType string
+
+const (
+	// ChangeSet means the key was created or replaced.
+	ChangeSet ChangeType = "set"
+	// ChangeDelete means the key was deleted by a client.
+	ChangeDelete ChangeType = "delete"
+	// ChangeExpire means the key was removed because it expired.
+	ChangeExpire ChangeType = "expired"
+	// ChangeEvict means the key was removed by the eviction policy.
+	ChangeEvict ChangeType = "evicted"
+	// ChangeTouch means a read pushed the expiration of a sliding key, Entity holds only the key and the expiration.
+	// It is persisted, so a restart keeps an active key, but not published to the watchers,
+	// as neither the value nor the version changed.
+	ChangeTouch ChangeType = "touched"
+)
+
+// Change describes a single mutation of a key in the storage.
+type Change struct {
+	Type ChangeType `json:"type"`
+	Key  string     `json:"key"`
+	// Entity is the new state of the key, it is set only for ChangeSet and ChangeTouch.
+	Entity Entity `json:"entity,omitempty"`
+	// Revision is the position of the change in the event log, it is assigned when the change is published.
+	Revision uint64 `json:"revision,omitempty"`
+}
+
+// ChangeListener is called for every change of the storage.
+// It is called while the key is locked, so changes of a key are observed in order
+// and the listener must not call back into the storage.
+type ChangeListener func(c Change)
@@ -1,15 +1,100 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// ValueType is the kind of value a key holds.
+type ValueType string
+
+const (
+	// TypeString is a plain string held in Entity.Value, it is the zero value of ValueType.
+	TypeString ValueType = ""
+	// TypeHash is a map of fields to values held in Entity.Hash.
+	TypeHash ValueType = "hash"
+	// TypeList is a sequence of values held in Entity.List.
+	TypeList ValueType = "list"
+	// TypeSet is a set of unique members held in Entity.Set.
+	TypeSet ValueType = "set"
+	// TypeZSet is a set of members ordered by score held in Entity.ZSet.
+	TypeZSet ValueType = "zset"
+)
+
+// String returns the name of the type, as reported by the Redis TYPE command.
+func (t ValueType) String() string {
+	if t == TypeString {
+		return "string"
+	}
+	return string(t)
+}
+
+// ParseValueType returns the type with the name reported by String, e.g. "string" or "zset".
+func ParseValueType(name string) (ValueType, bool) {
+	for _, t := range []ValueType{TypeString, TypeHash, TypeList, TypeSet, TypeZSet} {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return "", false
+}
 
 // Entity represents a key-value pair in the in-memory storage.
+// The value is held in the field matching the Type, the others are empty.
+// The storage never modifies an entity in place, so the maps and slices of a returned entity must not be modified either.
 type Entity struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+	// Type is the kind of the value, empty for a string.
+	Type ValueType `json:"type,omitempty"`
+	// Hash holds the fields of a hash.
+	Hash map[string]string `json:"hash,omitempty"`
+	// List holds the values of a list from the head to the tail.
+	List []string `json:"list,omitempty"`
+	// Set holds the members of a set.
+	Set Members `json:"set,omitempty"`
+	// ZSet holds the members of a sorted set, it is immutable.
+	ZSet SortedSet `json:"zset,omitempty"`
 	// Expiration is the time in nanoseconds when the key-value pair will expire.
 	Expiration int64 `json:"expiration"`
+	// Sliding makes every read of the key by Get push Expiration to SlidingTTL from the read,
+	// so the key expires after SlidingTTL of inactivity rather than SlidingTTL after it was written.
+	Sliding bool `json:"sliding,omitempty"`
+	// SlidingTTL is the ttl the sliding key was set with.
+	SlidingTTL time.Duration `json:"sliding_ttl,omitempty"`
+	// ContentType is the media type of a string value, e.g. image/png, empty when it was not given.
+	// The value is a sequence of bytes that does not have to be valid UTF-8.
+	ContentType string `json:"content_type,omitempty"`
+	// Flags are opaque to the storage, memcached clients keep the serialization format of the value in them.
+	Flags uint32 `json:"flags,omitempty"`
+	// Version is assigned by the storage on every write of the key and never repeats,
+	// so it tells whether the key changed since it was read.
+	Version uint64 `json:"version,omitempty"`
+}
+
+// UnmarshalJSON decodes an entity, its sorted set as ScoredMembers.
+func (e *Entity) UnmarshalJSON(data []byte) error {
+	// entity has the fields of Entity but not its methods, the ZSet field shadowing the one of Entity
+	type entity Entity
+	var decoded struct {
+		entity
+		ZSet ScoredMembers `json:"zset,omitempty"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = Entity(decoded.entity)
+	if decoded.ZSet != nil {
+		e.ZSet = decoded.ZSet
+	}
+	return nil
 }
 
 func (e *Entity) IsExpired() bool {
-	return e.Expiration > 0 && time.Now().UnixNano() > e.Expiration
+	return e.ExpiredAt(time.Now())
+}
+
+// ExpiredAt reports whether the entity has expired at the time.
+func (e *Entity) ExpiredAt(now time.Time) bool {
+	return e.Expiration > 0 && now.UnixNano() > e.Expiration
 }
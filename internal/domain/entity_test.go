@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntity_JSON(t *testing.T) {
+	entity := Entity{Key: "k", Type: TypeZSet, ZSet: ScoredMembers{{"a", 1}, {"b", 2}}, Version: 3}
+	data, err := json.Marshal(entity)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"k","value":"","type":"zset","zset":[{"member":"a","score":1},{"member":"b","score":2}],"expiration":0,"version":3}`, string(data))
+
+	var decoded Entity
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, entity, decoded)
+	score, ok := decoded.ZSet.Score("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, score)
+	assert.Equal(t, int64(2), decoded.ZSet.Bytes())
+
+	require.NoError(t, json.Unmarshal([]byte(`{"key":"s","value":"v"}`), &decoded))
+	assert.Equal(t, Entity{Key: "s", Value: "v"}, decoded, "the zset of the previous entity is not kept")
+	assert.Error(t, json.Unmarshal([]byte(`{"zset":[{"member":"a","score":"x"}]}`), &decoded))
+}
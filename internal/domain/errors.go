@@ -3,7 +3,20 @@ package domain
 import "errors"
 
 var (
-	ErrKeyExpired   = errors.New("key expired")
-	ErrKeyNotFound  = errors.New("key not found")
-	ErrStorageEmpty = errors.New("storage is empty")
+	ErrKeyExpired      = errors.New("key expired")
+	ErrKeyNotFound     = errors.New("key not found")
+	ErrStorageEmpty    = errors.New("storage is empty")
+	ErrOutOfMemory     = errors.New("out of memory, eviction policy does not allow to free space")
+	ErrConditionNotMet = errors.New("condition not met")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrWrongType       = errors.New("operation against a key holding the wrong kind of value")
+	ErrFieldNotFound   = errors.New("field not found")
+	ErrNotInteger      = errors.New("value is not an integer")
+	ErrOverflow        = errors.New("increment or decrement would overflow")
+	ErrIndexOutOfRange = errors.New("index out of range")
+	ErrTimeout         = errors.New("timed out")
+	ErrMemberNotFound  = errors.New("member not found")
+	ErrNotFloat        = errors.New("value is not a valid float")
+	ErrTxnAborted      = errors.New("transaction aborted, a watched key has changed")
+	ErrInvalidOp       = errors.New("invalid operation")
 )
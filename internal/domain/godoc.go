@@ -1,3 +1,4 @@
 // Package domain contains the domain model including one entity,
-// it's IsExpired method, the Repository interface, RateLimiter interface.
+// it's IsExpired method, the Repository interface, RateLimiter interface
+// and the Change notifications emitted by the storage.
 package domain
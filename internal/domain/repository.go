@@ -1,17 +1,220 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
+
+// NoExpiration is the ttl reported for a key that does not expire.
+const NoExpiration time.Duration = -1
+
+// SetMode restricts when a key is written.
+type SetMode int
+
+const (
+	// SetAlways writes the key whether it exists or not.
+	SetAlways SetMode = iota
+	// SetIfAbsent writes the key only if it does not exist.
+	SetIfAbsent
+	// SetIfPresent writes the key only if it already exists.
+	SetIfPresent
+	// SetIfVersion writes the key only if it exists with SetOptions.Version, a compare-and-swap.
+	SetIfVersion
+)
+
+// SetOptions holds the optional arguments of Repository.SetWith.
+type SetOptions struct {
+	Mode SetMode
+	// Version is the version the key must have with SetIfVersion, see Entity.Version.
+	Version uint64
+	// Sliding makes the ttl sliding, see Entity.Sliding. It is ignored without a ttl.
+	Sliding bool
+	// ContentType is stored along with the value, see Entity.ContentType.
+	ContentType string
+}
+
+// UpdateFunc computes the new state of a key from its current one, see Repository.Update.
+// exists is false when the key is missing or has expired, current then holds only the key.
+// A returned error aborts the update and is returned by Update.
+type UpdateFunc func(current Entity, exists bool) (Entity, error)
 
 // Repository defines the methods for interacting with the in-memory storage.
 type Repository interface {
-	// Set adds a new key-value pair to the storage or replaces it if it already exists.
-	// If the key already exists, it returns an error.
+	// Set adds a new key-value pair to the storage or replaces it if it already exists, whatever its type.
 	// If the ttl is 0, the key-value pair will not expire.
 	Set(key string, value string, ttl time.Duration) error
+	// SetWith is Set restricted by the options, it returns the new version of the key.
+	// An expired key counts as absent. If the mode does not allow the write, it returns ErrConditionNotMet.
+	SetWith(key string, value string, ttl time.Duration, opts SetOptions) (uint64, error)
 	// Delete deletes a key from the storage.
 	Delete(key string) error
 	// Get gets the value of a key from the storage.
+	// It returns ErrWrongType when the key does not hold a string.
+	// The read of a sliding key pushes its expiration, see Entity.Sliding.
 	Get(key string) (string, error)
+	// GetEntity gets the stored entity of a key, including its expiration, flags and version.
+	// It returns ErrWrongType when the key does not hold a string.
+	// Like Get, it pushes the expiration of a sliding key, see Entity.Sliding.
+	GetEntity(key string) (Entity, error)
 	// GetAll gets all the key-value pairs from the storage. Returns copy
 	GetAll() ([]Entity, error)
+	// MGet gets the values of the keys like Get, holding the locks once for all of them.
+	// The results are in the order of the keys, a key that can not be read has the error Get would return.
+	MGet(keys []string) []KeyResult
+	// MSet sets the key-value pairs like Set, holding the locks once for all of them.
+	// An item failing with ErrOutOfMemory is not written, the others are.
+	MSet(items []SetItem) []KeyResult
+	// MDelete deletes the keys like Delete, holding the locks once for all of them.
+	MDelete(keys []string) []KeyResult
+	// Update atomically replaces the entity of a key with the one computed by fn and returns the stored entity.
+	// The key and the version of the returned entity are set by the storage,
+	// an entity that has already expired deletes the key.
+	// fn gets the keys of any type, it must check the type of current before using its value.
+	// fn may be called more than once when the key is changed concurrently, so it must not have side effects.
+	Update(key string, fn UpdateFunc) (Entity, error)
+	// IncrBy atomically adds delta to the integer value of a key and returns the new value.
+	// A missing key is created at 0, an existing key keeps its expiration.
+	// It returns ErrNotInteger when the value is not a 64-bit integer and ErrOverflow when the result overflows.
+	IncrBy(key string, delta int64) (int64, error)
+	// IncrByFloat atomically adds delta to the float value of a key and returns the new value, like IncrBy.
+	// It returns ErrNotFloat when the value is not a float or the result is NaN or infinite.
+	IncrByFloat(key string, delta float64) (float64, error)
+	// Txn applies the operations in order as a single atomic step, provided every watched key is still at its version.
+	// Either all the operations are applied or none: a changed watched key returns ErrTxnAborted,
+	// a failing operation returns its error wrapped with its index, ErrInvalidOp for an unknown type.
+	// The results are in the order of the operations.
+	Txn(watches []Watch, ops []Op) ([]OpResult, error)
+	// Now returns the current time of the storage, the one the expirations are measured against,
+	// so an UpdateFunc computes an absolute expiration from it.
+	Now() time.Time
+	// TTL returns the remaining time to live of a key, or NoExpiration if the key does not expire.
+	// A missing key returns ErrKeyNotFound.
+	TTL(key string) (time.Duration, error)
+	// Expire sets the time to live of an existing key, a non-positive ttl deletes the key.
+	// Expire, ExpireAt, Persist and GetEx with a ttl end the sliding of a key.
+	Expire(key string, ttl time.Duration) error
+	// ExpireAt sets the expiration of an existing key to an absolute time, a time that is not in the future
+	// deletes the key.
+	ExpireAt(key string, at time.Time) error
+	// Persist removes the expiration of a key and reports whether it had one.
+	Persist(key string) (bool, error)
+	// GetEx gets the value of a key like Get and sets its time to live in the same step:
+	// a positive ttl replaces the expiration, NoExpiration removes it and 0 keeps it.
+	GetEx(key string, ttl time.Duration) (string, error)
+	// Scan returns a page of keys matching the glob pattern, an empty pattern matches every key,
+	// and the cursor to pass to the next call. The scan starts and ends with the cursor 0.
+	// Count is a hint of how many keys to return.
+	Scan(cursor uint64, match string, count int) ([]string, uint64, error)
+	// ScanEntities is Scan returning the entities of the keys, of any type, instead of their names.
+	ScanEntities(cursor uint64, match string, count int) ([]Entity, uint64, error)
+
+	HashRepository
+	ListRepository
+	SetRepository
+	SortedSetRepository
+}
+
+// HashRepository defines the methods for the keys holding a hash.
+// They return ErrWrongType when the key holds another type.
+// The reads of a missing key return ErrKeyNotFound or ErrKeyExpired, like Get.
+// The writes keep the expiration of the key.
+type HashRepository interface {
+	// HSet sets the fields of the hash, creating the key if needed, and returns the number of the new fields.
+	HSet(key string, fields map[string]string) (int, error)
+	// HGet gets the value of a field of the hash, it returns ErrFieldNotFound when the hash has no such field.
+	HGet(key, field string) (string, error)
+	// HDel deletes the fields of the hash and returns the number of the deleted ones, 0 for a missing key.
+	// Deleting the last field deletes the key.
+	HDel(key string, fields ...string) (int, error)
+	// HGetAll gets all the fields of the hash.
+	HGetAll(key string) (map[string]string, error)
+	// HLen returns the number of the fields of the hash.
+	HLen(key string) (int, error)
+	// HIncrBy adds delta to the integer value of a field of the hash and returns the new value.
+	// A missing field counts as 0. It returns ErrNotInteger when the value is not a 64-bit integer
+	// and ErrOverflow when the result does not fit in one.
+	HIncrBy(key, field string, delta int64) (int64, error)
+}
+
+// ListRepository defines the methods for the keys holding a list.
+// They return ErrWrongType when the key holds another type.
+// The reads of a missing key return ErrKeyNotFound or ErrKeyExpired, like Get, a list is never empty.
+// The writes keep the expiration of the key, removing the last value deletes the key.
+// Indexes start at 0 from the head, negative ones count from the tail, -1 being the last value.
+type ListRepository interface {
+	// LPush inserts the values at the head of the list one after another, creating the key if needed,
+	// and returns the length of the list. LPush(key, "a", "b") makes "b" the head.
+	LPush(key string, values ...string) (int, error)
+	// RPush appends the values at the tail of the list, creating the key if needed, and returns the length of the list.
+	RPush(key string, values ...string) (int, error)
+	// LPop removes and returns the head of the list.
+	LPop(key string) (string, error)
+	// RPop removes and returns the tail of the list.
+	RPop(key string) (string, error)
+	// LRange returns the values from start to stop, both inclusive, an empty slice when the range is outside the list.
+	LRange(key string, start, stop int) ([]string, error)
+	// LLen returns the length of the list.
+	LLen(key string) (int, error)
+	// LTrim keeps only the values from start to stop, both inclusive. A missing key is not an error.
+	LTrim(key string, start, stop int) error
+	// LIndex returns the value at the index, it returns ErrIndexOutOfRange when the list is shorter.
+	LIndex(key string, index int) (string, error)
+	// BLPop is LPop of the first of the keys holding a list, it returns the key along with the value.
+	// When all the lists are empty, it waits until a value is pushed to one of them, the timeout passes
+	// or ctx is done, returning ErrTimeout or the ctx error. A non-positive timeout waits indefinitely.
+	BLPop(ctx context.Context, keys []string, timeout time.Duration) (string, string, error)
+	// BRPop is BLPop popping the tail.
+	BRPop(ctx context.Context, keys []string, timeout time.Duration) (string, string, error)
+}
+
+// SetRepository defines the methods for the keys holding a set.
+// They return ErrWrongType when the key holds another type.
+// The reads of a missing key return ErrKeyNotFound or ErrKeyExpired, like Get, a set is never empty,
+// except for the operations on several sets, which take a missing key as an empty set.
+// The writes keep the expiration of the key, removing the last member deletes the key.
+// The members are returned in lexical order.
+type SetRepository interface {
+	// SAdd adds the members to the set, creating the key if needed, and returns the number of the new members.
+	SAdd(key string, members ...string) (int, error)
+	// SRem removes the members from the set and returns the number of the removed ones, 0 for a missing key.
+	SRem(key string, members ...string) (int, error)
+	// SMembers returns the members of the set.
+	SMembers(key string) ([]string, error)
+	// SIsMember reports whether the member belongs to the set.
+	SIsMember(key, member string) (bool, error)
+	// SInter returns the members belonging to all the sets.
+	SInter(keys ...string) ([]string, error)
+	// SUnion returns the members belonging to any of the sets.
+	SUnion(keys ...string) ([]string, error)
+	// SDiff returns the members of the first set not belonging to any of the others.
+	SDiff(keys ...string) ([]string, error)
+}
+
+// SortedSetRepository defines the methods for the keys holding a sorted set.
+// They return ErrWrongType when the key holds another type.
+// The reads of a missing key return ErrKeyNotFound or ErrKeyExpired, like Get, a sorted set is never empty.
+// The writes keep the expiration of the key, removing the last member deletes the key.
+// Scores are finite floats, a NaN or infinite score is refused with ErrNotFloat.
+// Members are ordered by score, the members with the same score in lexical order, ranks start at 0.
+type SortedSetRepository interface {
+	// ZAdd adds the members with their scores to the sorted set, or updates the scores of the existing ones,
+	// creating the key if needed, and returns the number of the new members.
+	ZAdd(key string, members map[string]float64) (int, error)
+	// ZIncrBy adds delta to the score of the member, a missing member counting as 0, and returns the new score.
+	ZIncrBy(key, member string, delta float64) (float64, error)
+	// ZScore returns the score of the member, it returns ErrMemberNotFound when the member is missing.
+	ZScore(key, member string) (float64, error)
+	// ZRank returns the rank of the member, counting from the highest score with rev.
+	// It returns ErrMemberNotFound when the member is missing.
+	ZRank(key, member string, rev bool) (int, error)
+	// ZRem removes the members and returns the number of the removed ones, 0 for a missing key.
+	ZRem(key string, members ...string) (int, error)
+	// ZRange returns the members from the rank start to the rank stop, both inclusive, negative ranks counting
+	// from the end. rev orders the members from the highest score.
+	ZRange(key string, start, stop int, rev bool) ([]ScoredMember, error)
+	// ZRangeByScore returns the members with a score between min and max, ordered from the highest score with rev.
+	// The first offset members are skipped and at most count members are returned, a negative count returning all.
+	ZRangeByScore(key string, min, max ScoreBound, rev bool, offset, count int) ([]ScoredMember, error)
+	// ZCount returns the number of the members with a score between min and max.
+	ZCount(key string, min, max ScoreBound) (int, error)
 }
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Members is the set of the members of a set.
+type Members map[string]struct{}
+
+// Sorted returns the members in lexical order.
+func (m Members) Sorted() []string {
+	members := make([]string, 0, len(m))
+	for member := range m {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// MarshalJSON encodes the set as an array of the members in lexical order.
+func (m Members) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Sorted())
+}
+
+// UnmarshalJSON decodes an array of members.
+func (m *Members) UnmarshalJSON(data []byte) error {
+	var members []string
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	*m = make(Members, len(members))
+	for _, member := range members {
+		(*m)[member] = struct{}{}
+	}
+	return nil
+}
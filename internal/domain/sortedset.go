@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ScoredMember is a member of a sorted set along with its score.
+type ScoredMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// ScoreBound is an end of a score range, Exclusive leaving the score itself out of the range.
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+// ParseScoreBound parses a score bound in the Redis syntax: a float, -inf or +inf,
+// prefixed with ( to exclude it, e.g. "(1.5".
+func ParseScoreBound(s string) (ScoreBound, error) {
+	var bound ScoreBound
+	if strings.HasPrefix(s, "(") {
+		bound.Exclusive = true
+		s = s[1:]
+	}
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) {
+		return ScoreBound{}, ErrNotFloat
+	}
+	bound.Score = score
+	return bound, nil
+}
+
+// SortedSet is an immutable set of members ordered by score, the members with the same score ordered lexically.
+// The storage keeps its own ordered structure behind it and never modifies a set once returned.
+// It is encoded as JSON as an array of the members ordered by score.
+type SortedSet interface {
+	// Len returns the number of the members.
+	Len() int
+	// Bytes returns the total length of the members.
+	Bytes() int64
+	// Score returns the score of the member.
+	Score(member string) (float64, bool)
+	// Members returns all the members ordered by score.
+	Members() []ScoredMember
+}
+
+// ScoredMembers is a SortedSet of members already ordered by score, as decoded from JSON or a file.
+// The storage turns it into its own structure when it is stored.
+type ScoredMembers []ScoredMember
+
+// Len returns the number of the members.
+func (m ScoredMembers) Len() int {
+	return len(m)
+}
+
+// Bytes returns the total length of the members.
+func (m ScoredMembers) Bytes() int64 {
+	var n int64
+	for _, member := range m {
+		n += int64(len(member.Member))
+	}
+	return n
+}
+
+// Score returns the score of the member.
+func (m ScoredMembers) Score(member string) (float64, bool) {
+	for _, scored := range m {
+		if scored.Member == member {
+			return scored.Score, true
+		}
+	}
+	return 0, false
+}
+
+// Members returns all the members ordered by score.
+func (m ScoredMembers) Members() []ScoredMember {
+	return m
+}
@@ -0,0 +1,18 @@
+package domain
+
+// Stats describes the state of the storage.
+type Stats struct {
+	Keys        int64 `json:"keys"`
+	KeysWithTTL int64 `json:"keys_with_ttl"`
+	UsedMemory  int64 `json:"used_memory"`
+	// MaxMemory is 0 when the memory is not limited.
+	MaxMemory      int64  `json:"max_memory"`
+	EvictionPolicy string `json:"eviction_policy"`
+	EvictedKeys    uint64 `json:"evicted_keys"`
+	ExpiredKeys    uint64 `json:"expired_keys"`
+}
+
+// StatsProvider is implemented by storages reporting their Stats.
+type StatsProvider interface {
+	Stats() Stats
+}
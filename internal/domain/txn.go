@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// OpType is the kind of an operation of a transaction.
+type OpType string
+
+const (
+	// OpSet sets the string value of the key with an optional ttl, like Repository.Set.
+	OpSet OpType = "set"
+	// OpDelete deletes the key, a missing key is not an error.
+	OpDelete OpType = "delete"
+	// OpIncr adds Delta to the integer value of the key, like Repository.IncrBy.
+	OpIncr OpType = "incr"
+)
+
+// Op is an operation of a transaction.
+type Op struct {
+	Type  OpType
+	Key   string
+	Value string
+	// TTL is the time to live set by OpSet, 0 meaning no expiration.
+	TTL   time.Duration
+	Delta int64
+}
+
+// Watch makes a transaction commit only if the key is still at the version, see Entity.Version.
+// Version 0 requires the key to be missing.
+type Watch struct {
+	Key     string
+	Version uint64
+}
+
+// OpResult is the outcome of an operation of a committed transaction.
+type OpResult struct {
+	// Version is the version of the key after the operation, 0 when it was deleted.
+	Version uint64
+	// Value is the new value of the key after OpIncr.
+	Value int64
+	// Deleted reports whether OpDelete removed an existing key.
+	Deleted bool
+}
@@ -0,0 +1,106 @@
+// Package glob implements the glob-style patterns of Redis KEYS, SCAN and PSUBSCRIBE.
+// Supported syntax:
+// - ? matches any single byte
+// - * matches any sequence of bytes, including the empty one
+// - [abc] matches one of the bytes, [^abc] any byte but them, [a-z] a range
+// - \x matches x literally
+//
+// Unlike path.Match, '/' has no special meaning and malformed patterns never fail,
+// an unclosed bracket matches like in Redis.
+package glob
+
+// Match reports whether s matches the pattern.
+func Match(pattern, s string) bool {
+	// star and starS remember the position after the last '*' and the input it was tried against,
+	// so a failed match backtracks by letting the '*' consume one more byte.
+	star, starS := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				for p < len(pattern) && pattern[p] == '*' {
+					p++
+				}
+				if p == len(pattern) {
+					return true
+				}
+				star, starS = p, i
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if next, ok := matchClass(pattern, p, s[i]); ok {
+					p = next
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) {
+					if pattern[p+1] == s[i] {
+						p += 2
+						i++
+						continue
+					}
+					break
+				}
+				fallthrough
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+
+		if star < 0 {
+			return false
+		}
+		starS++
+		p, i = star, starS
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the bracket expression starting at pattern[p] == '['
+// and returns the position after the expression.
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	p++
+	negate := p < len(pattern) && pattern[p] == '^'
+	if negate {
+		p++
+	}
+
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			matched = matched || pattern[p] == c
+			p++
+		case p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']':
+			lo, hi := pattern[p], pattern[p+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			p += 3
+		default:
+			matched = matched || pattern[p] == c
+			p++
+		}
+	}
+	if p < len(pattern) {
+		// skip the closing bracket
+		p++
+	}
+
+	return p, matched != negate
+}
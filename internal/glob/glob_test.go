@@ -0,0 +1,44 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "", s: "", want: true},
+		{pattern: "", s: "a", want: false},
+		{pattern: "*", s: "", want: true},
+		{pattern: "*", s: "anything/at:all", want: true},
+		{pattern: "user:*", s: "user:42", want: true},
+		{pattern: "user:*", s: "session:42", want: false},
+		{pattern: "*:42", s: "user:42", want: true},
+		{pattern: "u*r*2", s: "user:42", want: true},
+		{pattern: "u*r*3", s: "user:42", want: false},
+		{pattern: "h?llo", s: "hello", want: true},
+		{pattern: "h?llo", s: "hllo", want: false},
+		{pattern: "h[ae]llo", s: "hallo", want: true},
+		{pattern: "h[ae]llo", s: "hillo", want: false},
+		{pattern: "h[^e]llo", s: "hallo", want: true},
+		{pattern: "h[^e]llo", s: "hello", want: false},
+		{pattern: "h[a-c]llo", s: "hbllo", want: true},
+		{pattern: "h[a-c]llo", s: "hdllo", want: false},
+		{pattern: "h[c-a]llo", s: "hbllo", want: true},
+		{pattern: `h\*llo`, s: "h*llo", want: true},
+		{pattern: `h\*llo`, s: "hello", want: false},
+		{pattern: `[\]]`, s: "]", want: true},
+		{pattern: "a**b", s: "ab", want: true},
+		{pattern: "*a*a*a*", s: "aaa", want: true},
+		{pattern: "*a*a*a*", s: "aa", want: false},
+		{pattern: "[abc", s: "a", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"~"+tt.s, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+			}
+		})
+	}
+}
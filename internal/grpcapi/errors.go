@@ -0,0 +1,34 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	KeyCanNotBeEmpty   = "key can not be empty"
+	ValueCanNotBeEmpty = "value can not be empty"
+	InvalidDuration    = "invalid duration"
+	TooManyRequests    = "too many requests"
+)
+
+// toStatus maps the errors of the storage to the gRPC status codes.
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, domain.ErrKeyNotFound), errors.Is(err, domain.ErrKeyExpired):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrOutOfMemory):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, domain.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrConditionNotMet), errors.Is(err, domain.ErrWrongType):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,14 @@
+// Package grpcapi provides the gRPC API of the in-memory storage, defined in storagepb/storage.proto.
+// Contains the Storage service implementation on top of the domain.Repository
+// and the interceptors equivalent to the HTTP middlewares.
+// Implements the fallowing RPCs:
+/*
+	Get(GetRequest) returns (GetResponse)
+	Set(SetRequest) returns (SetResponse)
+	Delete(DeleteRequest) returns (DeleteResponse)
+	List(ListRequest) returns (ListResponse)
+	BatchGet(BatchGetRequest) returns (BatchGetResponse)
+	BatchSet(BatchSetRequest) returns (BatchSetResponse)
+	Watch(WatchRequest) returns (stream WatchEvent)
+*/
+package grpcapi
@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingUnaryInterceptor logs the calls like api.LoggingMiddleware logs the HTTP requests.
+func LoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	fmt.Printf("%s %s %v\n", info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+// LoggingStreamInterceptor logs the streams once they end.
+func LoggingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	fmt.Printf("%s %s %v\n", info.FullMethod, status.Code(err), time.Since(start))
+	return err
+}
+
+// RateLimiterUnaryInterceptor limits the number of calls per second for a given IP address,
+// like api.RateLimiterMiddleware limits the HTTP requests.
+func RateLimiterUnaryInterceptor(rl domain.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limit(ctx, rl); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// RateLimiterStreamInterceptor limits the number of streams opened per second for a given IP address.
+func RateLimiterStreamInterceptor(rl domain.RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limit(ss.Context(), rl); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// limit counts the call against the rate limit of the client and returns ResourceExhausted if it is exceeded.
+func limit(ctx context.Context, rl domain.RateLimiter) error {
+	ip := clientIP(ctx)
+	if !rl.Check(ip) {
+		return status.Error(codes.ResourceExhausted, TooManyRequests)
+	}
+	rl.Limit(ip)
+	return nil
+}
+
+// clientIP returns the IP the call came from, the X-Forwarded-For metadata takes precedence over the peer address.
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if forwardedFor := md.Get("x-forwarded-for"); len(forwardedFor) > 0 && forwardedFor[0] != "" {
+			return forwardedFor[0]
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return ip
+}
@@ -0,0 +1,74 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLimiter allows the first allowed calls and records the limited IPs.
+type fakeLimiter struct {
+	allowed int
+	ips     []string
+}
+
+func (f *fakeLimiter) Limit(ip string) {
+	f.ips = append(f.ips, ip)
+}
+
+func (f *fakeLimiter) Check(string) bool {
+	return len(f.ips) < f.allowed
+}
+
+func TestRateLimiterUnaryInterceptor(t *testing.T) {
+	rl := &fakeLimiter{allowed: 1}
+	interceptor := RateLimiterUnaryInterceptor(rl)
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}})
+	resp, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"10.0.0.1"}, rl.ips)
+}
+
+func Test_clientIP(t *testing.T) {
+	addr := &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "Peer address without the port",
+			ctx:  peer.NewContext(context.Background(), addr),
+			want: "10.0.0.1",
+		},
+		{
+			name: "X-Forwarded-For takes precedence",
+			ctx: metadata.NewIncomingContext(peer.NewContext(context.Background(), addr),
+				metadata.Pairs("X-Forwarded-For", "192.168.0.1")),
+			want: "192.168.0.1",
+		},
+		{
+			name: "Unknown peer",
+			ctx:  context.Background(),
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, clientIP(tt.ctx))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/grpcapi/storagepb"
+	"google.golang.org/grpc"
+)
+
+// NewServer returns a gRPC server serving the service behind the logging and the rate limiting interceptors.
+func NewServer(service *Service, rl domain.RateLimiter) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(LoggingUnaryInterceptor, RateLimiterUnaryInterceptor(rl)),
+		grpc.ChainStreamInterceptor(LoggingStreamInterceptor, RateLimiterStreamInterceptor(rl)),
+	)
+	storagepb.RegisterStorageServer(srv, service)
+	return srv
+}
+
+// Shutdown stops the server gracefully, waiting for the calls in progress to complete.
+// If ctx is done first, the remaining calls are cancelled and the ctx error is returned.
+// Watch streams only end once the event bus is closed, so close it first.
+func Shutdown(ctx context.Context, srv *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return ctx.Err()
+	}
+}
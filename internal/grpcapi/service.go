@@ -0,0 +1,208 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/grpcapi/storagepb"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultListCount and maxListCount bound the page size of List.
+	defaultListCount = 10
+	maxListCount     = 1000
+	// watchBuffer is the number of changes queued for a Watch stream before it is ended as lagging.
+	watchBuffer = 256
+)
+
+// Service implements storagepb.StorageServer on top of the repository.
+type Service struct {
+	storagepb.UnimplementedStorageServer
+	repo domain.Repository
+	bus  *events.Bus
+}
+
+// NewService returns a new instance of Service.
+// Watch streams the changes published on bus, register bus.Publish on the storage to feed it.
+func NewService(repo domain.Repository, bus *events.Bus) *Service {
+	return &Service{repo: repo, bus: bus}
+}
+
+// Get returns the entity of a key.
+func (s *Service) Get(_ context.Context, req *storagepb.GetRequest) (*storagepb.GetResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, KeyCanNotBeEmpty)
+	}
+
+	entity, err := s.repo.GetEntity(req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storagepb.GetResponse{Entity: toProto(entity)}, nil
+}
+
+// Set adds a key or replaces it if it already exists.
+func (s *Service) Set(_ context.Context, req *storagepb.SetRequest) (*storagepb.SetResponse, error) {
+	if err := s.set(req); err != nil {
+		return nil, err
+	}
+	return &storagepb.SetResponse{}, nil
+}
+
+// set validates and applies a single SetRequest.
+func (s *Service) set(req *storagepb.SetRequest) error {
+	if req.GetKey() == "" {
+		return status.Error(codes.InvalidArgument, KeyCanNotBeEmpty)
+	}
+	if len(req.GetValue()) == 0 {
+		return status.Error(codes.InvalidArgument, ValueCanNotBeEmpty)
+	}
+	ttl := req.GetTtl().AsDuration()
+	if ttl < 0 || (req.Ttl != nil && req.Ttl.CheckValid() != nil) {
+		return status.Error(codes.InvalidArgument, InvalidDuration)
+	}
+
+	return toStatus(s.repo.Set(req.GetKey(), string(req.GetValue()), ttl))
+}
+
+// Delete deletes a key.
+func (s *Service) Delete(_ context.Context, req *storagepb.DeleteRequest) (*storagepb.DeleteResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, KeyCanNotBeEmpty)
+	}
+
+	if err := s.repo.Delete(req.GetKey()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storagepb.DeleteResponse{}, nil
+}
+
+// List returns a page of the entities whose keys match the pattern.
+// The keys deleted between listing and reading them are left out.
+func (s *Service) List(_ context.Context, req *storagepb.ListRequest) (*storagepb.ListResponse, error) {
+	count := int(req.GetCount())
+	switch {
+	case count < 0:
+		return nil, status.Error(codes.InvalidArgument, "count can not be negative")
+	case count == 0:
+		count = defaultListCount
+	case count > maxListCount:
+		count = maxListCount
+	}
+
+	keys, next, err := s.repo.Scan(req.GetCursor(), req.GetMatch(), count)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &storagepb.ListResponse{NextCursor: next}
+	for _, key := range keys {
+		entity, err := s.repo.GetEntity(key)
+		// Only the string keys are listed, the values of the other types do not fit an Entity
+		if isMissing(err) || errors.Is(err, domain.ErrWrongType) {
+			continue
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		resp.Entities = append(resp.Entities, toProto(entity))
+	}
+	return resp, nil
+}
+
+// BatchGet returns the entities of several keys, the missing ones are left unset.
+func (s *Service) BatchGet(_ context.Context, req *storagepb.BatchGetRequest) (*storagepb.BatchGetResponse, error) {
+	resp := &storagepb.BatchGetResponse{Results: make([]*storagepb.BatchGetResult, 0, len(req.GetKeys()))}
+	for _, key := range req.GetKeys() {
+		result := &storagepb.BatchGetResult{Key: key}
+		entity, err := s.repo.GetEntity(key)
+		switch {
+		case err == nil:
+			result.Entity = toProto(entity)
+		case !isMissing(err):
+			return nil, toStatus(err)
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// BatchSet sets several keys, the result of every item reports whether it was set.
+func (s *Service) BatchSet(_ context.Context, req *storagepb.BatchSetRequest) (*storagepb.BatchSetResponse, error) {
+	resp := &storagepb.BatchSetResponse{Results: make([]*storagepb.BatchSetResult, 0, len(req.GetItems()))}
+	for _, item := range req.GetItems() {
+		st := status.Convert(s.set(item))
+		resp.Results = append(resp.Results, &storagepb.BatchSetResult{
+			Key:     item.GetKey(),
+			Code:    int32(st.Code()),
+			Message: st.Message(),
+		})
+	}
+	return resp, nil
+}
+
+// Watch streams the changes of the keys starting with the prefix until the client cancels.
+// The headers are sent once the subscription is in place, so the changes made after the client
+// received them are never missed.
+func (s *Service) Watch(req *storagepb.WatchRequest, stream storagepb.Storage_WatchServer) error {
+	sub := s.bus.Subscribe(req.GetPrefix(), watchBuffer)
+	defer sub.Close()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case c, ok := <-sub.C():
+			if !ok {
+				if errors.Is(sub.Err(), events.ErrLagged) {
+					return status.Error(codes.ResourceExhausted, sub.Err().Error())
+				}
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if err := stream.Send(toEvent(c)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isMissing reports whether the error means the key does not exist.
+func isMissing(err error) bool {
+	return errors.Is(err, domain.ErrKeyNotFound) || errors.Is(err, domain.ErrKeyExpired)
+}
+
+// toProto converts the entity to its message.
+func toProto(e domain.Entity) *storagepb.Entity {
+	return &storagepb.Entity{
+		Key:        e.Key,
+		Value:      []byte(e.Value),
+		Expiration: e.Expiration,
+		Version:    e.Version,
+	}
+}
+
+// eventTypes maps the change types to the event types.
+var eventTypes = map[domain.ChangeType]storagepb.WatchEvent_Type{
+	domain.ChangeSet:    storagepb.WatchEvent_TYPE_SET,
+	domain.ChangeDelete: storagepb.WatchEvent_TYPE_DELETE,
+	domain.ChangeExpire: storagepb.WatchEvent_TYPE_EXPIRED,
+	domain.ChangeEvict:  storagepb.WatchEvent_TYPE_EVICTED,
+}
+
+// toEvent converts the change to its message.
+func toEvent(c domain.Change) *storagepb.WatchEvent {
+	event := &storagepb.WatchEvent{Type: eventTypes[c.Type], Key: c.Key}
+	if c.Type == domain.ChangeSet {
+		event.Entity = toProto(c.Entity)
+	}
+	return event
+}
@@ -0,0 +1,197 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/grpcapi/storagepb"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/events"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// allowAll is a rate limiter that never limits.
+type allowAll struct{}
+
+func (allowAll) Limit(string)      {}
+func (allowAll) Check(string) bool { return true }
+
+// startServer serves an empty storage over an in-memory connection and returns a client of it.
+func startServer(t *testing.T) (storagepb.StorageClient, *events.Bus) {
+	repo := storage.NewInMemory()
+	bus := events.NewBus(16)
+	repo.OnChange(bus.Publish)
+
+	srv := NewServer(NewService(repo, bus), allowAll{})
+	l := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(l) }()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		bus.Close()
+		_ = Shutdown(context.Background(), srv)
+		_ = repo.Close()
+	})
+	return storagepb.NewStorageClient(conn), bus
+}
+
+func TestService_GetSetDelete(t *testing.T) {
+	client, _ := startServer(t)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, &storagepb.GetRequest{Key: "key1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Set(ctx, &storagepb.SetRequest{Key: "key1", Value: []byte("\x00value"), Ttl: durationpb.New(time.Minute)})
+	require.NoError(t, err)
+
+	resp, err := client.Get(ctx, &storagepb.GetRequest{Key: "key1"})
+	require.NoError(t, err)
+	assert.Equal(t, "key1", resp.Entity.Key)
+	assert.Equal(t, []byte("\x00value"), resp.Entity.Value, "values are binary safe")
+	assert.InDelta(t, time.Now().Add(time.Minute).UnixNano(), resp.Entity.Expiration, float64(time.Second))
+	assert.NotZero(t, resp.Entity.Version)
+
+	_, err = client.Delete(ctx, &storagepb.DeleteRequest{Key: "key1"})
+	require.NoError(t, err)
+	_, err = client.Delete(ctx, &storagepb.DeleteRequest{Key: "key1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestService_InvalidArgument(t *testing.T) {
+	client, _ := startServer(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "Get without a key",
+			call: func() error { _, err := client.Get(ctx, &storagepb.GetRequest{}); return err },
+		},
+		{
+			name: "Set without a key",
+			call: func() error { _, err := client.Set(ctx, &storagepb.SetRequest{Value: []byte("v")}); return err },
+		},
+		{
+			name: "Set without a value",
+			call: func() error { _, err := client.Set(ctx, &storagepb.SetRequest{Key: "key1"}); return err },
+		},
+		{
+			name: "Set with a negative ttl",
+			call: func() error {
+				_, err := client.Set(ctx, &storagepb.SetRequest{Key: "key1", Value: []byte("v"), Ttl: durationpb.New(-time.Second)})
+				return err
+			},
+		},
+		{
+			name: "Delete without a key",
+			call: func() error { _, err := client.Delete(ctx, &storagepb.DeleteRequest{}); return err },
+		},
+		{
+			name: "List with an invalid cursor",
+			call: func() error { _, err := client.List(ctx, &storagepb.ListRequest{Cursor: math.MaxUint64}); return err },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestService_List(t *testing.T) {
+	client, _ := startServer(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		_, err := client.Set(ctx, &storagepb.SetRequest{Key: key, Value: []byte("v")})
+		require.NoError(t, err)
+	}
+
+	resp, err := client.List(ctx, &storagepb.ListRequest{Match: "user:*"})
+	require.NoError(t, err)
+	assert.Zero(t, resp.NextCursor)
+	var keys []string
+	for _, entity := range resp.Entities {
+		keys = append(keys, entity.Key)
+	}
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, keys)
+}
+
+func TestService_Batch(t *testing.T) {
+	client, _ := startServer(t)
+	ctx := context.Background()
+
+	setResp, err := client.BatchSet(ctx, &storagepb.BatchSetRequest{Items: []*storagepb.SetRequest{
+		{Key: "key1", Value: []byte("value1")},
+		{Key: "", Value: []byte("value2")},
+		{Key: "key3", Value: []byte("value3")},
+	}})
+	require.NoError(t, err)
+	require.Len(t, setResp.Results, 3)
+	assert.Equal(t, int32(codes.OK), setResp.Results[0].Code)
+	assert.Equal(t, int32(codes.InvalidArgument), setResp.Results[1].Code)
+	assert.Equal(t, KeyCanNotBeEmpty, setResp.Results[1].Message)
+	assert.Equal(t, int32(codes.OK), setResp.Results[2].Code)
+
+	getResp, err := client.BatchGet(ctx, &storagepb.BatchGetRequest{Keys: []string{"key3", "missing", "key1"}})
+	require.NoError(t, err)
+	require.Len(t, getResp.Results, 3)
+	assert.Equal(t, []byte("value3"), getResp.Results[0].Entity.GetValue())
+	assert.Equal(t, "missing", getResp.Results[1].Key)
+	assert.Nil(t, getResp.Results[1].Entity)
+	assert.Equal(t, []byte("value1"), getResp.Results[2].Entity.GetValue())
+}
+
+func TestService_Watch(t *testing.T) {
+	client, bus := startServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &storagepb.WatchRequest{Prefix: "user:"})
+	require.NoError(t, err)
+	// The headers are sent once the subscription is in place.
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	_, err = client.Set(ctx, &storagepb.SetRequest{Key: "order:1", Value: []byte("v")})
+	require.NoError(t, err)
+	_, err = client.Set(ctx, &storagepb.SetRequest{Key: "user:1", Value: []byte("v1")})
+	require.NoError(t, err)
+	_, err = client.Delete(ctx, &storagepb.DeleteRequest{Key: "user:1"})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, storagepb.WatchEvent_TYPE_SET, event.Type)
+	assert.Equal(t, "user:1", event.Key)
+	assert.Equal(t, []byte("v1"), event.Entity.GetValue())
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, storagepb.WatchEvent_TYPE_DELETE, event.Type)
+	assert.Equal(t, "user:1", event.Key)
+	assert.Nil(t, event.Entity)
+
+	bus.Close()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err), "the stream ends when the bus is closed")
+}
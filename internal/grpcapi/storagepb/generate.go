@@ -0,0 +1,5 @@
+// Package storagepb holds the messages and the gRPC stubs of the storage service generated from storage.proto.
+package storagepb
+
+// The stubs are generated by make proto, with the versions of protoc and of the plugins pinned in the Makefile.
+//go:generate make -C ../../.. proto
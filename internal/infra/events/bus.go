@@ -0,0 +1,230 @@
+// Package events fans the changes of the storage out to the subscribers watching them.
+// Every published change gets the next revision and the latest ones are kept,
+// so a subscriber reconnecting after a failure resumes without missing any.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+var (
+	// ErrLagged ends a subscription whose buffer filled up, the subscriber has missed changes.
+	ErrLagged = errors.New("subscriber fell behind the changes")
+	// ErrClosed ends the subscriptions when the bus is closed.
+	ErrClosed = errors.New("event bus closed")
+	// ErrCompacted is returned when resuming after a revision the bus no longer keeps,
+	// the subscriber has to read the current state again.
+	ErrCompacted = errors.New("revision is no longer available")
+)
+
+// Bus delivers every published change to the subscriptions watching its key.
+type Bus struct {
+	// epoch tells the buses apart, the revisions restart at 0 with every bus, e.g. on a restart.
+	epoch string
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+
+	// revision is the revision of the last published change.
+	revision uint64
+	// history is a ring of the latest changes, oldest is the index of the oldest one.
+	history []domain.Change
+	oldest  int
+}
+
+// NewBus returns a bus without subscriptions keeping the latest history changes for resuming.
+func NewBus(history int) *Bus {
+	if history < 0 {
+		history = 0
+	}
+	return &Bus{
+		epoch:   newEpoch(),
+		subs:    make(map[*Subscription]struct{}),
+		history: make([]domain.Change, 0, history),
+	}
+}
+
+// newEpoch returns a random id of a bus.
+func newEpoch() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Epoch returns the id of the bus, its revisions are meaningful only along with it.
+func (b *Bus) Epoch() string {
+	return b.epoch
+}
+
+// Publish assigns the next revision to the change and delivers it to the subscriptions whose prefix matches the key.
+// It is a domain.ChangeListener, so it never blocks: a subscription with a full buffer is ended with ErrLagged.
+// A domain.ChangeTouch is dropped, as the watchers see the keys, not their sliding expirations.
+func (b *Bus) Publish(c domain.Change) {
+	if c.Type == domain.ChangeTouch {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.revision++
+	c.Revision = b.revision
+	b.remember(c)
+
+	for sub := range b.subs {
+		if !strings.HasPrefix(c.Key, sub.prefix) {
+			continue
+		}
+		select {
+		case sub.ch <- c:
+		default:
+			b.end(sub, ErrLagged)
+		}
+	}
+}
+
+// remember adds the change to the history, replacing the oldest one when it is full.
+// The caller must hold the lock.
+func (b *Bus) remember(c domain.Change) {
+	switch {
+	case cap(b.history) == 0:
+	case len(b.history) < cap(b.history):
+		b.history = append(b.history, c)
+	default:
+		b.history[b.oldest] = c
+		b.oldest = (b.oldest + 1) % len(b.history)
+	}
+}
+
+// Revision returns the revision of the last published change.
+func (b *Bus) Revision() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.revision
+}
+
+// Subscribe watches the changes of the keys starting with prefix from now on, an empty prefix watches every key.
+// Up to buffer changes are queued for the subscriber. Call Close when done.
+func (b *Bus) Subscribe(prefix string, buffer int) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribe(prefix, buffer, b.revision, nil)
+}
+
+// SubscribeAfter is Subscribe resuming after the given revision: the kept changes published after it
+// are queued first. It returns ErrCompacted when some of the changes after the revision are no longer kept,
+// or when the revision is ahead of the bus, e.g. it was issued before a restart.
+func (b *Bus) SubscribeAfter(prefix string, buffer int, revision uint64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if revision > b.revision {
+		return nil, ErrCompacted
+	}
+	missed := b.revision - revision
+	if missed > uint64(len(b.history)) {
+		return nil, ErrCompacted
+	}
+
+	var replay []domain.Change
+	for n := len(b.history) - int(missed); n < len(b.history); n++ {
+		c := b.history[(b.oldest+n)%len(b.history)]
+		if strings.HasPrefix(c.Key, prefix) {
+			replay = append(replay, c)
+		}
+	}
+	return b.subscribe(prefix, buffer, revision, replay), nil
+}
+
+// subscribe registers a subscription starting after the revision with the replayed changes queued ahead of the buffer.
+// The caller must hold the lock.
+func (b *Bus) subscribe(prefix string, buffer int, start uint64, replay []domain.Change) *Subscription {
+	sub := &Subscription{
+		bus:    b,
+		prefix: prefix,
+		ch:     make(chan domain.Change, buffer+len(replay)),
+		start:  start,
+	}
+	for _, c := range replay {
+		sub.ch <- c
+	}
+
+	if b.closed {
+		sub.err = ErrClosed
+		close(sub.ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close ends all the subscriptions with ErrClosed, later subscriptions end right away.
+// It is safe to call Close more than once.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.end(sub, ErrClosed)
+	}
+}
+
+// end removes the subscription and closes its channel.
+// The caller must hold the lock.
+func (b *Bus) end(sub *Subscription, err error) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+// Subscription is a stream of the changes of the watched keys.
+type Subscription struct {
+	bus    *Bus
+	prefix string
+	ch     chan domain.Change
+	// start is the revision the changes of the subscription follow.
+	start uint64
+	// err is why the subscription ended, guarded by the bus lock.
+	err error
+}
+
+// C returns the channel of the changes, it is closed when the subscription ends.
+func (s *Subscription) C() <-chan domain.Change {
+	return s.ch
+}
+
+// Start returns the revision the changes of the subscription follow in the order of their revisions:
+// the one given to SubscribeAfter, so the replayed changes come after it, or the last change published
+// before Subscribe.
+func (s *Subscription) Start() uint64 {
+	return s.start
+}
+
+// Err returns why the subscription ended: ErrLagged, ErrClosed, or nil when it was closed by the subscriber.
+// It is meaningful once C is closed.
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription. It is safe to call Close more than once.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.end(s, nil)
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func change(key string) domain.Change {
+	return domain.Change{Type: domain.ChangeSet, Key: key, Entity: domain.Entity{Key: key}}
+}
+
+// revisions returns the revisions of the changes.
+func revisions(changes []domain.Change) []uint64 {
+	var got []uint64
+	for _, c := range changes {
+		got = append(got, c.Revision)
+	}
+	return got
+}
+
+// drain returns the changes queued on the subscription.
+func drain(sub *Subscription) []domain.Change {
+	var got []domain.Change
+	for {
+		select {
+		case c, ok := <-sub.C():
+			if !ok {
+				return got
+			}
+			got = append(got, c)
+		default:
+			return got
+		}
+	}
+}
+
+func TestBus_Publish(t *testing.T) {
+	b := NewBus(0)
+	all := b.Subscribe("", 10)
+	users := b.Subscribe("user:", 10)
+
+	b.Publish(change("user:1"))
+	b.Publish(change("order:1"))
+	b.Publish(domain.Change{Type: domain.ChangeTouch, Key: "user:1", Entity: domain.Entity{Key: "user:1", Expiration: 1}})
+	b.Publish(change("user:2"))
+
+	got := drain(all)
+	assert.Equal(t, []uint64{1, 2, 3}, revisions(got))
+	assert.Equal(t, "order:1", got[1].Key)
+	got = drain(users)
+	assert.Equal(t, []uint64{1, 3}, revisions(got))
+	assert.Equal(t, "user:2", got[1].Key)
+	assert.Equal(t, uint64(3), b.Revision(), "a touch is neither delivered nor counted")
+}
+
+func TestBus_Epoch(t *testing.T) {
+	b := NewBus(0)
+	assert.Len(t, b.Epoch(), 16)
+	assert.Equal(t, b.Epoch(), b.Epoch())
+	assert.NotEqual(t, b.Epoch(), NewBus(0).Epoch(), "every bus has its own epoch")
+}
+
+func TestBus_SubscribeAfter(t *testing.T) {
+	b := NewBus(3)
+	for _, key := range []string{"user:1", "order:1", "user:2", "user:3"} {
+		b.Publish(change(key))
+	}
+
+	tests := []struct {
+		name     string
+		prefix   string
+		revision uint64
+		want     []uint64
+		wantErr  error
+	}{
+		{name: "replays the kept changes after the revision", revision: 1, want: []uint64{2, 3, 4}},
+		{name: "replays only the matching keys", prefix: "user:", revision: 2, want: []uint64{3, 4}},
+		{name: "replays nothing at the current revision", revision: 4},
+		{name: "refuses a revision whose next change is no longer kept", revision: 0, wantErr: ErrCompacted},
+		{name: "refuses a revision ahead of the bus", revision: 5, wantErr: ErrCompacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := b.SubscribeAfter(tt.prefix, 1, tt.revision)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			defer sub.Close()
+
+			assert.Equal(t, tt.revision, sub.Start())
+			assert.Equal(t, tt.want, revisions(drain(sub)))
+		})
+	}
+
+	sub, err := b.SubscribeAfter("", 1, 3)
+	assert.NoError(t, err)
+	b.Publish(change("user:4"))
+	assert.Equal(t, []uint64{4, 5}, revisions(drain(sub)), "the live changes follow the replayed ones")
+}
+
+func TestBus_Lagged(t *testing.T) {
+	b := NewBus(0)
+	slow := b.Subscribe("", 1)
+	fast := b.Subscribe("", 10)
+
+	b.Publish(change("a"))
+	b.Publish(change("b"))
+
+	assert.Equal(t, []uint64{1}, revisions(drain(slow)))
+	_, open := <-slow.C()
+	assert.False(t, open)
+	assert.ErrorIs(t, slow.Err(), ErrLagged)
+	assert.Len(t, drain(fast), 2, "a slow subscriber does not affect the others")
+}
+
+func TestBus_Close(t *testing.T) {
+	b := NewBus(0)
+	sub := b.Subscribe("", 1)
+	closed := b.Subscribe("", 1)
+	closed.Close()
+	closed.Close()
+	assert.NoError(t, closed.Err())
+
+	b.Close()
+	_, open := <-sub.C()
+	assert.False(t, open)
+	assert.ErrorIs(t, sub.Err(), ErrClosed)
+
+	late := b.Subscribe("", 1)
+	_, open = <-late.C()
+	assert.False(t, open)
+	assert.ErrorIs(t, late.Err(), ErrClosed)
+}
@@ -0,0 +1,421 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/rs/zerolog/log"
+)
+
+// FsyncPolicy defines how often the append-only file is flushed to the disk.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways syncs the file after every change, the slowest and the safest policy.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec syncs the file once a second, at most a second of changes can be lost.
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNo leaves flushing to the operating system.
+	FsyncNo FsyncPolicy = "no"
+)
+
+// aofMagic starts every append-only file, the last byte is the format version.
+var aofMagic = []byte("IMSAOF\x00\x01")
+
+// Operations of the append-only file records.
+const (
+	opSet    byte = 1
+	opDelete byte = 2
+	// opTouch holds the key and the new expiration of a sliding key pushed by a read.
+	opTouch byte = 3
+)
+
+// ErrRewriteDisabled is returned by Rewrite when EnableRewrite was not called.
+var ErrRewriteDisabled = errors.New("AOF rewrite is not enabled")
+
+// Restorer is the storage the append-only file is replayed into.
+type Restorer interface {
+	// Restore stores the entity as is, keeping its absolute expiration.
+	Restore(entity domain.Entity) error
+	// Delete deletes a key from the storage.
+	Delete(key string) error
+	// RestoreExpiration sets the absolute expiration in unix nanoseconds of an existing key as is,
+	// keeping its value and version.
+	RestoreExpiration(key string, expiration int64) error
+}
+
+// AOF is an append-only file of the storage changes.
+// Append is a domain.ChangeListener, register it on the storage after the file was replayed.
+type AOF struct {
+	path string
+
+	mu    sync.Mutex
+	file  *os.File
+	fsync FsyncPolicy
+	// dirty is set when the file was written since the last sync.
+	dirty bool
+	buf   []byte
+	// size is the current size of the file, baseSize is its size after opening or the last rewrite.
+	size     int64
+	baseSize int64
+	// rewriting is set while a rewrite runs, the changes are then also collected in rewriteBuf.
+	rewriting  bool
+	rewriteBuf []byte
+
+	// rewriteMu serializes rewrites.
+	rewriteMu sync.Mutex
+	// source is the storage the file is rewritten from, nil disables rewrites.
+	source Snapshotter
+	// rewritePercentage and rewriteMinSize trigger an automatic rewrite once the file grows by the percentage
+	// since the last rewrite and is at least the size, a non-positive percentage disables automatic rewrites.
+	rewritePercentage int
+	rewriteMinSize    int64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenAOF opens the append-only file at path for appending, creating it if it does not exist.
+// Call Replay before opening to load the existing changes.
+func OpenAOF(path string, fsync FsyncPolicy) (*AOF, error) {
+	file, err := openAppend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		if _, err = file.Write(aofMagic); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		size = int64(len(aofMagic))
+	}
+
+	a := &AOF{
+		path:     path,
+		file:     file,
+		fsync:    fsync,
+		size:     size,
+		baseSize: size,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+// openAppend opens the file at path for appending, creating it if it does not exist.
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// EnableRewrite allows rewriting the file from source. With a positive percentage the file is also rewritten
+// in the background once it grows by percentage since the last rewrite and is at least minSize bytes.
+// It must be called before the AOF is used concurrently.
+func (a *AOF) EnableRewrite(source Snapshotter, percentage int, minSize int64) {
+	a.source = source
+	a.rewritePercentage = percentage
+	a.rewriteMinSize = minSize
+}
+
+// Append writes the change to the file.
+// Expired and evicted keys are written as deletions, so they are not resurrected on replay.
+// Write errors are logged, the change is still applied to the storage.
+func (a *AOF) Append(c domain.Change) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.buf = appendRecord(a.buf[:0], encodeChange(c))
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, a.buf...)
+	}
+	if _, err := a.file.Write(a.buf); err != nil {
+		log.Error().Err(err).Str("key", c.Key).Msg("Failed to append to AOF")
+		return
+	}
+	a.size += int64(len(a.buf))
+	a.dirty = true
+
+	if a.fsync == FsyncAlways {
+		a.sync()
+	}
+}
+
+// run syncs the file under the FsyncEverySec policy and starts automatic rewrites every second until Close is called.
+func (a *AOF) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			if a.fsync == FsyncEverySec {
+				a.sync()
+			}
+			grown := a.needsRewrite()
+			a.mu.Unlock()
+
+			if grown {
+				if err := a.Rewrite(); err != nil {
+					log.Error().Err(err).Msg("Failed to rewrite AOF")
+				}
+			}
+		}
+	}
+}
+
+// needsRewrite reports whether the file grew enough for an automatic rewrite.
+// The caller must hold the lock.
+func (a *AOF) needsRewrite() bool {
+	if a.source == nil || a.rewritePercentage <= 0 || a.size < a.rewriteMinSize {
+		return false
+	}
+	return (a.size-a.baseSize)*100 >= a.baseSize*int64(a.rewritePercentage)
+}
+
+// sync flushes the file to the disk if it was written since the last sync.
+// The caller must hold the lock.
+func (a *AOF) sync() {
+	if !a.dirty {
+		return
+	}
+	if err := a.file.Sync(); err != nil {
+		log.Error().Err(err).Msg("Failed to sync AOF")
+		return
+	}
+	a.dirty = false
+}
+
+// Rewrite compacts the file to the minimal set of changes that recreate the current keyspace of the source
+// given to EnableRewrite.
+// Appending goes on while the snapshot is written to a temporary file,
+// the changes made in the meantime are collected and written after it, then the file is swapped in with a rename.
+// The changes collected may already be part of the snapshot, replaying them again is harmless
+// because every change carries the full state of the key.
+func (a *AOF) Rewrite() (err error) {
+	if a.source == nil {
+		return ErrRewriteDisabled
+	}
+
+	a.rewriteMu.Lock()
+	defer a.rewriteMu.Unlock()
+
+	a.mu.Lock()
+	a.rewriting = true
+	a.rewriteBuf = nil
+	a.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".rewrite-*")
+	if err != nil {
+		a.stopRewriting()
+		return err
+	}
+	defer func() {
+		if err != nil {
+			a.stopRewriting()
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	// The snapshot must be taken without holding the lock,
+	// Append is called under the storage locks the snapshot needs.
+	if err = writeAOFBase(tmp, a.source.Snapshot()); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err = tmp.Write(a.rewriteBuf); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	// The descriptor is opened before the rename, so it follows the file to its new name.
+	file, err := openAppend(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), a.path); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = syncDir(filepath.Dir(a.path)); err != nil {
+		log.Error().Err(err).Msg("Failed to sync AOF directory after rewrite")
+	}
+
+	_ = a.file.Close()
+	a.file = file
+	a.size, a.baseSize = info.Size(), info.Size()
+	a.dirty = false
+	a.rewriting = false
+	a.rewriteBuf = nil
+	return nil
+}
+
+// stopRewriting stops collecting the changes for a rewrite.
+func (a *AOF) stopRewriting() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rewriting = false
+	a.rewriteBuf = nil
+}
+
+// writeAOFBase writes the magic and a set for every entity.
+func writeAOFBase(w io.Writer, entities []domain.Entity) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(aofMagic); err != nil {
+		return err
+	}
+
+	var buf []byte
+	for _, entity := range entities {
+		buf = appendRecord(buf[:0], appendEntity([]byte{opSet}, entity))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Close syncs and closes the file.
+// It is safe to call Close more than once.
+func (a *AOF) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.stop)
+		<-a.done
+
+		a.rewriteMu.Lock()
+		defer a.rewriteMu.Unlock()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		a.dirty = true
+		a.sync()
+		a.closeErr = a.file.Close()
+	})
+	return a.closeErr
+}
+
+// Replay loads the append-only file at path into r and returns the number of replayed changes.
+// A missing file is not an error. Sets whose expiration has passed are applied as deletions.
+// A record cut short at the end of the file, e.g. by a crash in the middle of a write, is truncated.
+// A corrupted record in the middle of the file is reported with its offset.
+func Replay(path string, r Restorer) (int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	magic := make([]byte, len(aofMagic))
+	if _, err = io.ReadFull(reader, magic); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read AOF header: %w", err)
+	}
+	if !bytes.Equal(magic, aofMagic) {
+		return 0, fmt.Errorf("%s is not an append-only file of this storage", path)
+	}
+
+	offset := int64(len(aofMagic))
+	replayed := 0
+	for {
+		payload, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return replayed, nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Warn().Int64("offset", offset).Msg("AOF ends with an incomplete record, truncating")
+			return replayed, file.Truncate(offset)
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("AOF record at offset %d: %w", offset, err)
+		}
+
+		if err = applyChange(payload, r); err != nil {
+			return replayed, fmt.Errorf("AOF record at offset %d: %w", offset, err)
+		}
+		offset += int64(recordHeaderSize + len(payload))
+		replayed++
+	}
+}
+
+// encodeChange encodes the change as a record payload.
+func encodeChange(c domain.Change) []byte {
+	switch c.Type {
+	case domain.ChangeSet:
+		return appendEntity([]byte{opSet}, c.Entity)
+	case domain.ChangeTouch:
+		return appendEntity([]byte{opTouch}, domain.Entity{Key: c.Key, Expiration: c.Entity.Expiration})
+	}
+	return appendEntity([]byte{opDelete}, domain.Entity{Key: c.Key})
+}
+
+// applyChange decodes the record payload and applies it to r.
+func applyChange(payload []byte, r Restorer) error {
+	if len(payload) == 0 {
+		return fmt.Errorf("%w: empty record", ErrCorrupted)
+	}
+
+	entity, err := decodeEntity(payload[1:])
+	if err != nil {
+		return err
+	}
+
+	switch payload[0] {
+	case opSet:
+		return r.Restore(entity)
+	case opDelete:
+		err = r.Delete(entity.Key)
+		if errors.Is(err, domain.ErrKeyNotFound) || errors.Is(err, domain.ErrKeyExpired) {
+			return nil
+		}
+		return err
+	case opTouch:
+		err = r.RestoreExpiration(entity.Key, entity.Expiration)
+		if errors.Is(err, domain.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("%w: unknown operation %d", ErrCorrupted, payload[0])
+	}
+}
@@ -0,0 +1,369 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapRestorer is a Restorer keeping the entities in a map.
+type mapRestorer map[string]domain.Entity
+
+func (m mapRestorer) Restore(entity domain.Entity) error {
+	if entity.IsExpired() {
+		delete(m, entity.Key)
+		return nil
+	}
+	m[entity.Key] = entity
+	return nil
+}
+
+func (m mapRestorer) Delete(key string) error {
+	if _, ok := m[key]; !ok {
+		return domain.ErrKeyNotFound
+	}
+	delete(m, key)
+	return nil
+}
+
+func (m mapRestorer) RestoreExpiration(key string, expiration int64) error {
+	entity, ok := m[key]
+	if !ok {
+		return domain.ErrKeyNotFound
+	}
+	entity.Expiration = expiration
+	return m.Restore(entity)
+}
+
+// writeAOF appends the changes to a new append-only file and returns its path.
+func writeAOF(t *testing.T, fsync FsyncPolicy, changes ...domain.Change) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := OpenAOF(path, fsync)
+	require.NoError(t, err)
+	for _, c := range changes {
+		aof.Append(c)
+	}
+	require.NoError(t, aof.Close())
+	require.NoError(t, aof.Close())
+	return path
+}
+
+func set(key, value string, expiration int64) domain.Change {
+	return domain.Change{
+		Type:   domain.ChangeSet,
+		Key:    key,
+		Entity: domain.Entity{Key: key, Value: value, Expiration: expiration},
+	}
+}
+
+func TestReplay(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixNano()
+	past := time.Now().Add(-time.Hour).UnixNano()
+	tests := []struct {
+		name         string
+		fsync        FsyncPolicy
+		changes      []domain.Change
+		wantReplayed int
+		want         mapRestorer
+	}{
+		{
+			name:  "Replay restores sets with their absolute expiration",
+			fsync: FsyncAlways,
+			changes: []domain.Change{
+				set("key1", "value1", 0),
+				set("key2", "value2", future),
+				set("key1", "value3", 0),
+			},
+			wantReplayed: 3,
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "value3"},
+				"key2": {Key: "key2", Value: "value2", Expiration: future},
+			},
+		},
+		{
+			name:  "Replay applies deletions, expirations and evictions",
+			fsync: FsyncEverySec,
+			changes: []domain.Change{
+				set("key1", "value1", 0),
+				set("key2", "value2", 0),
+				set("key3", "value3", 0),
+				set("key4", "value4", 0),
+				{Type: domain.ChangeDelete, Key: "key1"},
+				{Type: domain.ChangeExpire, Key: "key2"},
+				{Type: domain.ChangeEvict, Key: "key3"},
+				{Type: domain.ChangeDelete, Key: "missing"},
+			},
+			wantReplayed: 8,
+			want: mapRestorer{
+				"key4": {Key: "key4", Value: "value4"},
+			},
+		},
+		{
+			name:  "Replay skips sets that have expired",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				set("key1", "value1", 0),
+				set("key1", "value1", past),
+				set("key2", "value2", past),
+			},
+			wantReplayed: 3,
+			want:         mapRestorer{},
+		},
+		{
+			name:  "Replay keeps binary values intact",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				set("key1", "\x00\xff\xfe", 0),
+			},
+			wantReplayed: 1,
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "\x00\xff\xfe"},
+			},
+		},
+		{
+			name:  "Replay keeps the content type",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Value: "\x89PNG", ContentType: "image/png"}},
+			},
+			wantReplayed: 1,
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "\x89PNG", ContentType: "image/png"},
+			},
+		},
+		{
+			name:  "Replay keeps the flags and the version",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Value: "value1", Flags: 1 << 31, Version: 42}},
+			},
+			wantReplayed: 1,
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "value1", Flags: 1 << 31, Version: 42},
+			},
+		},
+		{
+			name:  "Replay keeps the sliding ttl",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Value: "value1", Expiration: future, Sliding: true, SlidingTTL: time.Hour}},
+			},
+			wantReplayed: 1,
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "value1", Expiration: future, Sliding: true, SlidingTTL: time.Hour},
+			},
+		},
+		{
+			name:  "Replay pushes the expiration of a sliding key as the reads did",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Value: "value1", Expiration: future, Sliding: true, SlidingTTL: time.Hour, Version: 7}},
+				{Type: domain.ChangeTouch, Key: "key1", Entity: domain.Entity{Key: "key1", Expiration: future + 1}},
+				{Type: domain.ChangeTouch, Key: "key1", Entity: domain.Entity{Key: "key1", Expiration: future + 2}},
+				{Type: domain.ChangeTouch, Key: "missing", Entity: domain.Entity{Key: "missing", Expiration: future}},
+			},
+			wantReplayed: 4,
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "value1", Expiration: future + 2, Sliding: true, SlidingTTL: time.Hour, Version: 7},
+			},
+		},
+		{
+			name:  "Replay restores hashes",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Type: domain.TypeHash, Hash: map[string]string{"a": "1", "": "\x00"}}},
+			},
+			wantReplayed: 1,
+			want: mapRestorer{
+				"key1": {Key: "key1", Type: domain.TypeHash, Hash: map[string]string{"a": "1", "": "\x00"}},
+			},
+		},
+		{
+			name:  "Replay restores lists in order",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Type: domain.TypeList, List: []string{"c", "", "a"}}},
+			},
+			wantReplayed: 1,
+			want: mapRestorer{
+				"key1": {Key: "key1", Type: domain.TypeList, List: []string{"c", "", "a"}},
+			},
+		},
+		{
+			name:  "Replay restores sets and sorted sets",
+			fsync: FsyncNo,
+			changes: []domain.Change{
+				{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Type: domain.TypeSet, Set: domain.Members{"a": {}, "": {}}}},
+				{Type: domain.ChangeSet, Key: "key2", Entity: domain.Entity{Key: "key2", Type: domain.TypeZSet, ZSet: domain.ScoredMembers{{Member: "b", Score: -2}, {Member: "a", Score: 1.5}}}},
+			},
+			wantReplayed: 2,
+			want: mapRestorer{
+				"key1": {Key: "key1", Type: domain.TypeSet, Set: domain.Members{"a": {}, "": {}}},
+				"key2": {Key: "key2", Type: domain.TypeZSet, ZSet: domain.ScoredMembers{{Member: "b", Score: -2}, {Member: "a", Score: 1.5}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeAOF(t, tt.fsync, tt.changes...)
+
+			got := mapRestorer{}
+			replayed, err := Replay(path, got)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantReplayed, replayed)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReplay_MissingFile(t *testing.T) {
+	replayed, err := Replay(filepath.Join(t.TempDir(), "missing.aof"), mapRestorer{})
+	assert.NoError(t, err)
+	assert.Zero(t, replayed)
+}
+
+func TestReplay_NotAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.aof")
+	require.NoError(t, os.WriteFile(path, []byte("something else entirely"), 0o644))
+
+	_, err := Replay(path, mapRestorer{})
+	assert.Error(t, err)
+}
+
+func TestReplay_TruncatedTail(t *testing.T) {
+	path := writeAOF(t, FsyncNo, set("key1", "value1", 0), set("key2", "value2", 0))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// Simulate a crash in the middle of writing the third record.
+	partial := appendRecord(nil, encodeChange(set("key3", "value3", 0)))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.Write(partial[:len(partial)-3])
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	got := mapRestorer{}
+	replayed, err := Replay(path, got)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Len(t, got, 2)
+
+	truncated, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), truncated.Size())
+
+	// New changes are appended after the last complete record.
+	aof, err := OpenAOF(path, FsyncNo)
+	require.NoError(t, err)
+	aof.Append(set("key3", "value3", 0))
+	require.NoError(t, aof.Close())
+
+	got = mapRestorer{}
+	replayed, err = Replay(path, got)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	assert.Len(t, got, 3)
+}
+
+func TestReplay_Corrupted(t *testing.T) {
+	path := writeAOF(t, FsyncNo, set("key1", "value1", 0), set("key2", "value2", 0))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// Flip a byte of the first record value.
+	data[len(aofMagic)+recordHeaderSize+8] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	_, err = Replay(path, mapRestorer{})
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestAOF_Rewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := OpenAOF(path, FsyncNo)
+	require.NoError(t, err)
+	assert.ErrorIs(t, aof.Rewrite(), ErrRewriteDisabled)
+
+	for i := 0; i < 100; i++ {
+		aof.Append(set("key", "value", 0))
+	}
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	aof.EnableRewrite(staticSnapshotter{{Key: "key", Value: "value"}, {Key: "other", Value: "value"}}, 0, 0)
+	require.NoError(t, aof.Rewrite())
+
+	// Changes after the rewrite are appended to the new file.
+	aof.Append(domain.Change{Type: domain.ChangeDelete, Key: "other"})
+	require.NoError(t, aof.Close())
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	got := mapRestorer{}
+	replayed, err := Replay(path, got)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	assert.Equal(t, mapRestorer{"key": {Key: "key", Value: "value"}}, got)
+}
+
+// appendingSnapshotter appends a change to the AOF while the snapshot is taken, like a concurrent write would.
+type appendingSnapshotter struct {
+	aof      *AOF
+	entities []domain.Entity
+	change   domain.Change
+}
+
+func (s appendingSnapshotter) Snapshot() []domain.Entity {
+	s.aof.Append(s.change)
+	return s.entities
+}
+
+func TestAOF_RewriteKeepsConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := OpenAOF(path, FsyncNo)
+	require.NoError(t, err)
+
+	aof.EnableRewrite(appendingSnapshotter{
+		aof:      aof,
+		entities: []domain.Entity{{Key: "key1", Value: "value1"}},
+		change:   set("key2", "value2", 0),
+	}, 0, 0)
+	require.NoError(t, aof.Rewrite())
+	require.NoError(t, aof.Close())
+
+	got := mapRestorer{}
+	_, err = Replay(path, got)
+	assert.NoError(t, err)
+	assert.Equal(t, mapRestorer{
+		"key1": {Key: "key1", Value: "value1"},
+		"key2": {Key: "key2", Value: "value2"},
+	}, got)
+}
+
+func TestAOF_AutoRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof, err := OpenAOF(path, FsyncNo)
+	require.NoError(t, err)
+	defer aof.Close()
+
+	aof.EnableRewrite(staticSnapshotter{{Key: "key", Value: "value"}}, 100, 0)
+	for i := 0; i < 100; i++ {
+		aof.Append(set("key", "value", 0))
+	}
+
+	assert.Eventually(t, func() bool {
+		aof.mu.Lock()
+		defer aof.mu.Unlock()
+		return aof.baseSize > int64(len(aofMagic)) && aof.size == aof.baseSize
+	}, 3*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,234 @@
+package persistence
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// ErrCorrupted is returned when a record fails the checksum or can not be decoded.
+var ErrCorrupted = errors.New("corrupted record")
+
+const (
+	// recordHeaderSize is the size of the length and the checksum preceding every record payload.
+	recordHeaderSize = 8
+	// maxRecordSize guards against allocating huge buffers for a corrupted length.
+	maxRecordSize = 1 << 30
+)
+
+// Entity field tags.
+const (
+	tagKey        = 1
+	tagValue      = 2
+	tagExpiration = 3
+	tagFlags      = 4
+	tagVersion    = 5
+	tagType       = 6
+	// tagHashField is repeated for every field of a hash, holding the length of the name, the name and the value.
+	tagHashField = 7
+	// tagListItem is repeated for every value of a list, from the head to the tail.
+	tagListItem = 8
+	// tagSetMember is repeated for every member of a set.
+	tagSetMember = 9
+	// tagZSetMember is repeated for every member of a sorted set, holding the score as 8 bytes and the member.
+	tagZSetMember = 10
+	// tagSlidingTTL holds the ttl of a sliding key in nanoseconds, its presence making the key sliding.
+	tagSlidingTTL = 11
+	// tagContentType holds the media type of a string value written by PUT /keys/{key}.
+	tagContentType = 12
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// appendRecord appends the payload framed with its length and checksum to dst.
+func appendRecord(dst, payload []byte) []byte {
+	var header [recordHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], crc32.Checksum(payload, crcTable))
+	dst = append(dst, header[:]...)
+	return append(dst, payload...)
+}
+
+// readRecord reads the next framed record and returns its payload.
+// It returns io.EOF at the end of the input, io.ErrUnexpectedEOF for a record cut short
+// and ErrCorrupted when the checksum does not match.
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("%w: record size %d", ErrCorrupted, size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+	return payload, nil
+}
+
+// appendEntity appends the tagged fields of the entity to dst.
+func appendEntity(dst []byte, e domain.Entity) []byte {
+	dst = appendField(dst, tagKey, []byte(e.Key))
+	if e.Value != "" {
+		dst = appendField(dst, tagValue, []byte(e.Value))
+	}
+	if e.Expiration != 0 {
+		var exp [8]byte
+		binary.BigEndian.PutUint64(exp[:], uint64(e.Expiration))
+		dst = appendField(dst, tagExpiration, exp[:])
+	}
+	if e.Flags != 0 {
+		dst = appendField(dst, tagFlags, binary.AppendUvarint(nil, uint64(e.Flags)))
+	}
+	if e.Version != 0 {
+		dst = appendField(dst, tagVersion, binary.AppendUvarint(nil, e.Version))
+	}
+	if e.Type != domain.TypeString {
+		dst = appendField(dst, tagType, []byte(e.Type))
+	}
+	if e.ContentType != "" {
+		dst = appendField(dst, tagContentType, []byte(e.ContentType))
+	}
+	if e.Sliding {
+		dst = appendField(dst, tagSlidingTTL, binary.AppendUvarint(nil, uint64(e.SlidingTTL)))
+	}
+	var field []byte
+	for name, value := range e.Hash {
+		field = binary.AppendUvarint(field[:0], uint64(len(name)))
+		field = append(field, name...)
+		field = append(field, value...)
+		dst = appendField(dst, tagHashField, field)
+	}
+	for _, value := range e.List {
+		dst = appendField(dst, tagListItem, []byte(value))
+	}
+	for member := range e.Set {
+		dst = appendField(dst, tagSetMember, []byte(member))
+	}
+	var members []domain.ScoredMember
+	if e.ZSet != nil {
+		members = e.ZSet.Members()
+	}
+	for _, m := range members {
+		field = binary.BigEndian.AppendUint64(field[:0], math.Float64bits(m.Score))
+		field = append(field, m.Member...)
+		dst = appendField(dst, tagZSetMember, field)
+	}
+	return dst
+}
+
+// appendField appends a field as its tag, length and data.
+func appendField(dst []byte, tag uint64, data []byte) []byte {
+	dst = binary.AppendUvarint(dst, tag)
+	dst = binary.AppendUvarint(dst, uint64(len(data)))
+	return append(dst, data...)
+}
+
+// decodeEntity decodes the tagged fields written by appendEntity, skipping unknown tags.
+func decodeEntity(b []byte) (domain.Entity, error) {
+	var e domain.Entity
+	var zset domain.ScoredMembers
+	for len(b) > 0 {
+		tag, data, rest, err := readField(b)
+		if err != nil {
+			return domain.Entity{}, err
+		}
+		b = rest
+
+		switch tag {
+		case tagKey:
+			e.Key = string(data)
+		case tagValue:
+			e.Value = string(data)
+		case tagExpiration:
+			if len(data) != 8 {
+				return domain.Entity{}, fmt.Errorf("%w: expiration of %d bytes", ErrCorrupted, len(data))
+			}
+			e.Expiration = int64(binary.BigEndian.Uint64(data))
+		case tagFlags:
+			flags, n := binary.Uvarint(data)
+			if n != len(data) || flags > math.MaxUint32 {
+				return domain.Entity{}, fmt.Errorf("%w: invalid flags", ErrCorrupted)
+			}
+			e.Flags = uint32(flags)
+		case tagVersion:
+			version, n := binary.Uvarint(data)
+			if n != len(data) {
+				return domain.Entity{}, fmt.Errorf("%w: invalid version", ErrCorrupted)
+			}
+			e.Version = version
+		case tagType:
+			e.Type = domain.ValueType(data)
+		case tagContentType:
+			e.ContentType = string(data)
+		case tagSlidingTTL:
+			ttl, n := binary.Uvarint(data)
+			if n != len(data) || ttl > math.MaxInt64 {
+				return domain.Entity{}, fmt.Errorf("%w: invalid sliding ttl", ErrCorrupted)
+			}
+			e.Sliding, e.SlidingTTL = true, time.Duration(ttl)
+		case tagHashField:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return domain.Entity{}, fmt.Errorf("%w: invalid hash field", ErrCorrupted)
+			}
+			if e.Hash == nil {
+				e.Hash = make(map[string]string)
+			}
+			name := data[n : n+int(size)]
+			e.Hash[string(name)] = string(data[n+int(size):])
+		case tagListItem:
+			e.List = append(e.List, string(data))
+		case tagSetMember:
+			if e.Set == nil {
+				e.Set = make(domain.Members)
+			}
+			e.Set[string(data)] = struct{}{}
+		case tagZSetMember:
+			if len(data) < 8 {
+				return domain.Entity{}, fmt.Errorf("%w: invalid sorted set member", ErrCorrupted)
+			}
+			zset = append(zset, domain.ScoredMember{
+				Member: string(data[8:]),
+				Score:  math.Float64frombits(binary.BigEndian.Uint64(data)),
+			})
+		}
+	}
+	if zset != nil {
+		e.ZSet = zset
+	}
+	return e, nil
+}
+
+// readField splits the next field off b.
+func readField(b []byte) (tag uint64, data, rest []byte, err error) {
+	tag, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, nil, fmt.Errorf("%w: invalid field tag", ErrCorrupted)
+	}
+	b = b[n:]
+
+	size, n := binary.Uvarint(b)
+	if n <= 0 || size > uint64(len(b)-n) {
+		return 0, nil, nil, fmt.Errorf("%w: invalid field length", ErrCorrupted)
+	}
+	b = b[n:]
+
+	return tag, b[:size], b[size:], nil
+}
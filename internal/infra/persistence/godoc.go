@@ -0,0 +1,7 @@
+// Package persistence provides durability for the in-memory storage.
+// Contains the append-only file (AOF) of the storage changes,
+// which is replayed on startup to restore the keyspace and rewritten in the background to stay compact,
+// and the point-in-time snapshots of the keyspace saved on a schedule, on demand and on shutdown.
+// Records are framed with their length and a CRC-32C checksum,
+// entity fields are tagged, so new fields can be added without breaking old files.
+package persistence
@@ -0,0 +1,337 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/rs/zerolog/log"
+)
+
+// snapshotMagic starts every snapshot file, the last byte is the format version.
+var snapshotMagic = []byte("IMSSNP\x00\x01")
+
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotExt    = ".snap"
+
+	// opEntity and opFooter are the record kinds of a snapshot.
+	opEntity byte = 1
+	opFooter byte = 2
+)
+
+// ErrNoSnapshot is returned when the directory has no valid snapshot.
+var ErrNoSnapshot = errors.New("no valid snapshot found")
+
+// Snapshotter returns a point-in-time copy of the keyspace.
+type Snapshotter interface {
+	Snapshot() []domain.Entity
+}
+
+// WriteSnapshot writes the entities to a new snapshot file in dir and returns its path.
+// The file is written to a temporary file, synced and renamed, so a crash never leaves a partial snapshot.
+//
+// The file consists of the magic, one record per entity and a footer record holding the number
+// of entities and the CRC-32C of everything before it, so a truncated or altered file is refused on load.
+func WriteSnapshot(dir string, entities []domain.Entity) (string, error) {
+	tmp, err := os.CreateTemp(dir, snapshotPrefix+"*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = writeSnapshot(tmp, entities); err != nil {
+		return "", err
+	}
+	if err = tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s%020d%s", snapshotPrefix, time.Now().UnixNano(), snapshotExt))
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, syncDir(dir)
+}
+
+// writeSnapshot encodes the entities in the snapshot format.
+func writeSnapshot(w io.Writer, entities []domain.Entity) error {
+	checksum := crc32.New(crcTable)
+	bw := bufio.NewWriter(io.MultiWriter(w, checksum))
+
+	if _, err := bw.Write(snapshotMagic); err != nil {
+		return err
+	}
+	var buf []byte
+	for _, entity := range entities {
+		buf = appendRecord(buf[:0], appendEntity([]byte{opEntity}, entity))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	footer := binary.AppendUvarint([]byte{opFooter}, uint64(len(entities)))
+	footer = binary.BigEndian.AppendUint32(footer, checksum.Sum32())
+	_, err := w.Write(appendRecord(nil, footer))
+	return err
+}
+
+// LoadSnapshot validates the snapshot at path and restores it into r, returning the number of entities.
+// The whole file is validated before anything is restored, expired entities are skipped.
+func LoadSnapshot(path string, r Restorer) (int, error) {
+	entities, err := readSnapshot(path)
+	if err != nil {
+		return 0, err
+	}
+	return restore(entities, r)
+}
+
+// restore passes the entities that have not expired to r.
+func restore(entities []domain.Entity, r Restorer) (int, error) {
+	restored := 0
+	for _, entity := range entities {
+		if entity.IsExpired() {
+			continue
+		}
+		if err := r.Restore(entity); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// readSnapshot reads and validates the snapshot at path.
+func readSnapshot(path string) ([]domain.Entity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	checksum := crc32.New(crcTable)
+	reader := io.TeeReader(bufio.NewReader(file), checksum)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err = io.ReadFull(reader, magic); err != nil || !bytes.Equal(magic, snapshotMagic) {
+		return nil, fmt.Errorf("snapshot %s: %w: not a snapshot of this storage", path, ErrCorrupted)
+	}
+
+	var entities []domain.Entity
+	for {
+		sum := checksum.Sum32()
+		payload, err := readRecord(reader)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("snapshot %s: %w: file is truncated", path, ErrCorrupted)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", path, err)
+		}
+		if len(payload) == 0 {
+			return nil, fmt.Errorf("snapshot %s: %w: empty record", path, ErrCorrupted)
+		}
+
+		switch payload[0] {
+		case opEntity:
+			entity, err := decodeEntity(payload[1:])
+			if err != nil {
+				return nil, fmt.Errorf("snapshot %s: %w", path, err)
+			}
+			entities = append(entities, entity)
+		case opFooter:
+			if err = checkFooter(payload[1:], len(entities), sum, reader); err != nil {
+				return nil, fmt.Errorf("snapshot %s: %w", path, err)
+			}
+			return entities, nil
+		default:
+			return nil, fmt.Errorf("snapshot %s: %w: unknown record %d", path, ErrCorrupted, payload[0])
+		}
+	}
+}
+
+// checkFooter verifies the entity count and the checksum stored in the footer
+// and that nothing follows the footer.
+func checkFooter(footer []byte, count int, sum uint32, rest io.Reader) error {
+	stored, n := binary.Uvarint(footer)
+	if n <= 0 || len(footer[n:]) != 4 {
+		return fmt.Errorf("%w: invalid footer", ErrCorrupted)
+	}
+	if stored != uint64(count) {
+		return fmt.Errorf("%w: footer expects %d entities, found %d", ErrCorrupted, stored, count)
+	}
+	if binary.BigEndian.Uint32(footer[n:]) != sum {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+	if n, _ := rest.Read(make([]byte, 1)); n > 0 {
+		return fmt.Errorf("%w: data after the footer", ErrCorrupted)
+	}
+	return nil
+}
+
+// LoadLatestSnapshot restores the newest valid snapshot of dir into r and returns its path
+// and the number of restored entities.
+// Corrupted snapshots are refused with an error in the log and the next older one is tried.
+// It returns ErrNoSnapshot when there is no valid snapshot.
+func LoadLatestSnapshot(dir string, r Restorer) (string, int, error) {
+	paths, err := listSnapshots(dir)
+	if err != nil {
+		return "", 0, err
+	}
+
+	for idx := len(paths) - 1; idx >= 0; idx-- {
+		entities, err := readSnapshot(paths[idx])
+		if err != nil {
+			log.Error().Err(err).Msg("Refusing corrupted snapshot")
+			continue
+		}
+
+		restored, err := restore(entities, r)
+		return paths[idx], restored, err
+	}
+	return "", 0, ErrNoSnapshot
+}
+
+// listSnapshots returns the snapshot paths of dir from the oldest to the newest.
+func listSnapshots(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotExt) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	// The names embed a zero padded timestamp, so the lexical order is the chronological order.
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// syncDir syncs the directory, so a rename in it survives a power failure.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Snapshots saves snapshots of a storage to a directory on a schedule and on demand,
+// keeping only the newest ones.
+type Snapshots struct {
+	dir    string
+	retain int
+	source Snapshotter
+
+	// mu serializes saving, so scheduled and on demand snapshots do not interleave.
+	mu sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSnapshots creates the directory if needed and saves a snapshot of source every interval,
+// a non-positive interval disables the schedule. Only the newest retain snapshots are kept.
+// Call Close to stop the schedule.
+func NewSnapshots(dir string, interval time.Duration, retain int, source Snapshotter) (*Snapshots, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if retain < 1 {
+		retain = 1
+	}
+
+	s := &Snapshots{
+		dir:    dir,
+		retain: retain,
+		source: source,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run(interval)
+	return s, nil
+}
+
+// run saves a snapshot every interval until Close is called.
+func (s *Snapshots) run(interval time.Duration) {
+	defer close(s.done)
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.Save(); err != nil {
+				log.Error().Err(err).Msg("Failed to save scheduled snapshot")
+			}
+		}
+	}
+}
+
+// Save writes a snapshot of the source, removes the ones over the retention and returns its path.
+func (s *Snapshots) Save() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := WriteSnapshot(s.dir, s.source.Snapshot())
+	if err != nil {
+		return "", err
+	}
+
+	paths, err := listSnapshots(s.dir)
+	if err != nil {
+		return path, err
+	}
+	for idx := 0; idx < len(paths)-s.retain; idx++ {
+		if err = os.Remove(paths[idx]); err != nil {
+			log.Error().Err(err).Str("path", paths[idx]).Msg("Failed to remove old snapshot")
+		}
+	}
+	return path, nil
+}
+
+// Close stops the schedule and waits for a running snapshot to finish.
+// It is safe to call Close more than once.
+func (s *Snapshots) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+	return nil
+}
@@ -0,0 +1,184 @@
+package persistence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSnapshotter returns the same entities on every snapshot.
+type staticSnapshotter []domain.Entity
+
+func (s staticSnapshotter) Snapshot() []domain.Entity {
+	return s
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixNano()
+	tests := []struct {
+		name     string
+		entities []domain.Entity
+		want     mapRestorer
+	}{
+		{
+			name: "Snapshot restores keys, values and expirations",
+			entities: []domain.Entity{
+				{Key: "key1", Value: "value1"},
+				{Key: "key2", Value: "\x00binary\xff", Expiration: future},
+			},
+			want: mapRestorer{
+				"key1": {Key: "key1", Value: "value1"},
+				"key2": {Key: "key2", Value: "\x00binary\xff", Expiration: future},
+			},
+		},
+		{
+			name: "Snapshot skips the keys that expired since it was written",
+			entities: []domain.Entity{
+				{Key: "key1", Value: "value1", Expiration: time.Now().Add(-time.Hour).UnixNano()},
+			},
+			want: mapRestorer{},
+		},
+		{
+			name:     "Snapshot of an empty storage",
+			entities: nil,
+			want:     mapRestorer{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, err := WriteSnapshot(dir, tt.entities)
+			require.NoError(t, err)
+
+			got := mapRestorer{}
+			restored, err := LoadSnapshot(path, got)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.want), restored)
+			assert.Equal(t, tt.want, got)
+
+			// No temporary files are left behind.
+			paths, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+			assert.NoError(t, err)
+			assert.Empty(t, paths)
+		})
+	}
+}
+
+func TestLoadSnapshot_Corrupted(t *testing.T) {
+	entities := []domain.Entity{{Key: "key1", Value: "value1"}, {Key: "key2", Value: "value2"}}
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+	}{
+		{
+			name: "Flipped byte",
+			corrupt: func(data []byte) []byte {
+				data[len(snapshotMagic)+recordHeaderSize+3] ^= 0xff
+				return data
+			},
+		},
+		{
+			name: "Truncated file",
+			corrupt: func(data []byte) []byte {
+				return data[:len(data)-5]
+			},
+		},
+		{
+			name: "Missing footer",
+			corrupt: func(data []byte) []byte {
+				footer := appendRecord(nil, []byte{opFooter, 2, 0, 0, 0, 0})
+				return data[:len(data)-len(footer)]
+			},
+		},
+		{
+			name: "Wrong magic",
+			corrupt: func(data []byte) []byte {
+				data[0] = 'X'
+				return data
+			},
+		},
+		{
+			name: "Data after the footer",
+			corrupt: func(data []byte) []byte {
+				return append(data, 0)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := WriteSnapshot(t.TempDir(), entities)
+			require.NoError(t, err)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, tt.corrupt(data), 0o644))
+
+			got := mapRestorer{}
+			_, err = LoadSnapshot(path, got)
+			assert.ErrorIs(t, err, ErrCorrupted)
+			assert.Empty(t, got)
+		})
+	}
+}
+
+func TestLoadLatestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	_, _, err := LoadLatestSnapshot(dir, mapRestorer{})
+	assert.ErrorIs(t, err, ErrNoSnapshot)
+
+	older, err := WriteSnapshot(dir, []domain.Entity{{Key: "key", Value: "older"}})
+	require.NoError(t, err)
+	newer, err := WriteSnapshot(dir, []domain.Entity{{Key: "key", Value: "newer"}})
+	require.NoError(t, err)
+
+	got := mapRestorer{}
+	path, restored, err := LoadLatestSnapshot(dir, got)
+	assert.NoError(t, err)
+	assert.Equal(t, newer, path)
+	assert.Equal(t, 1, restored)
+	assert.Equal(t, "newer", got["key"].Value)
+
+	// A corrupted newest snapshot is refused in favor of the older one.
+	require.NoError(t, os.WriteFile(newer, []byte("garbage"), 0o644))
+	got = mapRestorer{}
+	path, _, err = LoadLatestSnapshot(dir, got)
+	assert.NoError(t, err)
+	assert.Equal(t, older, path)
+	assert.Equal(t, "older", got["key"].Value)
+}
+
+func TestSnapshots_Save(t *testing.T) {
+	dir := t.TempDir()
+	snapshots, err := NewSnapshots(dir, 0, 2, staticSnapshotter{{Key: "key", Value: "value"}})
+	require.NoError(t, err)
+	defer snapshots.Close()
+
+	var saved []string
+	for i := 0; i < 4; i++ {
+		path, err := snapshots.Save()
+		require.NoError(t, err)
+		saved = append(saved, path)
+	}
+
+	paths, err := listSnapshots(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, saved[2:], paths)
+}
+
+func TestSnapshots_Schedule(t *testing.T) {
+	dir := t.TempDir()
+	snapshots, err := NewSnapshots(dir, 10*time.Millisecond, 3, staticSnapshotter{{Key: "key", Value: "value"}})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		paths, err := listSnapshots(dir)
+		return err == nil && len(paths) > 0
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, snapshots.Close())
+	assert.NoError(t, snapshots.Close())
+}
@@ -0,0 +1,355 @@
+// Package pubsub provides fire-and-forget messaging between clients, like the Redis publish/subscribe.
+// Messages are not stored: a message published to a channel reaches the subscribers of the channel
+// and of the glob patterns matching it at the time, and nobody else.
+package pubsub
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/gynshu-one/in-memory-storage/internal/glob"
+)
+
+// SlowConsumerPolicy selects what happens when a subscriber's buffer is full.
+type SlowConsumerPolicy string
+
+const (
+	// DropOldest discards the oldest queued message of the subscriber to make room for the new one.
+	DropOldest SlowConsumerPolicy = "drop-oldest"
+	// Disconnect ends the subscriber with ErrSlowConsumer, the message is dropped.
+	Disconnect SlowConsumerPolicy = "disconnect"
+)
+
+var (
+	// ErrSlowConsumer ends a subscriber that did not keep up under the Disconnect policy.
+	ErrSlowConsumer = errors.New("subscriber did not keep up with the messages")
+	// ErrClosed ends the subscribers when the broker is closed.
+	ErrClosed = errors.New("broker closed")
+)
+
+// Message is a message received by a subscriber.
+type Message struct {
+	Channel string `json:"channel"`
+	// Pattern is the pattern the subscriber matched the channel with, it is empty for a channel subscription.
+	Pattern string `json:"pattern,omitempty"`
+	Payload string `json:"payload"`
+}
+
+// Stats holds the subscriptions and the counters of a broker.
+type Stats struct {
+	Channels    int `json:"channels"`
+	Patterns    int `json:"patterns"`
+	Subscribers int `json:"subscribers"`
+	// Delivered counts the messages queued for the subscribers.
+	Delivered uint64 `json:"delivered"`
+	// Dropped counts the messages discarded because a subscriber was too slow, under either policy.
+	Dropped uint64 `json:"dropped"`
+	// Disconnected counts the subscribers ended by the Disconnect policy.
+	Disconnected uint64 `json:"disconnected"`
+}
+
+// Broker routes the published messages to the subscribers.
+type Broker struct {
+	buffer int
+	policy SlowConsumerPolicy
+
+	mu          sync.Mutex
+	channels    map[string]map[*Subscriber]struct{}
+	patterns    map[string]map[*Subscriber]struct{}
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+
+	delivered    uint64
+	dropped      uint64
+	disconnected uint64
+}
+
+// NewBroker returns a broker queueing up to buffer messages per subscriber,
+// applying the policy to the subscribers that fall behind. buffer less than 1 is treated as 1.
+func NewBroker(buffer int, policy SlowConsumerPolicy) *Broker {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &Broker{
+		buffer:      buffer,
+		policy:      policy,
+		channels:    make(map[string]map[*Subscriber]struct{}),
+		patterns:    make(map[string]map[*Subscriber]struct{}),
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// Publish sends the payload to the subscribers of the channel and of the patterns matching it
+// and returns the number of messages queued.
+// A subscriber matching several times, e.g. by the channel and a pattern, gets the message several times.
+// It never blocks, the slow consumer policy is applied to the subscribers with a full buffer.
+func (b *Broker) Publish(channel, payload string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queued := 0
+	for sub := range b.channels[channel] {
+		if b.deliver(sub, Message{Channel: channel, Payload: payload}) {
+			queued++
+		}
+	}
+	for pattern, subs := range b.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		for sub := range subs {
+			if b.deliver(sub, Message{Channel: channel, Pattern: pattern, Payload: payload}) {
+				queued++
+			}
+		}
+	}
+	return queued
+}
+
+// deliver queues the message for the subscriber applying the slow consumer policy.
+// The caller must hold the lock.
+func (b *Broker) deliver(sub *Subscriber, m Message) bool {
+	for {
+		select {
+		case sub.ch <- m:
+			b.delivered++
+			return true
+		default:
+		}
+
+		if b.policy == Disconnect {
+			b.dropped++
+			b.disconnected++
+			b.end(sub, ErrSlowConsumer)
+			return false
+		}
+		// The subscriber receives concurrently, so the buffer may have room again by now.
+		select {
+		case <-sub.ch:
+			b.dropped++
+		default:
+		}
+	}
+}
+
+// NewSubscriber returns a subscriber without subscriptions. Call Close when done.
+func (b *Broker) NewSubscriber() *Subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscriber{
+		broker:   b,
+		ch:       make(chan Message, b.buffer),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+	if b.closed {
+		sub.ended = true
+		sub.err = ErrClosed
+		close(sub.ch)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Channels returns the channels with at least one subscriber matching the pattern in lexical order,
+// an empty pattern matches every channel. Pattern subscriptions are not counted.
+func (b *Broker) Channels(pattern string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var channels []string
+	for channel := range b.channels {
+		if pattern == "" || glob.Match(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NumSub returns the number of the subscribers of the channel, pattern subscriptions are not counted.
+func (b *Broker) NumSub(channel string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.channels[channel])
+}
+
+// NumPat returns the number of the patterns subscribed to.
+func (b *Broker) NumPat() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.patterns)
+}
+
+// Stats returns the subscriptions and the counters of the broker.
+func (b *Broker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{
+		Channels:     len(b.channels),
+		Patterns:     len(b.patterns),
+		Subscribers:  len(b.subscribers),
+		Delivered:    b.delivered,
+		Dropped:      b.dropped,
+		Disconnected: b.disconnected,
+	}
+}
+
+// Close ends all the subscribers with ErrClosed, later subscribers end right away.
+// It is safe to call Close more than once.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.end(sub, ErrClosed)
+	}
+}
+
+// end removes the subscriptions of the subscriber and closes its channel.
+// The caller must hold the lock.
+func (b *Broker) end(sub *Subscriber, err error) {
+	if sub.ended {
+		return
+	}
+	for channel := range sub.channels {
+		b.remove(b.channels, channel, sub)
+		delete(sub.channels, channel)
+	}
+	for pattern := range sub.patterns {
+		b.remove(b.patterns, pattern, sub)
+		delete(sub.patterns, pattern)
+	}
+	delete(b.subscribers, sub)
+
+	sub.ended = true
+	sub.err = err
+	close(sub.ch)
+}
+
+// remove removes the subscriber from the subscriptions of the name, dropping the name when nobody is left.
+// The caller must hold the lock.
+func (b *Broker) remove(subscriptions map[string]map[*Subscriber]struct{}, name string, sub *Subscriber) {
+	subs := subscriptions[name]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(subscriptions, name)
+	}
+}
+
+// Subscriber receives the messages of the channels and the patterns it subscribed to.
+// The subscriptions are guarded by the broker lock.
+type Subscriber struct {
+	broker   *Broker
+	ch       chan Message
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	ended    bool
+	// err is why the subscriber ended.
+	err error
+}
+
+// Subscribe subscribes to the channel and returns the number of the subscriptions of the subscriber.
+func (s *Subscriber) Subscribe(channel string) int {
+	return s.add(s.broker.channels, s.channels, channel)
+}
+
+// PSubscribe subscribes to the channels matching the glob pattern
+// and returns the number of the subscriptions of the subscriber.
+func (s *Subscriber) PSubscribe(pattern string) int {
+	return s.add(s.broker.patterns, s.patterns, pattern)
+}
+
+// Unsubscribe unsubscribes from the channel and returns the number of the subscriptions left.
+func (s *Subscriber) Unsubscribe(channel string) int {
+	return s.drop(s.broker.channels, s.channels, channel)
+}
+
+// PUnsubscribe unsubscribes from the pattern and returns the number of the subscriptions left.
+func (s *Subscriber) PUnsubscribe(pattern string) int {
+	return s.drop(s.broker.patterns, s.patterns, pattern)
+}
+
+// add adds the subscription unless the subscriber has ended.
+func (s *Subscriber) add(subscriptions map[string]map[*Subscriber]struct{}, own map[string]struct{}, name string) int {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	if !s.ended {
+		subs, ok := subscriptions[name]
+		if !ok {
+			subs = make(map[*Subscriber]struct{})
+			subscriptions[name] = subs
+		}
+		subs[s] = struct{}{}
+		own[name] = struct{}{}
+	}
+	return len(s.channels) + len(s.patterns)
+}
+
+// drop removes the subscription.
+func (s *Subscriber) drop(subscriptions map[string]map[*Subscriber]struct{}, own map[string]struct{}, name string) int {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	if _, ok := own[name]; ok {
+		delete(own, name)
+		s.broker.remove(subscriptions, name, s)
+	}
+	return len(s.channels) + len(s.patterns)
+}
+
+// Channels returns the channels the subscriber subscribed to in lexical order.
+func (s *Subscriber) Channels() []string {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return sorted(s.channels)
+}
+
+// Patterns returns the patterns the subscriber subscribed to in lexical order.
+func (s *Subscriber) Patterns() []string {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return sorted(s.patterns)
+}
+
+// Count returns the number of the subscriptions of the subscriber.
+func (s *Subscriber) Count() int {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return len(s.channels) + len(s.patterns)
+}
+
+// C returns the channel of the messages, it is closed when the subscriber ends.
+func (s *Subscriber) C() <-chan Message {
+	return s.ch
+}
+
+// Err returns why the subscriber ended: ErrSlowConsumer, ErrClosed, or nil when it was closed by the client.
+// It is meaningful once C is closed.
+func (s *Subscriber) Err() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscriber and all its subscriptions. It is safe to call Close more than once.
+func (s *Subscriber) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.end(s, nil)
+}
+
+// sorted returns the names of the set in lexical order.
+func sorted(set map[string]struct{}) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,126 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drain returns the messages queued for the subscriber.
+func drain(sub *Subscriber) []Message {
+	var got []Message
+	for {
+		select {
+		case m, ok := <-sub.C():
+			if !ok {
+				return got
+			}
+			got = append(got, m)
+		default:
+			return got
+		}
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	b := NewBroker(10, Disconnect)
+	news := b.NewSubscriber()
+	all := b.NewSubscriber()
+	defer news.Close()
+	defer all.Close()
+
+	assert.Equal(t, 1, news.Subscribe("news.tech"))
+	assert.Equal(t, 1, all.PSubscribe("news.*"))
+	assert.Equal(t, 2, all.Subscribe("news.tech"))
+
+	assert.Equal(t, 3, b.Publish("news.tech", "go 1.21"))
+	assert.Equal(t, 1, b.Publish("news.sport", "0:0"))
+	assert.Equal(t, 0, b.Publish("weather", "rain"))
+
+	assert.Equal(t, []Message{{Channel: "news.tech", Payload: "go 1.21"}}, drain(news))
+	assert.ElementsMatch(t, []Message{
+		{Channel: "news.tech", Payload: "go 1.21"},
+		{Channel: "news.tech", Pattern: "news.*", Payload: "go 1.21"},
+		{Channel: "news.sport", Pattern: "news.*", Payload: "0:0"},
+	}, drain(all))
+
+	assert.Equal(t, []string{"news.tech"}, b.Channels(""))
+	assert.Equal(t, 2, b.NumSub("news.tech"))
+	assert.Equal(t, 1, b.NumPat())
+
+	assert.Equal(t, 1, all.Unsubscribe("news.tech"))
+	assert.Equal(t, 0, all.PUnsubscribe("news.*"))
+	assert.Equal(t, 0, all.PUnsubscribe("news.*"), "unsubscribing twice is harmless")
+	assert.Equal(t, 1, b.Publish("news.tech", "go 1.22"))
+	assert.Equal(t, Stats{Channels: 1, Subscribers: 2, Delivered: 5}, b.Stats())
+}
+
+func TestBroker_SlowConsumer(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    SlowConsumerPolicy
+		want      []string
+		wantErr   error
+		wantStats Stats
+	}{
+		{
+			name:      "DropOldest keeps the newest messages",
+			policy:    DropOldest,
+			want:      []string{"2", "3"},
+			wantStats: Stats{Channels: 1, Subscribers: 1, Delivered: 3, Dropped: 1},
+		},
+		{
+			name:      "Disconnect ends the subscriber",
+			policy:    Disconnect,
+			want:      []string{"1", "2"},
+			wantErr:   ErrSlowConsumer,
+			wantStats: Stats{Delivered: 2, Dropped: 1, Disconnected: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker(2, tt.policy)
+			sub := b.NewSubscriber()
+			sub.Subscribe("ch")
+
+			b.Publish("ch", "1")
+			b.Publish("ch", "2")
+			b.Publish("ch", "3")
+
+			var got []string
+			for _, m := range drain(sub) {
+				got = append(got, m.Payload)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantStats, b.Stats())
+			if tt.wantErr != nil {
+				_, open := <-sub.C()
+				assert.False(t, open)
+				assert.ErrorIs(t, sub.Err(), tt.wantErr)
+				assert.Equal(t, 0, sub.Count())
+			}
+		})
+	}
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := NewBroker(1, DropOldest)
+	sub := b.NewSubscriber()
+	sub.Subscribe("ch")
+	closed := b.NewSubscriber()
+	closed.Close()
+	closed.Close()
+	assert.NoError(t, closed.Err())
+
+	b.Close()
+	_, open := <-sub.C()
+	assert.False(t, open)
+	assert.ErrorIs(t, sub.Err(), ErrClosed)
+	assert.Equal(t, Stats{}, b.Stats())
+
+	late := b.NewSubscriber()
+	assert.Equal(t, 0, late.Subscribe("ch"))
+	_, open = <-late.C()
+	assert.False(t, open)
+	assert.ErrorIs(t, late.Err(), ErrClosed)
+}
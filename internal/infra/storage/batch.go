@@ -0,0 +1,108 @@
+package storage
+
+import "github.com/gynshu-one/in-memory-storage/internal/domain"
+
+// MGet gets the values of the keys like Get, holding the read locks of their shards once.
+// The expired keys are removed and the expirations of the sliding keys pushed after the locks are released.
+func (i *storage) MGet(keys []string) []domain.KeyResult {
+	results := make([]domain.KeyResult, len(keys))
+	var expired []string
+	var sliding []domain.Entity
+
+	unlock := i.lockKeys(keys, false)
+	for idx, key := range keys {
+		results[idx].Key = key
+		rec, ok := i.shardFor(key).storage.get(key)
+		switch {
+		case !ok:
+			results[idx].Err = domain.ErrKeyNotFound
+		case rec.entity.ExpiredAt(i.clock.now()):
+			results[idx].Err = domain.ErrKeyExpired
+			expired = append(expired, key)
+		case rec.entity.Type != domain.TypeString:
+			rec.touch()
+			results[idx].Err = domain.ErrWrongType
+		default:
+			rec.touch()
+			results[idx].Value = rec.entity.Value
+			results[idx].Version = rec.entity.Version
+			if rec.entity.Sliding {
+				sliding = append(sliding, rec.entity)
+			}
+		}
+	}
+	unlock()
+
+	for _, key := range expired {
+		i.expire(i.shardFor(key), key)
+	}
+	for _, entity := range sliding {
+		i.slide(entity.Key, entity)
+	}
+	return results
+}
+
+// MSet sets the key-value pairs like Set, holding the write locks of their shards once.
+// Like SetWith, the memory is reserved before locking the shards.
+func (i *storage) MSet(items []domain.SetItem) []domain.KeyResult {
+	results := make([]domain.KeyResult, len(items))
+	keys := make([]string, 0, len(items))
+	var pending growth
+	for idx, item := range items {
+		results[idx].Key = item.Key
+		if err := i.reserveMore(&pending, i.shardFor(item.Key), item.Key, recordSize(item.Key, item.Value)); err != nil {
+			results[idx].Err = err
+			continue
+		}
+		keys = append(keys, item.Key)
+	}
+
+	unlock := i.lockKeys(keys, true)
+	defer unlock()
+	now := i.clock.now()
+	for idx, item := range items {
+		if results[idx].Err != nil {
+			continue
+		}
+		entity := domain.Entity{
+			Key:     item.Key,
+			Value:   item.Value,
+			Version: i.nextVersion(),
+		}
+		if item.TTL > 0 {
+			entity.Expiration = now.Add(item.TTL).UnixNano()
+		}
+		sh := i.shardFor(item.Key)
+		sh.put(entity)
+		sh.emit(domain.ChangeSet, item.Key, entity)
+		results[idx].Version = entity.Version
+	}
+	return results
+}
+
+// MDelete deletes the keys like Delete, holding the write locks of their shards once.
+func (i *storage) MDelete(keys []string) []domain.KeyResult {
+	results := make([]domain.KeyResult, len(keys))
+
+	unlock := i.lockKeys(keys, true)
+	defer unlock()
+	for idx, key := range keys {
+		results[idx].Key = key
+		sh := i.shardFor(key)
+		rec, ok := sh.storage.get(key)
+		if !ok {
+			results[idx].Err = domain.ErrKeyNotFound
+			continue
+		}
+
+		sh.remove(key)
+		if rec.entity.ExpiredAt(i.clock.now()) {
+			i.expiredLazily.Add(1)
+			sh.emit(domain.ChangeExpire, key, domain.Entity{})
+			results[idx].Err = domain.ErrKeyExpired
+			continue
+		}
+		sh.emit(domain.ChangeDelete, key, domain.Entity{})
+	}
+	return results
+}
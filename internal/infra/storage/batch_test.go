@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_MGet(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+		"a":       {Value: "1", Version: 1},
+		"b":       {Value: "2", Version: 2},
+		"expired": {Value: "3", Expiration: time.Now().Add(-time.Minute).UnixNano()},
+		"hash":    {Type: domain.TypeHash, Hash: map[string]string{"f": "v"}},
+	})
+
+	got := s.MGet([]string{"b", "missing", "a", "expired", "hash"})
+	assert.Equal(t, []domain.KeyResult{
+		{Key: "b", Value: "2", Version: 2},
+		{Key: "missing", Err: domain.ErrKeyNotFound},
+		{Key: "a", Value: "1", Version: 1},
+		{Key: "expired", Err: domain.ErrKeyExpired},
+		{Key: "hash", Err: domain.ErrWrongType},
+	}, got)
+
+	_, ok := s.shards[0].storage.get("expired")
+	assert.False(t, ok, "the expired key is removed")
+}
+
+func Test_storage_MGetSliding(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+	clk := &fakeClock{at: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	useClock(s, clk)
+	_, err := s.SetWith("session", "token", 30*time.Minute, domain.SetOptions{Sliding: true})
+	require.NoError(t, err)
+	require.NoError(t, s.Set("fixed", "value", 30*time.Minute))
+
+	clk.advance(20 * time.Minute)
+	got := s.MGet([]string{"session", "fixed", "session"})
+	assert.Equal(t, "token", got[0].Value)
+	entity, err := s.GetEntity("fixed")
+	require.NoError(t, err)
+	assert.Equal(t, clk.at.Add(10*time.Minute).UnixNano(), entity.Expiration, "a fixed ttl is not pushed")
+
+	clk.advance(20 * time.Minute)
+	got = s.MGet([]string{"session", "fixed"})
+	assert.NoError(t, got[0].Err, "a sliding key read by MGet expires 30 minutes after the last read")
+	assert.ErrorIs(t, got[1].Err, domain.ErrKeyExpired)
+
+	clk.advance(31 * time.Minute)
+	got = s.MGet([]string{"session"})
+	assert.ErrorIs(t, got[0].Err, domain.ErrKeyExpired)
+}
+
+func Test_storage_MSet(t *testing.T) {
+	s := newLimitedStorage(4, limits{maxKeys: 3, policy: NoEviction})
+	require.NoError(t, s.Set("a", "old", 0))
+
+	got := s.MSet([]domain.SetItem{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2", TTL: time.Minute},
+		{Key: "c", Value: "3"},
+		{Key: "d", Value: "4"},
+	})
+	require.Len(t, got, 4)
+	for idx, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, got[idx].Err, key)
+		entity, err := s.GetEntity(key)
+		assert.NoError(t, err)
+		assert.Equal(t, got[idx].Version, entity.Version, key)
+	}
+	assert.ErrorIs(t, got[3].Err, domain.ErrOutOfMemory, "the item over the limit is not written")
+
+	value, err := s.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+	ttl, err := s.TTL("b")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Second)
+	_, err = s.Get("d")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+}
+
+func Test_storage_MDelete(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+		"a":       {Value: "1"},
+		"expired": {Value: "3", Expiration: time.Now().Add(-time.Minute).UnixNano()},
+	})
+
+	got := s.MDelete([]string{"a", "missing", "expired", "a"})
+	assert.Equal(t, []domain.KeyResult{
+		{Key: "a"},
+		{Key: "missing", Err: domain.ErrKeyNotFound},
+		{Key: "expired", Err: domain.ErrKeyExpired},
+		{Key: "a", Err: domain.ErrKeyNotFound},
+	}, got)
+	assert.Zero(t, s.shards[0].storage.len())
+}
@@ -0,0 +1,15 @@
+package storage
+
+import "time"
+
+// clock tells the current time to the expiration, nil being the wall clock.
+// The tests replace it to expire the keys without sleeping.
+type clock func() time.Time
+
+// now returns the current time.
+func (c clock) now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c()
+}
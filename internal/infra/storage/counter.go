@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// IncrBy adds delta to the integer value of the key and returns the new value.
+// The update is atomic, concurrent increments are never lost.
+func (i *storage) IncrBy(key string, delta int64) (int64, error) {
+	var result int64
+	_, err := i.updateCounter(key, func(value string, exists bool) (string, error) {
+		var err error
+		result, err = addInt(value, exists, delta)
+		return strconv.FormatInt(result, 10), err
+	})
+	return result, err
+}
+
+// addInt adds delta to the integer value, a missing value counting as 0.
+func addInt(value string, exists bool, delta int64) (int64, error) {
+	var n int64
+	if exists {
+		var err error
+		if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return 0, domain.ErrNotInteger
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, domain.ErrOverflow
+	}
+	return n + delta, nil
+}
+
+// IncrByFloat adds delta to the float value of the key and returns the new value.
+// The value is stored without an exponent, e.g. 5000 rather than 5e3.
+func (i *storage) IncrByFloat(key string, delta float64) (float64, error) {
+	var result float64
+	_, err := i.updateCounter(key, func(value string, exists bool) (string, error) {
+		var n float64
+		if exists {
+			var err error
+			if n, err = strconv.ParseFloat(value, 64); err != nil || !finite(n) {
+				return "", domain.ErrNotFloat
+			}
+		}
+
+		result = n + delta
+		if !finite(result) {
+			return "", domain.ErrNotFloat
+		}
+		return strconv.FormatFloat(result, 'f', -1, 64), nil
+	})
+	return result, err
+}
+
+// updateCounter replaces the string value of the key with the one computed by fn, keeping its expiration.
+// fn gets exists false for a missing key, it may be called more than once.
+func (i *storage) updateCounter(key string, fn func(value string, exists bool) (string, error)) (domain.Entity, error) {
+	return i.Update(key, func(current domain.Entity, exists bool) (domain.Entity, error) {
+		if exists && current.Type != domain.TypeString {
+			return domain.Entity{}, domain.ErrWrongType
+		}
+		value, err := fn(current.Value, exists)
+		if err != nil {
+			return domain.Entity{}, err
+		}
+
+		current.Value = value
+		return current, nil
+	})
+}
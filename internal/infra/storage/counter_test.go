@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_IncrBy(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	require.NoError(t, i.Set("text", "abc", 0))
+	require.NoError(t, i.Set("max", "9223372036854775807", 0))
+	require.NoError(t, i.Set("float", "1.5", 0))
+	_, err := i.HSet("hash", map[string]string{"a": "1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		key     string
+		delta   int64
+		want    int64
+		wantErr error
+	}{
+		{name: "a missing key starts at 0", key: "hits", delta: 1, want: 1},
+		{name: "the value is incremented", key: "hits", delta: 5, want: 6},
+		{name: "a negative delta decrements", key: "hits", delta: -10, want: -4},
+		{name: "a non-integer value is refused", key: "text", delta: 1, wantErr: domain.ErrNotInteger},
+		{name: "a float value is refused", key: "float", delta: 1, wantErr: domain.ErrNotInteger},
+		{name: "an overflow is refused", key: "max", delta: 1, wantErr: domain.ErrOverflow},
+		{name: "an underflow is refused", key: "hits", delta: math.MinInt64, wantErr: domain.ErrOverflow},
+		{name: "a hash is refused", key: "hash", delta: 1, wantErr: domain.ErrWrongType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := i.IncrBy(tt.key, tt.delta)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	value, err := i.Get("max")
+	assert.NoError(t, err)
+	assert.Equal(t, "9223372036854775807", value, "a refused increment does not change the value")
+}
+
+func Test_storage_IncrByFloat(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	require.NoError(t, i.Set("text", "abc", 0))
+	require.NoError(t, i.Set("int", "10", 0))
+	require.NoError(t, i.Set("max", "1.7976931348623157e308", 0))
+
+	tests := []struct {
+		name      string
+		key       string
+		delta     float64
+		want      float64
+		wantValue string
+		wantErr   error
+	}{
+		{name: "a missing key starts at 0", key: "price", delta: 0.5, want: 0.5, wantValue: "0.5"},
+		{name: "an integer value is incremented", key: "int", delta: 0.25, want: 10.25, wantValue: "10.25"},
+		{name: "the value is stored without an exponent", key: "int", delta: 4989.75, want: 5000, wantValue: "5000"},
+		{name: "a non-float value is refused", key: "text", delta: 1, wantErr: domain.ErrNotFloat},
+		{name: "an infinite result is refused", key: "max", delta: math.MaxFloat64, wantErr: domain.ErrNotFloat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := i.IncrByFloat(tt.key, tt.delta)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			value, err := i.Get(tt.key)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantValue, value)
+		})
+	}
+}
+
+func Test_storage_IncrByConcurrent(t *testing.T) {
+	i := NewSharded(4)
+	defer i.Close()
+	require.NoError(t, i.Set("counter", "0", time.Hour))
+
+	const workers, increments = 8, 500
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < increments; n++ {
+				_, err := i.IncrBy("counter", 1)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := i.Get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, "4000", value, "no increment is lost")
+	ttl, err := i.TTL("counter")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute, "the expiration is kept")
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// EvictionPolicy selects the keys removed when the storage reaches its memory or key limit.
+type EvictionPolicy string
+
+const (
+	// NoEviction rejects writes with domain.ErrOutOfMemory once a limit is reached.
+	NoEviction EvictionPolicy = "noeviction"
+	// AllKeysLRU evicts the least recently used keys.
+	AllKeysLRU EvictionPolicy = "allkeys-lru"
+	// AllKeysLFU evicts the least frequently used keys.
+	AllKeysLFU EvictionPolicy = "allkeys-lfu"
+	// AllKeysRandom evicts random keys.
+	AllKeysRandom EvictionPolicy = "allkeys-random"
+	// VolatileLRU evicts the least recently used keys among the ones with a ttl.
+	VolatileLRU EvictionPolicy = "volatile-lru"
+	// VolatileTTL evicts the keys with the nearest expiration.
+	VolatileTTL EvictionPolicy = "volatile-ttl"
+)
+
+const (
+	// evictionSamples is the number of keys sampled to pick a victim, like maxmemory-samples in Redis.
+	evictionSamples = 5
+
+	// lfuInitialFreq is the access counter of a new key, so it is not evicted right after it was written.
+	lfuInitialFreq = 5
+	// lfuMaxFreq caps the access counter.
+	lfuMaxFreq = 255
+	// lfuDecayPeriod is the idle time after which the access counter is halved.
+	lfuDecayPeriod = time.Minute
+)
+
+// limits bounds the size of a storage.
+type limits struct {
+	// maxMemory is the maximum estimated memory in bytes, 0 means unlimited.
+	maxMemory int64
+	// maxKeys is the maximum number of keys, 0 means unlimited.
+	maxKeys int64
+	policy  EvictionPolicy
+}
+
+// MemoryStats holds the memory usage and the eviction counters of a storage.
+type MemoryStats struct {
+	UsedMemory int64          `json:"used_memory"`
+	Keys       int64          `json:"keys"`
+	MaxMemory  int64          `json:"max_memory"`
+	MaxKeys    int64          `json:"max_keys"`
+	Policy     EvictionPolicy `json:"policy"`
+	Evicted    uint64         `json:"evicted"`
+}
+
+// reserve makes room for writing a record of the given size under the key,
+// evicting keys according to the policy.
+// It returns domain.ErrOutOfMemory when the limits can not be satisfied.
+// The caller must not hold any shard lock, because victims may live in any shard.
+// Concurrent writers reserve independently, so the limits may be overshot slightly, as in Redis.
+func (i *storage) reserve(sh *shard, key string, size int64) error {
+	return i.reserveMore(&growth{}, sh, key, size)
+}
+
+// growth is the space reserved by a batch for the records it has not stored yet.
+type growth struct {
+	bytes, keys int64
+}
+
+// reserveMore is reserve for a record written along with the ones already reserved in pending,
+// which are not stored yet, so their space is added to the usage. It adds the record to pending.
+func (i *storage) reserveMore(pending *growth, sh *shard, key string, size int64) error {
+	if i.limits.maxMemory <= 0 && i.limits.maxKeys <= 0 {
+		return nil
+	}
+	if i.limits.maxMemory > 0 && size > i.limits.maxMemory {
+		return domain.ErrOutOfMemory
+	}
+
+	sh.mu.RLock()
+	old, exists := sh.storage.get(key)
+	sh.mu.RUnlock()
+
+	delta, newKeys := size, int64(1)
+	if exists {
+		delta -= old.size
+		newKeys = 0
+	}
+
+	for i.exceeds(pending.bytes+delta, pending.keys+newKeys) {
+		if i.limits.policy == NoEviction || !i.evictOne() {
+			return domain.ErrOutOfMemory
+		}
+	}
+	pending.bytes += delta
+	pending.keys += newKeys
+	return nil
+}
+
+// exceeds reports whether growing the storage by delta bytes and newKeys keys breaks a limit.
+func (i *storage) exceeds(delta, newKeys int64) bool {
+	if i.limits.maxMemory > 0 && delta > 0 && i.usage.bytes.Load()+delta > i.limits.maxMemory {
+		return true
+	}
+	if i.limits.maxKeys > 0 && newKeys > 0 && i.usage.keys.Load()+newKeys > i.limits.maxKeys {
+		return true
+	}
+	return false
+}
+
+// evictOne evicts a single key according to the policy, starting from a random shard.
+// It returns false when no shard has a key the policy may evict.
+func (i *storage) evictOne() bool {
+	start := rand.Intn(len(i.shards))
+	for n := 0; n < len(i.shards); n++ {
+		if i.shards[(start+n)%len(i.shards)].evict(i.limits.policy) {
+			i.evicted.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// evict removes the best victim among a sample of the shard keys.
+// Like Redis, it approximates the policy instead of keeping the keys ordered.
+func (s *shard) evict(policy EvictionPolicy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := evictionSamples
+	if policy == AllKeysRandom {
+		samples = 1
+	}
+
+	now := time.Now()
+	var victim string
+	var best int64
+	sampled := 0
+	consider := func(key string, rec *record) bool {
+		score := evictionScore(policy, rec, now)
+		if sampled == 0 || score < best {
+			victim, best = key, score
+		}
+		sampled++
+		return sampled < samples
+	}
+
+	switch policy {
+	case VolatileLRU, VolatileTTL:
+		for key := range s.expires {
+			rec, _ := s.storage.get(key)
+			if !consider(key, rec) {
+				break
+			}
+		}
+	default:
+		// The sample starts from a random bucket, the keys of a bucket are iterated in random order.
+		s.storage.each(rand.Intn(tableBuckets), consider)
+	}
+
+	if sampled == 0 {
+		return false
+	}
+	s.remove(victim)
+	s.emit(domain.ChangeEvict, victim, domain.Entity{})
+	return true
+}
+
+// evictionScore ranks a record for eviction, the lowest score is evicted first.
+func evictionScore(policy EvictionPolicy, rec *record, now time.Time) int64 {
+	switch policy {
+	case AllKeysLFU:
+		return int64(lfuDecay(rec, now))
+	case VolatileTTL:
+		return rec.entity.Expiration
+	case AllKeysRandom:
+		return 0
+	default:
+		return rec.lastAccess.Load()
+	}
+}
+
+// lfuDecay returns the access counter halved for every lfuDecayPeriod the record was idle.
+func lfuDecay(rec *record, now time.Time) uint32 {
+	periods := now.Sub(time.Unix(0, rec.lastAccess.Load())) / lfuDecayPeriod
+	if periods >= 32 {
+		return 0
+	}
+	return rec.freq.Load() >> uint(periods)
+}
+
+// MemoryStats returns the memory usage and the eviction counters.
+func (i *storage) MemoryStats() MemoryStats {
+	return MemoryStats{
+		UsedMemory: i.usage.bytes.Load(),
+		Keys:       i.usage.keys.Load(),
+		MaxMemory:  i.limits.maxMemory,
+		MaxKeys:    i.limits.maxKeys,
+		Policy:     i.limits.policy,
+		Evicted:    i.evicted.Load(),
+	}
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLimitedStorage returns a storage without the background sweeper bounded by the given limits.
+func newLimitedStorage(shards int, l limits) *storage {
+	s := &storage{usage: &usage{}, limits: l}
+	for idx := 0; idx < shards; idx++ {
+		s.shards = append(s.shards, newShard(s.usage, s.notify))
+	}
+	return s
+}
+
+func Test_storage_Eviction(t *testing.T) {
+	type set struct {
+		key   string
+		value string
+		ttl   time.Duration
+	}
+	tests := []struct {
+		name string
+		l    limits
+		// initial keys, written in order a few milliseconds apart
+		initial []set
+		// reads are done after the initial keys are written
+		reads   []string
+		set     set
+		wantErr error
+		// wantKeys are the keys that must survive
+		wantKeys []string
+		// wantGone are the keys that must be evicted
+		wantGone []string
+	}{
+		{
+			name:     "noeviction rejects a new key over the key limit",
+			l:        limits{maxKeys: 2, policy: NoEviction},
+			initial:  []set{{key: "a"}, {key: "b"}},
+			set:      set{key: "c"},
+			wantErr:  domain.ErrOutOfMemory,
+			wantKeys: []string{"a", "b"},
+			wantGone: []string{"c"},
+		},
+		{
+			name:     "noeviction allows overwriting a key at the key limit",
+			l:        limits{maxKeys: 2, policy: NoEviction},
+			initial:  []set{{key: "a"}, {key: "b"}},
+			set:      set{key: "a"},
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:     "noeviction rejects a value over the memory limit",
+			l:        limits{maxMemory: 2 * recordSize("a", "v"), policy: NoEviction},
+			initial:  []set{{key: "a", value: "v"}, {key: "b", value: "v"}},
+			set:      set{key: "a", value: "longer value"},
+			wantErr:  domain.ErrOutOfMemory,
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:     "allkeys-lru evicts the least recently used key",
+			l:        limits{maxKeys: 3, policy: AllKeysLRU},
+			initial:  []set{{key: "a"}, {key: "b"}, {key: "c"}},
+			reads:    []string{"a", "b"},
+			set:      set{key: "d"},
+			wantKeys: []string{"a", "b", "d"},
+			wantGone: []string{"c"},
+		},
+		{
+			name:     "allkeys-lru frees memory for a larger value",
+			l:        limits{maxMemory: 3 * recordSize("a", "v"), policy: AllKeysLRU},
+			initial:  []set{{key: "a", value: "v"}, {key: "b", value: "v"}, {key: "c", value: "v"}},
+			reads:    []string{"c"},
+			set:      set{key: "d", value: "vv"},
+			wantKeys: []string{"c", "d"},
+			wantGone: []string{"a", "b"},
+		},
+		{
+			name:     "allkeys-lfu evicts the least frequently used key",
+			l:        limits{maxKeys: 3, policy: AllKeysLFU},
+			initial:  []set{{key: "a"}, {key: "b"}, {key: "c"}},
+			reads:    []string{"a", "a", "c", "c"},
+			set:      set{key: "d"},
+			wantKeys: []string{"a", "c", "d"},
+			wantGone: []string{"b"},
+		},
+		{
+			name:     "volatile-lru evicts only keys with a ttl",
+			l:        limits{maxKeys: 3, policy: VolatileLRU},
+			initial:  []set{{key: "a", ttl: time.Hour}, {key: "b"}, {key: "c", ttl: time.Hour}},
+			reads:    []string{"c"},
+			set:      set{key: "d"},
+			wantKeys: []string{"b", "c", "d"},
+			wantGone: []string{"a"},
+		},
+		{
+			name:     "volatile-lru rejects a write when no key has a ttl",
+			l:        limits{maxKeys: 2, policy: VolatileLRU},
+			initial:  []set{{key: "a"}, {key: "b"}},
+			set:      set{key: "c"},
+			wantErr:  domain.ErrOutOfMemory,
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:     "volatile-ttl evicts the key with the nearest expiration",
+			l:        limits{maxKeys: 3, policy: VolatileTTL},
+			initial:  []set{{key: "a", ttl: time.Hour}, {key: "b", ttl: time.Minute}, {key: "c"}},
+			set:      set{key: "d"},
+			wantKeys: []string{"a", "c", "d"},
+			wantGone: []string{"b"},
+		},
+		{
+			name:     "allkeys-random evicts a key",
+			l:        limits{maxKeys: 3, policy: AllKeysRandom},
+			initial:  []set{{key: "a"}, {key: "b"}, {key: "c"}},
+			set:      set{key: "d"},
+			wantKeys: []string{"d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newLimitedStorage(1, tt.l)
+			for _, e := range tt.initial {
+				assert.NoError(t, s.Set(e.key, e.value, e.ttl))
+				time.Sleep(time.Millisecond)
+			}
+			for _, key := range tt.reads {
+				_, err := s.Get(key)
+				assert.NoError(t, err)
+				time.Sleep(time.Millisecond)
+			}
+
+			assert.ErrorIs(t, s.Set(tt.set.key, tt.set.value, tt.set.ttl), tt.wantErr)
+
+			for _, key := range tt.wantKeys {
+				_, err := s.Get(key)
+				assert.NoError(t, err, key)
+			}
+			for _, key := range tt.wantGone {
+				_, err := s.Get(key)
+				assert.ErrorIs(t, err, domain.ErrKeyNotFound, key)
+			}
+			if tt.l.maxKeys > 0 {
+				assert.LessOrEqual(t, s.MemoryStats().Keys, tt.l.maxKeys)
+			}
+			if tt.l.maxMemory > 0 {
+				assert.LessOrEqual(t, s.MemoryStats().UsedMemory, tt.l.maxMemory)
+			}
+		})
+	}
+}
+
+func Test_storage_MemoryStats(t *testing.T) {
+	s := newLimitedStorage(4, limits{maxKeys: 10, policy: AllKeysLRU})
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+		assert.NoError(t, s.Set(key, "value", 0))
+	}
+	assert.NoError(t, s.Delete("l"))
+
+	stats := s.MemoryStats()
+	assert.Equal(t, int64(9), stats.Keys)
+	assert.Equal(t, 9*recordSize("a", "value"), stats.UsedMemory)
+	assert.Equal(t, uint64(2), stats.Evicted)
+	assert.Equal(t, AllKeysLRU, stats.Policy)
+}
+
+func Test_storage_EvictionFailedCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		opts domain.SetOptions
+	}{
+		{
+			name: "SetWith does not evict when the mode does not allow the write",
+			opts: domain.SetOptions{Mode: domain.SetIfPresent},
+		},
+		{
+			name: "SetWith does not evict when the version does not match",
+			opts: domain.SetOptions{Mode: domain.SetIfVersion, Version: 42},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newLimitedStorage(1, limits{maxKeys: 3, policy: AllKeysLRU})
+			for _, key := range []string{"a", "b", "c"} {
+				assert.NoError(t, s.Set(key, "v", 0))
+			}
+
+			_, err := s.SetWith("new", "v", 0, tt.opts)
+			assert.ErrorIs(t, err, domain.ErrConditionNotMet)
+
+			for _, key := range []string{"a", "b", "c"} {
+				_, err := s.Get(key)
+				assert.NoError(t, err, key)
+			}
+			assert.Zero(t, s.MemoryStats().Evicted)
+		})
+	}
+}
+
+func Test_storage_EvictionAbortedTxn(t *testing.T) {
+	s := newLimitedStorage(1, limits{maxKeys: 3, policy: AllKeysLRU})
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, s.Set(key, "v", 0))
+	}
+
+	_, err := s.Txn([]domain.Watch{{Key: "a", Version: 42}}, []domain.Op{{Type: domain.OpSet, Key: "new", Value: "v"}})
+	assert.ErrorIs(t, err, domain.ErrTxnAborted)
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := s.Get(key)
+		assert.NoError(t, err, key)
+	}
+	assert.Zero(t, s.MemoryStats().Evicted)
+}
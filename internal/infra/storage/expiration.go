@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+const (
+	// sweepSampleSize is the number of keys with a ttl examined in one round of a sweep cycle.
+	sweepSampleSize = 20
+	// sweepRepeatRatio is the expired share of a sample above which the cycle runs one more round.
+	sweepRepeatRatio = 0.25
+)
+
+// ExpirationStats holds the counters of reclaimed expired keys.
+type ExpirationStats struct {
+	// Cycles is the number of sweep cycles run by the background sweeper.
+	Cycles uint64 `json:"cycles"`
+	// ExpiredActively is the number of keys reclaimed by the background sweeper.
+	ExpiredActively uint64 `json:"expired_actively"`
+	// ExpiredLazily is the number of keys reclaimed when they were accessed.
+	ExpiredLazily uint64 `json:"expired_lazily"`
+}
+
+// sweeper actively reclaims expired keys in the background, in the same way Redis does:
+// every interval it samples keys with a ttl and deletes the expired ones,
+// repeating while more than a quarter of the sample was expired and the budget allows.
+type sweeper struct {
+	interval time.Duration
+	budget   int
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	cycles  atomic.Uint64
+	expired atomic.Uint64
+}
+
+// startSweeper starts the background sweeper.
+// A non-positive interval or budget leaves the storage with lazy expiration only.
+func (i *storage) startSweeper(interval time.Duration, budget int) {
+	if interval <= 0 || budget <= 0 {
+		return
+	}
+
+	i.sweeper = &sweeper{
+		interval: interval,
+		budget:   budget,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go i.runSweeper()
+}
+
+// runSweeper runs a sweep cycle every interval until Close is called.
+func (i *storage) runSweeper() {
+	defer close(i.sweeper.done)
+
+	ticker := time.NewTicker(i.sweeper.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.sweeper.stop:
+			return
+		case <-ticker.C:
+			i.sweeper.expired.Add(uint64(i.sweep(i.sweeper.budget)))
+			i.sweeper.cycles.Add(1)
+		}
+	}
+}
+
+// sweep runs a single sweep cycle examining at most budget keys and returns the number of reclaimed keys.
+// Shards are swept round-robin, so with a small budget consecutive cycles continue where the last one stopped.
+func (i *storage) sweep(budget int) int {
+	reclaimed := 0
+	for n := 0; n < len(i.shards) && budget > 0; n++ {
+		sh := i.shards[i.sweepCursor]
+		i.sweepCursor = (i.sweepCursor + 1) % len(i.shards)
+
+		sampled, expired := sh.sweep(budget)
+		budget -= sampled
+		reclaimed += expired
+	}
+	return reclaimed
+}
+
+// sweep runs sampling rounds on the shard until the expired share of a sample drops
+// to sweepRepeatRatio or the budget is exhausted.
+// The lock is taken per round, so requests are not blocked for the whole cycle.
+func (s *shard) sweep(budget int) (sampled, expired int) {
+	for budget > 0 {
+		size := sweepSampleSize
+		if budget < size {
+			size = budget
+		}
+
+		n, e := s.sweepRound(size)
+		budget -= n
+		sampled += n
+		expired += e
+
+		if n < sweepSampleSize || float64(e) <= float64(n)*sweepRepeatRatio {
+			break
+		}
+	}
+	return sampled, expired
+}
+
+// sweepRound examines up to size keys with a ttl and deletes the expired ones.
+// Map iteration starts at a random position, which makes the sample random.
+func (s *shard) sweepRound(size int) (sampled, expired int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.expires {
+		if sampled == size {
+			break
+		}
+		sampled++
+
+		if rec, ok := s.storage.get(key); !ok || rec.entity.ExpiredAt(s.clock.now()) {
+			s.remove(key)
+			s.emit(domain.ChangeExpire, key, domain.Entity{})
+			expired++
+		}
+	}
+	return sampled, expired
+}
+
+// ExpirationStats returns the counters of reclaimed expired keys.
+func (i *storage) ExpirationStats() ExpirationStats {
+	stats := ExpirationStats{ExpiredLazily: i.expiredLazily.Load()}
+	if i.sweeper != nil {
+		stats.Cycles = i.sweeper.cycles.Load()
+		stats.ExpiredActively = i.sweeper.expired.Load()
+	}
+	return stats
+}
+
+// Close stops the background sweeper and waits for the running cycle to finish.
+// It is safe to call Close more than once.
+func (i *storage) Close() error {
+	if i.sweeper == nil {
+		return nil
+	}
+
+	i.sweeper.stopOnce.Do(func() {
+		close(i.sweeper.stop)
+	})
+	<-i.sweeper.done
+	return nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_storage_sweep(t *testing.T) {
+	tests := []struct {
+		name          string
+		expired       int
+		alive         int
+		budget        int
+		wantReclaimed int
+	}{
+		{
+			name:          "Sweep reclaims every expired key within the budget",
+			expired:       50,
+			alive:         0,
+			budget:        100,
+			wantReclaimed: 50,
+		},
+		{
+			name:          "Sweep stops when the budget is exhausted",
+			expired:       100,
+			alive:         0,
+			budget:        30,
+			wantReclaimed: 30,
+		},
+		{
+			name:          "Sweep keeps keys that are not expired",
+			expired:       0,
+			alive:         10,
+			budget:        100,
+			wantReclaimed: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities := make(map[string]domain.Entity)
+			for i := 0; i < tt.expired; i++ {
+				entities["expired"+strconv.Itoa(i)] = domain.Entity{Value: "v", Expiration: time.Now().Add(-time.Minute).UnixNano()}
+			}
+			for i := 0; i < tt.alive; i++ {
+				entities["alive"+strconv.Itoa(i)] = domain.Entity{Value: "v", Expiration: time.Now().Add(time.Minute).UnixNano()}
+			}
+			s := newTestStorage(&sync.RWMutex{}, entities)
+
+			assert.Equal(t, tt.wantReclaimed, s.sweep(tt.budget))
+			assert.Equal(t, tt.expired+tt.alive-tt.wantReclaimed, s.shards[0].storage.len())
+			assert.Len(t, s.shards[0].expires, tt.expired+tt.alive-tt.wantReclaimed)
+		})
+	}
+}
+
+func Test_storage_Sweeper(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, make(map[string]domain.Entity))
+	s.startSweeper(time.Millisecond, 100)
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Set("key"+strconv.Itoa(i), "value", time.Millisecond))
+	}
+	assert.NoError(t, s.Set("persistent", "value", 0))
+
+	assert.Eventually(t, func() bool {
+		s.shards[0].mu.RLock()
+		defer s.shards[0].mu.RUnlock()
+		return s.shards[0].storage.len() == 1
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+
+	stats := s.ExpirationStats()
+	assert.Equal(t, uint64(10), stats.ExpiredActively)
+	assert.NotZero(t, stats.Cycles)
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// removed is returned by an update function to delete the key, an expired entity deletes it, see Update.
+var removed = domain.Entity{Expiration: 1}
+
+// HSet sets the fields of the hash, creating the key if needed, and returns the number of the new fields.
+// The hash is copied on every write, because readers use the stored one without holding the lock.
+func (i *storage) HSet(key string, fields map[string]string) (int, error) {
+	var added int
+	_, err := i.updateHash(key, func(hash map[string]string) (map[string]string, error) {
+		updated := copyHash(hash, len(fields))
+		added = 0
+		for field, value := range fields {
+			if _, ok := updated[field]; !ok {
+				added++
+			}
+			updated[field] = value
+		}
+		return updated, nil
+	})
+	return added, err
+}
+
+// HGet gets the value of a field of the hash.
+func (i *storage) HGet(key, field string) (string, error) {
+	hash, err := i.hash(key)
+	if err != nil {
+		return "", err
+	}
+	value, ok := hash[field]
+	if !ok {
+		return "", domain.ErrFieldNotFound
+	}
+	return value, nil
+}
+
+// HDel deletes the fields of the hash and returns the number of the deleted ones.
+// Deleting the last field deletes the key.
+func (i *storage) HDel(key string, fields ...string) (int, error) {
+	var deleted int
+	_, err := i.updateHash(key, func(hash map[string]string) (map[string]string, error) {
+		updated := copyHash(hash, 0)
+		deleted = 0
+		for _, field := range fields {
+			if _, ok := updated[field]; ok {
+				delete(updated, field)
+				deleted++
+			}
+		}
+		return updated, nil
+	})
+	return deleted, err
+}
+
+// HGetAll gets a copy of all the fields of the hash.
+func (i *storage) HGetAll(key string) (map[string]string, error) {
+	hash, err := i.hash(key)
+	if err != nil {
+		return nil, err
+	}
+	return copyHash(hash, 0), nil
+}
+
+// HLen returns the number of the fields of the hash.
+func (i *storage) HLen(key string) (int, error) {
+	hash, err := i.hash(key)
+	return len(hash), err
+}
+
+// HIncrBy adds delta to the integer value of a field of the hash and returns the new value.
+func (i *storage) HIncrBy(key, field string, delta int64) (int64, error) {
+	var result int64
+	_, err := i.updateHash(key, func(hash map[string]string) (map[string]string, error) {
+		var n int64
+		if value, ok := hash[field]; ok {
+			var err error
+			if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return nil, domain.ErrNotInteger
+			}
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return nil, domain.ErrOverflow
+		}
+
+		result = n + delta
+		updated := copyHash(hash, 1)
+		updated[field] = strconv.FormatInt(result, 10)
+		return updated, nil
+	})
+	return result, err
+}
+
+// hash returns the stored hash of the key, it must not be modified.
+func (i *storage) hash(key string) (map[string]string, error) {
+	entity, err := i.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Type != domain.TypeHash {
+		return nil, domain.ErrWrongType
+	}
+	return entity.Hash, nil
+}
+
+// updateHash replaces the hash of the key with the one computed by fn, a missing key is an empty hash.
+// An empty result deletes the key. fn must not modify the hash it gets, it may be called more than once.
+func (i *storage) updateHash(key string, fn func(hash map[string]string) (map[string]string, error)) (domain.Entity, error) {
+	return i.Update(key, func(current domain.Entity, exists bool) (domain.Entity, error) {
+		if exists && current.Type != domain.TypeHash {
+			return domain.Entity{}, domain.ErrWrongType
+		}
+		hash, err := fn(current.Hash)
+		if err != nil {
+			return domain.Entity{}, err
+		}
+		if len(hash) == 0 {
+			return removed, nil
+		}
+
+		current.Type = domain.TypeHash
+		current.Hash = hash
+		return current, nil
+	})
+}
+
+// copyHash returns a copy of the hash with room for extra more fields.
+func copyHash(hash map[string]string, extra int) map[string]string {
+	dup := make(map[string]string, len(hash)+extra)
+	for field, value := range hash {
+		dup[field] = value
+	}
+	return dup
+}
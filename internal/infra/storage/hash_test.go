@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_Hash(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	var changes []domain.Change
+	i.OnChange(func(c domain.Change) { changes = append(changes, c) })
+
+	added, err := i.HSet("user:1", map[string]string{"name": "Ann", "age": "30"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+	added, err = i.HSet("user:1", map[string]string{"name": "Bob", "city": "Oslo"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added, "only the new fields are counted")
+
+	value, err := i.HGet("user:1", "name")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bob", value)
+	_, err = i.HGet("user:1", "missing")
+	assert.ErrorIs(t, err, domain.ErrFieldNotFound)
+	_, err = i.HGet("missing", "name")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+
+	all, err := i.HGetAll("user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "Bob", "age": "30", "city": "Oslo"}, all)
+	all["name"] = "changed"
+	value, _ = i.HGet("user:1", "name")
+	assert.Equal(t, "Bob", value, "HGetAll returns a copy")
+
+	n, err := i.HLen("user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	deleted, err := i.HDel("user:1", "age", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	deleted, err = i.HDel("user:1", "name", "city")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	_, err = i.HLen("user:1")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "deleting the last field deletes the key")
+	deleted, err = i.HDel("user:1", "name")
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	var types []domain.ChangeType
+	for _, c := range changes {
+		types = append(types, c.Type)
+	}
+	assert.Equal(t, []domain.ChangeType{domain.ChangeSet, domain.ChangeSet, domain.ChangeSet, domain.ChangeDelete}, types)
+	assert.Equal(t, domain.TypeHash, changes[0].Entity.Type)
+}
+
+func Test_storage_HIncrBy(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	_, err := i.HSet("counters", map[string]string{"text": "abc", "max": "9223372036854775807"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		field   string
+		delta   int64
+		want    int64
+		wantErr error
+	}{
+		{name: "a missing field counts as 0", field: "hits", delta: 5, want: 5},
+		{name: "the value is incremented", field: "hits", delta: 2, want: 7},
+		{name: "a negative delta decrements", field: "hits", delta: -10, want: -3},
+		{name: "a non-integer value is refused", field: "text", delta: 1, wantErr: domain.ErrNotInteger},
+		{name: "an overflow is refused", field: "max", delta: 1, wantErr: domain.ErrOverflow},
+		{name: "an underflow is refused", field: "hits", delta: math.MinInt64, wantErr: domain.ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := i.HIncrBy("counters", tt.field, tt.delta)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_storage_HashWrongType(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	require.NoError(t, i.Set("string", "value", 0))
+	_, err := i.HSet("hash", map[string]string{"field": "value"})
+	require.NoError(t, err)
+
+	_, err = i.HSet("string", map[string]string{"field": "value"})
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.HGet("string", "field")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.HDel("string", "field")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.HIncrBy("string", "field", 1)
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+
+	_, err = i.Get("hash")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.GetEntity("hash")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+
+	require.NoError(t, i.Set("hash", "value", 0), "Set replaces a key of any type")
+	value, err := i.Get("hash")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func Test_storage_HashKeepsExpiration(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	_, err := i.HSet("hash", map[string]string{"a": "1"})
+	require.NoError(t, err)
+	require.NoError(t, i.Expire("hash", time.Hour))
+
+	_, err = i.HSet("hash", map[string]string{"b": "2"})
+	require.NoError(t, err)
+	ttl, err := i.TTL("hash")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+}
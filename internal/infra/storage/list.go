@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// LPush inserts the values at the head of the list one after another and returns the length of the list.
+// The list is copied on every push, because readers use the stored one without holding the lock.
+func (i *storage) LPush(key string, values ...string) (int, error) {
+	return i.push(key, func(list []string) []string {
+		updated := make([]string, 0, len(values)+len(list))
+		for idx := len(values) - 1; idx >= 0; idx-- {
+			updated = append(updated, values[idx])
+		}
+		return append(updated, list...)
+	})
+}
+
+// RPush appends the values at the tail of the list and returns the length of the list.
+func (i *storage) RPush(key string, values ...string) (int, error) {
+	return i.push(key, func(list []string) []string {
+		updated := make([]string, 0, len(list)+len(values))
+		updated = append(updated, list...)
+		return append(updated, values...)
+	})
+}
+
+// LPop removes and returns the head of the list.
+func (i *storage) LPop(key string) (string, error) {
+	return i.pop(key, true)
+}
+
+// RPop removes and returns the tail of the list.
+func (i *storage) RPop(key string) (string, error) {
+	return i.pop(key, false)
+}
+
+// LRange returns a copy of the values from start to stop, both inclusive.
+func (i *storage) LRange(key string, start, stop int) ([]string, error) {
+	list, err := i.list(key)
+	if err != nil {
+		return nil, err
+	}
+	start, stop = listRange(len(list), start, stop)
+	values := make([]string, stop-start)
+	copy(values, list[start:stop])
+	return values, nil
+}
+
+// LLen returns the length of the list.
+func (i *storage) LLen(key string) (int, error) {
+	list, err := i.list(key)
+	return len(list), err
+}
+
+// LTrim keeps only the values from start to stop, both inclusive, an empty range deletes the key.
+func (i *storage) LTrim(key string, start, stop int) error {
+	_, err := i.updateList(key, func(list []string) ([]string, error) {
+		from, to := listRange(len(list), start, stop)
+		return list[from:to], nil
+	})
+	return err
+}
+
+// LIndex returns the value at the index.
+func (i *storage) LIndex(key string, index int) (string, error) {
+	list, err := i.list(key)
+	if err != nil {
+		return "", err
+	}
+	if index < 0 {
+		index += len(list)
+	}
+	if index < 0 || index >= len(list) {
+		return "", domain.ErrIndexOutOfRange
+	}
+	return list[index], nil
+}
+
+// BLPop pops the head of the first of the keys holding a list, waiting for a push when all of them are empty.
+func (i *storage) BLPop(ctx context.Context, keys []string, timeout time.Duration) (string, string, error) {
+	return i.blockingPop(ctx, keys, timeout, true)
+}
+
+// BRPop pops the tail of the first of the keys holding a list, waiting for a push when all of them are empty.
+func (i *storage) BRPop(ctx context.Context, keys []string, timeout time.Duration) (string, string, error) {
+	return i.blockingPop(ctx, keys, timeout, false)
+}
+
+// push replaces the list of the key with the one computed by fn and wakes up the pops waiting for the key.
+func (i *storage) push(key string, fn func(list []string) []string) (int, error) {
+	entity, err := i.updateList(key, func(list []string) ([]string, error) {
+		return fn(list), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	i.waiters.wake(key)
+	return len(entity.List), nil
+}
+
+// pop removes and returns the head or the tail of the list.
+// The stored list is resliced, never modified, so the readers holding it are not affected.
+func (i *storage) pop(key string, head bool) (string, error) {
+	var value string
+	_, err := i.updateList(key, func(list []string) ([]string, error) {
+		if len(list) == 0 {
+			return nil, domain.ErrKeyNotFound
+		}
+		if head {
+			value = list[0]
+			return list[1:], nil
+		}
+		value = list[len(list)-1]
+		return list[:len(list)-1], nil
+	})
+	return value, err
+}
+
+// blockingPop pops from the first of the keys holding a list.
+// The waiter is registered before the keys are checked, so a push landing in between is not missed.
+func (i *storage) blockingPop(ctx context.Context, keys []string, timeout time.Duration, head bool) (string, string, error) {
+	wake := i.waiters.add(keys)
+	defer i.waiters.remove(keys, wake)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		for _, key := range keys {
+			value, err := i.pop(key, head)
+			switch {
+			case err == nil:
+				return key, value, nil
+			case isMissing(err):
+			default:
+				return "", "", err
+			}
+		}
+
+		select {
+		case <-wake:
+		case <-expired:
+			return "", "", domain.ErrTimeout
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		}
+	}
+}
+
+// list returns the stored list of the key, it must not be modified.
+func (i *storage) list(key string) ([]string, error) {
+	entity, err := i.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Type != domain.TypeList {
+		return nil, domain.ErrWrongType
+	}
+	return entity.List, nil
+}
+
+// updateList replaces the list of the key with the one computed by fn, a missing key is an empty list.
+// An empty result deletes the key. fn must not modify the list it gets, it may be called more than once.
+func (i *storage) updateList(key string, fn func(list []string) ([]string, error)) (domain.Entity, error) {
+	return i.Update(key, func(current domain.Entity, exists bool) (domain.Entity, error) {
+		if exists && current.Type != domain.TypeList {
+			return domain.Entity{}, domain.ErrWrongType
+		}
+		list, err := fn(current.List)
+		if err != nil {
+			return domain.Entity{}, err
+		}
+		if len(list) == 0 {
+			return removed, nil
+		}
+
+		current.Type = domain.TypeList
+		current.List = list
+		return current, nil
+	})
+}
+
+// listRange converts the inclusive start and stop indexes, negative ones counting from the tail,
+// to the bounds of a slice of a list of length n.
+func listRange(n, start, stop int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return 0, 0
+	}
+	return start, stop + 1
+}
+
+// waiters tracks the blocking pops waiting for a push to a key.
+type waiters struct {
+	mu   sync.Mutex
+	keys map[string]map[chan struct{}]struct{}
+}
+
+func newWaiters() *waiters {
+	return &waiters{keys: make(map[string]map[chan struct{}]struct{})}
+}
+
+// add registers a waiter for the keys and returns the channel signaled on a push to one of them.
+func (w *waiters) add(keys []string) chan struct{} {
+	wake := make(chan struct{}, 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		set, ok := w.keys[key]
+		if !ok {
+			set = make(map[chan struct{}]struct{})
+			w.keys[key] = set
+		}
+		set[wake] = struct{}{}
+	}
+	return wake
+}
+
+// remove unregisters the waiter from the keys.
+func (w *waiters) remove(keys []string, wake chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		delete(w.keys[key], wake)
+		if len(w.keys[key]) == 0 {
+			delete(w.keys, key)
+		}
+	}
+}
+
+// wake signals all the waiters of the key. A waiter already signaled is not blocked on,
+// it checks all its keys when it wakes up anyway. The waiters that find the list empty wait again.
+func (w *waiters) wake(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for wake := range w.keys[key] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_List(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+
+	n, err := i.RPush("queue", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = i.LPush("queue", "a", "z")
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	all, err := i.LRange("queue", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"z", "a", "b", "c"}, all, "LPush inserts the values one after another")
+
+	n, err = i.LLen("queue")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	value, err := i.LIndex("queue", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", value)
+	_, err = i.LIndex("queue", 4)
+	assert.ErrorIs(t, err, domain.ErrIndexOutOfRange)
+
+	value, err = i.LPop("queue")
+	assert.NoError(t, err)
+	assert.Equal(t, "z", value)
+	value, err = i.RPop("queue")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", value)
+	assert.Equal(t, []string{"z", "a", "b", "c"}, all, "a popped list does not affect the returned copies")
+
+	require.NoError(t, i.LTrim("queue", 1, 1))
+	all, err = i.LRange("queue", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, all)
+
+	value, err = i.LPop("queue")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", value)
+	_, err = i.LLen("queue")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "popping the last value deletes the key")
+	_, err = i.LPop("queue")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+	assert.NoError(t, i.LTrim("queue", 0, 1), "trimming a missing key is not an error")
+}
+
+func Test_storage_LRange(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	_, err := i.RPush("list", "a", "b", "c", "d")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		start, stop int
+		want        []string
+	}{
+		{name: "all the values", start: 0, stop: -1, want: []string{"a", "b", "c", "d"}},
+		{name: "negative indexes count from the tail", start: -3, stop: -2, want: []string{"b", "c"}},
+		{name: "stop past the tail is the tail", start: 2, stop: 100, want: []string{"c", "d"}},
+		{name: "start before the head is the head", start: -100, stop: 0, want: []string{"a"}},
+		{name: "start past the tail is empty", start: 4, stop: 10, want: []string{}},
+		{name: "start after stop is empty", start: 2, stop: 1, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := i.LRange("list", tt.start, tt.stop)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_storage_ListWrongType(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	require.NoError(t, i.Set("string", "value", 0))
+	_, err := i.RPush("list", "a")
+	require.NoError(t, err)
+
+	_, err = i.LPush("string", "a")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.LPop("string")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.LRange("string", 0, -1)
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, _, err = i.BLPop(context.Background(), []string{"string"}, time.Second)
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.Get("list")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+}
+
+func Test_storage_BLPop(t *testing.T) {
+	t.Run("returns the first non-empty list right away", func(t *testing.T) {
+		i := NewInMemory()
+		defer i.Close()
+		_, err := i.RPush("second", "a", "b")
+		require.NoError(t, err)
+
+		key, value, err := i.BRPop(context.Background(), []string{"first", "second"}, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", key)
+		assert.Equal(t, "b", value)
+	})
+
+	t.Run("is woken by a push", func(t *testing.T) {
+		i := NewInMemory()
+		defer i.Close()
+
+		type popped struct {
+			key, value string
+			err        error
+		}
+		result := make(chan popped)
+		go func() {
+			key, value, err := i.BLPop(context.Background(), []string{"jobs"}, 0)
+			result <- popped{key, value, err}
+		}()
+
+		// Wait for the pop to block
+		assert.Eventually(t, func() bool {
+			i.waiters.mu.Lock()
+			defer i.waiters.mu.Unlock()
+			return len(i.waiters.keys["jobs"]) == 1
+		}, time.Second, time.Millisecond)
+		_, err := i.RPush("jobs", "job1")
+		require.NoError(t, err)
+
+		select {
+		case got := <-result:
+			assert.Equal(t, popped{"jobs", "job1", nil}, got)
+		case <-time.After(time.Second):
+			t.Fatal("the pop was not woken")
+		}
+		_, err = i.LLen("jobs")
+		assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+		assert.Empty(t, i.waiters.keys, "the waiter is removed")
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		i := NewInMemory()
+		defer i.Close()
+
+		start := time.Now()
+		_, _, err := i.BLPop(context.Background(), []string{"jobs"}, 20*time.Millisecond)
+		assert.ErrorIs(t, err, domain.ErrTimeout)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("returns when the context is canceled", func(t *testing.T) {
+		i := NewInMemory()
+		defer i.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, _, err := i.BLPop(ctx, []string{"jobs"}, 0)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -9,96 +9,253 @@
 package storage
 
 import (
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/gynshu-one/in-memory-storage/internal/config"
 	"github.com/gynshu-one/in-memory-storage/internal/domain"
 )
 
 // storage represents the in-memory storage.
+// The keyspace is hash-partitioned into shards, each guarded by its own lock.
 type storage struct {
-	mu      *sync.RWMutex
-	storage map[string]domain.Entity
+	shards []*shard
+	usage  *usage
+	limits limits
+	// evicted counts keys removed by the eviction policy.
+	evicted atomic.Uint64
+
+	sweeper *sweeper
+	// sweepCursor is the shard the next sweep cycle starts from.
+	sweepCursor int
+	// expiredLazily counts expired keys removed on access.
+	expiredLazily atomic.Uint64
+
+	listeners []domain.ChangeListener
+
+	// version is the last version assigned to a write.
+	version atomic.Uint64
+
+	// waiters are the blocking pops waiting for a push.
+	waiters *waiters
+
+	// clock is the time of the expiration, shared with the shards.
+	clock clock
 }
 
 // NewInMemory creates a new instance of storage.
 // It returns a pointer to the newly created instance.
+// All the keys share a single lock.
+// The background expiration sweeper is started according to the config, call Close to stop it.
 func NewInMemory() *storage {
-	return &storage{
-		mu:      &sync.RWMutex{},
-		storage: make(map[string]domain.Entity),
+	return NewSharded(1)
+}
+
+// NewSharded creates a new instance of storage partitioned into n shards, each with its own lock.
+// Operations on keys in different shards do not contend with each other.
+// n less than 1 is treated as 1.
+// The memory limits and the eviction policy are taken from the config.
+// The background expiration sweeper is started according to the config, call Close to stop it.
+func NewSharded(n int) *storage {
+	if n < 1 {
+		n = 1
+	}
+
+	s := &storage{
+		shards:  make([]*shard, n),
+		usage:   &usage{},
+		waiters: newWaiters(),
+		limits: limits{
+			maxMemory: config.GetConf().MaxMemory,
+			maxKeys:   config.GetConf().MaxKeys,
+			policy:    EvictionPolicy(config.GetConf().EvictionPolicy),
+		},
 	}
+	for idx := range s.shards {
+		s.shards[idx] = newShard(s.usage, s.notify)
+	}
+	s.startSweeper(config.GetConf().SweepInterval, config.GetConf().SweepBudget)
+	return s
 }
 
-// Set adds a new key-value pair to the storage or replaces it if it already exists.
-// If the key already exists, it returns an error.
+// Set adds a new key-value pair to the storage or replaces it if it already exists, whatever its type.
 // If the ttl is 0, the key-value pair will not expire.
+// If the memory limit is reached and the eviction policy can not free space, it returns domain.ErrOutOfMemory.
 func (i *storage) Set(key string, value string, ttl time.Duration) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
+	_, err := i.SetWith(key, value, ttl, domain.SetOptions{})
+	return err
+}
 
-	exp := time.Now().Add(ttl).UnixNano()
+// SetWith is Set restricted by the options, it returns the new version of the key.
+// An expired key counts as absent.
+// If the mode does not allow the write, it returns domain.ErrConditionNotMet.
+// The mode is checked before reserving the memory, so a write that is not allowed does not evict keys,
+// and again under the lock, as the key may have changed meanwhile.
+func (i *storage) SetWith(key string, value string, ttl time.Duration, opts domain.SetOptions) (uint64, error) {
+	sh := i.shardFor(key)
+	if opts.Mode != domain.SetAlways {
+		sh.mu.RLock()
+		allowed := setAllowed(sh, key, opts)
+		sh.mu.RUnlock()
+		if !allowed {
+			return 0, domain.ErrConditionNotMet
+		}
+	}
+	if err := i.reserve(sh, key, recordSize(key, value)+int64(len(opts.ContentType))); err != nil {
+		return 0, err
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if !setAllowed(sh, key, opts) {
+		return 0, domain.ErrConditionNotMet
+	}
+
+	exp := i.clock.now().Add(ttl).UnixNano()
 
 	if ttl == 0 {
 		exp = 0
 	}
 
-	i.storage[key] = domain.Entity{
-		Key:        key,
-		Value:      value,
-		Expiration: exp,
+	entity := domain.Entity{
+		Key:         key,
+		Value:       value,
+		Expiration:  exp,
+		ContentType: opts.ContentType,
+		Version:     i.nextVersion(),
+	}
+	if opts.Sliding && ttl > 0 {
+		entity.Sliding, entity.SlidingTTL = true, ttl
 	}
+	sh.put(entity)
+	sh.emit(domain.ChangeSet, key, entity)
 
-	return nil
+	return entity.Version, nil
+}
+
+// setAllowed reports whether the mode of the options allows writing the key.
+// The caller must hold the lock of the shard.
+func setAllowed(sh *shard, key string, opts domain.SetOptions) bool {
+	rec, exists := sh.live(key)
+	switch opts.Mode {
+	case domain.SetIfAbsent:
+		return !exists
+	case domain.SetIfPresent:
+		return exists
+	case domain.SetIfVersion:
+		return exists && rec.entity.Version == opts.Version
+	default:
+		return true
+	}
 }
 
 // Delete deletes a key from the storage.
 func (i *storage) Delete(key string) error {
-	i.mu.Lock()
-	defer i.mu.Unlock()
+	sh := i.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
 
-	if entity, ok := i.storage[key]; !ok {
-		if entity.IsExpired() {
-			delete(i.storage, key)
-			return domain.ErrKeyExpired
-		}
+	rec, ok := sh.storage.get(key)
+	if !ok {
 		return domain.ErrKeyNotFound
 	}
 
-	delete(i.storage, key)
+	sh.remove(key)
+	if rec.entity.ExpiredAt(sh.clock.now()) {
+		i.expiredLazily.Add(1)
+		sh.emit(domain.ChangeExpire, key, domain.Entity{})
+		return domain.ErrKeyExpired
+	}
+	sh.emit(domain.ChangeDelete, key, domain.Entity{})
 
 	return nil
 }
 
 // Get gets the value of a key from the storage.
 func (i *storage) Get(key string) (string, error) {
-	i.mu.RLock()
-	defer i.mu.RUnlock()
+	entity, err := i.GetEntity(key)
+	return entity.Value, err
+}
 
-	if entity, ok := i.storage[key]; ok {
-		if entity.IsExpired() {
-			delete(i.storage, key)
-			return "", domain.ErrKeyExpired
-		}
-		return entity.Value, nil
+// GetEntity gets the stored entity of a key, including its expiration, flags and version.
+// It returns domain.ErrWrongType when the key does not hold a string.
+// The read of a sliding key pushes its expiration, see slide.
+func (i *storage) GetEntity(key string) (domain.Entity, error) {
+	entity, err := i.lookup(key)
+	if err != nil {
+		return domain.Entity{}, err
+	}
+	if entity.Type != domain.TypeString {
+		return domain.Entity{}, domain.ErrWrongType
+	}
+	if entity.Sliding {
+		entity = i.slide(key, entity)
+	}
+	return entity, nil
+}
+
+// slide pushes the expiration of the sliding entity read from the key to SlidingTTL from now
+// and returns the stored entity. The version is kept, as the value did not change,
+// and a ChangeTouch is emitted, so a restarted storage does not expire an active key.
+// If the key was written since it was read, the entity is returned as read.
+func (i *storage) slide(key string, read domain.Entity) domain.Entity {
+	sh := i.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	rec, ok := sh.live(key)
+	if !ok || rec.entity.Version != read.Version || !rec.entity.Sliding {
+		return read
+	}
+	entity := rec.entity
+	entity.Expiration = sh.clock.now().Add(entity.SlidingTTL).UnixNano()
+	sh.replace(rec, entity)
+	sh.emit(domain.ChangeTouch, key, domain.Entity{Key: key, Expiration: entity.Expiration})
+	return entity
+}
+
+// lookup gets the stored entity of a key of any type, evicting it lazily if it has expired.
+func (i *storage) lookup(key string) (domain.Entity, error) {
+	sh := i.shardFor(key)
+	sh.mu.RLock()
+	rec, ok := sh.storage.get(key)
+	if ok {
+		rec.touch()
 	}
+	sh.mu.RUnlock()
 
-	return "", domain.ErrKeyNotFound
+	if !ok {
+		return domain.Entity{}, domain.ErrKeyNotFound
+	}
+	if rec.entity.ExpiredAt(sh.clock.now()) {
+		i.expire(sh, key)
+		return domain.Entity{}, domain.ErrKeyExpired
+	}
+
+	return rec.entity, nil
 }
 
 // GetAll gets all the key-value pairs from the storage. Returns copy
+// Shards are read one after another, so the result is not a point-in-time view across shards.
 func (i *storage) GetAll() ([]domain.Entity, error) {
-	i.mu.RLock()
-	defer i.mu.RUnlock()
-
 	var result []domain.Entity
-	for key, entity := range i.storage {
-		if entity.IsExpired() {
-			delete(i.storage, key)
-			continue
-		}
+	for _, sh := range i.shards {
+		sh.mu.RLock()
+		var expired []string
+		sh.storage.each(0, func(key string, rec *record) bool {
+			if rec.entity.ExpiredAt(sh.clock.now()) {
+				expired = append(expired, key)
+			} else {
+				result = append(result, rec.entity)
+			}
+			return true
+		})
+		sh.mu.RUnlock()
 
-		result = append(result, entity)
+		for _, key := range expired {
+			i.expire(sh, key)
+		}
 	}
 
 	if len(result) == 0 {
@@ -106,3 +263,126 @@ func (i *storage) GetAll() ([]domain.Entity, error) {
 	}
 	return result, nil
 }
+
+// Snapshot returns a point-in-time copy of all the entities that have not expired.
+// The read locks of all the shards are held while copying, so writes wait until the copy is done.
+func (i *storage) Snapshot() []domain.Entity {
+	for _, sh := range i.shards {
+		sh.mu.RLock()
+	}
+	defer func() {
+		for _, sh := range i.shards {
+			sh.mu.RUnlock()
+		}
+	}()
+
+	var size int64
+	for _, sh := range i.shards {
+		size += int64(sh.storage.len())
+	}
+
+	result := make([]domain.Entity, 0, size)
+	for _, sh := range i.shards {
+		sh.storage.each(0, func(_ string, rec *record) bool {
+			if !rec.entity.ExpiredAt(sh.clock.now()) {
+				result = append(result, rec.entity)
+			}
+			return true
+		})
+	}
+	return result
+}
+
+// expire removes the key from the shard if it is still expired.
+// Readers only hold the read lock, so they call it to evict lazily.
+func (i *storage) expire(sh *shard, key string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if rec, ok := sh.storage.get(key); ok && rec.entity.ExpiredAt(sh.clock.now()) {
+		sh.remove(key)
+		i.expiredLazily.Add(1)
+		sh.emit(domain.ChangeExpire, key, domain.Entity{})
+	}
+}
+
+// Restore stores the entity as is, keeping its absolute expiration and version.
+// An expired entity removes the key instead. A sorted set is turned into the structure of the storage.
+// It is used to load persisted data, so the memory limits are not applied.
+func (i *storage) Restore(entity domain.Entity) error {
+	sh := i.shardFor(entity.Key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if entity.ExpiredAt(sh.clock.now()) {
+		if _, ok := sh.storage.get(entity.Key); ok {
+			sh.remove(entity.Key)
+			sh.emit(domain.ChangeExpire, entity.Key, domain.Entity{})
+		}
+		return nil
+	}
+
+	if entity.Version == 0 {
+		entity.Version = i.nextVersion()
+	} else {
+		i.observeVersion(entity.Version)
+	}
+	if entity.ZSet != nil {
+		entity.ZSet = toSortedSet(entity.ZSet)
+	}
+	sh.put(entity)
+	sh.emit(domain.ChangeSet, entity.Key, entity)
+	return nil
+}
+
+// RestoreExpiration sets the absolute expiration of an existing key as is, keeping its value, version and sliding,
+// so the replayed touches of a sliding key push it like the reads did. An expiration in the past removes the key.
+// If the key does not exist or has expired, it returns domain.ErrKeyNotFound.
+func (i *storage) RestoreExpiration(key string, expiration int64) error {
+	sh := i.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	rec, ok := sh.live(key)
+	if !ok {
+		return domain.ErrKeyNotFound
+	}
+	entity := rec.entity
+	entity.Expiration = expiration
+	if entity.ExpiredAt(sh.clock.now()) {
+		sh.remove(key)
+		sh.emit(domain.ChangeExpire, key, domain.Entity{})
+		return nil
+	}
+	sh.replace(rec, entity)
+	sh.emit(domain.ChangeTouch, key, domain.Entity{Key: key, Expiration: expiration})
+	return nil
+}
+
+// nextVersion returns a new version for a write.
+func (i *storage) nextVersion() uint64 {
+	return i.version.Add(1)
+}
+
+// observeVersion makes sure the versions assigned later are greater than the restored one.
+func (i *storage) observeVersion(v uint64) {
+	for {
+		last := i.version.Load()
+		if last >= v || i.version.CompareAndSwap(last, v) {
+			return
+		}
+	}
+}
+
+// OnChange registers a listener called for every change of the storage, see domain.ChangeListener.
+// Listeners must be registered before the storage is used concurrently.
+func (i *storage) OnChange(listener domain.ChangeListener) {
+	i.listeners = append(i.listeners, listener)
+}
+
+// notify passes the change to every registered listener.
+func (i *storage) notify(c domain.Change) {
+	for _, listener := range i.listeners {
+		listener(c)
+	}
+}
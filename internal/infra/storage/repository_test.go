@@ -3,12 +3,46 @@ package storage
 import (
 	"github.com/gynshu-one/in-memory-storage/internal/domain"
 	"github.com/stretchr/testify/assert"
-	"reflect"
+	"github.com/stretchr/testify/require"
 	"sync"
 	"testing"
 	"time"
 )
 
+// newTestStorage returns a single shard storage without the background sweeper holding the given entities.
+func newTestStorage(mu *sync.RWMutex, entities map[string]domain.Entity) *storage {
+	s := &storage{usage: &usage{}}
+	sh := newShard(s.usage, s.notify)
+	sh.mu = mu
+	for key, entity := range entities {
+		entity.Key = key
+		sh.put(entity)
+	}
+	s.shards = []*shard{sh}
+	return s
+}
+
+// fakeClock is a clock moved forward by the tests.
+type fakeClock struct {
+	at time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.at
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.at = c.at.Add(d)
+}
+
+// useClock makes the storage and its shards tell the time from the fake clock.
+func useClock(s *storage, c *fakeClock) {
+	s.clock = c.now
+	for _, sh := range s.shards {
+		sh.clock = c.now
+	}
+}
+
 func TestNewInMemory(t *testing.T) {
 	tests := []struct {
 		name string
@@ -17,16 +51,19 @@ func TestNewInMemory(t *testing.T) {
 		{
 			name: "NewInMemory returns a new instance of storage",
 			want: &storage{
-				mu:      &sync.RWMutex{},
-				storage: make(map[string]domain.Entity),
+				shards: []*shard{newShard(&usage{}, nil)},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewInMemory(); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewInMemory() = %v, want %v", got, tt.want)
-			}
+			got := NewInMemory()
+			defer got.Close()
+
+			assert.NotNil(t, got.sweeper)
+			assert.Len(t, got.shards, len(tt.want.shards))
+			assert.Equal(t, tt.want.shards[0].storage, got.shards[0].storage)
+			assert.Equal(t, tt.want.shards[0].expires, got.shards[0].expires)
 		})
 	}
 }
@@ -61,6 +98,22 @@ func Test_storage_Delete(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Delete a key that has expired",
+			fields: fields{
+				mu: &sync.RWMutex{},
+				storage: map[string]domain.Entity{
+					"key1": {
+						Value:      "value1",
+						Expiration: time.Now().Add(-time.Minute).UnixNano(),
+					},
+				},
+			},
+			args: args{
+				key: "key1",
+			},
+			wantErr: true,
+		},
 		{
 			name: "Delete a key that does not exist in the storage",
 			fields: fields{
@@ -75,10 +128,7 @@ func Test_storage_Delete(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &storage{
-				mu:      tt.fields.mu,
-				storage: tt.fields.storage,
-			}
+			s := newTestStorage(tt.fields.mu, tt.fields.storage)
 			if err := s.Delete(tt.args.key); (err != nil) != tt.wantErr {
 				t.Errorf("storage.Delete() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -150,10 +200,7 @@ func Test_storage_Get(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &storage{
-				mu:      tt.fields.mu,
-				storage: tt.fields.storage,
-			}
+			s := newTestStorage(tt.fields.mu, tt.fields.storage)
 			got, err := s.Get(tt.args.key)
 			if !assert.Equal(t, tt.wantErr, err) {
 				t.Errorf("storage.Get() error = %v, wantErr %v", err, tt.wantErr)
@@ -171,10 +218,11 @@ func Test_storage_GetAll(t *testing.T) {
 		mu      *sync.RWMutex
 		storage map[string]domain.Entity
 	}
+	expiration := time.Now().Add(time.Minute).UnixNano()
 	tests := []struct {
 		name    string
 		fields  fields
-		want    map[string]string
+		want    []domain.Entity
 		wantErr bool
 	}{
 		{
@@ -183,18 +231,42 @@ func Test_storage_GetAll(t *testing.T) {
 				mu: &sync.RWMutex{},
 				storage: map[string]domain.Entity{
 					"key1": {
+						Key:        "key1",
 						Value:      "value1",
-						Expiration: time.Now().Add(time.Minute).UnixNano(),
+						Expiration: expiration,
 					},
 					"key2": {
+						Key:        "key2",
 						Value:      "value2",
-						Expiration: time.Now().Add(time.Minute).UnixNano(),
+						Expiration: expiration,
+					},
+				},
+			},
+			want: []domain.Entity{
+				{Key: "key1", Value: "value1", Expiration: expiration},
+				{Key: "key2", Value: "value2", Expiration: expiration},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Get all skips expired key-value pairs",
+			fields: fields{
+				mu: &sync.RWMutex{},
+				storage: map[string]domain.Entity{
+					"key1": {
+						Key:        "key1",
+						Value:      "value1",
+						Expiration: expiration,
+					},
+					"key2": {
+						Key:        "key2",
+						Value:      "value2",
+						Expiration: time.Now().Add(-time.Minute).UnixNano(),
 					},
 				},
 			},
-			want: map[string]string{
-				"key1": "value1",
-				"key2": "value2",
+			want: []domain.Entity{
+				{Key: "key1", Value: "value1", Expiration: expiration},
 			},
 			wantErr: false,
 		},
@@ -210,18 +282,13 @@ func Test_storage_GetAll(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &storage{
-				mu:      tt.fields.mu,
-				storage: tt.fields.storage,
-			}
+			s := newTestStorage(tt.fields.mu, tt.fields.storage)
 			got, err := s.GetAll()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("storage.GetAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("storage.GetAll() = %v, want %v", got, tt.want)
-			}
+			assert.ElementsMatch(t, tt.want, got)
 		})
 	}
 }
@@ -294,13 +361,338 @@ func Test_storage_Set(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &storage{
-				mu:      tt.fields.mu,
-				storage: tt.fields.storage,
-			}
+			s := newTestStorage(tt.fields.mu, tt.fields.storage)
 			if err := s.Set(tt.args.key, tt.args.value, tt.args.expiresAt); (err != nil) != tt.wantErr {
 				t.Errorf("storage.Set() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func Test_storage_SetWith(t *testing.T) {
+	live := domain.Entity{Value: "old", Expiration: time.Now().Add(time.Minute).UnixNano(), Version: 7}
+	expired := domain.Entity{Value: "old", Expiration: time.Now().Add(-time.Minute).UnixNano(), Version: 7}
+
+	tests := []struct {
+		name      string
+		storage   map[string]domain.Entity
+		mode      domain.SetMode
+		version   uint64
+		wantErr   error
+		wantValue string
+	}{
+		{
+			name:      "SetIfAbsent writes a missing key",
+			storage:   map[string]domain.Entity{},
+			mode:      domain.SetIfAbsent,
+			wantValue: "new",
+		},
+		{
+			name:      "SetIfAbsent does not overwrite an existing key",
+			storage:   map[string]domain.Entity{"key1": live},
+			mode:      domain.SetIfAbsent,
+			wantErr:   domain.ErrConditionNotMet,
+			wantValue: "old",
+		},
+		{
+			name:      "SetIfAbsent overwrites an expired key",
+			storage:   map[string]domain.Entity{"key1": expired},
+			mode:      domain.SetIfAbsent,
+			wantValue: "new",
+		},
+		{
+			name:      "SetIfPresent overwrites an existing key",
+			storage:   map[string]domain.Entity{"key1": live},
+			mode:      domain.SetIfPresent,
+			wantValue: "new",
+		},
+		{
+			name:    "SetIfPresent does not write a missing key",
+			storage: map[string]domain.Entity{},
+			mode:    domain.SetIfPresent,
+			wantErr: domain.ErrConditionNotMet,
+		},
+		{
+			name:    "SetIfPresent does not write an expired key",
+			storage: map[string]domain.Entity{"key1": expired},
+			mode:    domain.SetIfPresent,
+			wantErr: domain.ErrConditionNotMet,
+		},
+		{
+			name:      "SetIfVersion overwrites a key with the version",
+			storage:   map[string]domain.Entity{"key1": live},
+			mode:      domain.SetIfVersion,
+			version:   7,
+			wantValue: "new",
+		},
+		{
+			name:      "SetIfVersion does not overwrite a key with another version",
+			storage:   map[string]domain.Entity{"key1": live},
+			mode:      domain.SetIfVersion,
+			version:   6,
+			wantErr:   domain.ErrConditionNotMet,
+			wantValue: "old",
+		},
+		{
+			name:    "SetIfVersion does not write an expired key",
+			storage: map[string]domain.Entity{"key1": expired},
+			mode:    domain.SetIfVersion,
+			version: 7,
+			wantErr: domain.ErrConditionNotMet,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(&sync.RWMutex{}, tt.storage)
+			s.observeVersion(7)
+			version, err := s.SetWith("key1", "new", 0, domain.SetOptions{Mode: tt.mode, Version: tt.version})
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			entity, _ := s.GetEntity("key1")
+			assert.Equal(t, tt.wantValue, entity.Value)
+			if tt.wantErr == nil {
+				assert.Greater(t, version, uint64(7), "the version increases")
+				assert.Equal(t, version, entity.Version)
+			}
+		})
+	}
+}
+
+func Test_storage_Restore(t *testing.T) {
+	expiration := time.Now().Add(time.Minute).UnixNano()
+	tests := []struct {
+		name    string
+		stored  map[string]domain.Entity
+		entity  domain.Entity
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "Restore keeps the absolute expiration and the version",
+			stored: map[string]domain.Entity{},
+			entity: domain.Entity{Key: "key1", Value: "value1", Expiration: expiration, Version: 7},
+			want:   "value1",
+		},
+		{
+			name:    "Restore of an expired entity removes the key",
+			stored:  map[string]domain.Entity{"key1": {Value: "value1"}},
+			entity:  domain.Entity{Key: "key1", Value: "value2", Expiration: time.Now().Add(-time.Minute).UnixNano()},
+			wantErr: domain.ErrKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(&sync.RWMutex{}, tt.stored)
+			assert.NoError(t, s.Restore(tt.entity))
+
+			got, err := s.Get(tt.entity.Key)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.entity, s.shards[0].storage.buckets[bucketIndex(tt.entity.Key)][tt.entity.Key].entity)
+			}
+		})
+	}
+}
+
+func Test_storage_RestoreVersions(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+
+	assert.NoError(t, s.Restore(domain.Entity{Key: "key1", Value: "value1", Version: 10}))
+	assert.NoError(t, s.Restore(domain.Entity{Key: "key2", Value: "value2"}))
+	assert.NoError(t, s.Set("key3", "value3", 0))
+
+	key2, _ := s.GetEntity("key2")
+	key3, _ := s.GetEntity("key3")
+	assert.Equal(t, uint64(11), key2.Version, "an entity without a version gets a new one")
+	assert.Equal(t, uint64(12), key3.Version, "versions keep growing after the restored ones")
+}
+
+func Test_storage_OnChange(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+		"expired": {Value: "value", Expiration: time.Now().Add(-time.Minute).UnixNano()},
+	})
+	var got []domain.Change
+	s.OnChange(func(c domain.Change) {
+		got = append(got, c)
+	})
+
+	assert.NoError(t, s.Set("key1", "value1", 0))
+	assert.NoError(t, s.Delete("key1"))
+	_, err := s.Get("expired")
+	assert.ErrorIs(t, err, domain.ErrKeyExpired)
+
+	assert.Equal(t, []domain.Change{
+		{Type: domain.ChangeSet, Key: "key1", Entity: domain.Entity{Key: "key1", Value: "value1", Version: 1}},
+		{Type: domain.ChangeDelete, Key: "key1"},
+		{Type: domain.ChangeExpire, Key: "expired"},
+	}, got)
+}
+
+func Test_storage_Snapshot(t *testing.T) {
+	expiration := time.Now().Add(time.Minute).UnixNano()
+	s := NewSharded(4)
+	defer s.Close()
+	for _, entity := range []domain.Entity{
+		{Key: "key1", Value: "value1", Version: 1},
+		{Key: "key2", Value: "value2", Expiration: expiration, Version: 2},
+		{Key: "key3", Value: "value3", Expiration: time.Now().Add(-time.Minute).UnixNano(), Version: 3},
+	} {
+		assert.NoError(t, s.Restore(entity))
+	}
+
+	assert.ElementsMatch(t, []domain.Entity{
+		{Key: "key1", Value: "value1", Version: 1},
+		{Key: "key2", Value: "value2", Expiration: expiration, Version: 2},
+	}, s.Snapshot())
+}
+
+func Test_storage_GetSliding(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+	clk := &fakeClock{at: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	useClock(s, clk)
+
+	version, err := s.SetWith("session", "token", 30*time.Minute, domain.SetOptions{Sliding: true})
+	assert.NoError(t, err)
+	_, err = s.SetWith("fixed", "value", 30*time.Minute, domain.SetOptions{})
+	assert.NoError(t, err)
+	_, err = s.SetWith("persistent", "value", 0, domain.SetOptions{Sliding: true})
+	assert.NoError(t, err)
+
+	clk.advance(20 * time.Minute)
+	entity, err := s.GetEntity("session")
+	assert.NoError(t, err)
+	assert.Equal(t, clk.at.Add(30*time.Minute).UnixNano(), entity.Expiration, "a read pushes the expiration")
+	assert.Equal(t, version, entity.Version, "a read keeps the version")
+	_, err = s.Get("fixed")
+	assert.NoError(t, err)
+
+	clk.advance(20 * time.Minute)
+	_, err = s.Get("fixed")
+	assert.ErrorIs(t, err, domain.ErrKeyExpired, "a fixed ttl expires 30 minutes after the write")
+	value, err := s.Get("session")
+	assert.NoError(t, err, "a sliding ttl expires 30 minutes after the last read")
+	assert.Equal(t, "token", value)
+
+	ttl, err := s.TTL("persistent")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.NoExpiration, ttl, "sliding is ignored without a ttl")
+
+	clk.advance(31 * time.Minute)
+	_, err = s.Get("session")
+	assert.ErrorIs(t, err, domain.ErrKeyExpired, "a sliding key expires after 30 minutes of inactivity")
+}
+
+func Test_storage_GetSlidingEmitsTouches(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+	clk := &fakeClock{at: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	useClock(s, clk)
+	version, err := s.SetWith("session", "token", 30*time.Minute, domain.SetOptions{Sliding: true})
+	require.NoError(t, err)
+	var changes []domain.Change
+	s.OnChange(func(c domain.Change) { changes = append(changes, c) })
+
+	for step := 1; step <= 3; step++ {
+		clk.advance(5 * time.Minute)
+		_, err = s.Get("session")
+		require.NoError(t, err)
+
+		require.Len(t, changes, step, "every read emits the pushed expiration")
+		assert.Equal(t, domain.Change{
+			Type:   domain.ChangeTouch,
+			Key:    "session",
+			Entity: domain.Entity{Key: "session", Expiration: clk.at.Add(30 * time.Minute).UnixNano()},
+		}, changes[step-1])
+	}
+	entity, err := s.GetEntity("session")
+	require.NoError(t, err)
+	assert.Equal(t, version, entity.Version, "a read keeps the version")
+}
+
+func Test_storage_RestoreExpiration(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+	clk := &fakeClock{at: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	useClock(s, clk)
+	version, err := s.SetWith("session", "token", 30*time.Minute, domain.SetOptions{Sliding: true})
+	require.NoError(t, err)
+	var changes []domain.Change
+	s.OnChange(func(c domain.Change) { changes = append(changes, c) })
+
+	later := clk.at.Add(time.Hour).UnixNano()
+	require.NoError(t, s.RestoreExpiration("session", later))
+	rec, ok := s.shards[0].storage.get("session")
+	require.True(t, ok)
+	entity := rec.entity
+	assert.Equal(t, later, entity.Expiration)
+	assert.Equal(t, version, entity.Version)
+	assert.True(t, entity.Sliding)
+	assert.Equal(t, domain.ChangeTouch, changes[0].Type)
+
+	assert.ErrorIs(t, s.RestoreExpiration("missing", later), domain.ErrKeyNotFound)
+
+	require.NoError(t, s.RestoreExpiration("session", clk.at.Add(-time.Minute).UnixNano()))
+	_, err = s.Get("session")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "an expiration in the past removes the key")
+}
+
+func Test_storage_GetSlidingEnds(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(s *storage) error
+	}{
+		{
+			name: "Set without sliding",
+			write: func(s *storage) error {
+				return s.Set("session", "token", 30*time.Minute)
+			},
+		},
+		{
+			name: "Expire",
+			write: func(s *storage) error {
+				return s.Expire("session", 30*time.Minute)
+			},
+		},
+		{
+			name: "GetEx with a ttl",
+			write: func(s *storage) error {
+				_, err := s.GetEx("session", 30*time.Minute)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(&sync.RWMutex{}, nil)
+			clk := &fakeClock{at: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+			useClock(s, clk)
+			_, err := s.SetWith("session", "token", time.Hour, domain.SetOptions{Sliding: true})
+			assert.NoError(t, err)
+
+			assert.NoError(t, tt.write(s))
+			clk.advance(20 * time.Minute)
+			_, err = s.Get("session")
+			assert.NoError(t, err)
+
+			clk.advance(20 * time.Minute)
+			_, err = s.Get("session")
+			assert.ErrorIs(t, err, domain.ErrKeyExpired, "an explicit ttl ends the sliding")
+		})
+	}
+}
+
+func Test_storage_SetWithContentType(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+	value := "\x89PNG\r\n\x1a\n\x00\xff"
+
+	_, err := s.SetWith("image", value, 0, domain.SetOptions{ContentType: "image/png"})
+	assert.NoError(t, err)
+	entity, err := s.GetEntity("image")
+	assert.NoError(t, err)
+	assert.Equal(t, value, entity.Value, "the value is kept byte for byte")
+	assert.Equal(t, "image/png", entity.ContentType)
+
+	assert.NoError(t, s.Set("image", "text", 0))
+	entity, err = s.GetEntity("image")
+	assert.NoError(t, err)
+	assert.Empty(t, entity.ContentType, "a write without a content type clears it")
+}
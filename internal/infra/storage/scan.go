@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/glob"
+)
+
+// scanExamined is the number of records a call of Scan examines at most for every key of count,
+// so a pattern matching few keys does not read the whole keyspace in one call, like the maxiterations of Redis.
+const scanExamined = 10
+
+// Scan returns a page of keys matching the glob pattern and the cursor to pass to the next call.
+// The cursor is the position of the next key to read across the tables of all the shards, every call returns
+// at most count keys, as the keys sharing a position are returned together, and examines at most scanExamined
+// times count keys, so the page may be short or empty before the end. The keys present for the whole scan are
+// returned exactly once. The read lock of a shard is held for a single bucket at a time.
+func (i *storage) Scan(cursor uint64, match string, count int) ([]string, uint64, error) {
+	var keys []string
+	next, err := i.scan(cursor, match, count, func(rec *record) {
+		keys = append(keys, rec.entity.Key)
+	})
+	return keys, next, err
+}
+
+// ScanEntities is Scan returning the entities of the keys, of any type.
+func (i *storage) ScanEntities(cursor uint64, match string, count int) ([]domain.Entity, uint64, error) {
+	var entities []domain.Entity
+	next, err := i.scan(cursor, match, count, func(rec *record) {
+		entities = append(entities, rec.entity)
+	})
+	return entities, next, err
+}
+
+// scan calls collect for the live records matching the pattern from the cursor on, until count records are
+// collected or scanExamined times count records are examined, and returns the cursor of the next record, 0 at
+// the end. The cursor holds the index of the shard in its high 32 bits and the position in the table in the low ones.
+func (i *storage) scan(cursor uint64, match string, count int, collect func(rec *record)) (uint64, error) {
+	end := uint64(len(i.shards)) << 32
+	if cursor >= end {
+		return 0, domain.ErrInvalidCursor
+	}
+
+	collected, examined := 0, 0
+	done := func() bool { return collected >= count || examined >= scanExamined*count }
+	for cursor < end && !done() {
+		sh := i.shards[cursor>>32]
+		// The start of the next bucket, or of the next shard after the last bucket.
+		next := cursor | (1<<24 - 1) + 1
+		sh.mu.RLock()
+		now := sh.clock.now()
+		recs := sh.storage.from(uint32(cursor))
+		for idx, p := range recs {
+			if idx > 0 && p.pos != recs[idx-1].pos && done() {
+				next = cursor&^(1<<32-1) | uint64(p.pos)
+				break
+			}
+			examined++
+			if p.rec.entity.ExpiredAt(now) || (match != "" && !glob.Match(match, p.rec.entity.Key)) {
+				continue
+			}
+			collect(p.rec)
+			collected++
+		}
+		sh.mu.RUnlock()
+		cursor = next
+	}
+	if cursor >= end {
+		return 0, nil
+	}
+	return cursor, nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_storage_Scan(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+		"user:1":  {Value: "v"},
+		"user:2":  {Value: "v"},
+		"order:1": {Value: "v"},
+		"user:3":  {Value: "v", Expiration: time.Now().Add(-time.Minute).UnixNano()},
+	})
+
+	keys, cursor, err := s.Scan(0, "user:*", 10)
+	assert.NoError(t, err)
+	assert.Zero(t, cursor)
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, keys)
+
+	keys, _, err = s.Scan(0, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	_, _, err = s.Scan(1<<32, "", 10)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+
+	entities, _, err := s.ScanEntities(0, "order:*", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Entity{{Key: "order:1", Value: "v"}}, entities)
+}
+
+func Test_storage_ScanSharded(t *testing.T) {
+	s := NewSharded(8)
+	defer s.Close()
+
+	want := make([]string, 100)
+	for idx := range want {
+		want[idx] = fmt.Sprintf("key%d", idx)
+		assert.NoError(t, s.Set(want[idx], "v", 0))
+	}
+
+	var got []string
+	var cursor uint64
+	pages := 0
+	for {
+		keys, next, err := s.Scan(cursor, "", 5)
+		assert.NoError(t, err)
+		got = append(got, keys...)
+		pages++
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Greater(t, pages, 1)
+	assert.ElementsMatch(t, want, got, "every key is returned exactly once")
+}
+
+func Test_storage_ScanCount(t *testing.T) {
+	s := NewInMemory()
+	defer s.Close()
+
+	const keys = 100000
+	for idx := 0; idx < keys; idx++ {
+		assert.NoError(t, s.Set(fmt.Sprintf("key%d", idx), "v", 0))
+	}
+
+	seen := make(map[string]struct{}, keys)
+	var cursor uint64
+	for {
+		page, next, err := s.Scan(cursor, "", 10)
+		assert.NoError(t, err)
+		// The keys sharing a position are returned together, a collision of two may exceed the count by one.
+		assert.LessOrEqual(t, len(page), 11)
+		for _, key := range page {
+			seen[key] = struct{}{}
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	assert.Len(t, seen, keys)
+
+	page, next, err := s.Scan(0, "missing:*", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, page)
+	assert.NotZero(t, next, "a pattern matching nothing examines a bounded part of the keyspace")
+}
+
+func Test_storage_ScanSingleShardWhileWriting(t *testing.T) {
+	s := NewInMemory()
+	defer s.Close()
+
+	want := make([]string, 1000)
+	for idx := range want {
+		want[idx] = fmt.Sprintf("key%d", idx)
+		assert.NoError(t, s.Set(want[idx], "v", 0))
+	}
+
+	// Keys are added and removed between the pages, the ones present for the whole scan are returned once.
+	seen := make(map[string]int)
+	var cursor uint64
+	pages := 0
+	for {
+		keys, next, err := s.Scan(cursor, "key*", 20)
+		assert.NoError(t, err)
+		for _, key := range keys {
+			seen[key]++
+		}
+		pages++
+		assert.NoError(t, s.Set(fmt.Sprintf("key-new%d", pages), "v", 0))
+		_ = s.Delete(fmt.Sprintf("key-new%d", pages-1))
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Greater(t, pages, 10, "a single shard is scanned incrementally")
+	for _, key := range want {
+		assert.Equal(t, 1, seen[key], key)
+	}
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// SAdd adds the members to the set, creating the key if needed, and returns the number of the new members.
+// The set is copied on every write, because readers use the stored one without holding the lock.
+func (i *storage) SAdd(key string, members ...string) (int, error) {
+	var added int
+	_, err := i.updateSet(key, func(set domain.Members) (domain.Members, error) {
+		updated := copySet(set, len(members))
+		added = 0
+		for _, member := range members {
+			if _, ok := updated[member]; !ok {
+				updated[member] = struct{}{}
+				added++
+			}
+		}
+		return updated, nil
+	})
+	return added, err
+}
+
+// SRem removes the members from the set and returns the number of the removed ones.
+// Removing the last member deletes the key.
+func (i *storage) SRem(key string, members ...string) (int, error) {
+	var removedMembers int
+	_, err := i.updateSet(key, func(set domain.Members) (domain.Members, error) {
+		updated := copySet(set, 0)
+		removedMembers = 0
+		for _, member := range members {
+			if _, ok := updated[member]; ok {
+				delete(updated, member)
+				removedMembers++
+			}
+		}
+		return updated, nil
+	})
+	return removedMembers, err
+}
+
+// SMembers returns the members of the set in lexical order.
+func (i *storage) SMembers(key string) ([]string, error) {
+	set, err := i.set(key)
+	if err != nil {
+		return nil, err
+	}
+	return set.Sorted(), nil
+}
+
+// SIsMember reports whether the member belongs to the set.
+func (i *storage) SIsMember(key, member string) (bool, error) {
+	set, err := i.set(key)
+	if err != nil {
+		return false, err
+	}
+	_, ok := set[member]
+	return ok, nil
+}
+
+// SInter returns the members belonging to all the sets.
+// The sets are read one by one, so the result may mix the states before and after a concurrent write.
+func (i *storage) SInter(keys ...string) ([]string, error) {
+	sets, err := i.sets(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := domain.Members{}
+	for member := range sets[0] {
+		found := true
+		for _, set := range sets[1:] {
+			if _, found = set[member]; !found {
+				break
+			}
+		}
+		if found {
+			result[member] = struct{}{}
+		}
+	}
+	return result.Sorted(), nil
+}
+
+// SUnion returns the members belonging to any of the sets.
+func (i *storage) SUnion(keys ...string) ([]string, error) {
+	sets, err := i.sets(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := domain.Members{}
+	for _, set := range sets {
+		for member := range set {
+			result[member] = struct{}{}
+		}
+	}
+	return result.Sorted(), nil
+}
+
+// SDiff returns the members of the first set not belonging to any of the others.
+func (i *storage) SDiff(keys ...string) ([]string, error) {
+	sets, err := i.sets(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := domain.Members{}
+	for member := range sets[0] {
+		found := false
+		for _, set := range sets[1:] {
+			if _, found = set[member]; found {
+				break
+			}
+		}
+		if !found {
+			result[member] = struct{}{}
+		}
+	}
+	return result.Sorted(), nil
+}
+
+// set returns the stored set of the key, it must not be modified.
+func (i *storage) set(key string) (domain.Members, error) {
+	entity, err := i.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Type != domain.TypeSet {
+		return nil, domain.ErrWrongType
+	}
+	return entity.Set, nil
+}
+
+// sets returns the stored sets of the keys, a missing key being an empty set. At least one key is needed.
+func (i *storage) sets(keys []string) ([]domain.Members, error) {
+	if len(keys) == 0 {
+		return []domain.Members{nil}, nil
+	}
+	sets := make([]domain.Members, len(keys))
+	for idx, key := range keys {
+		set, err := i.set(key)
+		if err != nil && !isMissing(err) {
+			return nil, err
+		}
+		sets[idx] = set
+	}
+	return sets, nil
+}
+
+// isMissing reports whether the error is about a missing or expired key.
+func isMissing(err error) bool {
+	return errors.Is(err, domain.ErrKeyNotFound) || errors.Is(err, domain.ErrKeyExpired)
+}
+
+// updateSet replaces the set of the key with the one computed by fn, a missing key is an empty set.
+// An empty result deletes the key. fn must not modify the set it gets, it may be called more than once.
+func (i *storage) updateSet(key string, fn func(set domain.Members) (domain.Members, error)) (domain.Entity, error) {
+	return i.Update(key, func(current domain.Entity, exists bool) (domain.Entity, error) {
+		if exists && current.Type != domain.TypeSet {
+			return domain.Entity{}, domain.ErrWrongType
+		}
+		set, err := fn(current.Set)
+		if err != nil {
+			return domain.Entity{}, err
+		}
+		if len(set) == 0 {
+			return removed, nil
+		}
+
+		current.Type = domain.TypeSet
+		current.Set = set
+		return current, nil
+	})
+}
+
+// copySet returns a copy of the set with room for extra more members.
+func copySet(set domain.Members, extra int) domain.Members {
+	dup := make(domain.Members, len(set)+extra)
+	for member := range set {
+		dup[member] = struct{}{}
+	}
+	return dup
+}
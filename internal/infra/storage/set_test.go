@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_SetType(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+
+	added, err := i.SAdd("tags", "go", "db", "go")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added, "duplicates are added once")
+	added, err = i.SAdd("tags", "db", "cache")
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	members, err := i.SMembers("tags")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cache", "db", "go"}, members)
+
+	ok, err := i.SIsMember("tags", "go")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = i.SIsMember("tags", "rust")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, err = i.SIsMember("missing", "go")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+
+	n, err := i.SRem("tags", "go", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = i.SRem("tags", "db", "cache")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	_, err = i.SMembers("tags")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "removing the last member deletes the key")
+}
+
+func Test_storage_SetOperations(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	_, err := i.SAdd("a", "1", "2", "3")
+	require.NoError(t, err)
+	_, err = i.SAdd("b", "2", "3", "4")
+	require.NoError(t, err)
+	_, err = i.SAdd("c", "3", "5")
+	require.NoError(t, err)
+	require.NoError(t, i.Set("string", "value", 0))
+
+	tests := []struct {
+		name    string
+		op      func(keys ...string) ([]string, error)
+		keys    []string
+		want    []string
+		wantErr error
+	}{
+		{name: "SInter", op: i.SInter, keys: []string{"a", "b", "c"}, want: []string{"3"}},
+		{name: "SInter with a missing key", op: i.SInter, keys: []string{"a", "missing"}, want: []string{}},
+		{name: "SUnion", op: i.SUnion, keys: []string{"a", "b", "missing"}, want: []string{"1", "2", "3", "4"}},
+		{name: "SDiff", op: i.SDiff, keys: []string{"a", "b"}, want: []string{"1"}},
+		{name: "SDiff of a missing key", op: i.SDiff, keys: []string{"missing", "a"}, want: []string{}},
+		{name: "SUnion with a string key", op: i.SUnion, keys: []string{"a", "string"}, wantErr: domain.ErrWrongType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op(tt.keys...)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+const (
+	// fnvOffset32 and fnvPrime32 are the FNV-1a parameters used to pick a shard for a key.
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+
+	// recordOverhead is the estimated number of bytes a record takes besides its key and value.
+	recordOverhead = 96
+	// fieldOverhead is the estimated number of bytes a field of a hash takes besides its name and value.
+	fieldOverhead = 48
+	// elementOverhead is the estimated number of bytes a value of a list takes besides its data.
+	elementOverhead = 16
+	// zsetMemberOverhead is the estimated number of bytes a member of a sorted set takes besides its data,
+	// a node in each of its two treaps.
+	zsetMemberOverhead = 128
+)
+
+// shard is a partition of the keyspace guarded by its own lock.
+type shard struct {
+	mu      *sync.RWMutex
+	storage table
+	// expires holds the keys that have an expiration, so the sweeper samples only those.
+	expires map[string]struct{}
+	// usage is shared by all the shards of a storage.
+	usage *usage
+	// notify is called for every change of the shard.
+	notify domain.ChangeListener
+	// clock is the clock of the storage.
+	clock clock
+}
+
+// usage tracks the estimated memory and the number of keys of a storage.
+type usage struct {
+	bytes atomic.Int64
+	keys  atomic.Int64
+}
+
+// record is a stored entity along with the metadata used by the eviction policies.
+// The metadata is atomic, so readers update it holding only the read lock.
+type record struct {
+	entity domain.Entity
+	// size is the estimated memory taken by the record.
+	size int64
+	// lastAccess is the unix time in nanoseconds the record was last read or written.
+	lastAccess atomic.Int64
+	// freq is the access counter used by the LFU policies.
+	freq atomic.Uint32
+}
+
+// newShard returns an empty shard accounting its memory in u and reporting its changes to notify.
+func newShard(u *usage, notify domain.ChangeListener) *shard {
+	return &shard{
+		mu:      &sync.RWMutex{},
+		expires: make(map[string]struct{}),
+		usage:   u,
+		notify:  notify,
+	}
+}
+
+// newRecord wraps the entity into a record accessed just now.
+func newRecord(entity domain.Entity) *record {
+	rec := &record{
+		entity: entity,
+		size:   entitySize(entity),
+	}
+	rec.lastAccess.Store(time.Now().UnixNano())
+	rec.freq.Store(lfuInitialFreq)
+	return rec
+}
+
+// recordSize estimates the memory taken by a record with the given key and value.
+func recordSize(key, value string) int64 {
+	return int64(len(key)+len(value)) + recordOverhead
+}
+
+// entitySize estimates the memory taken by a record of the entity, including the fields of a hash,
+// the values of a list and the members of the sets.
+func entitySize(entity domain.Entity) int64 {
+	size := recordSize(entity.Key, entity.Value) + int64(len(entity.ContentType))
+	for field, value := range entity.Hash {
+		size += int64(len(field)+len(value)) + fieldOverhead
+	}
+	for _, value := range entity.List {
+		size += int64(len(value)) + elementOverhead
+	}
+	for member := range entity.Set {
+		size += int64(len(member)) + fieldOverhead
+	}
+	if entity.ZSet != nil {
+		size += entity.ZSet.Bytes() + int64(entity.ZSet.Len())*zsetMemberOverhead
+	}
+	return size
+}
+
+// touch marks the record as accessed.
+func (r *record) touch() {
+	r.lastAccess.Store(time.Now().UnixNano())
+	if freq := r.freq.Load(); freq < lfuMaxFreq {
+		r.freq.CompareAndSwap(freq, freq+1)
+	}
+}
+
+// put stores the entity and keeps the expires index and the usage in sync.
+// The caller must hold the write lock.
+func (s *shard) put(entity domain.Entity) {
+	s.putRecord(newRecord(entity))
+}
+
+// putRecord stores the record and keeps the expires index and the usage in sync.
+// The caller must hold the write lock.
+func (s *shard) putRecord(rec *record) {
+	key := rec.entity.Key
+	if old, ok := s.storage.get(key); ok {
+		s.usage.bytes.Add(-old.size)
+	} else {
+		s.usage.keys.Add(1)
+	}
+	s.usage.bytes.Add(rec.size)
+
+	s.storage.set(key, rec)
+	if rec.entity.Expiration > 0 {
+		s.expires[key] = struct{}{}
+	} else {
+		delete(s.expires, key)
+	}
+}
+
+// replace stores the updated entity of the record, keeping its access metadata.
+// Records are never modified in place, because readers use them after releasing the lock.
+// The caller must hold the write lock.
+func (s *shard) replace(old *record, entity domain.Entity) {
+	rec := newRecord(entity)
+	rec.lastAccess.Store(old.lastAccess.Load())
+	rec.freq.Store(old.freq.Load())
+	s.putRecord(rec)
+}
+
+// live returns the record of the key if it exists and has not expired.
+// The caller must hold the lock.
+func (s *shard) live(key string) (*record, bool) {
+	rec, ok := s.storage.get(key)
+	if !ok || rec.entity.ExpiredAt(s.clock.now()) {
+		return nil, false
+	}
+	return rec, true
+}
+
+// remove deletes the key from the shard and the expires index.
+// The caller must hold the write lock.
+func (s *shard) remove(key string) {
+	rec, ok := s.storage.get(key)
+	if !ok {
+		return
+	}
+
+	s.usage.bytes.Add(-rec.size)
+	s.usage.keys.Add(-1)
+	s.storage.delete(key)
+	delete(s.expires, key)
+}
+
+// emit reports the change of a key.
+// The caller must hold the write lock.
+func (s *shard) emit(t domain.ChangeType, key string, entity domain.Entity) {
+	if s.notify != nil {
+		s.notify(domain.Change{Type: t, Key: key, Entity: entity})
+	}
+}
+
+// shardFor returns the shard owning the key.
+func (i *storage) shardFor(key string) *shard {
+	if len(i.shards) == 1 {
+		return i.shards[0]
+	}
+	return i.shards[shardIndex(key, len(i.shards))]
+}
+
+// lockKeys locks the shards of the keys in the order of their index and returns the function unlocking them.
+// exclusive takes the write locks, otherwise the read locks.
+func (i *storage) lockKeys(keys []string, exclusive bool) func() {
+	seen := make(map[int]bool)
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		idx := 0
+		if len(i.shards) > 1 {
+			idx = shardIndex(key, len(i.shards))
+		}
+		if !seen[idx] {
+			seen[idx] = true
+			indexes = append(indexes, idx)
+		}
+	}
+	sort.Ints(indexes)
+
+	for _, idx := range indexes {
+		if exclusive {
+			i.shards[idx].mu.Lock()
+		} else {
+			i.shards[idx].mu.RLock()
+		}
+	}
+	return func() {
+		for n := len(indexes) - 1; n >= 0; n-- {
+			if exclusive {
+				i.shards[indexes[n]].mu.Unlock()
+			} else {
+				i.shards[indexes[n]].mu.RUnlock()
+			}
+		}
+	}
+}
+
+// shardIndex hashes the key with FNV-1a and maps it onto one of n shards.
+func shardIndex(key string, n int) int {
+	return int(fnvHash(key) % uint32(n))
+}
+
+// fnvHash returns the FNV-1a hash of the key.
+// It is inlined instead of using hash/fnv to avoid allocating on every call.
+func fnvHash(key string) uint32 {
+	h := uint32(fnvOffset32)
+	for idx := 0; idx < len(key); idx++ {
+		h ^= uint32(key[idx])
+		h *= fnvPrime32
+	}
+	return h
+}
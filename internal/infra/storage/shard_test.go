@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSharded(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int
+		wantShards int
+	}{
+		{
+			name:       "NewSharded creates the requested number of shards",
+			n:          16,
+			wantShards: 16,
+		},
+		{
+			name:       "NewSharded creates a single shard for a non-positive count",
+			n:          0,
+			wantShards: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewSharded(tt.n)
+			defer got.Close()
+
+			assert.Len(t, got.shards, tt.wantShards)
+		})
+	}
+}
+
+func Test_shardIndex(t *testing.T) {
+	const n = 16
+	counts := make([]int, n)
+	for i := 0; i < 16000; i++ {
+		idx := shardIndex("key"+strconv.Itoa(i), n)
+		assert.Equal(t, idx, shardIndex("key"+strconv.Itoa(i), n))
+		counts[idx]++
+	}
+
+	// Every shard gets a reasonable share of the keys.
+	for idx, count := range counts {
+		assert.Greater(t, count, 500, "shard %d", idx)
+	}
+}
+
+func Test_storage_Sharded(t *testing.T) {
+	s := NewSharded(8)
+	defer s.Close()
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, s.Set("key"+strconv.Itoa(i), "value"+strconv.Itoa(i), 0))
+	}
+	assert.NoError(t, s.Set("expired", "value", time.Nanosecond))
+	time.Sleep(time.Millisecond)
+
+	for i := 0; i < 100; i++ {
+		got, err := s.Get("key" + strconv.Itoa(i))
+		assert.NoError(t, err)
+		assert.Equal(t, "value"+strconv.Itoa(i), got)
+	}
+
+	all, err := s.GetAll()
+	assert.NoError(t, err)
+	assert.Len(t, all, 100)
+
+	assert.NoError(t, s.Delete("key0"))
+	_, err = s.Get("key0")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+}
+
+func Test_storage_sweepSharded(t *testing.T) {
+	s := &storage{usage: &usage{}}
+	for i := 0; i < 4; i++ {
+		s.shards = append(s.shards, newShard(s.usage, s.notify))
+	}
+	for i := 0; i < 40; i++ {
+		key := "key" + strconv.Itoa(i)
+		s.shardFor(key).put(domain.Entity{Key: key, Value: "v", Expiration: time.Now().Add(-time.Minute).UnixNano()})
+	}
+
+	assert.Equal(t, 40, s.sweep(100))
+	for _, sh := range s.shards {
+		assert.Zero(t, sh.storage.len())
+	}
+}
+
+// singleLock is the storage as it was before the sharding, one map behind one RWMutex.
+// It is the baseline the sharded storage is benchmarked against.
+type singleLock struct {
+	mu       sync.RWMutex
+	entities map[string]domain.Entity
+	expires  map[string]struct{}
+}
+
+func newSingleLock() *singleLock {
+	return &singleLock{entities: make(map[string]domain.Entity), expires: make(map[string]struct{})}
+}
+
+func (s *singleLock) Set(key string, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var exp int64
+	if ttl != 0 {
+		exp = time.Now().Add(ttl).UnixNano()
+	}
+	s.entities[key] = domain.Entity{Key: key, Value: value, Expiration: exp}
+	if exp > 0 {
+		s.expires[key] = struct{}{}
+	} else {
+		delete(s.expires, key)
+	}
+	return nil
+}
+
+func (s *singleLock) Get(key string) (string, error) {
+	s.mu.RLock()
+	entity, ok := s.entities[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return "", domain.ErrKeyNotFound
+	}
+	if entity.IsExpired() {
+		s.mu.Lock()
+		delete(s.entities, key)
+		delete(s.expires, key)
+		s.mu.Unlock()
+		return "", domain.ErrKeyExpired
+	}
+	return entity.Value, nil
+}
+
+// benchmarkStorage runs a parallel mixed workload of three writes per read over 10k keys.
+func benchmarkStorage(b *testing.B, s interface {
+	Set(key string, value string, ttl time.Duration) error
+	Get(key string) (string, error)
+}) {
+	const keys = 10000
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "key" + strconv.Itoa(i)
+		_ = s.Set(names[i], "value", 0)
+	}
+
+	var seed atomic.Uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(seed.Add(7919))
+		for pb.Next() {
+			i++
+			key := names[i%keys]
+			if i%4 == 0 {
+				_, _ = s.Get(key)
+				continue
+			}
+			_ = s.Set(key, "value", time.Minute)
+		}
+	})
+}
+
+func BenchmarkSingleLock(b *testing.B) {
+	benchmarkStorage(b, newSingleLock())
+}
+
+func BenchmarkSharded1(b *testing.B) {
+	s := NewSharded(1)
+	defer s.Close()
+	benchmarkStorage(b, s)
+}
+
+func BenchmarkSharded16(b *testing.B) {
+	s := NewSharded(16)
+	defer s.Close()
+	benchmarkStorage(b, s)
+}
+
+func BenchmarkSharded64(b *testing.B) {
+	s := NewSharded(64)
+	defer s.Close()
+	benchmarkStorage(b, s)
+}
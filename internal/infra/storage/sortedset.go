@@ -0,0 +1,356 @@
+package storage
+
+import (
+	"encoding/json"
+	"hash/maphash"
+	"strings"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// sortedSet is an immutable set of members ordered by score, the members with the same score ordered lexically.
+// It is made of two persistent treaps holding the same members, one ordered by score and one by member.
+// A write copies only the nodes on the path to the changed member and returns a new set, O(log n),
+// so the readers of the old set are not affected. The nil *sortedSet is the empty set.
+// It is the domain.SortedSet of the stored entities.
+type sortedSet struct {
+	byScore  *zsetNode
+	byMember *zsetNode
+	// bytes is the total length of the members.
+	bytes int64
+}
+
+// toSortedSet returns the sorted set as a *sortedSet, building one from the members of another domain.SortedSet,
+// e.g. one decoded from a file.
+func toSortedSet(s domain.SortedSet) *sortedSet {
+	switch s := s.(type) {
+	case nil:
+		return nil
+	case *sortedSet:
+		return s
+	default:
+		var z *sortedSet
+		for _, m := range s.Members() {
+			z = z.Add(m.Member, m.Score)
+		}
+		return z
+	}
+}
+
+// zsetNode is a node of a treap, the size being the number of the nodes of its subtree.
+type zsetNode struct {
+	domain.ScoredMember
+	priority    uint64
+	size        int
+	left, right *zsetNode
+}
+
+// zsetOrder compares two members of a treap, returning a negative number when a goes first.
+type zsetOrder func(a, b *domain.ScoredMember) int
+
+func byScore(a, b *domain.ScoredMember) int {
+	switch {
+	case a.Score < b.Score:
+		return -1
+	case a.Score > b.Score:
+		return 1
+	default:
+		return strings.Compare(a.Member, b.Member)
+	}
+}
+
+func byMember(a, b *domain.ScoredMember) int {
+	return strings.Compare(a.Member, b.Member)
+}
+
+// prioritySeed randomizes the priorities of the members, so the shape of the treaps can not be forced by the clients.
+var prioritySeed = maphash.MakeSeed()
+
+// Len returns the number of the members.
+func (z *sortedSet) Len() int {
+	if z == nil {
+		return 0
+	}
+	return treapSize(z.byScore)
+}
+
+// Bytes returns the total length of the members.
+func (z *sortedSet) Bytes() int64 {
+	if z == nil {
+		return 0
+	}
+	return z.bytes
+}
+
+// Score returns the score of the member.
+func (z *sortedSet) Score(member string) (float64, bool) {
+	if z == nil {
+		return 0, false
+	}
+	for n := z.byMember; n != nil; {
+		switch c := strings.Compare(member, n.Member); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.Score, true
+		}
+	}
+	return 0, false
+}
+
+// Rank returns the position of the member ordered by score, starting at 0.
+func (z *sortedSet) Rank(member string) (int, bool) {
+	score, ok := z.Score(member)
+	if !ok {
+		return 0, false
+	}
+	item := &domain.ScoredMember{Member: member, Score: score}
+	return treapPrefix(z.byScore, func(m *domain.ScoredMember) bool { return byScore(m, item) < 0 }), true
+}
+
+// Add returns the set with the member added or its score replaced.
+func (z *sortedSet) Add(member string, score float64) *sortedSet {
+	dup := &sortedSet{}
+	if z != nil {
+		*dup = *z
+	}
+	if old, ok := z.Score(member); ok {
+		if old == score {
+			return z
+		}
+		dup.byScore = treapRemove(dup.byScore, &domain.ScoredMember{Member: member, Score: old}, byScore)
+		dup.byMember = treapRemove(dup.byMember, &domain.ScoredMember{Member: member}, byMember)
+	} else {
+		dup.bytes += int64(len(member))
+	}
+
+	item := domain.ScoredMember{Member: member, Score: score}
+	priority := maphash.String(prioritySeed, member)
+	dup.byScore = treapInsert(dup.byScore, item, priority, byScore)
+	dup.byMember = treapInsert(dup.byMember, item, priority, byMember)
+	return dup
+}
+
+// Remove returns the set without the member, the set itself when the member is missing.
+// Removing the last member returns nil.
+func (z *sortedSet) Remove(member string) *sortedSet {
+	score, ok := z.Score(member)
+	if !ok {
+		return z
+	}
+	if z.Len() == 1 {
+		return nil
+	}
+	return &sortedSet{
+		byScore:  treapRemove(z.byScore, &domain.ScoredMember{Member: member, Score: score}, byScore),
+		byMember: treapRemove(z.byMember, &domain.ScoredMember{Member: member}, byMember),
+		bytes:    z.bytes - int64(len(member)),
+	}
+}
+
+// Range returns the members from the rank start to the rank stop, both inclusive, ordered by score.
+// Negative ranks count from the highest score, -1 being the last member. rev orders the members
+// from the highest score, the ranks counting from it too.
+func (z *sortedSet) Range(start, stop int, rev bool) []domain.ScoredMember {
+	n := z.Len()
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []domain.ScoredMember{}
+	}
+	if rev {
+		start, stop = n-1-stop, n-1-start
+	}
+	return z.slice(start, stop+1, rev)
+}
+
+// RangeByScore returns the members with a score between min and max, ordered by score, or from the highest score
+// with rev. The first offset members are skipped, and at most count members are returned, a negative count
+// returning all of them.
+func (z *sortedSet) RangeByScore(min, max domain.ScoreBound, rev bool, offset, count int) []domain.ScoredMember {
+	from, to := z.scoreRanks(min, max)
+	if offset < 0 {
+		offset = 0
+	}
+	if to-from <= offset {
+		return []domain.ScoredMember{}
+	}
+	if count < 0 || count > to-from-offset {
+		count = to - from - offset
+	}
+	if rev {
+		return z.slice(to-offset-count, to-offset, true)
+	}
+	return z.slice(from+offset, from+offset+count, false)
+}
+
+// Count returns the number of the members with a score between min and max.
+func (z *sortedSet) Count(min, max domain.ScoreBound) int {
+	from, to := z.scoreRanks(min, max)
+	return to - from
+}
+
+// Members returns all the members ordered by score.
+func (z *sortedSet) Members() []domain.ScoredMember {
+	return z.slice(0, z.Len(), false)
+}
+
+// MarshalJSON encodes the set as an array of the members ordered by score.
+func (z *sortedSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(z.Members())
+}
+
+// scoreRanks returns the ranks of the first member with a score between min and max and of the one after the last.
+func (z *sortedSet) scoreRanks(min, max domain.ScoreBound) (int, int) {
+	if z == nil {
+		return 0, 0
+	}
+	from := treapPrefix(z.byScore, func(m *domain.ScoredMember) bool { return !aboveMin(min, m.Score) })
+	to := treapPrefix(z.byScore, func(m *domain.ScoredMember) bool { return belowMax(max, m.Score) })
+	if to < from {
+		return 0, 0
+	}
+	return from, to
+}
+
+// slice returns the members from the rank from, inclusive, to the rank to, exclusive, reversed with rev.
+func (z *sortedSet) slice(from, to int, rev bool) []domain.ScoredMember {
+	if z == nil || from >= to {
+		return []domain.ScoredMember{}
+	}
+	members := treapAppendRange(make([]domain.ScoredMember, 0, to-from), z.byScore, from, to)
+	if rev {
+		for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+			members[i], members[j] = members[j], members[i]
+		}
+	}
+	return members
+}
+
+func treapSize(n *zsetNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// with returns a copy of the node with the children replaced.
+func (n *zsetNode) with(left, right *zsetNode) *zsetNode {
+	return &zsetNode{
+		ScoredMember: n.ScoredMember,
+		priority:     n.priority,
+		size:         treapSize(left) + treapSize(right) + 1,
+		left:         left,
+		right:        right,
+	}
+}
+
+// split splits the treap into the members going before the item, along with the item itself when inclusive,
+// and the others. Only the nodes on the path are copied.
+func treapSplit(n *zsetNode, item *domain.ScoredMember, order zsetOrder, inclusive bool) (*zsetNode, *zsetNode) {
+	if n == nil {
+		return nil, nil
+	}
+	c := order(&n.ScoredMember, item)
+	if c < 0 || (inclusive && c == 0) {
+		left, right := treapSplit(n.right, item, order, inclusive)
+		return n.with(n.left, left), right
+	}
+	left, right := treapSplit(n.left, item, order, inclusive)
+	return left, n.with(right, n.right)
+}
+
+// merge joins two treaps, all the members of a going before the ones of b.
+func treapMerge(a, b *zsetNode) *zsetNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.priority > b.priority:
+		return a.with(a.left, treapMerge(a.right, b))
+	default:
+		return b.with(treapMerge(a, b.left), b.right)
+	}
+}
+
+func treapInsert(n *zsetNode, item domain.ScoredMember, priority uint64, order zsetOrder) *zsetNode {
+	left, right := treapSplit(n, &item, order, false)
+	return treapMerge(treapMerge(left, &zsetNode{ScoredMember: item, priority: priority, size: 1}), right)
+}
+
+func treapRemove(n *zsetNode, item *domain.ScoredMember, order zsetOrder) *zsetNode {
+	left, right := treapSplit(n, item, order, false)
+	_, right = treapSplit(right, item, order, true)
+	return treapMerge(left, right)
+}
+
+// prefix returns the number of the leading members for which before is true, before being true for a prefix
+// of the members only.
+func treapPrefix(n *zsetNode, before func(m *domain.ScoredMember) bool) int {
+	count := 0
+	for n != nil {
+		if before(&n.ScoredMember) {
+			count += treapSize(n.left) + 1
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return count
+}
+
+// appendRange appends the members of the treap from the rank from, inclusive, to the rank to, exclusive,
+// visiting only the nodes on the way to them.
+func treapAppendRange(dst []domain.ScoredMember, n *zsetNode, from, to int) []domain.ScoredMember {
+	if n == nil || from >= to {
+		return dst
+	}
+	leftSize := treapSize(n.left)
+	if from < leftSize {
+		stop := to
+		if stop > leftSize {
+			stop = leftSize
+		}
+		dst = treapAppendRange(dst, n.left, from, stop)
+	}
+	if from <= leftSize && leftSize < to {
+		dst = append(dst, n.ScoredMember)
+	}
+	if to > leftSize+1 {
+		start := from - leftSize - 1
+		if start < 0 {
+			start = 0
+		}
+		dst = treapAppendRange(dst, n.right, start, to-leftSize-1)
+	}
+	return dst
+}
+
+// aboveMin reports whether the score is above the bound taken as a minimum.
+func aboveMin(b domain.ScoreBound, score float64) bool {
+	if b.Exclusive {
+		return score > b.Score
+	}
+	return score >= b.Score
+}
+
+// belowMax reports whether the score is below the bound taken as a maximum.
+func belowMax(b domain.ScoreBound, score float64) bool {
+	if b.Exclusive {
+		return score < b.Score
+	}
+	return score <= b.Score
+}
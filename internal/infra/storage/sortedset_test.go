@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sortedSet(t *testing.T) {
+	var z *sortedSet
+	z = z.Add("carol", 30).Add("alice", 10).Add("bob", 20).Add("dave", 20)
+	old := z
+	z = z.Add("alice", 25)
+
+	assert.Equal(t, 4, z.Len())
+	assert.Equal(t, []domain.ScoredMember{{Member: "bob", Score: 20}, {Member: "dave", Score: 20}, {Member: "alice", Score: 25}, {Member: "carol", Score: 30}}, z.Members())
+	assert.Equal(t, []domain.ScoredMember{{Member: "alice", Score: 10}, {Member: "bob", Score: 20}, {Member: "dave", Score: 20}, {Member: "carol", Score: 30}}, old.Members(),
+		"a write does not affect the previous set")
+
+	score, ok := z.Score("alice")
+	assert.True(t, ok)
+	assert.Equal(t, 25.0, score)
+	rank, ok := z.Rank("dave")
+	assert.True(t, ok)
+	assert.Equal(t, 1, rank)
+	_, ok = z.Rank("missing")
+	assert.False(t, ok)
+
+	z = z.Remove("bob").Remove("missing")
+	assert.Equal(t, []domain.ScoredMember{{Member: "dave", Score: 20}, {Member: "alice", Score: 25}, {Member: "carol", Score: 30}}, z.Members())
+	assert.Equal(t, int64(len("dave")+len("alice")+len("carol")), z.Bytes())
+	assert.Nil(t, new(sortedSet).Add("a", 1).Remove("a"), "removing the last member returns the empty set")
+}
+
+func Test_sortedSet_Range(t *testing.T) {
+	var z *sortedSet
+	for idx, member := range []string{"a", "b", "c", "d", "e"} {
+		z = z.Add(member, float64(idx+1))
+	}
+	members := func(scored []domain.ScoredMember) []string {
+		names := make([]string, len(scored))
+		for idx, m := range scored {
+			names[idx] = m.Member
+		}
+		return names
+	}
+	bound := func(s string) domain.ScoreBound {
+		b, err := domain.ParseScoreBound(s)
+		require.NoError(t, err)
+		return b
+	}
+
+	tests := []struct {
+		name string
+		got  []domain.ScoredMember
+		want []string
+	}{
+		{name: "all by rank", got: z.Range(0, -1, false), want: []string{"a", "b", "c", "d", "e"}},
+		{name: "negative ranks", got: z.Range(-2, -1, false), want: []string{"d", "e"}},
+		{name: "reversed ranks count from the highest score", got: z.Range(0, 1, true), want: []string{"e", "d"}},
+		{name: "ranks out of range", got: z.Range(5, 10, false), want: []string{}},
+		{name: "inclusive scores", got: z.RangeByScore(bound("2"), bound("4"), false, 0, -1), want: []string{"b", "c", "d"}},
+		{name: "exclusive scores", got: z.RangeByScore(bound("(2"), bound("(4"), false, 0, -1), want: []string{"c"}},
+		{name: "infinite scores", got: z.RangeByScore(bound("-inf"), bound("+inf"), false, 1, 2), want: []string{"b", "c"}},
+		{name: "reversed scores with a limit", got: z.RangeByScore(bound("2"), bound("+inf"), true, 1, 2), want: []string{"d", "c"}},
+		{name: "empty score range", got: z.RangeByScore(bound("4"), bound("2"), false, 0, -1), want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, members(tt.got))
+		})
+	}
+
+	assert.Equal(t, 3, z.Count(bound("(1"), bound("4")))
+	assert.Equal(t, 0, z.Count(bound("6"), bound("+inf")))
+	_, err := domain.ParseScoreBound("nan")
+	assert.ErrorIs(t, err, domain.ErrNotFloat)
+}
+
+// TestSortedSet_Model compares random writes and reads with a sorted slice.
+func Test_sortedSet_Model(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var z *sortedSet
+	model := map[string]float64{}
+
+	for step := 0; step < 2000; step++ {
+		member := strconv.Itoa(rnd.Intn(200))
+		if rnd.Intn(3) == 0 {
+			z = z.Remove(member)
+			delete(model, member)
+		} else {
+			score := float64(rnd.Intn(50))
+			z = z.Add(member, score)
+			model[member] = score
+		}
+	}
+
+	want := make([]domain.ScoredMember, 0, len(model))
+	for member, score := range model {
+		want = append(want, domain.ScoredMember{Member: member, Score: score})
+	}
+	sort.Slice(want, func(i, j int) bool { return byScore(&want[i], &want[j]) < 0 })
+
+	require.Equal(t, want, z.Members())
+	for rank, m := range want {
+		got, ok := z.Rank(m.Member)
+		require.True(t, ok)
+		require.Equal(t, rank, got)
+	}
+	assert.Equal(t, want[10:20], z.Range(10, 19, false))
+	assert.Less(t, depth(z.byScore), 40, "the treap stays balanced")
+	assert.Less(t, depth(z.byMember), 40, "the treap stays balanced")
+}
+
+func Test_sortedSet_JSON(t *testing.T) {
+	z := new(sortedSet).Add("b", 2).Add("a", 1)
+	data, err := json.Marshal(domain.Entity{Key: "k", Type: domain.TypeZSet, ZSet: z})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"k","value":"","type":"zset","zset":[{"member":"a","score":1},{"member":"b","score":2}],"expiration":0}`, string(data))
+
+	var decoded domain.Entity
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	restored := toSortedSet(decoded.ZSet)
+	assert.Equal(t, z.Members(), restored.Members())
+	rank, ok := restored.Rank("b")
+	assert.True(t, ok)
+	assert.Equal(t, 1, rank)
+	assert.Same(t, z, toSortedSet(z), "a stored set is not rebuilt")
+	assert.Nil(t, toSortedSet(nil))
+}
+
+func depth(n *zsetNode) int {
+	if n == nil {
+		return 0
+	}
+	l, r := depth(n.left), depth(n.right)
+	if l > r {
+		return l + 1
+	}
+	return r + 1
+}
@@ -0,0 +1,26 @@
+package storage
+
+import "github.com/gynshu-one/in-memory-storage/internal/domain"
+
+// Stats returns the state of the storage, see domain.StatsProvider.
+func (i *storage) Stats() domain.Stats {
+	memory := i.MemoryStats()
+	expiration := i.ExpirationStats()
+
+	var withTTL int64
+	for _, sh := range i.shards {
+		sh.mu.RLock()
+		withTTL += int64(len(sh.expires))
+		sh.mu.RUnlock()
+	}
+
+	return domain.Stats{
+		Keys:           memory.Keys,
+		KeysWithTTL:    withTTL,
+		UsedMemory:     memory.UsedMemory,
+		MaxMemory:      memory.MaxMemory,
+		EvictionPolicy: string(memory.Policy),
+		EvictedKeys:    memory.Evicted,
+		ExpiredKeys:    expiration.ExpiredActively + expiration.ExpiredLazily,
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import "sort"
+
+// tableBuckets is the number of buckets of the table of a shard.
+const tableBuckets = 256
+
+// table holds the records of a shard in tableBuckets maps, picked by the position of the key.
+// Scan reads the records of a bucket in the order of their positions, so it neither holds the lock of a shard
+// for the whole shard nor misses a key: a key keeps its position for its whole life, whatever is added or
+// removed around it. The buckets are allocated on the first write.
+type table struct {
+	buckets [tableBuckets]map[string]*record
+	size    int
+}
+
+// get returns the record of the key.
+func (t *table) get(key string) (*record, bool) {
+	rec, ok := t.buckets[bucketIndex(key)][key]
+	return rec, ok
+}
+
+// set stores the record under the key.
+func (t *table) set(key string, rec *record) {
+	idx := bucketIndex(key)
+	if t.buckets[idx] == nil {
+		t.buckets[idx] = make(map[string]*record)
+	}
+	if _, ok := t.buckets[idx][key]; !ok {
+		t.size++
+	}
+	t.buckets[idx][key] = rec
+}
+
+// delete removes the key.
+func (t *table) delete(key string) {
+	idx := bucketIndex(key)
+	if _, ok := t.buckets[idx][key]; ok {
+		delete(t.buckets[idx], key)
+		t.size--
+	}
+}
+
+// len returns the number of the keys.
+func (t *table) len() int {
+	return t.size
+}
+
+// each calls fn for the records of the buckets from start on, wrapping around, until fn returns false.
+// Within a bucket the order is random, like for a map.
+func (t *table) each(start int, fn func(key string, rec *record) bool) {
+	for n := 0; n < tableBuckets; n++ {
+		for key, rec := range t.buckets[(start+n)%tableBuckets] {
+			if !fn(key, rec) {
+				return
+			}
+		}
+	}
+}
+
+// positioned is a record along with the position of its key.
+type positioned struct {
+	pos uint32
+	rec *record
+}
+
+// from returns the records of the bucket of the position whose keys are at or after it, ordered by their positions.
+func (t *table) from(pos uint32) []positioned {
+	var recs []positioned
+	for key, rec := range t.buckets[pos>>24] {
+		if at := keyPosition(key); at >= pos {
+			recs = append(recs, positioned{pos: at, rec: rec})
+		}
+	}
+	sort.Slice(recs, func(a, b int) bool { return recs[a].pos < recs[b].pos })
+	return recs
+}
+
+// keyPosition maps the key onto its position in the table, the top 8 bits of which are its bucket.
+// The FNV-1a hash is scrambled with a Fibonacci multiplier, so the position is independent of the shard,
+// which uses the low bits of the same hash.
+func keyPosition(key string) uint32 {
+	return fnvHash(key) * 2654435769
+}
+
+// bucketIndex maps the key onto a bucket.
+func bucketIndex(key string) int {
+	return int(keyPosition(key) >> 24)
+}
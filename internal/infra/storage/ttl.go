@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// TTL returns the remaining time to live of a key, or domain.NoExpiration if the key does not expire.
+// If the key does not exist or has expired, it returns domain.ErrKeyNotFound.
+func (i *storage) TTL(key string) (time.Duration, error) {
+	sh := i.shardFor(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	rec, ok := sh.live(key)
+	if !ok {
+		return 0, domain.ErrKeyNotFound
+	}
+	if rec.entity.Expiration == 0 {
+		return domain.NoExpiration, nil
+	}
+	return time.Unix(0, rec.entity.Expiration).Sub(sh.clock.now()), nil
+}
+
+// Now returns the current time of the storage, see domain.Repository.Now.
+func (i *storage) Now() time.Time {
+	return i.clock.now()
+}
+
+// Expire sets the time to live of an existing key, a non-positive ttl deletes the key.
+// If the key does not exist or has expired, it returns domain.ErrKeyNotFound.
+func (i *storage) Expire(key string, ttl time.Duration) error {
+	return i.ExpireAt(key, i.clock.now().Add(ttl))
+}
+
+// ExpireAt sets the expiration of an existing key to an absolute time, a time that is not in the future deletes the key.
+// If the key does not exist or has expired, it returns domain.ErrKeyNotFound.
+func (i *storage) ExpireAt(key string, at time.Time) error {
+	sh := i.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	rec, ok := sh.live(key)
+	if !ok {
+		return domain.ErrKeyNotFound
+	}
+
+	if !at.After(sh.clock.now()) {
+		sh.remove(key)
+		sh.emit(domain.ChangeDelete, key, domain.Entity{})
+		return nil
+	}
+
+	entity := rec.entity
+	entity.Expiration = at.UnixNano()
+	entity.Sliding, entity.SlidingTTL = false, 0
+	entity.Version = i.nextVersion()
+	sh.replace(rec, entity)
+	sh.emit(domain.ChangeSet, key, entity)
+	return nil
+}
+
+// Persist removes the expiration of a key and reports whether it had one.
+// If the key does not exist or has expired, it returns domain.ErrKeyNotFound.
+func (i *storage) Persist(key string) (bool, error) {
+	sh := i.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	rec, ok := sh.live(key)
+	if !ok {
+		return false, domain.ErrKeyNotFound
+	}
+	if rec.entity.Expiration == 0 {
+		return false, nil
+	}
+
+	entity := rec.entity
+	entity.Expiration = 0
+	entity.Sliding, entity.SlidingTTL = false, 0
+	entity.Version = i.nextVersion()
+	sh.replace(rec, entity)
+	sh.emit(domain.ChangeSet, key, entity)
+	return true, nil
+}
+
+// GetEx gets the value of a key and sets its time to live in the same step, see domain.Repository.GetEx.
+// If the key does not exist or has expired, it returns domain.ErrKeyNotFound.
+func (i *storage) GetEx(key string, ttl time.Duration) (string, error) {
+	sh := i.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	rec, ok := sh.live(key)
+	if !ok {
+		return "", domain.ErrKeyNotFound
+	}
+	if rec.entity.Type != domain.TypeString {
+		return "", domain.ErrWrongType
+	}
+	rec.touch()
+
+	entity := rec.entity
+	switch {
+	case ttl > 0:
+		entity.Expiration = sh.clock.now().Add(ttl).UnixNano()
+	case ttl == domain.NoExpiration && entity.Expiration != 0:
+		entity.Expiration = 0
+	default:
+		return entity.Value, nil
+	}
+	entity.Sliding, entity.SlidingTTL = false, 0
+	entity.Version = i.nextVersion()
+	sh.replace(rec, entity)
+	sh.emit(domain.ChangeSet, key, entity)
+	return entity.Value, nil
+}
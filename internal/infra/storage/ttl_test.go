@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_storage_TTL(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+		"persistent": {Value: "v"},
+		"volatile":   {Value: "v", Expiration: time.Now().Add(time.Minute).UnixNano()},
+		"expired":    {Value: "v", Expiration: time.Now().Add(-time.Minute).UnixNano()},
+	})
+
+	ttl, err := s.TTL("persistent")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.NoExpiration, ttl)
+
+	ttl, err = s.TTL("volatile")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	_, err = s.TTL("expired")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+
+	_, err = s.TTL("missing")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+}
+
+func Test_storage_Expire(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		ttl        time.Duration
+		wantErr    error
+		wantExists bool
+		wantChange domain.ChangeType
+	}{
+		{
+			name:       "Expire sets the ttl of an existing key",
+			key:        "key1",
+			ttl:        time.Minute,
+			wantExists: true,
+			wantChange: domain.ChangeSet,
+		},
+		{
+			name:       "Expire with a non-positive ttl deletes the key",
+			key:        "key1",
+			ttl:        0,
+			wantChange: domain.ChangeDelete,
+		},
+		{
+			name:    "Expire of a missing key",
+			key:     "missing",
+			ttl:     time.Minute,
+			wantErr: domain.ErrKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{"key1": {Value: "v"}})
+			var changes []domain.Change
+			s.OnChange(func(c domain.Change) { changes = append(changes, c) })
+
+			err := s.Expire(tt.key, tt.ttl)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Empty(t, changes)
+				return
+			}
+
+			ttl, err := s.TTL(tt.key)
+			assert.Equal(t, tt.wantExists, err == nil)
+			if tt.wantExists {
+				assert.InDelta(t, tt.ttl, ttl, float64(time.Second))
+			}
+			if assert.Len(t, changes, 1) {
+				assert.Equal(t, tt.wantChange, changes[0].Type)
+			}
+		})
+	}
+}
+
+func Test_storage_Persist(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+		"persistent": {Value: "v"},
+		"volatile":   {Value: "v", Expiration: time.Now().Add(time.Minute).UnixNano()},
+	})
+
+	removed, err := s.Persist("volatile")
+	assert.NoError(t, err)
+	assert.True(t, removed)
+	ttl, _ := s.TTL("volatile")
+	assert.Equal(t, domain.NoExpiration, ttl)
+	assert.Empty(t, s.shards[0].expires)
+
+	removed, err = s.Persist("persistent")
+	assert.NoError(t, err)
+	assert.False(t, removed)
+
+	_, err = s.Persist("missing")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound)
+}
+
+func Test_storage_ExpireAt(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{"key1": {Value: "v"}, "key2": {Value: "v"}})
+
+	at := time.Now().Add(time.Hour)
+	assert.NoError(t, s.ExpireAt("key1", at))
+	rec, _ := s.shards[0].storage.get("key1")
+	assert.Equal(t, at.UnixNano(), rec.entity.Expiration)
+
+	assert.NoError(t, s.ExpireAt("key2", time.Now().Add(-time.Second)))
+	_, err := s.TTL("key2")
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "a time in the past deletes the key")
+
+	assert.ErrorIs(t, s.ExpireAt("missing", at), domain.ErrKeyNotFound)
+}
+
+func Test_storage_GetEx(t *testing.T) {
+	volatile := time.Now().Add(time.Minute).UnixNano()
+	tests := []struct {
+		name       string
+		key        string
+		ttl        time.Duration
+		wantErr    error
+		wantTTL    time.Duration
+		wantChange bool
+	}{
+		{
+			name:       "GetEx sets the ttl",
+			key:        "persistent",
+			ttl:        time.Hour,
+			wantTTL:    time.Hour,
+			wantChange: true,
+		},
+		{
+			name:       "GetEx refreshes the ttl",
+			key:        "volatile",
+			ttl:        time.Hour,
+			wantTTL:    time.Hour,
+			wantChange: true,
+		},
+		{
+			name:       "GetEx with NoExpiration removes the ttl",
+			key:        "volatile",
+			ttl:        domain.NoExpiration,
+			wantTTL:    domain.NoExpiration,
+			wantChange: true,
+		},
+		{
+			name:    "GetEx with NoExpiration of a key without ttl changes nothing",
+			key:     "persistent",
+			ttl:     domain.NoExpiration,
+			wantTTL: domain.NoExpiration,
+		},
+		{
+			name:    "GetEx with 0 keeps the ttl",
+			key:     "volatile",
+			ttl:     0,
+			wantTTL: time.Minute,
+		},
+		{
+			name:    "GetEx of a hash",
+			key:     "hash",
+			ttl:     time.Hour,
+			wantErr: domain.ErrWrongType,
+		},
+		{
+			name:    "GetEx of a missing key",
+			key:     "missing",
+			ttl:     time.Hour,
+			wantErr: domain.ErrKeyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(&sync.RWMutex{}, map[string]domain.Entity{
+				"persistent": {Value: "v"},
+				"volatile":   {Value: "v", Expiration: volatile},
+				"hash":       {Type: domain.TypeHash, Hash: map[string]string{"f": "v"}},
+			})
+			var changes []domain.Change
+			s.OnChange(func(c domain.Change) { changes = append(changes, c) })
+
+			value, err := s.GetEx(tt.key, tt.ttl)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Empty(t, changes)
+				return
+			}
+			assert.Equal(t, "v", value)
+
+			ttl, err := s.TTL(tt.key)
+			assert.NoError(t, err)
+			if tt.wantTTL == domain.NoExpiration {
+				assert.Equal(t, domain.NoExpiration, ttl)
+			} else {
+				assert.InDelta(t, tt.wantTTL, ttl, float64(time.Second))
+			}
+			assert.Equal(t, tt.wantChange, len(changes) == 1)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// maxIntLen is the length of the longest 64-bit integer, -9223372036854775808.
+const maxIntLen = 20
+
+// txnWrite is a change computed by a transaction, entity nil meaning the key is deleted.
+type txnWrite struct {
+	op     int
+	key    string
+	entity *domain.Entity
+}
+
+// Txn applies the operations in order as a single atomic step, see domain.Repository.Txn.
+//
+// The shards of all the keys are locked in the order of their index, so concurrent transactions can not deadlock.
+// The operations are first run against a view of the locked keys and applied only when all of them succeed.
+// Like SetWith, the memory is reserved before locking the shards, once the watches are checked,
+// so an aborted transaction does not evict keys; the watches are checked again under the locks.
+func (i *storage) Txn(watches []domain.Watch, ops []domain.Op) ([]domain.OpResult, error) {
+	if len(watches) > 0 {
+		keys := make([]string, 0, len(watches))
+		for _, w := range watches {
+			keys = append(keys, w.Key)
+		}
+		unlock := i.lockKeys(keys, false)
+		unchanged := i.unchanged(watches)
+		unlock()
+		if !unchanged {
+			return nil, domain.ErrTxnAborted
+		}
+	}
+
+	var pending growth
+	for idx, op := range ops {
+		var size int64
+		switch op.Type {
+		case domain.OpSet:
+			size = recordSize(op.Key, op.Value)
+		case domain.OpIncr:
+			size = int64(len(op.Key)+maxIntLen) + recordOverhead
+		case domain.OpDelete:
+			continue
+		default:
+			return nil, fmt.Errorf("operation %d: %w %q", idx, domain.ErrInvalidOp, op.Type)
+		}
+		if err := i.reserveMore(&pending, i.shardFor(op.Key), op.Key, size); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", idx, err)
+		}
+	}
+
+	keys := make([]string, 0, len(watches)+len(ops))
+	for _, w := range watches {
+		keys = append(keys, w.Key)
+	}
+	for _, op := range ops {
+		keys = append(keys, op.Key)
+	}
+	unlock := i.lockKeys(keys, true)
+	defer unlock()
+
+	if !i.unchanged(watches) {
+		return nil, domain.ErrTxnAborted
+	}
+
+	results := make([]domain.OpResult, len(ops))
+	writes, err := i.plan(ops, results)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, write := range writes {
+		sh := i.shardFor(write.key)
+		if write.entity == nil {
+			sh.remove(write.key)
+			sh.emit(domain.ChangeDelete, write.key, domain.Entity{})
+			continue
+		}
+		entity := *write.entity
+		entity.Version = i.nextVersion()
+		if old, ok := sh.live(write.key); ok && ops[write.op].Type == domain.OpIncr {
+			sh.replace(old, entity)
+		} else {
+			sh.put(entity)
+		}
+		sh.emit(domain.ChangeSet, write.key, entity)
+		results[write.op].Version = entity.Version
+	}
+	return results, nil
+}
+
+// unchanged reports whether the watched keys still have the versions of the watches.
+// The caller must hold the locks of the keys.
+func (i *storage) unchanged(watches []domain.Watch) bool {
+	for _, w := range watches {
+		var version uint64
+		if rec, ok := i.shardFor(w.Key).live(w.Key); ok {
+			version = rec.entity.Version
+		}
+		if version != w.Version {
+			return false
+		}
+	}
+	return true
+}
+
+// plan runs the operations against the locked keys, as changed by the previous operations,
+// and returns the writes to apply. It fills the results of the operations but their versions.
+// The caller must hold the locks of the keys.
+func (i *storage) plan(ops []domain.Op, results []domain.OpResult) ([]txnWrite, error) {
+	// view holds the keys changed by the previous operations, nil meaning deleted.
+	view := make(map[string]*domain.Entity)
+	current := func(key string) (*domain.Entity, bool) {
+		if entity, ok := view[key]; ok {
+			return entity, entity != nil
+		}
+		if rec, ok := i.shardFor(key).live(key); ok {
+			return &rec.entity, true
+		}
+		return nil, false
+	}
+
+	writes := make([]txnWrite, 0, len(ops))
+	for idx, op := range ops {
+		entity, exists := current(op.Key)
+		switch op.Type {
+		case domain.OpSet:
+			entity = &domain.Entity{Key: op.Key, Value: op.Value}
+			if op.TTL > 0 {
+				entity.Expiration = i.clock.now().Add(op.TTL).UnixNano()
+			}
+		case domain.OpDelete:
+			if !exists {
+				continue
+			}
+			entity = nil
+			results[idx].Deleted = true
+		case domain.OpIncr:
+			if exists && entity.Type != domain.TypeString {
+				return nil, fmt.Errorf("operation %d: %w", idx, domain.ErrWrongType)
+			}
+			var value string
+			if exists {
+				value = entity.Value
+			}
+			n, err := addInt(value, exists, op.Delta)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", idx, err)
+			}
+			updated := domain.Entity{Key: op.Key}
+			if exists {
+				updated = *entity
+			}
+			updated.Value = strconv.FormatInt(n, 10)
+			entity = &updated
+			results[idx].Value = n
+		}
+		view[op.Key] = entity
+		writes = append(writes, txnWrite{op: idx, key: op.Key, entity: entity})
+	}
+	return writes, nil
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_Txn(t *testing.T) {
+	tests := []struct {
+		name        string
+		watches     func(versions map[string]uint64) []domain.Watch
+		ops         []domain.Op
+		want        []domain.OpResult
+		wantErr     error
+		wantValues  map[string]string
+		wantMissing []string
+	}{
+		{
+			name: "Txn moves a value between keys",
+			ops: []domain.Op{
+				{Type: domain.OpSet, Key: "to", Value: "100"},
+				{Type: domain.OpDelete, Key: "from"},
+			},
+			want:        []domain.OpResult{{}, {Deleted: true}},
+			wantValues:  map[string]string{"to": "100"},
+			wantMissing: []string{"from"},
+		},
+		{
+			name: "Txn applies the operations in order",
+			ops: []domain.Op{
+				{Type: domain.OpIncr, Key: "balance", Delta: -30},
+				{Type: domain.OpIncr, Key: "counter", Delta: 1},
+				{Type: domain.OpIncr, Key: "counter", Delta: 1},
+				{Type: domain.OpDelete, Key: "missing"},
+			},
+			want:       []domain.OpResult{{Value: 70}, {Value: 1}, {Value: 2}, {}},
+			wantValues: map[string]string{"balance": "70", "counter": "2"},
+		},
+		{
+			name: "Txn commits when the watched keys did not change",
+			watches: func(versions map[string]uint64) []domain.Watch {
+				return []domain.Watch{{Key: "balance", Version: versions["balance"]}, {Key: "missing"}}
+			},
+			ops:        []domain.Op{{Type: domain.OpSet, Key: "balance", Value: "0"}},
+			want:       []domain.OpResult{{}},
+			wantValues: map[string]string{"balance": "0"},
+		},
+		{
+			name: "Txn aborts when a watched key changed",
+			watches: func(versions map[string]uint64) []domain.Watch {
+				return []domain.Watch{{Key: "balance", Version: versions["balance"] - 1}}
+			},
+			ops:        []domain.Op{{Type: domain.OpSet, Key: "balance", Value: "0"}},
+			wantErr:    domain.ErrTxnAborted,
+			wantValues: map[string]string{"balance": "100"},
+		},
+		{
+			name: "Txn aborts when a watched missing key was created",
+			watches: func(map[string]uint64) []domain.Watch {
+				return []domain.Watch{{Key: "from"}}
+			},
+			ops:        []domain.Op{{Type: domain.OpDelete, Key: "from"}},
+			wantErr:    domain.ErrTxnAborted,
+			wantValues: map[string]string{"from": "100"},
+		},
+		{
+			name: "Txn applies nothing when an operation fails",
+			ops: []domain.Op{
+				{Type: domain.OpDelete, Key: "from"},
+				{Type: domain.OpIncr, Key: "text", Delta: 1},
+			},
+			wantErr:    domain.ErrNotInteger,
+			wantValues: map[string]string{"from": "100", "text": "abc"},
+		},
+		{
+			name:       "Txn refuses to increment a hash",
+			ops:        []domain.Op{{Type: domain.OpIncr, Key: "hash", Delta: 1}},
+			wantErr:    domain.ErrWrongType,
+			wantValues: map[string]string{},
+		},
+		{
+			name:       "Txn refuses an unknown operation",
+			ops:        []domain.Op{{Type: "rename", Key: "from"}},
+			wantErr:    domain.ErrInvalidOp,
+			wantValues: map[string]string{"from": "100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSharded(4)
+			defer s.Close()
+			versions := make(map[string]uint64)
+			for key, value := range map[string]string{"from": "100", "balance": "100", "text": "abc"} {
+				version, err := s.SetWith(key, value, 0, domain.SetOptions{})
+				require.NoError(t, err)
+				versions[key] = version
+			}
+			_, err := s.HSet("hash", map[string]string{"f": "1"})
+			require.NoError(t, err)
+
+			var watches []domain.Watch
+			if tt.watches != nil {
+				watches = tt.watches(versions)
+			}
+			got, err := s.Txn(watches, tt.ops)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				require.Len(t, got, len(tt.want))
+				for idx := range got {
+					if tt.ops[idx].Type != domain.OpDelete {
+						assert.NotZero(t, got[idx].Version, "operation %d", idx)
+					}
+					got[idx].Version = 0
+				}
+				assert.Equal(t, tt.want, got)
+			}
+
+			for key, want := range tt.wantValues {
+				value, err := s.Get(key)
+				assert.NoError(t, err)
+				assert.Equal(t, want, value, key)
+			}
+			for _, key := range tt.wantMissing {
+				_, err := s.Get(key)
+				assert.ErrorIs(t, err, domain.ErrKeyNotFound, key)
+			}
+		})
+	}
+}
+
+func Test_storage_TxnKeepsExpiration(t *testing.T) {
+	s := NewInMemory()
+	defer s.Close()
+	require.NoError(t, s.Set("counter", "1", time.Hour))
+
+	_, err := s.Txn(nil, []domain.Op{
+		{Type: domain.OpIncr, Key: "counter", Delta: 1},
+		{Type: domain.OpSet, Key: "session", Value: "abc", TTL: time.Minute},
+	})
+	require.NoError(t, err)
+
+	ttl, err := s.TTL("counter")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute)
+	ttl, err = s.TTL("session")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Second)
+}
+
+func Test_storage_TxnConcurrent(t *testing.T) {
+	s := NewSharded(8)
+	defer s.Close()
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		require.NoError(t, s.Set(key, "100", 0))
+	}
+
+	// Every transaction moves 1 from a key to another, in opposite orders, so the total never changes
+	// and a wrong lock order would deadlock.
+	const workers, transfers = 6, 200
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < transfers; n++ {
+				from, to := keys[(w+n)%len(keys)], keys[(w+n+1)%len(keys)]
+				if w%2 == 1 {
+					from, to = to, from
+				}
+				_, err := s.Txn(nil, []domain.Op{
+					{Type: domain.OpIncr, Key: from, Delta: -1},
+					{Type: domain.OpIncr, Key: to, Delta: 1},
+				})
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var total int
+	for _, key := range keys {
+		value, err := s.Get(key)
+		require.NoError(t, err)
+		n, err := addInt(value, true, 0)
+		require.NoError(t, err)
+		total += int(n)
+	}
+	assert.Equal(t, 100*len(keys), total)
+}
@@ -0,0 +1,66 @@
+package storage
+
+import "github.com/gynshu-one/in-memory-storage/internal/domain"
+
+// Update atomically replaces the entity of a key with the one computed by fn and returns the stored entity,
+// see domain.Repository.Update.
+//
+// fn is called without holding the lock, because the memory for the new entity must be reserved before
+// locking the shard. The write is applied only if the key still holds the record fn saw,
+// otherwise fn is called again with the new state.
+func (i *storage) Update(key string, fn domain.UpdateFunc) (domain.Entity, error) {
+	sh := i.shardFor(key)
+	for {
+		sh.mu.RLock()
+		rec, exists := sh.live(key)
+		sh.mu.RUnlock()
+
+		current := domain.Entity{Key: key}
+		if exists {
+			current = rec.entity
+		}
+		entity, err := fn(current, exists)
+		if err != nil {
+			return domain.Entity{}, err
+		}
+		entity.Key = key
+
+		if !entity.ExpiredAt(sh.clock.now()) {
+			if err = i.reserve(sh, key, entitySize(entity)); err != nil {
+				return domain.Entity{}, err
+			}
+		}
+
+		if stored, ok := i.apply(sh, rec, entity); ok {
+			return stored, nil
+		}
+	}
+}
+
+// apply stores the entity if the key still holds the record old, nil meaning the key was absent.
+// It reports false when the key was changed in the meantime.
+func (i *storage) apply(sh *shard, old *record, entity domain.Entity) (domain.Entity, bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if rec, _ := sh.live(entity.Key); rec != old {
+		return domain.Entity{}, false
+	}
+
+	if entity.ExpiredAt(sh.clock.now()) {
+		if old != nil {
+			sh.remove(entity.Key)
+			sh.emit(domain.ChangeDelete, entity.Key, domain.Entity{})
+		}
+		return domain.Entity{}, true
+	}
+
+	entity.Version = i.nextVersion()
+	if old != nil {
+		sh.replace(old, entity)
+	} else {
+		sh.put(entity)
+	}
+	sh.emit(domain.ChangeSet, entity.Key, entity)
+	return entity, true
+}
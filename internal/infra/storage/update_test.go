@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// appendValue appends "x" to the value of the key, creating it if it does not exist.
+func appendValue(current domain.Entity, _ bool) (domain.Entity, error) {
+	current.Value += "x"
+	return current, nil
+}
+
+func Test_storage_Update(t *testing.T) {
+	errAbort := errors.New("abort")
+	tests := []struct {
+		name       string
+		stored     map[string]domain.Entity
+		fn         domain.UpdateFunc
+		wantErr    error
+		wantValue  string
+		wantExists bool
+		wantChange domain.ChangeType
+	}{
+		{
+			name:       "Update creates a missing key",
+			stored:     map[string]domain.Entity{},
+			fn:         appendValue,
+			wantValue:  "x",
+			wantExists: true,
+			wantChange: domain.ChangeSet,
+		},
+		{
+			name:       "Update replaces an existing key",
+			stored:     map[string]domain.Entity{"key1": {Value: "v"}},
+			fn:         appendValue,
+			wantValue:  "vx",
+			wantExists: true,
+			wantChange: domain.ChangeSet,
+		},
+		{
+			name:       "Update sees an expired key as missing",
+			stored:     map[string]domain.Entity{"key1": {Value: "v", Expiration: time.Now().Add(-time.Minute).UnixNano()}},
+			fn:         appendValue,
+			wantValue:  "x",
+			wantExists: true,
+			wantChange: domain.ChangeSet,
+		},
+		{
+			name:   "Update returns the error of fn",
+			stored: map[string]domain.Entity{"key1": {Value: "v"}},
+			fn: func(domain.Entity, bool) (domain.Entity, error) {
+				return domain.Entity{}, errAbort
+			},
+			wantErr:    errAbort,
+			wantValue:  "v",
+			wantExists: true,
+		},
+		{
+			name:   "Update with an expired entity deletes the key",
+			stored: map[string]domain.Entity{"key1": {Value: "v"}},
+			fn: func(current domain.Entity, _ bool) (domain.Entity, error) {
+				current.Expiration = time.Now().Add(-time.Second).UnixNano()
+				return current, nil
+			},
+			wantChange: domain.ChangeDelete,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(&sync.RWMutex{}, tt.stored)
+			var changes []domain.Change
+			s.OnChange(func(c domain.Change) { changes = append(changes, c) })
+
+			_, err := s.Update("key1", tt.fn)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			entity, err := s.GetEntity("key1")
+			assert.Equal(t, tt.wantExists, err == nil)
+			assert.Equal(t, tt.wantValue, entity.Value)
+
+			if tt.wantChange == "" {
+				assert.Empty(t, changes)
+			} else if assert.Len(t, changes, 1) {
+				assert.Equal(t, tt.wantChange, changes[0].Type)
+			}
+		})
+	}
+}
+
+func Test_storage_UpdateVersion(t *testing.T) {
+	s := newTestStorage(&sync.RWMutex{}, nil)
+	assert.NoError(t, s.Set("key1", "v", 0))
+	before, _ := s.GetEntity("key1")
+
+	updated, err := s.Update("key1", func(current domain.Entity, _ bool) (domain.Entity, error) {
+		current.Version = 100
+		current.Flags = 3
+		return current, nil
+	})
+	assert.NoError(t, err)
+	assert.Greater(t, updated.Version, before.Version, "the version is assigned by the storage")
+	assert.NotEqual(t, uint64(100), updated.Version)
+	assert.Equal(t, uint32(3), updated.Flags)
+
+	stored, _ := s.GetEntity("key1")
+	assert.Equal(t, updated, stored)
+}
+
+func Test_storage_UpdateConcurrent(t *testing.T) {
+	s := NewSharded(4)
+	defer s.Close()
+
+	incr := func(current domain.Entity, _ bool) (domain.Entity, error) {
+		n, _ := strconv.Atoi(current.Value)
+		current.Value = strconv.Itoa(n + 1)
+		return current, nil
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				_, err := s.Update("counter", incr)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := s.Get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, "800", value, "no increment is lost")
+}
+
+func Test_storage_UpdateOutOfMemory(t *testing.T) {
+	s := newLimitedStorage(1, limits{maxKeys: 1, policy: NoEviction})
+	assert.NoError(t, s.Set("a", "v", 0))
+
+	_, err := s.Update("b", appendValue)
+	assert.ErrorIs(t, err, domain.ErrOutOfMemory)
+
+	_, err = s.Update("a", appendValue)
+	assert.NoError(t, err, "updating an existing key does not add a key")
+}
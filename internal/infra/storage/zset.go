@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"math"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// ZAdd adds the members with their scores to the sorted set, or updates the scores of the existing ones,
+// and returns the number of the new members.
+// Unlike the other types, a write copies only O(log n) nodes of the sorted set, see sortedSet.
+func (i *storage) ZAdd(key string, members map[string]float64) (int, error) {
+	for _, score := range members {
+		if !finite(score) {
+			return 0, domain.ErrNotFloat
+		}
+	}
+
+	var added int
+	_, err := i.updateZSet(key, func(zset *sortedSet) (*sortedSet, error) {
+		added = 0
+		for member, score := range members {
+			if _, ok := zset.Score(member); !ok {
+				added++
+			}
+			zset = zset.Add(member, score)
+		}
+		return zset, nil
+	})
+	return added, err
+}
+
+// ZIncrBy adds delta to the score of the member and returns the new score.
+func (i *storage) ZIncrBy(key, member string, delta float64) (float64, error) {
+	var result float64
+	_, err := i.updateZSet(key, func(zset *sortedSet) (*sortedSet, error) {
+		score, _ := zset.Score(member)
+		result = score + delta
+		if !finite(result) {
+			return nil, domain.ErrNotFloat
+		}
+		return zset.Add(member, result), nil
+	})
+	return result, err
+}
+
+// ZScore returns the score of the member.
+func (i *storage) ZScore(key, member string) (float64, error) {
+	zset, err := i.zset(key)
+	if err != nil {
+		return 0, err
+	}
+	score, ok := zset.Score(member)
+	if !ok {
+		return 0, domain.ErrMemberNotFound
+	}
+	return score, nil
+}
+
+// ZRank returns the rank of the member, counting from the highest score with rev.
+func (i *storage) ZRank(key, member string, rev bool) (int, error) {
+	zset, err := i.zset(key)
+	if err != nil {
+		return 0, err
+	}
+	rank, ok := zset.Rank(member)
+	if !ok {
+		return 0, domain.ErrMemberNotFound
+	}
+	if rev {
+		rank = zset.Len() - 1 - rank
+	}
+	return rank, nil
+}
+
+// ZRem removes the members and returns the number of the removed ones.
+// Removing the last member deletes the key.
+func (i *storage) ZRem(key string, members ...string) (int, error) {
+	var removedMembers int
+	_, err := i.updateZSet(key, func(zset *sortedSet) (*sortedSet, error) {
+		removedMembers = 0
+		for _, member := range members {
+			if _, ok := zset.Score(member); ok {
+				zset = zset.Remove(member)
+				removedMembers++
+			}
+		}
+		return zset, nil
+	})
+	return removedMembers, err
+}
+
+// ZRange returns the members from the rank start to the rank stop, both inclusive.
+func (i *storage) ZRange(key string, start, stop int, rev bool) ([]domain.ScoredMember, error) {
+	zset, err := i.zset(key)
+	if err != nil {
+		return nil, err
+	}
+	return zset.Range(start, stop, rev), nil
+}
+
+// ZRangeByScore returns the members with a score between min and max.
+func (i *storage) ZRangeByScore(key string, min, max domain.ScoreBound, rev bool, offset, count int) ([]domain.ScoredMember, error) {
+	zset, err := i.zset(key)
+	if err != nil {
+		return nil, err
+	}
+	return zset.RangeByScore(min, max, rev, offset, count), nil
+}
+
+// ZCount returns the number of the members with a score between min and max.
+func (i *storage) ZCount(key string, min, max domain.ScoreBound) (int, error) {
+	zset, err := i.zset(key)
+	if err != nil {
+		return 0, err
+	}
+	return zset.Count(min, max), nil
+}
+
+// zset returns the stored sorted set of the key.
+func (i *storage) zset(key string) (*sortedSet, error) {
+	entity, err := i.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Type != domain.TypeZSet {
+		return nil, domain.ErrWrongType
+	}
+	return toSortedSet(entity.ZSet), nil
+}
+
+// updateZSet replaces the sorted set of the key with the one computed by fn, a missing key is an empty set.
+// An empty result deletes the key. fn may be called more than once.
+func (i *storage) updateZSet(key string, fn func(zset *sortedSet) (*sortedSet, error)) (domain.Entity, error) {
+	return i.Update(key, func(current domain.Entity, exists bool) (domain.Entity, error) {
+		if exists && current.Type != domain.TypeZSet {
+			return domain.Entity{}, domain.ErrWrongType
+		}
+		zset, err := fn(toSortedSet(current.ZSet))
+		if err != nil {
+			return domain.Entity{}, err
+		}
+		if zset.Len() == 0 {
+			return removed, nil
+		}
+
+		current.Type = domain.TypeZSet
+		current.ZSet = zset
+		return current, nil
+	})
+}
+
+// finite reports whether the float can be stored, NaN and infinite values can not.
+func finite(score float64) bool {
+	return !math.IsNaN(score) && !math.IsInf(score, 0)
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_storage_ZSet(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+
+	added, err := i.ZAdd("board", map[string]float64{"alice": 10, "bob": 20, "carol": 30})
+	require.NoError(t, err)
+	assert.Equal(t, 3, added)
+	added, err = i.ZAdd("board", map[string]float64{"alice": 40, "dave": 5})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added, "only the new members are counted")
+
+	score, err := i.ZIncrBy("board", "bob", 2.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 22.5, score)
+	score, err = i.ZScore("board", "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, 22.5, score)
+	_, err = i.ZScore("board", "missing")
+	assert.ErrorIs(t, err, domain.ErrMemberNotFound)
+
+	top, err := i.ZRange("board", 0, 1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ScoredMember{{Member: "alice", Score: 40}, {Member: "carol", Score: 30}}, top)
+
+	rank, err := i.ZRank("board", "bob", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rank)
+	rank, err = i.ZRank("board", "bob", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rank)
+	_, err = i.ZRank("board", "missing", false)
+	assert.ErrorIs(t, err, domain.ErrMemberNotFound)
+
+	between, err := i.ZRangeByScore("board", domain.ScoreBound{Score: 10}, domain.ScoreBound{Score: 30, Exclusive: true}, false, 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ScoredMember{{Member: "bob", Score: 22.5}}, between)
+	n, err := i.ZCount("board", domain.ScoreBound{Score: math.Inf(-1)}, domain.ScoreBound{Score: 30})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	removedMembers, err := i.ZRem("board", "alice", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removedMembers)
+	removedMembers, err = i.ZRem("board", "bob", "carol", "dave")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removedMembers)
+	_, err = i.ZRange("board", 0, -1, false)
+	assert.ErrorIs(t, err, domain.ErrKeyNotFound, "removing the last member deletes the key")
+}
+
+func Test_storage_ZSetInvalidScores(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	_, err := i.ZAdd("board", map[string]float64{"max": math.MaxFloat64})
+	require.NoError(t, err)
+
+	_, err = i.ZAdd("board", map[string]float64{"a": math.NaN()})
+	assert.ErrorIs(t, err, domain.ErrNotFloat)
+	_, err = i.ZAdd("board", map[string]float64{"a": math.Inf(1)})
+	assert.ErrorIs(t, err, domain.ErrNotFloat)
+	_, err = i.ZIncrBy("board", "max", math.MaxFloat64)
+	assert.ErrorIs(t, err, domain.ErrNotFloat, "an infinite result is refused")
+}
+
+func Test_storage_ZSetWrongTypeAndExpiration(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+	_, err := i.SAdd("set", "a")
+	require.NoError(t, err)
+	_, err = i.ZAdd("zset", map[string]float64{"a": 1})
+	require.NoError(t, err)
+	require.NoError(t, i.Expire("zset", time.Hour))
+
+	_, err = i.ZAdd("set", map[string]float64{"a": 1})
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.SAdd("zset", "a")
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+	_, err = i.ZRange("set", 0, -1, false)
+	assert.ErrorIs(t, err, domain.ErrWrongType)
+
+	_, err = i.ZIncrBy("zset", "b", 1)
+	require.NoError(t, err)
+	ttl, err := i.TTL("zset")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 59*time.Minute, "the writes keep the expiration")
+}
+
+func Test_storage_ZSetRestore(t *testing.T) {
+	i := NewInMemory()
+	defer i.Close()
+
+	require.NoError(t, i.Restore(domain.Entity{Key: "board", Type: domain.TypeZSet,
+		ZSet: domain.ScoredMembers{{Member: "bob", Score: 20}, {Member: "alice", Score: 10}}}))
+
+	rank, err := i.ZRank("board", "bob", false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rank, "the restored members are ordered by score")
+	_, err = i.ZAdd("board", map[string]float64{"carol": 30})
+	require.NoError(t, err)
+	members, err := i.ZRange("board", 0, -1, false)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ScoredMember{{Member: "alice", Score: 10}, {Member: "bob", Score: 20}, {Member: "carol", Score: 30}}, members)
+}
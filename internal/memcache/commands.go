@@ -0,0 +1,400 @@
+package memcache
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/rs/zerolog/log"
+)
+
+// version is reported by the version command.
+const version = "1.6.0"
+
+// flushScanCount is the page size flush_all scans the keyspace with.
+const flushScanCount = 1000
+
+// Outcomes of the storage commands, returned from the update functions to abort the write.
+var (
+	errNotStored  = errors.New("not stored")
+	errExists     = errors.New("exists")
+	errNotFound   = errors.New("not found")
+	errNonNumeric = errors.New("cannot increment or decrement non-numeric value")
+)
+
+// command is a handler along with the shape of its input.
+// The handler reports whether the connection must be closed.
+type command struct {
+	// storage commands are followed by a data block.
+	storage bool
+	// noreply commands accept a trailing noreply argument.
+	noreply bool
+	handler func(s *Server, c *client, args []string, data []byte) bool
+}
+
+// commands are the supported commands by name.
+var commands = map[string]command{
+	"get":       {handler: get(false)},
+	"gets":      {handler: get(true)},
+	"set":       {storage: true, noreply: true, handler: store(modeSet)},
+	"add":       {storage: true, noreply: true, handler: store(modeAdd)},
+	"replace":   {storage: true, noreply: true, handler: store(modeReplace)},
+	"append":    {storage: true, noreply: true, handler: store(modeAppend)},
+	"prepend":   {storage: true, noreply: true, handler: store(modePrepend)},
+	"cas":       {storage: true, noreply: true, handler: store(modeCAS)},
+	"delete":    {noreply: true, handler: del},
+	"incr":      {noreply: true, handler: incr(false)},
+	"decr":      {noreply: true, handler: incr(true)},
+	"touch":     {noreply: true, handler: touch},
+	"flush_all": {noreply: true, handler: flushAll},
+	"stats":     {handler: stats},
+	"version":   {handler: versionCmd},
+	"quit":      {handler: quit},
+}
+
+// get <key>*
+// gets <key>*, which also returns the cas unique of every key.
+func get(withCAS bool) func(s *Server, c *client, args []string, _ []byte) bool {
+	return func(s *Server, c *client, args []string, _ []byte) bool {
+		if len(args) == 0 {
+			c.reply("ERROR")
+			return false
+		}
+
+		for _, key := range args {
+			s.stats.cmdGet.Add(1)
+			entity, err := s.repo.GetEntity(key)
+			if err != nil {
+				s.stats.getMisses.Add(1)
+				continue
+			}
+			s.stats.getHits.Add(1)
+
+			header := "VALUE " + key + " " + strconv.FormatUint(uint64(entity.Flags), 10) + " " + strconv.Itoa(len(entity.Value))
+			if withCAS {
+				header += " " + strconv.FormatUint(entity.Version, 10)
+			}
+			c.reply(header)
+			c.reply(entity.Value)
+		}
+		c.reply("END")
+		return false
+	}
+}
+
+// storeMode is the condition and the kind of write of a storage command.
+type storeMode int
+
+const (
+	modeSet storeMode = iota
+	modeAdd
+	modeReplace
+	modeAppend
+	modePrepend
+	modeCAS
+)
+
+// set|add|replace|append|prepend <key> <flags> <exptime> <bytes> [noreply]
+// cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+// append and prepend keep the flags and the expiration of the key, like in memcached.
+func store(mode storeMode) func(s *Server, c *client, args []string, data []byte) bool {
+	return func(s *Server, c *client, args []string, data []byte) bool {
+		s.stats.cmdSet.Add(1)
+
+		want := 4
+		if mode == modeCAS {
+			want = 5
+		}
+		if len(args) != want || !validKey(args[0]) {
+			c.clientError(errBadFormat)
+			return false
+		}
+		flags, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			c.clientError(errBadFormat)
+			return false
+		}
+		exptime, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			c.clientError(errBadFormat)
+			return false
+		}
+		var casUnique uint64
+		if mode == modeCAS {
+			if casUnique, err = strconv.ParseUint(args[4], 10, 64); err != nil {
+				c.clientError(errBadFormat)
+				return false
+			}
+		}
+
+		now := time.Now()
+		value := string(data)
+		entity := domain.Entity{
+			Value:      value,
+			Flags:      uint32(flags),
+			Expiration: expiration(ttl(exptime, now), now),
+		}
+
+		_, err = s.repo.Update(args[0], func(current domain.Entity, exists bool) (domain.Entity, error) {
+			switch {
+			case mode == modeAdd && exists:
+				return domain.Entity{}, errNotStored
+			case (mode == modeReplace || mode == modeAppend || mode == modePrepend) && !exists:
+				return domain.Entity{}, errNotStored
+			case mode == modeCAS && !exists:
+				return domain.Entity{}, errNotFound
+			case mode == modeCAS && current.Version != casUnique:
+				return domain.Entity{}, errExists
+			case (mode == modeAppend || mode == modePrepend) && current.Type != domain.TypeString:
+				return domain.Entity{}, domain.ErrWrongType
+			case mode == modeAppend:
+				current.Value += value
+				return current, nil
+			case mode == modePrepend:
+				current.Value = value + current.Value
+				return current, nil
+			}
+			return entity, nil
+		})
+		switch {
+		case err == nil:
+			c.reply("STORED")
+		case errors.Is(err, errNotStored):
+			c.reply("NOT_STORED")
+		case errors.Is(err, errExists):
+			c.reply("EXISTS")
+		case errors.Is(err, errNotFound):
+			c.reply("NOT_FOUND")
+		case errors.Is(err, domain.ErrOutOfMemory):
+			c.serverError(errors.New("out of memory storing object"))
+		default:
+			c.serverError(err)
+		}
+		return false
+	}
+}
+
+// delete <key> [noreply]
+func del(s *Server, c *client, args []string, _ []byte) bool {
+	if len(args) != 1 {
+		c.clientError(errBadFormat)
+		return false
+	}
+
+	err := s.repo.Delete(args[0])
+	switch {
+	case err == nil:
+		c.reply("DELETED")
+	case errors.Is(err, domain.ErrKeyNotFound) || errors.Is(err, domain.ErrKeyExpired):
+		c.reply("NOT_FOUND")
+	default:
+		c.serverError(err)
+	}
+	return false
+}
+
+// incr|decr <key> <value> [noreply]
+// The value of the key must be a decimal unsigned 64-bit integer.
+// incr wraps around at the 64-bit limit and decr stops at 0, like in memcached.
+func incr(decrement bool) func(s *Server, c *client, args []string, _ []byte) bool {
+	return func(s *Server, c *client, args []string, _ []byte) bool {
+		if len(args) != 2 {
+			c.clientError(errBadFormat)
+			return false
+		}
+		delta, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			c.clientError(errInvalidNumber)
+			return false
+		}
+
+		entity, err := s.repo.Update(args[0], func(current domain.Entity, exists bool) (domain.Entity, error) {
+			if !exists {
+				return domain.Entity{}, errNotFound
+			}
+			if current.Type != domain.TypeString {
+				return domain.Entity{}, domain.ErrWrongType
+			}
+			n, err := strconv.ParseUint(strings.TrimRight(current.Value, " "), 10, 64)
+			if err != nil {
+				return domain.Entity{}, errNonNumeric
+			}
+
+			switch {
+			case !decrement:
+				n += delta
+			case delta > n:
+				n = 0
+			default:
+				n -= delta
+			}
+			current.Value = strconv.FormatUint(n, 10)
+			return current, nil
+		})
+		switch {
+		case err == nil:
+			c.reply(entity.Value)
+		case errors.Is(err, errNotFound):
+			c.reply("NOT_FOUND")
+		case errors.Is(err, errNonNumeric):
+			c.clientError(err)
+		case errors.Is(err, domain.ErrOutOfMemory):
+			c.serverError(errors.New("out of memory"))
+		default:
+			c.serverError(err)
+		}
+		return false
+	}
+}
+
+// touch <key> <exptime> [noreply]
+func touch(s *Server, c *client, args []string, _ []byte) bool {
+	s.stats.cmdTouch.Add(1)
+	if len(args) != 2 {
+		c.clientError(errBadFormat)
+		return false
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.clientError(errors.New("invalid exptime argument"))
+		return false
+	}
+
+	now := time.Now()
+	_, err = s.repo.Update(args[0], func(current domain.Entity, exists bool) (domain.Entity, error) {
+		if !exists {
+			return domain.Entity{}, errNotFound
+		}
+		current.Expiration = expiration(ttl(exptime, now), now)
+		// An explicit touch ends the sliding, like EXPIRE.
+		current.Sliding, current.SlidingTTL = false, 0
+		return current, nil
+	})
+	switch {
+	case err == nil:
+		c.reply("TOUCHED")
+	case errors.Is(err, errNotFound):
+		c.reply("NOT_FOUND")
+	default:
+		c.serverError(err)
+	}
+	return false
+}
+
+// flush_all [delay] [noreply]
+// The keys are deleted one by one, so the ones written during the flush may survive it.
+func flushAll(s *Server, c *client, args []string, _ []byte) bool {
+	s.stats.cmdFlush.Add(1)
+	if len(args) > 1 {
+		c.clientError(errBadFormat)
+		return false
+	}
+
+	var delay int64
+	if len(args) == 1 {
+		var err error
+		if delay, err = strconv.ParseInt(args[0], 10, 64); err != nil || delay < 0 {
+			c.clientError(errBadFormat)
+			return false
+		}
+	}
+
+	s.flushMu.Lock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	if delay > 0 {
+		s.flushTimer = time.AfterFunc(time.Duration(delay)*time.Second, s.flush)
+	}
+	s.flushMu.Unlock()
+
+	if delay == 0 {
+		s.flush()
+	}
+	c.reply("OK")
+	return false
+}
+
+// flush deletes every key of the storage.
+func (s *Server) flush() {
+	var cursor uint64
+	for {
+		keys, next, err := s.repo.Scan(cursor, "", flushScanCount)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to flush the storage")
+			return
+		}
+		for _, key := range keys {
+			_ = s.repo.Delete(key)
+		}
+		if next == 0 {
+			return
+		}
+		cursor = next
+	}
+}
+
+// stats
+func stats(s *Server, c *client, args []string, _ []byte) bool {
+	if len(args) > 0 {
+		c.reply("ERROR")
+		return false
+	}
+
+	var st domain.Stats
+	if provider, ok := s.repo.(domain.StatsProvider); ok {
+		st = provider.Stats()
+	}
+
+	now := time.Now()
+	for _, stat := range [][2]string{
+		{"pid", strconv.Itoa(os.Getpid())},
+		{"uptime", strconv.FormatInt(int64(now.Sub(s.started)/time.Second), 10)},
+		{"time", strconv.FormatInt(now.Unix(), 10)},
+		{"version", version},
+		{"curr_connections", strconv.Itoa(s.Active())},
+		{"total_connections", strconv.FormatUint(s.Accepted(), 10)},
+		{"cmd_get", strconv.FormatUint(s.stats.cmdGet.Load(), 10)},
+		{"cmd_set", strconv.FormatUint(s.stats.cmdSet.Load(), 10)},
+		{"cmd_flush", strconv.FormatUint(s.stats.cmdFlush.Load(), 10)},
+		{"cmd_touch", strconv.FormatUint(s.stats.cmdTouch.Load(), 10)},
+		{"get_hits", strconv.FormatUint(s.stats.getHits.Load(), 10)},
+		{"get_misses", strconv.FormatUint(s.stats.getMisses.Load(), 10)},
+		{"curr_items", strconv.FormatInt(st.Keys, 10)},
+		{"bytes", strconv.FormatInt(st.UsedMemory, 10)},
+		{"limit_maxbytes", strconv.FormatInt(st.MaxMemory, 10)},
+		{"evictions", strconv.FormatUint(st.EvictedKeys, 10)},
+	} {
+		c.reply("STAT " + stat[0] + " " + stat[1])
+	}
+	c.reply("END")
+	return false
+}
+
+// version
+func versionCmd(_ *Server, c *client, _ []string, _ []byte) bool {
+	c.reply("VERSION " + version)
+	return false
+}
+
+// quit
+func quit(*Server, *client, []string, []byte) bool {
+	return true
+}
+
+// validKey reports whether the key is accepted by memcached: at most 250 bytes without spaces or control characters.
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLength {
+		return false
+	}
+	for idx := 0; idx < len(key); idx++ {
+		if key[idx] <= ' ' || key[idx] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package memcache
+
+import "time"
+
+// relativeExptimeLimit is the largest exptime taken as seconds from now, larger ones are unix timestamps.
+const relativeExptimeLimit = 30 * 24 * 60 * 60
+
+// ttl translates a memcached exptime into a time to live.
+// 0 means the key does not expire, exptimes up to 30 days are seconds from now
+// and larger ones are absolute unix times. A negative result means the key has already expired,
+// as it does for a negative exptime or a unix time in the past.
+func ttl(exptime int64, now time.Time) time.Duration {
+	switch {
+	case exptime == 0:
+		return 0
+	case exptime < 0:
+		return -time.Nanosecond
+	case exptime <= relativeExptimeLimit:
+		return time.Duration(exptime) * time.Second
+	}
+
+	d := time.Unix(exptime, 0).Sub(now)
+	if d <= 0 {
+		return -time.Nanosecond
+	}
+	return d
+}
+
+// expiration returns the absolute expiration in nanoseconds of an entity with the ttl, 0 if it does not expire.
+func expiration(ttl time.Duration, now time.Time) int64 {
+	if ttl == 0 {
+		return 0
+	}
+	return now.Add(ttl).UnixNano()
+}
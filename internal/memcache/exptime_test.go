@@ -0,0 +1,30 @@
+package memcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ttl(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name    string
+		exptime int64
+		want    time.Duration
+	}{
+		{name: "0 never expires", exptime: 0, want: 0},
+		{name: "Negative has already expired", exptime: -1, want: -time.Nanosecond},
+		{name: "Seconds from now", exptime: 60, want: time.Minute},
+		{name: "30 days is still relative", exptime: relativeExptimeLimit, want: 30 * 24 * time.Hour},
+		{name: "Above 30 days is a unix time", exptime: now.Unix() + 90, want: 90 * time.Second},
+		{name: "Unix time in the past has already expired", exptime: now.Unix() - 90, want: -time.Nanosecond},
+		{name: "Unix time of now has already expired", exptime: now.Unix(), want: -time.Nanosecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ttl(tt.exptime, now))
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Package memcache provides a TCP listener speaking the memcached ASCII protocol
+// on top of the domain.Repository, for the clients that only have a memcached driver.
+// Implements the fallowing commands:
+/*
+	get <key>*
+	gets <key>*
+	set|add|replace|append|prepend <key> <flags> <exptime> <bytes> [noreply]
+	cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+	delete <key> [noreply]
+	incr|decr <key> <value> [noreply]
+	touch <key> <exptime> [noreply]
+	flush_all [delay] [noreply]
+	stats
+	version
+	quit
+*/
+// The cas unique of a key is its domain.Entity.Version and the flags are kept in domain.Entity.Flags.
+package memcache
@@ -0,0 +1,245 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/tcpserver"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxLineLength is the maximum length of a command line, enough for a get of several maximum length keys.
+	maxLineLength = 8 << 10
+	// maxKeyLength is the maximum length of a key, like in memcached.
+	maxKeyLength = 250
+	// maxItemSize is the maximum size of a value, the default item size limit of memcached.
+	maxItemSize = 1 << 20
+)
+
+var (
+	// ErrServerClosed is returned by Serve and ListenAndServe after Shutdown was called.
+	ErrServerClosed = tcpserver.ErrServerClosed
+
+	errLineTooLong   = errors.New("line too long")
+	errBadDataChunk  = errors.New("bad data chunk")
+	errBadFormat     = errors.New("bad command line format")
+	errItemTooLarge  = errors.New("object too large for cache")
+	errInvalidNumber = errors.New("invalid numeric delta argument")
+)
+
+// Server serves the storage to memcached clients over TCP.
+// ListenAndServe and Serve come from the embedded tcpserver.Server.
+type Server struct {
+	*tcpserver.Server
+	repo    domain.Repository
+	limiter domain.RateLimiter
+	started time.Time
+	stats   counters
+
+	// flushTimer is the pending delayed flush_all.
+	flushMu    sync.Mutex
+	flushTimer *time.Timer
+}
+
+// counters are the command statistics reported by stats.
+type counters struct {
+	cmdGet    atomic.Uint64
+	cmdSet    atomic.Uint64
+	cmdTouch  atomic.Uint64
+	cmdFlush  atomic.Uint64
+	getHits   atomic.Uint64
+	getMisses atomic.Uint64
+}
+
+// NewServer creates a server listening on addr, e.g. ":11211".
+// Every command counts as a request for the limiter, keyed by the client IP.
+func NewServer(addr string, repo domain.Repository, limiter domain.RateLimiter) *Server {
+	s := &Server{
+		repo:    repo,
+		limiter: limiter,
+		started: time.Now(),
+	}
+	s.Server = tcpserver.New(addr, s.serve)
+	return s
+}
+
+// Shutdown cancels a pending delayed flush_all and shuts the listener down, see tcpserver.Server.Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.flushMu.Lock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+	}
+	s.flushMu.Unlock()
+	return s.Server.Shutdown(ctx)
+}
+
+// serve runs the commands of the client until it quits, the connection fails or the server shuts down.
+func (s *Server) serve(conn net.Conn) {
+	c := &client{
+		r:  bufio.NewReaderSize(conn, maxLineLength),
+		w:  bufio.NewWriter(conn),
+		ip: remoteIP(conn.RemoteAddr().String()),
+	}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				c.clientError(err)
+				_ = c.w.Flush()
+			} else if !errors.Is(err, io.EOF) && !s.ShuttingDown() {
+				log.Debug().Err(err).Str("client", conn.RemoteAddr().String()).Msg("Memcached connection failed")
+			}
+			return
+		}
+
+		done, err := s.execute(c, strings.Fields(line))
+		if err != nil {
+			// The rest of the input can not be parsed after a broken data block.
+			c.clientError(err)
+			done = true
+		}
+		done = done || s.ShuttingDown()
+		// Replies to pipelined commands are flushed together.
+		if done || c.r.Buffered() == 0 {
+			if err = c.w.Flush(); err != nil {
+				return
+			}
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// execute runs a single command and reports whether the connection must be closed.
+// It returns an error when the data block of a storage command is malformed.
+func (s *Server) execute(c *client, fields []string) (bool, error) {
+	if len(fields) == 0 {
+		c.reply("ERROR")
+		return false, nil
+	}
+
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		c.reply("ERROR")
+		return false, nil
+	}
+	args := fields[1:]
+
+	c.noreply = false
+	if cmd.noreply && len(args) > 0 && args[len(args)-1] == "noreply" {
+		c.noreply = true
+		args = args[:len(args)-1]
+	}
+
+	var data []byte
+	if cmd.storage {
+		var err error
+		data, err = c.readData(args)
+		if errors.Is(err, errItemTooLarge) {
+			c.serverError(err)
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if s.limiter != nil {
+		if !s.limiter.Check(c.ip) {
+			c.serverError(errors.New("rate limit exceeded"))
+			return false, nil
+		}
+		s.limiter.Limit(c.ip)
+	}
+
+	return cmd.handler(s, c, args, data), nil
+}
+
+// client is a connection of a memcached client.
+type client struct {
+	r  *bufio.Reader
+	w  *bufio.Writer
+	ip string
+	// noreply is set when the current command asked not to be answered.
+	noreply bool
+}
+
+// readLine reads a command line without the trailing CRLF.
+func (c *client) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// readData reads the data block following a storage command, its size is the fourth argument.
+// A block over maxItemSize is discarded and errItemTooLarge is returned.
+func (c *client) readData(args []string) ([]byte, error) {
+	if len(args) < 4 {
+		return nil, errBadFormat
+	}
+	size, err := strconv.Atoi(args[3])
+	if err != nil || size < 0 {
+		return nil, errBadFormat
+	}
+
+	if size > maxItemSize {
+		if _, err = c.r.Discard(size + 2); err != nil {
+			return nil, err
+		}
+		return nil, errItemTooLarge
+	}
+
+	data := make([]byte, size+2)
+	if _, err = io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		return nil, errBadDataChunk
+	}
+	return data[:size], nil
+}
+
+// reply writes a line unless the command asked for no reply.
+func (c *client) reply(line string) {
+	if c.noreply {
+		return
+	}
+	_, _ = c.w.WriteString(line)
+	_, _ = c.w.WriteString("\r\n")
+}
+
+// clientError replies that the command was malformed, it is sent even with noreply.
+func (c *client) clientError(err error) {
+	c.noreply = false
+	c.reply("CLIENT_ERROR " + err.Error())
+}
+
+// serverError replies that the command could not be executed.
+func (c *client) serverError(err error) {
+	c.reply("SERVER_ERROR " + err.Error())
+}
+
+// remoteIP strips the port off the remote address, so all the connections of a host share a rate limit.
+func remoteIP(addr string) string {
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return ip
+}
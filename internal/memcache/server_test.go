@@ -0,0 +1,243 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer serves an empty storage on a random port and returns a connection to it.
+func startServer(t *testing.T) (*Server, *testClient) {
+	repo := storage.NewInMemory()
+	t.Cleanup(func() { _ = repo.Close() })
+
+	srv := NewServer("127.0.0.1:0", repo, nil)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return srv, &testClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// testClient speaks the memcached protocol to a server.
+type testClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// do sends the raw request and reads the number of reply lines.
+func (c *testClient) do(t *testing.T, request string, lines int) string {
+	_, err := c.conn.Write([]byte(request))
+	require.NoError(t, err)
+
+	var reply strings.Builder
+	for ; lines > 0; lines-- {
+		line, err := c.r.ReadString('\n')
+		require.NoError(t, err)
+		reply.WriteString(line)
+	}
+	return reply.String()
+}
+
+func TestServer_Commands(t *testing.T) {
+	type step struct {
+		request string
+		lines   int
+		want    string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "set then get returns the value and the flags",
+			steps: []step{
+				{"set key 42 0 5\r\nvalue\r\n", 1, "STORED\r\n"},
+				{"get key missing\r\n", 3, "VALUE key 42 5\r\nvalue\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "add stores only a missing key",
+			steps: []step{
+				{"add key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"add key 0 0 1\r\nb\r\n", 1, "NOT_STORED\r\n"},
+				{"get key\r\n", 3, "VALUE key 0 1\r\na\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "replace stores only an existing key",
+			steps: []step{
+				{"replace key 0 0 1\r\na\r\n", 1, "NOT_STORED\r\n"},
+				{"set key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"replace key 0 0 1\r\nb\r\n", 1, "STORED\r\n"},
+				{"get key\r\n", 3, "VALUE key 0 1\r\nb\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "append and prepend keep the flags",
+			steps: []step{
+				{"append key 0 0 1\r\na\r\n", 1, "NOT_STORED\r\n"},
+				{"set key 7 0 1\r\nb\r\n", 1, "STORED\r\n"},
+				{"append key 0 0 1\r\nc\r\n", 1, "STORED\r\n"},
+				{"prepend key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"get key\r\n", 3, "VALUE key 7 3\r\nabc\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "cas stores only with the current cas unique",
+			steps: []step{
+				{"cas key 0 0 1 1\r\na\r\n", 1, "NOT_FOUND\r\n"},
+				{"set key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"gets key\r\n", 3, "VALUE key 0 1 1\r\na\r\nEND\r\n"},
+				{"cas key 0 0 1 1\r\nb\r\n", 1, "STORED\r\n"},
+				{"cas key 0 0 1 1\r\nc\r\n", 1, "EXISTS\r\n"},
+				{"gets key\r\n", 3, "VALUE key 0 1 2\r\nb\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "delete removes the key",
+			steps: []step{
+				{"set key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"delete key\r\n", 1, "DELETED\r\n"},
+				{"delete key\r\n", 1, "NOT_FOUND\r\n"},
+				{"get key\r\n", 1, "END\r\n"},
+			},
+		},
+		{
+			name: "incr wraps around and decr stops at 0",
+			steps: []step{
+				{"incr key 1\r\n", 1, "NOT_FOUND\r\n"},
+				{"set key 0 0 20\r\n18446744073709551615\r\n", 1, "STORED\r\n"},
+				{"incr key 2\r\n", 1, "1\r\n"},
+				{"decr key 5\r\n", 1, "0\r\n"},
+				{"set key 0 0 3\r\nabc\r\n", 1, "STORED\r\n"},
+				{"incr key 1\r\n", 1, "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+				{"incr key x\r\n", 1, "CLIENT_ERROR invalid numeric delta argument\r\n"},
+			},
+		},
+		{
+			name: "negative exptime expires the key right away",
+			steps: []step{
+				{"set key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"set key 0 -1 1\r\nb\r\n", 1, "STORED\r\n"},
+				{"get key\r\n", 1, "END\r\n"},
+			},
+		},
+		{
+			name: "touch updates the expiration",
+			steps: []step{
+				{"touch key 10\r\n", 1, "NOT_FOUND\r\n"},
+				{"set key 0 0 1\r\na\r\n", 1, "STORED\r\n"},
+				{"touch key -1\r\n", 1, "TOUCHED\r\n"},
+				{"get key\r\n", 1, "END\r\n"},
+			},
+		},
+		{
+			name: "noreply suppresses the reply",
+			steps: []step{
+				{"set key 0 0 1 noreply\r\na\r\nget key\r\n", 3, "VALUE key 0 1\r\na\r\nEND\r\n"},
+			},
+		},
+		{
+			name: "flush_all deletes every key",
+			steps: []step{
+				{"set a 0 0 1\r\na\r\nset b 0 0 1\r\nb\r\n", 2, "STORED\r\nSTORED\r\n"},
+				{"flush_all\r\n", 1, "OK\r\n"},
+				{"get a b\r\n", 1, "END\r\n"},
+			},
+		},
+		{
+			name: "version and unknown commands",
+			steps: []step{
+				{"version\r\n", 1, "VERSION 1.6.0\r\n"},
+				{"bogus\r\n", 1, "ERROR\r\n"},
+			},
+		},
+		{
+			name: "an invalid key is refused",
+			steps: []step{
+				{"set " + strings.Repeat("k", maxKeyLength+1) + " 0 0 1\r\na\r\n", 1, "CLIENT_ERROR bad command line format\r\n"},
+			},
+		},
+		{
+			name: "a value over the item size is refused and discarded",
+			steps: []step{
+				{"set key 0 0 1048577\r\n" + strings.Repeat("v", maxItemSize+1) + "\r\n", 1, "SERVER_ERROR object too large for cache\r\n"},
+				{"get key\r\n", 1, "END\r\n"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, c := startServer(t)
+			for _, step := range tt.steps {
+				assert.Equal(t, step.want, c.do(t, step.request, step.lines), "reply to %q", step.request)
+			}
+		})
+	}
+}
+
+func TestServer_TouchEndsSliding(t *testing.T) {
+	srv, c := startServer(t)
+	_, err := srv.repo.SetWith("key", "a", time.Hour, domain.SetOptions{Sliding: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, "TOUCHED\r\n", c.do(t, "touch key 100\r\n", 1))
+
+	entity, err := srv.repo.GetEntity("key")
+	require.NoError(t, err)
+	assert.False(t, entity.Sliding)
+	assert.Zero(t, entity.SlidingTTL)
+	ttl, err := srv.repo.TTL("key")
+	require.NoError(t, err)
+	assert.InDelta(t, 100*time.Second, ttl, float64(time.Second), "the read does not push the touched expiration")
+}
+
+func TestServer_BadDataChunk(t *testing.T) {
+	_, c := startServer(t)
+
+	assert.Equal(t, "CLIENT_ERROR bad data chunk\r\n", c.do(t, "set key 0 0 1\r\nabc\r\n", 1))
+	_, err := c.r.ReadString('\n')
+	assert.Error(t, err, "the connection is closed after a broken data block")
+}
+
+func TestServer_Stats(t *testing.T) {
+	_, c := startServer(t)
+
+	c.do(t, "set key 0 0 1\r\na\r\n", 1)
+	c.do(t, "get key missing\r\n", 3)
+
+	_, err := c.conn.Write([]byte("stats\r\n"))
+	require.NoError(t, err)
+	var reply strings.Builder
+	for !strings.HasSuffix(reply.String(), "END\r\n") {
+		line, err := c.r.ReadString('\n')
+		require.NoError(t, err)
+		reply.WriteString(line)
+	}
+	assert.Contains(t, reply.String(), "STAT curr_items 1\r\n")
+	assert.Contains(t, reply.String(), "STAT get_hits 1\r\n")
+	assert.Contains(t, reply.String(), "STAT get_misses 1\r\n")
+}
+
+func TestServer_Quit(t *testing.T) {
+	_, c := startServer(t)
+
+	_, err := c.conn.Write([]byte("quit\r\n"))
+	require.NoError(t, err)
+	_, err = c.r.ReadString('\n')
+	assert.Error(t, err)
+}
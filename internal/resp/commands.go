@@ -0,0 +1,605 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+)
+
+const (
+	// version is reported by HELLO and INFO, clients use it to detect the supported commands.
+	version = "7.0.0"
+	// defaultScanCount is the COUNT of SCAN when it is not given.
+	defaultScanCount = 10
+	// keysScanCount is the page size KEYS scans the keyspace with.
+	keysScanCount = 1000
+)
+
+// command is a handler along with its arity, counting the command name.
+// A positive arity is the exact number of arguments, a negative one is the minimum, like in Redis.
+// The handler reports whether the connection must be closed.
+// Only the subscribed commands are allowed to a RESP2 client with subscriptions.
+type command struct {
+	arity      int
+	handler    func(s *Server, c *client, args []string) bool
+	subscribed bool
+}
+
+// commands are the supported commands by their lower case name.
+var commands = map[string]command{
+	"get":      {arity: 2, handler: get},
+	"set":      {arity: -3, handler: set},
+	"del":      {arity: -2, handler: del},
+	"exists":   {arity: -2, handler: exists},
+	"ttl":      {arity: 2, handler: ttl},
+	"pttl":     {arity: 2, handler: pttl},
+	"expire":   {arity: 3, handler: expire},
+	"expireat": {arity: 3, handler: expireat},
+	"persist":  {arity: 2, handler: persist},
+	"getex":    {arity: -2, handler: getex},
+	"keys":     {arity: 2, handler: keys},
+	"scan":     {arity: -2, handler: scan},
+	"ping":     {arity: -1, handler: ping, subscribed: true},
+	"info":     {arity: -1, handler: info},
+	"hello":    {arity: -1, handler: hello},
+	"quit":     {arity: -1, handler: quit, subscribed: true},
+
+	"incr":        {arity: 2, handler: incr},
+	"decr":        {arity: 2, handler: decr},
+	"incrby":      {arity: 3, handler: incrby},
+	"decrby":      {arity: 3, handler: decrby},
+	"incrbyfloat": {arity: 3, handler: incrbyfloat},
+
+	"hset":    {arity: -4, handler: hset},
+	"hget":    {arity: 3, handler: hget},
+	"hexists": {arity: 3, handler: hexists},
+	"hdel":    {arity: -3, handler: hdel},
+	"hgetall": {arity: 2, handler: hgetall},
+	"hlen":    {arity: 2, handler: hlen},
+	"hincrby": {arity: 4, handler: hincrby},
+
+	"lpush":  {arity: -3, handler: lpush},
+	"rpush":  {arity: -3, handler: rpush},
+	"lpop":   {arity: 2, handler: lpop},
+	"rpop":   {arity: 2, handler: rpop},
+	"lrange": {arity: 4, handler: lrange},
+	"llen":   {arity: 2, handler: llen},
+	"ltrim":  {arity: 4, handler: ltrim},
+	"lindex": {arity: 3, handler: lindex},
+	"blpop":  {arity: -3, handler: blpop},
+	"brpop":  {arity: -3, handler: brpop},
+
+	"sadd":      {arity: -3, handler: sadd},
+	"srem":      {arity: -3, handler: srem},
+	"smembers":  {arity: 2, handler: smembers},
+	"sismember": {arity: 3, handler: sismember},
+	"sinter":    {arity: -2, handler: sinter},
+	"sunion":    {arity: -2, handler: sunion},
+	"sdiff":     {arity: -2, handler: sdiff},
+
+	"zadd":     {arity: -4, handler: zadd},
+	"zincrby":  {arity: 4, handler: zincrby},
+	"zscore":   {arity: 3, handler: zscore},
+	"zrank":    {arity: 3, handler: zrank},
+	"zrevrank": {arity: 3, handler: zrevrank},
+	"zrem":     {arity: -3, handler: zrem},
+	"zrange":   {arity: -4, handler: zrange},
+	"zcount":   {arity: 4, handler: zcount},
+
+	"subscribe":    {arity: -2, handler: subscribe, subscribed: true},
+	"psubscribe":   {arity: -2, handler: psubscribe, subscribed: true},
+	"unsubscribe":  {arity: -1, handler: unsubscribe, subscribed: true},
+	"punsubscribe": {arity: -1, handler: punsubscribe, subscribed: true},
+	"publish":      {arity: 3, handler: publish},
+	"pubsub":       {arity: -2, handler: pubsubCommand},
+}
+
+// GET key
+func get(s *Server, c *client, args []string) bool {
+	value, err := s.repo.Get(args[0])
+	switch {
+	case isMissing(err):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.bulk(value)
+	}
+	return false
+}
+
+// SET key value [EX seconds | PX milliseconds] [NX | XX]
+// A write prevented by NX or XX replies with null.
+func set(s *Server, c *client, args []string) bool {
+	var ttl time.Duration
+	var opts domain.SetOptions
+	for idx := 2; idx < len(args); idx++ {
+		switch opt := strings.ToUpper(args[idx]); {
+		case (opt == "EX" || opt == "PX") && ttl == 0 && idx+1 < len(args):
+			idx++
+			n, err := strconv.ParseInt(args[idx], 10, 64)
+			if err != nil {
+				c.writer.error("ERR value is not an integer or out of range")
+				return false
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			if n <= 0 || expireOverflows(n, unit) {
+				c.writer.error("ERR invalid expire time in 'set' command")
+				return false
+			}
+			ttl = time.Duration(n) * unit
+		case opt == "NX" && opts.Mode == domain.SetAlways:
+			opts.Mode = domain.SetIfAbsent
+		case opt == "XX" && opts.Mode == domain.SetAlways:
+			opts.Mode = domain.SetIfPresent
+		default:
+			c.writer.error("ERR syntax error")
+			return false
+		}
+	}
+
+	_, err := s.repo.SetWith(args[0], args[1], ttl, opts)
+	switch {
+	case errors.Is(err, domain.ErrConditionNotMet):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.simple("OK")
+	}
+	return false
+}
+
+// DEL key [key ...]
+func del(s *Server, c *client, args []string) bool {
+	var deleted int64
+	for _, key := range args {
+		err := s.repo.Delete(key)
+		if err != nil && !isMissing(err) {
+			replyError(c, err)
+			return false
+		}
+		if err == nil {
+			deleted++
+		}
+	}
+	c.writer.integer(deleted)
+	return false
+}
+
+// EXISTS key [key ...]
+// A key given several times is counted several times, like in Redis.
+func exists(s *Server, c *client, args []string) bool {
+	var found int64
+	for _, key := range args {
+		if _, err := s.repo.TTL(key); err == nil {
+			found++
+		}
+	}
+	c.writer.integer(found)
+	return false
+}
+
+// TTL key
+func ttl(s *Server, c *client, args []string) bool {
+	return replyTTL(s, c, args[0], time.Second)
+}
+
+// PTTL key
+func pttl(s *Server, c *client, args []string) bool {
+	return replyTTL(s, c, args[0], time.Millisecond)
+}
+
+// replyTTL replies with the remaining time to live of the key in units,
+// -2 if the key does not exist and -1 if it does not expire.
+func replyTTL(s *Server, c *client, key string, unit time.Duration) bool {
+	d, err := s.repo.TTL(key)
+	switch {
+	case isMissing(err):
+		c.writer.integer(-2)
+	case err != nil:
+		replyError(c, err)
+	case d == domain.NoExpiration:
+		c.writer.integer(-1)
+	default:
+		// Rounded like in Redis, so a fresh EX 10 reports 10 rather than 9.
+		c.writer.integer(int64((d + unit/2) / unit))
+	}
+	return false
+}
+
+// expireOverflows reports whether n units from the unix epoch, or from now, do not fit in nanoseconds,
+// so they would wrap around to a time in the past and delete the key.
+func expireOverflows(n int64, unit time.Duration) bool {
+	return n > math.MaxInt64/int64(unit)
+}
+
+// EXPIRE key seconds
+// A non-positive ttl deletes the key.
+func expire(s *Server, c *client, args []string) bool {
+	seconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return false
+	}
+	if expireOverflows(seconds, time.Second) {
+		c.writer.error("ERR invalid expire time in 'expire' command")
+		return false
+	}
+
+	err = s.repo.Expire(args[0], time.Duration(seconds)*time.Second)
+	switch {
+	case isMissing(err):
+		c.writer.integer(0)
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(1)
+	}
+	return false
+}
+
+// EXPIREAT key unix-time-seconds
+// A time in the past deletes the key.
+func expireat(s *Server, c *client, args []string) bool {
+	seconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return false
+	}
+	if expireOverflows(seconds, time.Second) {
+		c.writer.error("ERR invalid expire time in 'expireat' command")
+		return false
+	}
+
+	err = s.repo.ExpireAt(args[0], time.Unix(seconds, 0))
+	switch {
+	case isMissing(err):
+		c.writer.integer(0)
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(1)
+	}
+	return false
+}
+
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+// An absolute time in the past expires the key right after the read.
+func getex(s *Server, c *client, args []string) bool {
+	var ttl time.Duration
+	switch {
+	case len(args) == 2 && strings.ToUpper(args[1]) == "PERSIST":
+		ttl = domain.NoExpiration
+	case len(args) == 3:
+		n, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			c.writer.error("ERR value is not an integer or out of range")
+			return false
+		}
+		unit := time.Second
+		switch strings.ToUpper(args[1]) {
+		case "EX":
+			ttl = time.Duration(n) * time.Second
+		case "PX":
+			ttl, unit = time.Duration(n)*time.Millisecond, time.Millisecond
+		case "EXAT":
+			ttl = time.Until(time.Unix(n, 0))
+		case "PXAT":
+			ttl, unit = time.Until(time.UnixMilli(n)), time.Millisecond
+		default:
+			c.writer.error("ERR syntax error")
+			return false
+		}
+		if n <= 0 || expireOverflows(n, unit) {
+			c.writer.error("ERR invalid expire time in 'getex' command")
+			return false
+		}
+		if ttl <= 0 {
+			ttl = time.Nanosecond
+		}
+	case len(args) != 1:
+		c.writer.error("ERR syntax error")
+		return false
+	}
+
+	value, err := s.repo.GetEx(args[0], ttl)
+	switch {
+	case isMissing(err):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.bulk(value)
+	}
+	return false
+}
+
+// PERSIST key
+func persist(s *Server, c *client, args []string) bool {
+	removed, err := s.repo.Persist(args[0])
+	switch {
+	case isMissing(err) || (err == nil && !removed):
+		c.writer.integer(0)
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(1)
+	}
+	return false
+}
+
+// KEYS pattern
+// The keyspace is scanned page by page, so the shards are not locked all at once.
+func keys(s *Server, c *client, args []string) bool {
+	var result []string
+	var cursor uint64
+	for {
+		page, next, err := s.repo.Scan(cursor, args[0], keysScanCount)
+		if err != nil {
+			replyError(c, err)
+			return false
+		}
+		result = append(result, page...)
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	c.writer.strings(result)
+	return false
+}
+
+// SCAN cursor [MATCH pattern] [COUNT count]
+func scan(s *Server, c *client, args []string) bool {
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		c.writer.error("ERR invalid cursor")
+		return false
+	}
+
+	match, count := "", defaultScanCount
+	var typ domain.ValueType
+	var filterType bool
+	for idx := 1; idx < len(args); idx += 2 {
+		if idx+1 >= len(args) {
+			c.writer.error("ERR syntax error")
+			return false
+		}
+		switch strings.ToUpper(args[idx]) {
+		case "MATCH":
+			match = args[idx+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[idx+1])
+			if err != nil || count < 1 {
+				c.writer.error("ERR value is out of range, must be positive")
+				return false
+			}
+		case "TYPE":
+			var ok bool
+			if typ, ok = domain.ParseValueType(strings.ToLower(args[idx+1])); !ok {
+				c.writer.error("ERR unknown type name '" + args[idx+1] + "'")
+				return false
+			}
+			filterType = true
+		default:
+			c.writer.error("ERR syntax error")
+			return false
+		}
+	}
+
+	var page []string
+	var next uint64
+	if filterType {
+		// Like in Redis, the type is filtered after reading the page, which may come out shorter or empty.
+		var entities []domain.Entity
+		entities, next, err = s.repo.ScanEntities(cursor, match, count)
+		for _, entity := range entities {
+			if entity.Type == typ {
+				page = append(page, entity.Key)
+			}
+		}
+	} else {
+		page, next, err = s.repo.Scan(cursor, match, count)
+	}
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.array(2)
+	c.writer.bulk(strconv.FormatUint(next, 10))
+	c.writer.strings(page)
+	return false
+}
+
+// PING [message]
+// A subscribed client gets the pong as a push, like a message.
+func ping(_ *Server, c *client, args []string) bool {
+	message := ""
+	if len(args) == 1 {
+		message = args[0]
+	}
+
+	switch {
+	case len(args) > 1:
+		c.writer.error("ERR wrong number of arguments for 'ping' command")
+	case c.subscribed():
+		c.writer.push(2)
+		c.writer.bulk("pong")
+		c.writer.bulk(message)
+	case len(args) == 1:
+		c.writer.bulk(message)
+	default:
+		c.writer.simple("PONG")
+	}
+	return false
+}
+
+// INFO [section]
+// The sections are server, clients, stats, memory and keyspace.
+func info(s *Server, c *client, args []string) bool {
+	if len(args) > 1 {
+		c.writer.error("ERR syntax error")
+		return false
+	}
+	section := "all"
+	if len(args) == 1 {
+		section = strings.ToLower(args[0])
+	}
+
+	var stats domain.Stats
+	if provider, ok := s.repo.(domain.StatsProvider); ok {
+		stats = provider.Stats()
+	}
+	var pubsubStats pubsub.Stats
+	if s.broker != nil {
+		pubsubStats = s.broker.Stats()
+	}
+
+	var b strings.Builder
+	add := func(name string, lines ...string) {
+		if section != "all" && section != "default" && section != name {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + strings.ToUpper(name[:1]) + name[1:] + "\r\n")
+		for _, line := range lines {
+			b.WriteString(line + "\r\n")
+		}
+	}
+
+	add("server",
+		"redis_version:"+version,
+		"redis_mode:standalone",
+		"os:"+runtime.GOOS+" "+runtime.GOARCH,
+		"process_id:"+strconv.Itoa(os.Getpid()),
+		"tcp_port:"+port(s),
+		"uptime_in_seconds:"+strconv.FormatInt(int64(time.Since(s.started)/time.Second), 10),
+	)
+	add("clients",
+		"connected_clients:"+strconv.Itoa(s.Active()),
+	)
+	add("memory",
+		"used_memory:"+strconv.FormatInt(stats.UsedMemory, 10),
+		"maxmemory:"+strconv.FormatInt(stats.MaxMemory, 10),
+		"maxmemory_policy:"+stats.EvictionPolicy,
+	)
+	add("stats",
+		"total_connections_received:"+strconv.FormatUint(s.Accepted(), 10),
+		"total_commands_processed:"+strconv.FormatUint(s.commands.Load(), 10),
+		"expired_keys:"+strconv.FormatUint(stats.ExpiredKeys, 10),
+		"evicted_keys:"+strconv.FormatUint(stats.EvictedKeys, 10),
+		"pubsub_channels:"+strconv.Itoa(pubsubStats.Channels),
+		"pubsub_patterns:"+strconv.Itoa(pubsubStats.Patterns),
+		"pubsub_delivered_messages:"+strconv.FormatUint(pubsubStats.Delivered, 10),
+		"pubsub_dropped_messages:"+strconv.FormatUint(pubsubStats.Dropped, 10),
+	)
+	add("keyspace",
+		fmt.Sprintf("db0:keys=%d,expires=%d,avg_ttl=0", stats.Keys, stats.KeysWithTTL),
+	)
+
+	c.writer.verbatim(b.String())
+	return false
+}
+
+// port returns the port the server listens on.
+func port(s *Server) string {
+	addr := s.Addr()
+	if addr == nil {
+		return ""
+	}
+	_, p, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+// HELLO [protover [SETNAME name]]
+// Switches the connection to the protocol version and replies with the server properties.
+func hello(s *Server, c *client, args []string) bool {
+	proto := c.writer.proto
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			c.writer.error("ERR Protocol version is not an integer or out of range")
+			return false
+		}
+		if v != 2 && v != 3 {
+			c.writer.error("NOPROTO unsupported protocol version")
+			return false
+		}
+		proto = v
+
+		for idx := 1; idx < len(args); idx += 2 {
+			if strings.ToUpper(args[idx]) != "SETNAME" || idx+1 >= len(args) {
+				c.writer.error("ERR syntax error in HELLO option '" + args[idx] + "'")
+				return false
+			}
+			c.name = args[idx+1]
+		}
+	}
+	c.writer.proto = proto
+
+	c.writer.mapHeader(7)
+	c.writer.bulk("server")
+	c.writer.bulk("redis")
+	c.writer.bulk("version")
+	c.writer.bulk(version)
+	c.writer.bulk("proto")
+	c.writer.integer(int64(proto))
+	c.writer.bulk("id")
+	c.writer.integer(c.id)
+	c.writer.bulk("mode")
+	c.writer.bulk("standalone")
+	c.writer.bulk("role")
+	c.writer.bulk("master")
+	c.writer.bulk("modules")
+	c.writer.array(0)
+	return false
+}
+
+// QUIT
+func quit(_ *Server, c *client, _ []string) bool {
+	c.writer.simple("OK")
+	return true
+}
+
+// isMissing reports whether the error means the key does not exist.
+func isMissing(err error) bool {
+	return errors.Is(err, domain.ErrKeyNotFound) || errors.Is(err, domain.ErrKeyExpired)
+}
+
+// replyError replies with the error of the storage.
+func replyError(c *client, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOutOfMemory):
+		c.writer.error("OOM command not allowed when used memory > 'maxmemory'.")
+	case errors.Is(err, domain.ErrInvalidCursor):
+		c.writer.error("ERR invalid cursor")
+	case errors.Is(err, domain.ErrWrongType):
+		c.writer.error("WRONGTYPE Operation against a key holding the wrong kind of value")
+	case errors.Is(err, domain.ErrNotInteger):
+		c.writer.error("ERR value is not an integer or out of range")
+	case errors.Is(err, domain.ErrOverflow):
+		c.writer.error("ERR increment or decrement would overflow")
+	case errors.Is(err, domain.ErrNotFloat):
+		c.writer.error("ERR value is not a valid float")
+	default:
+		c.writer.error("ERR " + err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+package resp
+
+import (
+	"math"
+	"strconv"
+)
+
+// INCR key
+func incr(s *Server, c *client, args []string) bool {
+	replyIncrBy(s, c, args[0], 1)
+	return false
+}
+
+// DECR key
+func decr(s *Server, c *client, args []string) bool {
+	replyIncrBy(s, c, args[0], -1)
+	return false
+}
+
+// INCRBY key increment
+func incrby(s *Server, c *client, args []string) bool {
+	delta, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return false
+	}
+	replyIncrBy(s, c, args[0], delta)
+	return false
+}
+
+// DECRBY key decrement
+func decrby(s *Server, c *client, args []string) bool {
+	delta, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return false
+	}
+	if delta == math.MinInt64 {
+		c.writer.error("ERR decrement would overflow")
+		return false
+	}
+	replyIncrBy(s, c, args[0], -delta)
+	return false
+}
+
+// INCRBYFLOAT key increment
+// Like in Redis, the new value is replied as a bulk string.
+func incrbyfloat(s *Server, c *client, args []string) bool {
+	delta, ok := parseScore(c, args[1])
+	if !ok {
+		return false
+	}
+
+	n, err := s.repo.IncrByFloat(args[0], delta)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.bulk(strconv.FormatFloat(n, 'f', -1, 64))
+	return false
+}
+
+func replyIncrBy(s *Server, c *client, key string, delta int64) {
+	n, err := s.repo.IncrBy(key, delta)
+	if err != nil {
+		replyError(c, err)
+		return
+	}
+	c.writer.integer(n)
+}
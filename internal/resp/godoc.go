@@ -0,0 +1,69 @@
+// Package resp provides a TCP listener speaking the Redis serialization protocol (RESP2 and RESP3)
+// on top of the domain.Repository, so existing Redis clients can talk to the storage.
+// Implements the fallowing commands:
+/*
+	GET key
+	SET key value [EX seconds | PX milliseconds] [NX | XX]
+	DEL key [key ...]
+	EXISTS key [key ...]
+	TTL key
+	PTTL key
+	EXPIRE key seconds
+	EXPIREAT key unix-time-seconds
+	PERSIST key
+	GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]
+	KEYS pattern
+	SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
+	PING [message]
+	INFO [section]
+	HELLO [protover]
+	QUIT
+	INCR key
+	DECR key
+	INCRBY key increment
+	DECRBY key decrement
+	INCRBYFLOAT key increment
+	HSET key field value [field value ...]
+	HGET key field
+	HEXISTS key field
+	HDEL key field [field ...]
+	HGETALL key
+	HLEN key
+	HINCRBY key field increment
+	LPUSH key value [value ...]
+	RPUSH key value [value ...]
+	LPOP key
+	RPOP key
+	LRANGE key start stop
+	LLEN key
+	LTRIM key start stop
+	LINDEX key index
+	BLPOP key [key ...] timeout
+	BRPOP key [key ...] timeout
+	SADD key member [member ...]
+	SREM key member [member ...]
+	SMEMBERS key
+	SISMEMBER key member
+	SINTER key [key ...]
+	SUNION key [key ...]
+	SDIFF key [key ...]
+	ZADD key score member [score member ...]
+	ZINCRBY key increment member
+	ZSCORE key member
+	ZRANK key member
+	ZREVRANK key member
+	ZREM key member [member ...]
+	ZRANGE key start stop [BYSCORE] [REV] [LIMIT offset count] [WITHSCORES]
+	ZCOUNT key min max
+	SUBSCRIBE channel [channel ...]
+	PSUBSCRIBE pattern [pattern ...]
+	UNSUBSCRIBE [channel [channel ...]]
+	PUNSUBSCRIBE [pattern [pattern ...]]
+	PUBLISH channel message
+	PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT
+*/
+// Clients start with RESP2 and switch to RESP3 with HELLO 3.
+// A RESP2 client with subscriptions may only manage them, RESP3 clients get the messages as pushes
+// and run any command meanwhile.
+// BLPOP and BRPOP block the connection until a value is pushed, the timeout passes or the server shuts down.
+package resp
@@ -0,0 +1,123 @@
+package resp
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// HSET key field value [field value ...]
+// Replies with the number of the new fields.
+func hset(s *Server, c *client, args []string) bool {
+	if len(args)%2 != 1 {
+		c.writer.error("ERR wrong number of arguments for 'hset' command")
+		return false
+	}
+	fields := make(map[string]string, len(args)/2)
+	for idx := 1; idx < len(args); idx += 2 {
+		fields[args[idx]] = args[idx+1]
+	}
+
+	added, err := s.repo.HSet(args[0], fields)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(added))
+	return false
+}
+
+// HGET key field
+func hget(s *Server, c *client, args []string) bool {
+	value, err := s.repo.HGet(args[0], args[1])
+	switch {
+	case isMissing(err) || errors.Is(err, domain.ErrFieldNotFound):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.bulk(value)
+	}
+	return false
+}
+
+// HEXISTS key field
+func hexists(s *Server, c *client, args []string) bool {
+	_, err := s.repo.HGet(args[0], args[1])
+	switch {
+	case isMissing(err) || errors.Is(err, domain.ErrFieldNotFound):
+		c.writer.integer(0)
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(1)
+	}
+	return false
+}
+
+// HDEL key field [field ...]
+func hdel(s *Server, c *client, args []string) bool {
+	deleted, err := s.repo.HDel(args[0], args[1:]...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(deleted))
+	return false
+}
+
+// HGETALL key
+// The fields are replied in lexical order.
+func hgetall(s *Server, c *client, args []string) bool {
+	hash, err := s.repo.HGetAll(args[0])
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	c.writer.mapHeader(len(fields))
+	for _, field := range fields {
+		c.writer.bulk(field)
+		c.writer.bulk(hash[field])
+	}
+	return false
+}
+
+// HLEN key
+func hlen(s *Server, c *client, args []string) bool {
+	n, err := s.repo.HLen(args[0])
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// HINCRBY key field increment
+func hincrby(s *Server, c *client, args []string) bool {
+	delta, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return false
+	}
+
+	n, err := s.repo.HIncrBy(args[0], args[1], delta)
+	switch {
+	case errors.Is(err, domain.ErrNotInteger):
+		c.writer.error("ERR hash value is not an integer")
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(n)
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// LPUSH key value [value ...]
+// Replies with the length of the list.
+func lpush(s *Server, c *client, args []string) bool {
+	n, err := s.repo.LPush(args[0], args[1:]...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// RPUSH key value [value ...]
+// Replies with the length of the list.
+func rpush(s *Server, c *client, args []string) bool {
+	n, err := s.repo.RPush(args[0], args[1:]...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// LPOP key
+func lpop(s *Server, c *client, args []string) bool {
+	value, err := s.repo.LPop(args[0])
+	replyValue(c, value, err)
+	return false
+}
+
+// RPOP key
+func rpop(s *Server, c *client, args []string) bool {
+	value, err := s.repo.RPop(args[0])
+	replyValue(c, value, err)
+	return false
+}
+
+// LRANGE key start stop
+func lrange(s *Server, c *client, args []string) bool {
+	start, stop, ok := parseRange(c, args[1], args[2])
+	if !ok {
+		return false
+	}
+
+	values, err := s.repo.LRange(args[0], start, stop)
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+	c.writer.strings(values)
+	return false
+}
+
+// LLEN key
+func llen(s *Server, c *client, args []string) bool {
+	n, err := s.repo.LLen(args[0])
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// LTRIM key start stop
+func ltrim(s *Server, c *client, args []string) bool {
+	start, stop, ok := parseRange(c, args[1], args[2])
+	if !ok {
+		return false
+	}
+
+	if err := s.repo.LTrim(args[0], start, stop); err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.simple("OK")
+	return false
+}
+
+// LINDEX key index
+func lindex(s *Server, c *client, args []string) bool {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return false
+	}
+
+	value, err := s.repo.LIndex(args[0], index)
+	if errors.Is(err, domain.ErrIndexOutOfRange) {
+		c.writer.null()
+		return false
+	}
+	replyValue(c, value, err)
+	return false
+}
+
+// BLPOP key [key ...] timeout
+func blpop(s *Server, c *client, args []string) bool {
+	blockingPop(s, c, args, s.repo.BLPop)
+	return false
+}
+
+// BRPOP key [key ...] timeout
+func brpop(s *Server, c *client, args []string) bool {
+	blockingPop(s, c, args, s.repo.BRPop)
+	return false
+}
+
+// blockingPop runs a blocking pop, the timeout is the last argument, in seconds, 0 blocking indefinitely.
+// It replies with the key and the value, or with a null array on timeout.
+// The pop is released when the server shuts down.
+func blockingPop(s *Server, c *client, args []string,
+	pop func(ctx context.Context, keys []string, timeout time.Duration) (string, string, error)) {
+	seconds, err := strconv.ParseFloat(args[len(args)-1], 64)
+	if err != nil || math.IsNaN(seconds) {
+		c.writer.error("ERR timeout is not a float or out of range")
+		return
+	}
+	if seconds < 0 || seconds > math.MaxInt64/float64(time.Second) {
+		c.writer.error("ERR timeout is negative or out of range")
+		return
+	}
+
+	key, value, err := pop(s.Context(), args[:len(args)-1], time.Duration(seconds*float64(time.Second)))
+	switch {
+	case err == nil:
+		c.writer.strings([]string{key, value})
+	case errors.Is(err, domain.ErrTimeout):
+		c.writer.nullArray()
+	case errors.Is(err, context.Canceled):
+		c.writer.error("ERR server is shutting down")
+	default:
+		replyError(c, err)
+	}
+}
+
+// replyValue replies with the popped or indexed value, a missing key being null.
+func replyValue(c *client, value string, err error) {
+	switch {
+	case isMissing(err):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.bulk(value)
+	}
+}
+
+// parseRange parses the start and stop indexes, replying with an error when one of them is not an integer.
+func parseRange(c *client, rawStart, rawStop string) (int, int, bool) {
+	start, err := strconv.Atoi(rawStart)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return 0, 0, false
+	}
+	stop, err := strconv.Atoi(rawStop)
+	if err != nil {
+		c.writer.error("ERR value is not an integer or out of range")
+		return 0, 0, false
+	}
+	return start, stop, true
+}
@@ -0,0 +1,233 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBulkLength is the maximum length of a bulk string, the same as proto-max-bulk-len in Redis.
+	maxBulkLength = 512 << 20
+	// maxArrayLength is the maximum number of arguments of a command.
+	maxArrayLength = 1 << 20
+	// maxInlineLength is the maximum length of an inline command.
+	maxInlineLength = 64 << 10
+)
+
+// errProtocol is returned for malformed input, the connection is closed after replying with it.
+var errProtocol = errors.New("Protocol error")
+
+// reader reads commands sent by clients,
+// either as arrays of bulk strings or as inline commands typed in telnet.
+type reader struct {
+	r *bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReader(r)}
+}
+
+// buffered reports whether more input is already buffered, i.e. the client pipelines commands.
+func (r *reader) buffered() bool {
+	return r.r.Buffered() > 0
+}
+
+// readCommand reads the next command and returns its arguments.
+// An empty inline line returns no arguments.
+func (r *reader) readCommand() ([]string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		if len(line) > maxInlineLength {
+			return nil, fmt.Errorf("%w: too big inline request", errProtocol)
+		}
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > maxArrayLength {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+
+	args := make([]string, 0, n)
+	for ; n > 0; n-- {
+		arg, err := r.readBulk()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// readBulk reads a bulk string.
+func (r *reader) readBulk() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return "", fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, line)
+	}
+
+	size, err := strconv.Atoi(line[1:])
+	if err != nil || size < 0 || size > maxBulkLength {
+		return "", fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+
+	buf := make([]byte, size+2)
+	if _, err = io.ReadFull(r.r, buf); err != nil {
+		return "", err
+	}
+	if buf[size] != '\r' || buf[size+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string is not terminated by CRLF", errProtocol)
+	}
+	return string(buf[:size]), nil
+}
+
+// readLine reads a line without the trailing CRLF.
+func (r *reader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// writer encodes replies in the protocol version negotiated with the client.
+type writer struct {
+	w *bufio.Writer
+	// proto is 2 or 3.
+	proto int
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w), proto: 2}
+}
+
+func (w *writer) flush() error {
+	return w.w.Flush()
+}
+
+// simple writes a simple string.
+func (w *writer) simple(s string) {
+	w.line('+', s)
+}
+
+// error writes an error, msg starts with the error code, e.g. "ERR syntax error".
+func (w *writer) error(msg string) {
+	w.line('-', msg)
+}
+
+// integer writes an integer.
+func (w *writer) integer(n int64) {
+	w.line(':', strconv.FormatInt(n, 10))
+}
+
+// bulk writes a binary-safe string.
+func (w *writer) bulk(s string) {
+	w.line('$', strconv.Itoa(len(s)))
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// verbatim writes a text, as a verbatim string in RESP3 and a bulk string in RESP2.
+func (w *writer) verbatim(s string) {
+	if w.proto < 3 {
+		w.bulk(s)
+		return
+	}
+	w.line('=', strconv.Itoa(len(s)+4))
+	_, _ = w.w.WriteString("txt:")
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// null writes the null reply, a null bulk string in RESP2.
+func (w *writer) null() {
+	if w.proto < 3 {
+		_, _ = w.w.WriteString("$-1\r\n")
+		return
+	}
+	_, _ = w.w.WriteString("_\r\n")
+}
+
+// nullArray writes the null reply, a null array in RESP2.
+func (w *writer) nullArray() {
+	if w.proto < 3 {
+		_, _ = w.w.WriteString("*-1\r\n")
+		return
+	}
+	_, _ = w.w.WriteString("_\r\n")
+}
+
+// array writes the header of an array of n elements, the elements are written next.
+func (w *writer) array(n int) {
+	w.line('*', strconv.Itoa(n))
+}
+
+// push writes the header of an out-of-band push of n elements, an array in RESP2.
+func (w *writer) push(n int) {
+	if w.proto < 3 {
+		w.array(n)
+		return
+	}
+	w.line('>', strconv.Itoa(n))
+}
+
+// strings writes an array of bulk strings.
+func (w *writer) strings(values []string) {
+	w.array(len(values))
+	for _, v := range values {
+		w.bulk(v)
+	}
+}
+
+// setHeader writes the header of a set of n elements, the elements are written next.
+// RESP2 has no sets, so an array is written instead.
+func (w *writer) setHeader(n int) {
+	if w.proto < 3 {
+		w.array(n)
+		return
+	}
+	w.line('~', strconv.Itoa(n))
+}
+
+// double writes a float, a bulk string in RESP2.
+func (w *writer) double(f float64) {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	switch {
+	case math.IsInf(f, 1):
+		s = "inf"
+	case math.IsInf(f, -1):
+		s = "-inf"
+	}
+	if w.proto < 3 {
+		w.bulk(s)
+		return
+	}
+	w.line(',', s)
+}
+
+// mapHeader writes the header of a map of n pairs, the keys and values are written next.
+// RESP2 has no maps, so a flat array of 2n elements is written instead.
+func (w *writer) mapHeader(n int) {
+	if w.proto < 3 {
+		w.array(2 * n)
+		return
+	}
+	w.line('%', strconv.Itoa(n))
+}
+
+func (w *writer) line(prefix byte, s string) {
+	_ = w.w.WriteByte(prefix)
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
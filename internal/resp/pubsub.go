@@ -0,0 +1,128 @@
+package resp
+
+import (
+	"strings"
+)
+
+// SUBSCRIBE channel [channel ...]
+func subscribe(s *Server, c *client, args []string) bool {
+	if !pubsubEnabled(s, c) {
+		return false
+	}
+	sub := c.subscriber(s.broker)
+	for _, channel := range args {
+		replySubscription(c, "subscribe", channel, sub.Subscribe(channel))
+	}
+	return false
+}
+
+// PSUBSCRIBE pattern [pattern ...]
+func psubscribe(s *Server, c *client, args []string) bool {
+	if !pubsubEnabled(s, c) {
+		return false
+	}
+	sub := c.subscriber(s.broker)
+	for _, pattern := range args {
+		replySubscription(c, "psubscribe", pattern, sub.PSubscribe(pattern))
+	}
+	return false
+}
+
+// UNSUBSCRIBE [channel [channel ...]]
+// Without channels, all the channels are unsubscribed from.
+func unsubscribe(s *Server, c *client, args []string) bool {
+	if !pubsubEnabled(s, c) {
+		return false
+	}
+	sub := c.subscriber(s.broker)
+	if len(args) == 0 {
+		args = sub.Channels()
+	}
+	for _, channel := range args {
+		replySubscription(c, "unsubscribe", channel, sub.Unsubscribe(channel))
+	}
+	if len(args) == 0 {
+		replyUnsubscribed(c, "unsubscribe", sub.Count())
+	}
+	return false
+}
+
+// PUNSUBSCRIBE [pattern [pattern ...]]
+// Without patterns, all the patterns are unsubscribed from.
+func punsubscribe(s *Server, c *client, args []string) bool {
+	if !pubsubEnabled(s, c) {
+		return false
+	}
+	sub := c.subscriber(s.broker)
+	if len(args) == 0 {
+		args = sub.Patterns()
+	}
+	for _, pattern := range args {
+		replySubscription(c, "punsubscribe", pattern, sub.PUnsubscribe(pattern))
+	}
+	if len(args) == 0 {
+		replyUnsubscribed(c, "punsubscribe", sub.Count())
+	}
+	return false
+}
+
+// PUBLISH channel message
+// Replies with the number of the receivers.
+func publish(s *Server, c *client, args []string) bool {
+	if !pubsubEnabled(s, c) {
+		return false
+	}
+	c.writer.integer(int64(s.broker.Publish(args[0], args[1])))
+	return false
+}
+
+// PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT
+func pubsubCommand(s *Server, c *client, args []string) bool {
+	if !pubsubEnabled(s, c) {
+		return false
+	}
+	switch sub := strings.ToUpper(args[0]); {
+	case sub == "CHANNELS" && len(args) <= 2:
+		pattern := ""
+		if len(args) == 2 {
+			pattern = args[1]
+		}
+		c.writer.strings(s.broker.Channels(pattern))
+	case sub == "NUMSUB":
+		c.writer.mapHeader(len(args) - 1)
+		for _, channel := range args[1:] {
+			c.writer.bulk(channel)
+			c.writer.integer(int64(s.broker.NumSub(channel)))
+		}
+	case sub == "NUMPAT" && len(args) == 1:
+		c.writer.integer(int64(s.broker.NumPat()))
+	default:
+		c.writer.error("ERR unknown subcommand or wrong number of arguments for '" + args[0] + "'")
+	}
+	return false
+}
+
+// pubsubEnabled replies with an error when the server has no broker.
+func pubsubEnabled(s *Server, c *client) bool {
+	if s.broker == nil {
+		c.writer.error("ERR publish/subscribe is not enabled")
+		return false
+	}
+	return true
+}
+
+// replySubscription confirms a change of a subscription with the number of the subscriptions left.
+func replySubscription(c *client, kind, name string, count int) {
+	c.writer.push(3)
+	c.writer.bulk(kind)
+	c.writer.bulk(name)
+	c.writer.integer(int64(count))
+}
+
+// replyUnsubscribed confirms unsubscribing from everything without having subscriptions.
+func replyUnsubscribed(c *client, kind string, count int) {
+	c.writer.push(3)
+	c.writer.bulk(kind)
+	c.writer.null()
+	c.writer.integer(int64(count))
+}
@@ -0,0 +1,202 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+	"github.com/gynshu-one/in-memory-storage/internal/tcpserver"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Shutdown was called.
+var ErrServerClosed = tcpserver.ErrServerClosed
+
+// Server serves the storage to Redis clients over TCP.
+// ListenAndServe, Serve and Shutdown come from the embedded tcpserver.Server.
+type Server struct {
+	*tcpserver.Server
+	repo    domain.Repository
+	broker  *pubsub.Broker
+	limiter domain.RateLimiter
+	started time.Time
+
+	nextID   atomic.Int64
+	commands atomic.Uint64
+}
+
+// NewServer creates a server listening on addr, e.g. ":6379".
+// The publish/subscribe commands use the broker, they are refused when it is nil.
+// Every command counts as a request for the limiter, keyed by the client IP.
+func NewServer(addr string, repo domain.Repository, broker *pubsub.Broker, limiter domain.RateLimiter) *Server {
+	s := &Server{
+		repo:    repo,
+		broker:  broker,
+		limiter: limiter,
+		started: time.Now(),
+	}
+	s.Server = tcpserver.New(addr, s.serve)
+	return s
+}
+
+// serve runs the commands of the client until it quits, the connection fails or the server shuts down.
+func (s *Server) serve(conn net.Conn) {
+	c := newClient(s, conn)
+	defer c.unsubscribe()
+	for {
+		args, err := c.reader.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.mu.Lock()
+				c.writer.error("ERR " + err.Error())
+				_ = c.writer.flush()
+				c.mu.Unlock()
+			} else if !errors.Is(err, io.EOF) && !s.ShuttingDown() {
+				log.Debug().Err(err).Str("client", c.addr).Msg("RESP connection failed")
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		c.mu.Lock()
+		done := s.execute(c, args) || s.ShuttingDown()
+		// Replies to pipelined commands are flushed together.
+		if done || !c.reader.buffered() {
+			err = c.writer.flush()
+		}
+		c.mu.Unlock()
+		if done || err != nil {
+			return
+		}
+	}
+}
+
+// execute runs a single command and reports whether the client asked to close the connection.
+func (s *Server) execute(c *client, args []string) bool {
+	s.commands.Add(1)
+
+	if s.limiter != nil {
+		if !s.limiter.Check(c.ip) {
+			c.writer.error("ERR rate limit exceeded")
+			return false
+		}
+		s.limiter.Limit(c.ip)
+	}
+
+	name := strings.ToLower(args[0])
+	cmd, ok := commands[name]
+	if !ok {
+		c.writer.error("ERR unknown command '" + args[0] + "'")
+		return false
+	}
+	// RESP2 has no way to tell replies from messages, so a subscribed client may only manage its subscriptions
+	if c.subscribed() && !cmd.subscribed {
+		c.writer.error("ERR Can't execute '" + name + "': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context")
+		return false
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		c.writer.error("ERR wrong number of arguments for '" + name + "' command")
+		return false
+	}
+	return cmd.handler(s, c, args[1:])
+}
+
+// client is a connection of a Redis client.
+type client struct {
+	id int64
+	// name is set by HELLO SETNAME.
+	name   string
+	addr   string
+	ip     string
+	conn   net.Conn
+	reader *reader
+	// mu guards the writer, the messages of the subscriptions are written concurrently with the replies.
+	mu     sync.Mutex
+	writer *writer
+
+	// sub is created by the first subscription, forwarded is closed once its messages are no longer written.
+	sub       *pubsub.Subscriber
+	forwarded chan struct{}
+}
+
+func newClient(s *Server, conn net.Conn) *client {
+	addr := conn.RemoteAddr().String()
+	return &client{
+		id:     s.nextID.Add(1),
+		addr:   addr,
+		ip:     remoteIP(addr),
+		conn:   conn,
+		reader: newReader(conn),
+		writer: newWriter(conn),
+	}
+}
+
+// subscriber returns the subscriber of the client, creating it along with the goroutine writing its messages.
+// The caller must hold the writer lock.
+func (c *client) subscriber(broker *pubsub.Broker) *pubsub.Subscriber {
+	if c.sub == nil {
+		c.sub = broker.NewSubscriber()
+		c.forwarded = make(chan struct{})
+		go c.forward()
+	}
+	return c.sub
+}
+
+// subscribed reports whether the client is in the RESP2 subscribed mode.
+// The caller must hold the writer lock.
+func (c *client) subscribed() bool {
+	return c.writer.proto < 3 && c.sub != nil && c.sub.Count() > 0
+}
+
+// forward writes the messages of the subscriber until it ends.
+// A client ended for not keeping up is disconnected, like Redis does on reaching the output buffer limit.
+func (c *client) forward() {
+	defer close(c.forwarded)
+	for m := range c.sub.C() {
+		c.mu.Lock()
+		if m.Pattern == "" {
+			c.writer.push(3)
+			c.writer.bulk("message")
+		} else {
+			c.writer.push(4)
+			c.writer.bulk("pmessage")
+			c.writer.bulk(m.Pattern)
+		}
+		c.writer.bulk(m.Channel)
+		c.writer.bulk(m.Payload)
+		err := c.writer.flush()
+		c.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+	if errors.Is(c.sub.Err(), pubsub.ErrSlowConsumer) {
+		_ = c.conn.Close()
+	}
+}
+
+// unsubscribe ends the subscriptions of the client and waits for their messages to stop.
+func (c *client) unsubscribe() {
+	if c.sub == nil {
+		return
+	}
+	c.sub.Close()
+	<-c.forwarded
+}
+
+// remoteIP strips the port off the remote address, so all the connections of a host share a rate limit.
+func remoteIP(addr string) string {
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return ip
+}
@@ -0,0 +1,514 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/in-memory-storage/internal/infra/pubsub"
+	"github.com/gynshu-one/in-memory-storage/internal/infra/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLimiter allows the first allowed requests.
+type fakeLimiter struct {
+	allowed int
+	calls   int
+}
+
+func (f *fakeLimiter) Limit(string) {
+	f.calls++
+}
+
+func (f *fakeLimiter) Check(string) bool {
+	return f.calls < f.allowed
+}
+
+// startServer serves an empty storage on a random port and shuts it down at the end of the test.
+func startServer(t *testing.T) *Server {
+	repo := storage.NewInMemory()
+	t.Cleanup(func() { _ = repo.Close() })
+
+	srv := NewServer("127.0.0.1:0", repo, pubsub.NewBroker(16, pubsub.Disconnect), nil)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+	return srv
+}
+
+// testClient speaks RESP to a server and returns the raw replies.
+type testClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &testClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// do sends the command and returns the raw reply.
+func (c *testClient) do(t *testing.T, args ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := c.conn.Write([]byte(b.String()))
+	require.NoError(t, err)
+
+	reply, err := c.reply()
+	require.NoError(t, err)
+	return reply
+}
+
+// reply reads a whole reply, including the nested elements of aggregates.
+func (c *testClient) reply() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	switch line[0] {
+	case '$', '=':
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if n < 0 {
+			return line, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, buf); err != nil {
+			return "", err
+		}
+		return line + string(buf), nil
+	case '*', '%', '>', '~':
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if line[0] == '%' {
+			n *= 2
+		}
+		for ; n > 0; n-- {
+			elem, err := c.reply()
+			if err != nil {
+				return "", err
+			}
+			line += elem
+		}
+		return line, nil
+	default:
+		return line, nil
+	}
+}
+
+func TestServer_Commands(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands [][]string
+		want     []string
+	}{
+		{
+			name:     "PING replies with PONG or the message",
+			commands: [][]string{{"PING"}, {"ping", "hello"}},
+			want:     []string{"+PONG\r\n", "$5\r\nhello\r\n"},
+		},
+		{
+			name:     "SET then GET returns the value",
+			commands: [][]string{{"SET", "key", "value"}, {"GET", "key"}},
+			want:     []string{"+OK\r\n", "$5\r\nvalue\r\n"},
+		},
+		{
+			name:     "GET of a missing key returns null",
+			commands: [][]string{{"GET", "missing"}},
+			want:     []string{"$-1\r\n"},
+		},
+		{
+			name:     "SET NX does not overwrite an existing key",
+			commands: [][]string{{"SET", "key", "a"}, {"SET", "key", "b", "NX"}, {"GET", "key"}},
+			want:     []string{"+OK\r\n", "$-1\r\n", "$1\r\na\r\n"},
+		},
+		{
+			name:     "SET XX does not create a missing key",
+			commands: [][]string{{"SET", "key", "a", "XX"}, {"EXISTS", "key"}},
+			want:     []string{"$-1\r\n", ":0\r\n"},
+		},
+		{
+			name:     "SET EX sets the ttl",
+			commands: [][]string{{"SET", "key", "a", "EX", "100"}, {"TTL", "key"}},
+			want:     []string{"+OK\r\n", ":100\r\n"},
+		},
+		{
+			name:     "SET PX sets the ttl in milliseconds",
+			commands: [][]string{{"SET", "key", "a", "px", "100000"}, {"TTL", "key"}},
+			want:     []string{"+OK\r\n", ":100\r\n"},
+		},
+		{
+			name:     "SET with conflicting options is a syntax error",
+			commands: [][]string{{"SET", "key", "a", "NX", "XX"}, {"SET", "key", "a", "EX", "1", "PX", "1"}},
+			want:     []string{"-ERR syntax error\r\n", "-ERR syntax error\r\n"},
+		},
+		{
+			name:     "SET with a non-positive expire is refused",
+			commands: [][]string{{"SET", "key", "a", "EX", "0"}},
+			want:     []string{"-ERR invalid expire time in 'set' command\r\n"},
+		},
+		{
+			name: "An expire out of range is refused and keeps the key",
+			commands: [][]string{{"SET", "key", "a"}, {"SET", "key", "b", "EX", "10000000000"},
+				{"SET", "key", "b", "PX", "10000000000000"}, {"EXPIRE", "key", "10000000000"},
+				{"EXPIREAT", "key", "10000000000"}, {"GETEX", "key", "EX", "10000000000"},
+				{"GETEX", "key", "PXAT", "10000000000000"}, {"GET", "key"}, {"TTL", "key"}},
+			want: []string{"+OK\r\n", "-ERR invalid expire time in 'set' command\r\n",
+				"-ERR invalid expire time in 'set' command\r\n", "-ERR invalid expire time in 'expire' command\r\n",
+				"-ERR invalid expire time in 'expireat' command\r\n", "-ERR invalid expire time in 'getex' command\r\n",
+				"-ERR invalid expire time in 'getex' command\r\n", "$1\r\na\r\n", ":-1\r\n"},
+		},
+		{
+			name:     "DEL and EXISTS count the keys",
+			commands: [][]string{{"SET", "a", "1"}, {"SET", "b", "2"}, {"EXISTS", "a", "b", "c"}, {"DEL", "a", "b", "c"}, {"EXISTS", "a", "b"}},
+			want:     []string{"+OK\r\n", "+OK\r\n", ":2\r\n", ":2\r\n", ":0\r\n"},
+		},
+		{
+			name:     "TTL and PTTL of a missing and a persistent key",
+			commands: [][]string{{"TTL", "missing"}, {"SET", "key", "a"}, {"TTL", "key"}, {"PTTL", "key"}},
+			want:     []string{":-2\r\n", "+OK\r\n", ":-1\r\n", ":-1\r\n"},
+		},
+		{
+			name:     "EXPIRE and PERSIST change the ttl",
+			commands: [][]string{{"SET", "key", "a"}, {"EXPIRE", "key", "50"}, {"TTL", "key"}, {"PERSIST", "key"}, {"PERSIST", "key"}, {"TTL", "key"}},
+			want:     []string{"+OK\r\n", ":1\r\n", ":50\r\n", ":1\r\n", ":0\r\n", ":-1\r\n"},
+		},
+		{
+			name:     "EXPIRE of a missing key returns 0",
+			commands: [][]string{{"EXPIRE", "missing", "10"}},
+			want:     []string{":0\r\n"},
+		},
+		{
+			name:     "EXPIREAT sets an absolute expiration",
+			commands: [][]string{{"SET", "key", "a"}, {"EXPIREAT", "key", "4102444800"}, {"PERSIST", "key"}, {"EXPIREAT", "key", "1"}, {"GET", "key"}},
+			want:     []string{"+OK\r\n", ":1\r\n", ":1\r\n", ":1\r\n", "$-1\r\n"},
+		},
+		{
+			name: "GETEX reads the value and changes the ttl",
+			commands: [][]string{{"SET", "key", "a"}, {"GETEX", "key", "EX", "50"}, {"TTL", "key"}, {"GETEX", "key", "PERSIST"},
+				{"TTL", "key"}, {"GETEX", "key"}, {"GETEX", "missing", "EX", "1"}, {"GETEX", "key", "EX", "0"}},
+			want: []string{"+OK\r\n", "$1\r\na\r\n", ":50\r\n", "$1\r\na\r\n", ":-1\r\n", "$1\r\na\r\n", "$-1\r\n",
+				"-ERR invalid expire time in 'getex' command\r\n"},
+		},
+		{
+			name:     "KEYS returns the matching keys",
+			commands: [][]string{{"SET", "user:1", "a"}, {"SET", "order:1", "b"}, {"KEYS", "user:*"}},
+			want:     []string{"+OK\r\n", "+OK\r\n", "*1\r\n$6\r\nuser:1\r\n"},
+		},
+		{
+			name:     "SCAN returns the cursor and the matching keys",
+			commands: [][]string{{"SET", "user:1", "a"}, {"SCAN", "0", "MATCH", "user:*", "COUNT", "10"}},
+			want:     []string{"+OK\r\n", "*2\r\n$1\r\n0\r\n*1\r\n$6\r\nuser:1\r\n"},
+		},
+		{
+			name:     "SCAN filters the keys by type",
+			commands: [][]string{{"SET", "user:1", "a"}, {"SADD", "user:2", "a"}, {"SCAN", "0", "TYPE", "set"}, {"SCAN", "0", "TYPE", "tree"}},
+			want:     []string{"+OK\r\n", ":1\r\n", "*2\r\n$1\r\n0\r\n*1\r\n$6\r\nuser:2\r\n", "-ERR unknown type name 'tree'\r\n"},
+		},
+		{
+			name:     "SCAN with an invalid cursor",
+			commands: [][]string{{"SCAN", "abc"}, {"SCAN", "18446744073709551615"}},
+			want:     []string{"-ERR invalid cursor\r\n", "-ERR invalid cursor\r\n"},
+		},
+		{
+			name: "Hash commands set, read and delete fields",
+			commands: [][]string{
+				{"HSET", "user", "name", "Ann", "age", "30"}, {"HSET", "user", "name", "Bob"}, {"HGET", "user", "name"},
+				{"HGET", "user", "missing"}, {"HEXISTS", "user", "age"}, {"HLEN", "user"}, {"HINCRBY", "user", "age", "2"},
+				{"HGETALL", "user"}, {"HDEL", "user", "age", "missing"}, {"HLEN", "missing"}, {"HGETALL", "missing"},
+			},
+			want: []string{
+				":2\r\n", ":0\r\n", "$3\r\nBob\r\n",
+				"$-1\r\n", ":1\r\n", ":2\r\n", ":32\r\n",
+				"*4\r\n$3\r\nage\r\n$2\r\n32\r\n$4\r\nname\r\n$3\r\nBob\r\n", ":1\r\n", ":0\r\n", "*0\r\n",
+			},
+		},
+		{
+			name: "List commands push, read and pop values",
+			commands: [][]string{
+				{"RPUSH", "jobs", "b", "c"}, {"LPUSH", "jobs", "a"}, {"LRANGE", "jobs", "0", "-1"}, {"LLEN", "jobs"},
+				{"LINDEX", "jobs", "-1"}, {"LINDEX", "jobs", "5"}, {"LPOP", "jobs"}, {"RPOP", "jobs"},
+				{"LTRIM", "jobs", "1", "0"}, {"LLEN", "jobs"}, {"LPOP", "jobs"}, {"LRANGE", "jobs", "x", "1"},
+			},
+			want: []string{
+				":2\r\n", ":3\r\n", "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n", ":3\r\n",
+				"$1\r\nc\r\n", "$-1\r\n", "$1\r\na\r\n", "$1\r\nc\r\n",
+				"+OK\r\n", ":0\r\n", "$-1\r\n", "-ERR value is not an integer or out of range\r\n",
+			},
+		},
+		{
+			name:     "BLPOP pops right away or times out",
+			commands: [][]string{{"RPUSH", "jobs", "a"}, {"BLPOP", "empty", "jobs", "1"}, {"BLPOP", "jobs", "0.01"}, {"BRPOP", "jobs", "-1"}},
+			want:     []string{":1\r\n", "*2\r\n$4\r\njobs\r\n$1\r\na\r\n", "*-1\r\n", "-ERR timeout is negative or out of range\r\n"},
+		},
+		{
+			name: "Set commands add, read and combine members",
+			commands: [][]string{
+				{"SADD", "a", "x", "y", "x"}, {"SADD", "b", "y", "z"}, {"SMEMBERS", "a"}, {"SISMEMBER", "a", "z"},
+				{"SINTER", "a", "b"}, {"SUNION", "a", "b"}, {"SDIFF", "a", "b", "missing"}, {"SREM", "a", "x", "w"}, {"SMEMBERS", "missing"},
+			},
+			want: []string{
+				":2\r\n", ":2\r\n", "*2\r\n$1\r\nx\r\n$1\r\ny\r\n", ":0\r\n",
+				"*1\r\n$1\r\ny\r\n", "*3\r\n$1\r\nx\r\n$1\r\ny\r\n$1\r\nz\r\n", "*1\r\n$1\r\nx\r\n", ":1\r\n", "*0\r\n",
+			},
+		},
+		{
+			name: "Sorted set commands rank and range members",
+			commands: [][]string{
+				{"ZADD", "board", "10", "alice", "20", "bob", "30", "carol"}, {"ZINCRBY", "board", "15", "alice"},
+				{"ZRANGE", "board", "0", "-1", "WITHSCORES"}, {"ZRANGE", "board", "+inf", "(20", "BYSCORE", "REV"},
+				{"ZRANGE", "board", "-inf", "+inf", "BYSCORE", "LIMIT", "1", "1"}, {"ZRANK", "board", "alice"},
+				{"ZREVRANK", "board", "alice"}, {"ZSCORE", "board", "missing"}, {"ZCOUNT", "board", "20", "25"},
+				{"ZREM", "board", "bob", "dave"}, {"ZADD", "board", "nan", "x"}, {"ZRANGE", "board", "0", "1", "LIMIT", "0", "1"},
+			},
+			want: []string{
+				":3\r\n", "$2\r\n25\r\n",
+				"*6\r\n$3\r\nbob\r\n$2\r\n20\r\n$5\r\nalice\r\n$2\r\n25\r\n$5\r\ncarol\r\n$2\r\n30\r\n", "*2\r\n$5\r\ncarol\r\n$5\r\nalice\r\n",
+				"*1\r\n$5\r\nalice\r\n", ":1\r\n",
+				":1\r\n", "$-1\r\n", ":2\r\n",
+				":1\r\n", "-ERR value is not a valid float\r\n", "-ERR syntax error, LIMIT is only supported in combination with BYSCORE\r\n",
+			},
+		},
+		{
+			name: "Counter commands increment and keep the expiration",
+			commands: [][]string{
+				{"INCR", "hits"}, {"INCRBY", "hits", "10"}, {"DECR", "hits"}, {"DECRBY", "hits", "-5"},
+				{"SET", "price", "10", "EX", "100"}, {"INCRBYFLOAT", "price", "0.5"}, {"TTL", "price"},
+				{"SET", "text", "abc"}, {"INCR", "text"}, {"INCRBYFLOAT", "text", "1"},
+				{"SET", "max", "9223372036854775807"}, {"INCR", "max"}, {"DECRBY", "hits", "-9223372036854775808"},
+			},
+			want: []string{
+				":1\r\n", ":11\r\n", ":10\r\n", ":15\r\n",
+				"+OK\r\n", "$4\r\n10.5\r\n", ":100\r\n",
+				"+OK\r\n", "-ERR value is not an integer or out of range\r\n", "-ERR value is not a valid float\r\n",
+				"+OK\r\n", "-ERR increment or decrement would overflow\r\n", "-ERR decrement would overflow\r\n",
+			},
+		},
+		{
+			name:     "RESP3 replies sets and scores with their own types",
+			commands: [][]string{{"HELLO", "3"}, {"SADD", "s", "a"}, {"SMEMBERS", "s"}, {"ZADD", "z", "1.5", "a"}, {"ZRANGE", "z", "0", "-1", "WITHSCORES"}},
+			want: []string{
+				"%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.0.0\r\n$5\r\nproto\r\n:3\r\n" +
+					"$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n",
+				":1\r\n", "~1\r\n$1\r\na\r\n", ":1\r\n", "*1\r\n*2\r\n$1\r\na\r\n,1.5\r\n",
+			},
+		},
+		{
+			name:     "Commands against a key of the wrong type",
+			commands: [][]string{{"SET", "string", "a"}, {"HGET", "string", "field"}, {"HSET", "hash", "f", "abc"}, {"GET", "hash"}, {"HINCRBY", "hash", "f", "1"}},
+			want: []string{
+				"+OK\r\n",
+				"-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+				":1\r\n",
+				"-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+				"-ERR hash value is not an integer\r\n",
+			},
+		},
+		{
+			name:     "Unknown command and wrong arity",
+			commands: [][]string{{"FLUSHALL"}, {"GET"}},
+			want:     []string{"-ERR unknown command 'FLUSHALL'\r\n", "-ERR wrong number of arguments for 'get' command\r\n"},
+		},
+		{
+			name:     "HELLO 3 switches to RESP3 nulls",
+			commands: [][]string{{"HELLO", "3"}, {"GET", "missing"}, {"HELLO", "4"}},
+			want: []string{
+				"%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n7.0.0\r\n$5\r\nproto\r\n:3\r\n" +
+					"$2\r\nid\r\n:1\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n",
+				"_\r\n",
+				"-NOPROTO unsupported protocol version\r\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startServer(t)
+			c := dial(t, srv.listenerAddr(t))
+
+			for idx, cmd := range tt.commands {
+				assert.Equal(t, tt.want[idx], c.do(t, cmd...), "reply to %v", cmd)
+			}
+		})
+	}
+}
+
+func TestServer_Info(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv.listenerAddr(t))
+
+	c.do(t, "SET", "key", "value", "EX", "10")
+	reply := c.do(t, "INFO", "keyspace")
+	assert.Contains(t, reply, "# Keyspace\r\ndb0:keys=1,expires=1")
+	assert.NotContains(t, reply, "# Server")
+
+	reply = c.do(t, "INFO")
+	assert.Contains(t, reply, "# Server\r\n")
+	assert.Contains(t, reply, "total_commands_processed:3\r\n")
+}
+
+func TestServer_Inline(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv.listenerAddr(t))
+
+	_, err := c.conn.Write([]byte("SET key value\r\nGET key\r\n"))
+	require.NoError(t, err)
+
+	for _, want := range []string{"+OK\r\n", "$5\r\nvalue\r\n"} {
+		reply, err := c.reply()
+		require.NoError(t, err)
+		assert.Equal(t, want, reply)
+	}
+}
+
+func TestServer_ProtocolError(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv.listenerAddr(t))
+
+	_, err := c.conn.Write([]byte("*1\r\n+GET\r\n"))
+	require.NoError(t, err)
+
+	reply, err := c.reply()
+	require.NoError(t, err)
+	assert.Equal(t, "-ERR Protocol error: expected '$', got '+'\r\n", reply)
+
+	_, err = c.reply()
+	assert.Error(t, err, "the connection is closed after a protocol error")
+}
+
+func TestServer_Quit(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv.listenerAddr(t))
+
+	assert.Equal(t, "+OK\r\n", c.do(t, "QUIT"))
+	_, err := c.reply()
+	assert.Error(t, err)
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	repo := storage.NewInMemory()
+	defer repo.Close()
+
+	srv := NewServer("127.0.0.1:0", repo, nil, &fakeLimiter{allowed: 1})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Shutdown(context.Background())
+
+	c := dial(t, l.Addr().String())
+	assert.Equal(t, "+PONG\r\n", c.do(t, "PING"))
+	assert.Equal(t, "-ERR rate limit exceeded\r\n", c.do(t, "PING"))
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv.listenerAddr(t))
+	assert.Equal(t, "+PONG\r\n", c.do(t, "PING"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+
+	_, err := c.reply()
+	assert.Error(t, err, "idle connections are closed")
+
+	_, err = net.Dial("tcp", srv.Addr().String())
+	assert.Error(t, err, "new connections are refused")
+}
+
+// listenerAddr waits until the server accepts connections and returns its address.
+func (s *Server) listenerAddr(t *testing.T) string {
+	require.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, time.Millisecond)
+	return s.Addr().String()
+}
+
+func TestServer_PubSub(t *testing.T) {
+	srv := startServer(t)
+	subscriber := dial(t, srv.listenerAddr(t))
+	publisher := dial(t, srv.listenerAddr(t))
+
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", subscriber.do(t, "SUBSCRIBE", "news"))
+	assert.Equal(t, "*3\r\n$10\r\npsubscribe\r\n$6\r\nnews.*\r\n:2\r\n", subscriber.do(t, "PSUBSCRIBE", "news.*"))
+	assert.Equal(t, "-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT are allowed in this context\r\n",
+		subscriber.do(t, "GET", "key"))
+	assert.Equal(t, "*2\r\n$4\r\npong\r\n$0\r\n\r\n", subscriber.do(t, "PING"))
+
+	assert.Equal(t, "*1\r\n$4\r\nnews\r\n", publisher.do(t, "PUBSUB", "CHANNELS"))
+	assert.Equal(t, "*4\r\n$4\r\nnews\r\n:1\r\n$5\r\nother\r\n:0\r\n", publisher.do(t, "PUBSUB", "NUMSUB", "news", "other"))
+	assert.Equal(t, ":1\r\n", publisher.do(t, "PUBSUB", "NUMPAT"))
+	assert.Equal(t, ":1\r\n", publisher.do(t, "PUBLISH", "news", "hello"))
+	assert.Equal(t, ":1\r\n", publisher.do(t, "PUBLISH", "news.tech", "go"))
+
+	reply, err := subscriber.reply()
+	require.NoError(t, err)
+	assert.Equal(t, "*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n", reply)
+	reply, err = subscriber.reply()
+	require.NoError(t, err)
+	assert.Equal(t, "*4\r\n$8\r\npmessage\r\n$6\r\nnews.*\r\n$9\r\nnews.tech\r\n$2\r\ngo\r\n", reply)
+
+	assert.Equal(t, "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:1\r\n", subscriber.do(t, "UNSUBSCRIBE"))
+	assert.Equal(t, "*3\r\n$12\r\npunsubscribe\r\n$6\r\nnews.*\r\n:0\r\n", subscriber.do(t, "PUNSUBSCRIBE"))
+	assert.Equal(t, "*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n", subscriber.do(t, "UNSUBSCRIBE"))
+	assert.Equal(t, "$-1\r\n", subscriber.do(t, "GET", "key"), "commands are allowed again without subscriptions")
+}
+
+func TestServer_BLPop(t *testing.T) {
+	t.Run("is woken by a push of another client", func(t *testing.T) {
+		srv := startServer(t)
+		consumer := dial(t, srv.listenerAddr(t))
+		producer := dial(t, srv.listenerAddr(t))
+
+		replies := make(chan string)
+		go func() { replies <- consumer.do(t, "BRPOP", "jobs", "0") }()
+		assert.Eventually(t, func() bool { return srv.Active() == 2 }, time.Second, time.Millisecond)
+		assert.Equal(t, ":1\r\n", producer.do(t, "LPUSH", "jobs", "job1"))
+
+		select {
+		case reply := <-replies:
+			assert.Equal(t, "*2\r\n$4\r\njobs\r\n$4\r\njob1\r\n", reply)
+		case <-time.After(2 * time.Second):
+			t.Fatal("BRPOP was not woken")
+		}
+	})
+
+	t.Run("is released by Shutdown", func(t *testing.T) {
+		srv := startServer(t)
+		consumer := dial(t, srv.listenerAddr(t))
+
+		replies := make(chan string)
+		go func() { replies <- consumer.do(t, "BLPOP", "jobs", "0") }()
+		// Wait for the command to run
+		assert.Eventually(t, func() bool { return srv.commands.Load() == 1 }, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.NoError(t, srv.Shutdown(ctx))
+		assert.Equal(t, "-ERR server is shutting down\r\n", <-replies)
+	})
+}
+
+func TestServer_PubSubRESP3(t *testing.T) {
+	srv := startServer(t)
+	subscriber := dial(t, srv.listenerAddr(t))
+	subscriber.do(t, "HELLO", "3")
+
+	assert.Equal(t, ">3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", subscriber.do(t, "SUBSCRIBE", "news"))
+	assert.Equal(t, "_\r\n", subscriber.do(t, "GET", "key"), "RESP3 clients run any command while subscribed")
+	assert.Equal(t, ":1\r\n", subscriber.do(t, "PUBLISH", "news", "hello"))
+
+	reply, err := subscriber.reply()
+	require.NoError(t, err)
+	assert.Equal(t, ">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n", reply)
+}
@@ -0,0 +1,92 @@
+package resp
+
+// SADD key member [member ...]
+// Replies with the number of the new members.
+func sadd(s *Server, c *client, args []string) bool {
+	added, err := s.repo.SAdd(args[0], args[1:]...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(added))
+	return false
+}
+
+// SREM key member [member ...]
+// Replies with the number of the removed members.
+func srem(s *Server, c *client, args []string) bool {
+	n, err := s.repo.SRem(args[0], args[1:]...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// SMEMBERS key
+// The members are replied in lexical order.
+func smembers(s *Server, c *client, args []string) bool {
+	members, err := s.repo.SMembers(args[0])
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+	replyMembers(c, members)
+	return false
+}
+
+// SISMEMBER key member
+func sismember(s *Server, c *client, args []string) bool {
+	ok, err := s.repo.SIsMember(args[0], args[1])
+	switch {
+	case isMissing(err) || (err == nil && !ok):
+		c.writer.integer(0)
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(1)
+	}
+	return false
+}
+
+// SINTER key [key ...]
+func sinter(s *Server, c *client, args []string) bool {
+	members, err := s.repo.SInter(args...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	replyMembers(c, members)
+	return false
+}
+
+// SUNION key [key ...]
+func sunion(s *Server, c *client, args []string) bool {
+	members, err := s.repo.SUnion(args...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	replyMembers(c, members)
+	return false
+}
+
+// SDIFF key [key ...]
+func sdiff(s *Server, c *client, args []string) bool {
+	members, err := s.repo.SDiff(args...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	replyMembers(c, members)
+	return false
+}
+
+// replyMembers replies with the members as a set.
+func replyMembers(c *client, members []string) {
+	c.writer.setHeader(len(members))
+	for _, member := range members {
+		c.writer.bulk(member)
+	}
+}
@@ -0,0 +1,234 @@
+package resp
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gynshu-one/in-memory-storage/internal/domain"
+)
+
+// ZADD key score member [score member ...]
+// Replies with the number of the new members.
+func zadd(s *Server, c *client, args []string) bool {
+	if len(args)%2 != 1 {
+		c.writer.error("ERR syntax error")
+		return false
+	}
+	members := make(map[string]float64, len(args)/2)
+	for idx := 1; idx < len(args); idx += 2 {
+		score, ok := parseScore(c, args[idx])
+		if !ok {
+			return false
+		}
+		members[args[idx+1]] = score
+	}
+
+	added, err := s.repo.ZAdd(args[0], members)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(added))
+	return false
+}
+
+// ZINCRBY key increment member
+// Replies with the new score.
+func zincrby(s *Server, c *client, args []string) bool {
+	delta, ok := parseScore(c, args[1])
+	if !ok {
+		return false
+	}
+
+	score, err := s.repo.ZIncrBy(args[0], args[2], delta)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.double(score)
+	return false
+}
+
+// ZSCORE key member
+func zscore(s *Server, c *client, args []string) bool {
+	score, err := s.repo.ZScore(args[0], args[1])
+	switch {
+	case isMissing(err) || errors.Is(err, domain.ErrMemberNotFound):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.double(score)
+	}
+	return false
+}
+
+// ZRANK key member
+func zrank(s *Server, c *client, args []string) bool {
+	replyRank(s, c, args, false)
+	return false
+}
+
+// ZREVRANK key member
+func zrevrank(s *Server, c *client, args []string) bool {
+	replyRank(s, c, args, true)
+	return false
+}
+
+func replyRank(s *Server, c *client, args []string, rev bool) {
+	rank, err := s.repo.ZRank(args[0], args[1], rev)
+	switch {
+	case isMissing(err) || errors.Is(err, domain.ErrMemberNotFound):
+		c.writer.null()
+	case err != nil:
+		replyError(c, err)
+	default:
+		c.writer.integer(int64(rank))
+	}
+}
+
+// ZREM key member [member ...]
+// Replies with the number of the removed members.
+func zrem(s *Server, c *client, args []string) bool {
+	n, err := s.repo.ZRem(args[0], args[1:]...)
+	if err != nil {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// ZRANGE key start stop [BYSCORE] [REV] [LIMIT offset count] [WITHSCORES]
+// start and stop are ranks, or score bounds with BYSCORE. With REV the members are ordered from the highest score,
+// and the score bounds are given from the highest, like in Redis: ZRANGE key +inf 100 BYSCORE REV.
+func zrange(s *Server, c *client, args []string) bool {
+	var byScore, rev, withScores, limited bool
+	offset, count := 0, -1
+	for idx := 3; idx < len(args); idx++ {
+		switch strings.ToUpper(args[idx]) {
+		case "BYSCORE":
+			byScore = true
+		case "REV":
+			rev = true
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if idx+2 >= len(args) {
+				c.writer.error("ERR syntax error")
+				return false
+			}
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(args[idx+1])
+			count, err2 = strconv.Atoi(args[idx+2])
+			if err1 != nil || err2 != nil {
+				c.writer.error("ERR value is not an integer or out of range")
+				return false
+			}
+			limited = true
+			idx += 2
+		default:
+			c.writer.error("ERR syntax error")
+			return false
+		}
+	}
+	if limited && !byScore {
+		c.writer.error("ERR syntax error, LIMIT is only supported in combination with BYSCORE")
+		return false
+	}
+
+	var members []domain.ScoredMember
+	var err error
+	if byScore {
+		min, max := args[1], args[2]
+		if rev {
+			min, max = max, min
+		}
+		var minBound, maxBound domain.ScoreBound
+		minBound, err = domain.ParseScoreBound(min)
+		if err == nil {
+			maxBound, err = domain.ParseScoreBound(max)
+		}
+		if err != nil {
+			c.writer.error("ERR min or max is not a float")
+			return false
+		}
+		if offset < 0 {
+			// Like in Redis, a negative offset returns nothing
+			offset, count = 0, 0
+		}
+		members, err = s.repo.ZRangeByScore(args[0], minBound, maxBound, rev, offset, count)
+	} else {
+		start, stop, ok := parseRange(c, args[1], args[2])
+		if !ok {
+			return false
+		}
+		members, err = s.repo.ZRange(args[0], start, stop, rev)
+	}
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+
+	replyScoredMembers(c, members, withScores)
+	return false
+}
+
+// ZCOUNT key min max
+func zcount(s *Server, c *client, args []string) bool {
+	min, err := domain.ParseScoreBound(args[1])
+	if err != nil {
+		c.writer.error("ERR min or max is not a float")
+		return false
+	}
+	max, err := domain.ParseScoreBound(args[2])
+	if err != nil {
+		c.writer.error("ERR min or max is not a float")
+		return false
+	}
+
+	n, err := s.repo.ZCount(args[0], min, max)
+	if err != nil && !isMissing(err) {
+		replyError(c, err)
+		return false
+	}
+	c.writer.integer(int64(n))
+	return false
+}
+
+// replyScoredMembers replies with the members, followed by their scores with withScores.
+// RESP3 clients get a pair per member, RESP2 clients a flat array.
+func replyScoredMembers(c *client, members []domain.ScoredMember, withScores bool) {
+	switch {
+	case !withScores:
+		c.writer.array(len(members))
+		for _, m := range members {
+			c.writer.bulk(m.Member)
+		}
+	case c.writer.proto < 3:
+		c.writer.array(2 * len(members))
+		for _, m := range members {
+			c.writer.bulk(m.Member)
+			c.writer.double(m.Score)
+		}
+	default:
+		c.writer.array(len(members))
+		for _, m := range members {
+			c.writer.array(2)
+			c.writer.bulk(m.Member)
+			c.writer.double(m.Score)
+		}
+	}
+}
+
+// parseScore parses a score, replying with an error when it is not a float.
+func parseScore(c *client, raw string) (float64, bool) {
+	score, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(score) {
+		c.writer.error("ERR value is not a valid float")
+		return 0, false
+	}
+	return score, true
+}
@@ -0,0 +1,192 @@
+// Package tcpserver runs the TCP listeners of the storage protocols,
+// serving every connection in its own goroutine and tracking them for the graceful shutdown.
+package tcpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Shutdown was called.
+var ErrServerClosed = errors.New("tcpserver: server closed")
+
+// shutdownPollInterval is how often Shutdown checks whether the connections are done.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Handler serves a connection until the client is done.
+// It must return once a read fails or after the command in progress when ShuttingDown reports true.
+// The connection is closed when the handler returns.
+type Handler func(conn net.Conn)
+
+// Server accepts TCP connections and passes them to the handler.
+type Server struct {
+	addr    string
+	handler Handler
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closing  bool
+
+	// ctx is canceled when Shutdown is called.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	accepted atomic.Uint64
+}
+
+// New creates a server listening on addr, e.g. ":6379".
+func New(addr string, handler Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		addr:    addr,
+		handler: handler,
+		conns:   make(map[net.Conn]struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Context returns a context canceled when Shutdown is called.
+// Handlers pass it to the operations that block waiting for other clients, so they are released on shutdown.
+func (s *Server) Context() context.Context {
+	return s.ctx
+}
+
+// ListenAndServe listens on the address of the server and serves the connections,
+// it blocks until Shutdown is called and then returns ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves each in its own goroutine,
+// it blocks until Shutdown is called and then returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		_ = l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.ShuttingDown() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.untrack(conn)
+	defer conn.Close()
+	s.handler(conn)
+}
+
+// Addr returns the address the server listens on, or nil before it started serving.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Shutdown stops accepting connections and waits for the handlers to return.
+// The pending reads are interrupted, so idle connections are closed right away
+// and busy ones once their command is answered.
+// If ctx is done first, the remaining connections are closed and the ctx error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	s.cancel()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.Active() == 0 {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			for conn := range s.conns {
+				_ = conn.Close()
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ShuttingDown reports whether Shutdown was called.
+func (s *Server) ShuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// Active returns the number of open connections.
+func (s *Server) Active() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Accepted returns the number of connections accepted since the start.
+func (s *Server) Accepted() uint64 {
+	return s.accepted.Load()
+}
+
+// track registers the connection, it returns false when the server is shutting down.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.accepted.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
@@ -0,0 +1,84 @@
+package tcpserver
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echo writes back every line until the server shuts down.
+func echo(s *Server) Handler {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if _, err = conn.Write([]byte(line)); err != nil || s.ShuttingDown() {
+				return
+			}
+		}
+	}
+}
+
+func startEcho(t *testing.T, handler func(s *Server) Handler) (*Server, string) {
+	s := New("127.0.0.1:0", nil)
+	s.handler = handler(s)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+	t.Cleanup(func() {
+		_ = s.Shutdown(context.Background())
+		assert.ErrorIs(t, <-served, ErrServerClosed)
+	})
+	return s, l.Addr().String()
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	s, addr := startEcho(t, echo)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping\n"))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "ping\n", line)
+	assert.Equal(t, 1, s.Active())
+	assert.Equal(t, uint64(1), s.Accepted())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx), "the idle connection is closed right away")
+	assert.Zero(t, s.Active())
+
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err, "new connections are refused")
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	s, addr := startEcho(t, func(*Server) Handler {
+		// The handler ignores the interrupted read, like a command that takes too long.
+		return func(net.Conn) { <-release }
+	})
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Eventually(t, func() bool { return s.Active() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded)
+}